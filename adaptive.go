@@ -0,0 +1,96 @@
+package processor
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithAdaptiveBatchTimeout makes the batch timeout follow the arrival rate,
+// between minTimeout and maxTimeout. The timeout is set to the time a batch
+// takes to fill at the recent arrival rate: it shrinks under high load, where
+// batches fill quickly anyway, and stretches when traffic is sparse so
+// exports stay efficient. The batch timeout set with WithBatchTimeout is used
+// until the arrival rate is known.
+func WithAdaptiveBatchTimeout(minTimeout, maxTimeout time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.AdaptiveMinBatchTimeout = minTimeout
+		o.AdaptiveMaxBatchTimeout = maxTimeout
+	}
+}
+
+// adaptiveTimeout derives the batch timeout from the arrival rate. Arrivals
+// are counted by the batch builder, and the rate is sampled each time a batch
+// is flushed or the timer fires.
+type adaptiveTimeout struct {
+	minTimeout time.Duration
+	maxTimeout time.Duration
+	batchSize  int
+
+	// rate is an exponentially weighted moving average of arrivals per
+	// second. Only the batch builder touches rate, arrivals and since.
+	rate     float64
+	arrivals int
+	since    time.Time
+
+	current atomic.Int64
+}
+
+func newAdaptiveTimeout(minTimeout, maxTimeout, initial time.Duration, batchSize int) *adaptiveTimeout {
+	a := &adaptiveTimeout{
+		minTimeout: minTimeout,
+		maxTimeout: maxTimeout,
+		batchSize:  batchSize,
+		since:      time.Now(),
+	}
+
+	a.current.Store(int64(min(max(initial, minTimeout), maxTimeout)))
+
+	return a
+}
+
+// arrived counts an item dequeued by the batch builder.
+func (a *adaptiveTimeout) arrived() {
+	a.arrivals++
+}
+
+// sample folds the arrivals since the last sample in to the rate and updates
+// the timeout.
+func (a *adaptiveTimeout) sample(now time.Time) {
+	elapsed := now.Sub(a.since)
+	if elapsed <= 0 {
+		return
+	}
+
+	rate := float64(a.arrivals) / elapsed.Seconds()
+
+	if a.rate == 0 {
+		a.rate = rate
+	} else {
+		a.rate += (rate - a.rate) / 4
+	}
+
+	a.arrivals = 0
+	a.since = now
+
+	timeout := a.maxTimeout
+	if a.rate > 0 {
+		timeout = time.Duration(float64(a.batchSize) / a.rate * float64(time.Second))
+	}
+
+	a.current.Store(int64(min(max(timeout, a.minTimeout), a.maxTimeout)))
+}
+
+// timeout returns the current batch timeout.
+func (a *adaptiveTimeout) timeout() time.Duration {
+	return time.Duration(a.current.Load())
+}
+
+// batchTimeout returns the batch timeout, adapted to the arrival rate if
+// WithAdaptiveBatchTimeout is set.
+func (bvp *BatchItemProcessor[T]) batchTimeout() time.Duration {
+	if bvp.adaptive != nil {
+		return bvp.adaptive.timeout()
+	}
+
+	return bvp.o.BatchTimeout
+}
@@ -0,0 +1,47 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeout(t *testing.T) {
+	start := time.Now()
+	a := newAdaptiveTimeout(10*time.Millisecond, time.Second, 5*time.Second, 100)
+	a.since = start
+
+	if got := a.timeout(); got != time.Second {
+		t.Fatalf("expected the initial timeout to be clamped to 1s, got %s", got)
+	}
+
+	// 1000 items a second fill a batch of 100 in 100ms.
+	for range 1000 {
+		a.arrived()
+	}
+
+	a.sample(start.Add(time.Second))
+
+	if got := a.timeout(); got != 100*time.Millisecond {
+		t.Errorf("expected 100ms, got %s", got)
+	}
+
+	// A burst pushes the timeout down to the minimum.
+	for range 100000 {
+		a.arrived()
+	}
+
+	a.sample(start.Add(2 * time.Second))
+
+	if got := a.timeout(); got != 10*time.Millisecond {
+		t.Errorf("expected the minimum of 10ms, got %s", got)
+	}
+
+	// Without arrivals the timeout stretches back towards the maximum.
+	for i := range 20 {
+		a.sample(start.Add(time.Duration(3+i) * time.Second))
+	}
+
+	if got := a.timeout(); got != time.Second {
+		t.Errorf("expected the maximum of 1s, got %s", got)
+	}
+}
@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// discardExporter drops every batch without allocating.
+type discardExporter[T any] struct{}
+
+func (discardExporter[T]) ExportItems(_ context.Context, _ []*T) error {
+	return nil
+}
+
+func (discardExporter[T]) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func newAllocProcessor(tb testing.TB) *BatchItemProcessor[int] {
+	tb.Helper()
+
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	proc, err := NewBatchItemProcessor[int](
+		discardExporter[int]{},
+		"alloc",
+		log,
+		WithMaxQueueSize(1<<16),
+		WithMaxExportBatchSize(512),
+		WithWorkers(1),
+	)
+	if err != nil {
+		tb.Fatalf("failed to create processor: %v", err)
+	}
+
+	if err := proc.Start(context.Background()); err != nil {
+		tb.Fatalf("failed to start: %v", err)
+	}
+
+	tb.Cleanup(func() {
+		proc.Shutdown(context.Background())
+	})
+
+	return proc
+}
+
+func BenchmarkBatchItemProcessor_Write(b *testing.B) {
+	proc := newAllocProcessor(b)
+	ctx := context.Background()
+
+	items := make([]*int, 512)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		// Keep the writer from outpacing the exporter.
+		for proc.queuedItems() > 1<<15 {
+			runtime.Gosched()
+		}
+
+		if err := proc.Write(ctx, items); err != nil {
+			b.Fatalf("failed to write items: %v", err)
+		}
+	}
+
+	b.ReportMetric(float64(b.N*len(items))/b.Elapsed().Seconds(), "items/s")
+}
+
+// TestBatchItemProcessor_WriteAllocations enforces that the Write, enqueue
+// and batch path doesn't allocate per item. Allocations per write and per
+// export are amortized over the batch.
+func TestBatchItemProcessor_WriteAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector makes pools drop items")
+	}
+
+	proc := newAllocProcessor(t)
+	ctx := context.Background()
+
+	items := make([]*int, 512)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	write := func() {
+		// Let exports keep up, so exported items are recycled.
+		for proc.queuedItems() > 0 {
+			runtime.Gosched()
+		}
+
+		if err := proc.Write(ctx, items); err != nil {
+			t.Fatalf("failed to write items: %v", err)
+		}
+	}
+
+	// Warm up the item pool.
+	for range 10 {
+		write()
+	}
+
+	allocs := testing.AllocsPerRun(200, write)
+
+	if perItem := allocs / float64(len(items)); perItem > 0.1 {
+		t.Errorf("expected no allocations per item, got %.2f (%.0f per write of %d)", perItem, allocs, len(items))
+	}
+}
+
+// TestBatchItemProcessor_WriteValuesAllocations enforces that WriteValues
+// reuses the buffers holding values once they are exported, rather than
+// allocating them per write on top of what Write allocates.
+func TestBatchItemProcessor_WriteValuesAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector makes pools drop items")
+	}
+
+	proc := newAllocProcessor(t)
+	ctx := context.Background()
+
+	values := make([]int, 512)
+	items := make([]*int, len(values))
+
+	for i := range values {
+		values[i] = i
+		items[i] = &values[i]
+	}
+
+	// Let exports keep up, so exported items and values are recycled.
+	drain := func() {
+		for proc.queuedItems() > 0 {
+			runtime.Gosched()
+		}
+	}
+
+	write := func() {
+		drain()
+
+		if err := proc.Write(ctx, items); err != nil {
+			t.Fatalf("failed to write items: %v", err)
+		}
+	}
+
+	writeValues := func() {
+		drain()
+
+		if err := proc.WriteValues(ctx, values); err != nil {
+			t.Fatalf("failed to write values: %v", err)
+		}
+	}
+
+	// Warm up the item and value pools.
+	for range 10 {
+		write()
+		writeValues()
+	}
+
+	writeAllocs := testing.AllocsPerRun(200, write)
+	valueAllocs := testing.AllocsPerRun(200, writeValues)
+
+	if valueAllocs > writeAllocs {
+		t.Errorf("expected WriteValues to allocate no more than Write, got %.0f allocations per write, Write %.0f", valueAllocs, writeAllocs)
+	}
+}
@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes how long to wait before a retry.
+type Backoff interface {
+	// Next returns the delay before the given retry attempt, starting at 1.
+	Next(attempt int) time.Duration
+}
+
+// BackoffConfig is an exponential backoff with jitter. The delay before
+// attempt n is InitialInterval * Multiplier^(n-1), capped at MaxInterval, then
+// randomized by up to ±Jitter of its value.
+type BackoffConfig struct {
+	// InitialInterval is the delay before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval caps the delay. Zero means no cap.
+	MaxInterval time.Duration
+	// Multiplier is the growth factor between attempts. Values below 1 are
+	// treated as 1.
+	Multiplier float64
+	// Jitter is the fraction of the delay, between 0 and 1, that is randomized.
+	Jitter float64
+}
+
+var _ Backoff = BackoffConfig{}
+
+// DefaultBackoffConfig returns a backoff starting at 100ms, doubling up to 30s
+// with 20% jitter.
+func DefaultBackoffConfig() BackoffConfig {
+	return BackoffConfig{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     30 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+	}
+}
+
+// Next returns the delay before the given retry attempt.
+func (c BackoffConfig) Next(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	multiplier := math.Max(c.Multiplier, 1)
+	delay := float64(c.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+
+	if c.MaxInterval > 0 && delay > float64(c.MaxInterval) {
+		delay = float64(c.MaxInterval)
+	}
+
+	if jitter := math.Min(math.Max(c.Jitter, 0), 1); jitter > 0 {
+		//nolint:gosec // Jitter doesn't need a cryptographic source.
+		delay += delay * jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// ConstantBackoff waits the same duration before every retry.
+type ConstantBackoff time.Duration
+
+// Next returns the constant delay.
+func (b ConstantBackoff) Next(_ int) time.Duration {
+	return time.Duration(b)
+}
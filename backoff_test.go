@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffConfig_Next(t *testing.T) {
+	b := BackoffConfig{
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     time.Second,
+		Multiplier:      2,
+	}
+
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+
+	for i, w := range want {
+		if got := b.Next(i + 1); got != w {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, w, got)
+		}
+	}
+
+	b.Jitter = 0.5
+
+	for i := 0; i < 100; i++ {
+		if got := b.Next(1); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("expected jittered delay within 50%%, got %v", got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package processor
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// minQueueFullBackoff and maxQueueFullBackoff bound how often a blocked
+	// write checks the queue for space.
+	minQueueFullBackoff = 50 * time.Microsecond
+	maxQueueFullBackoff = 5 * time.Millisecond
+)
+
+// WithBlockOnQueueFull makes writes wait for queue space, applying
+// backpressure to callers, instead of dropping items when the queue is full.
+// A write blocks until its items are queued, its context is done or the
+// processor shuts down, and returns the context's error or ErrShuttingDown
+// in the latter cases. Items not queued because of it aren't counted as
+// dropped. Time spent blocked is recorded in producer_blocked_duration_seconds.
+//
+// It can't be combined with write coalescing or an overflow writer.
+func WithBlockOnQueueFull(block bool) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.BlockOnQueueFull = block
+	}
+}
+
+// waitToEnqueue retries enqueueing an item until there is room for it, ctx
+// is done or the processor shuts down.
+func (bvp *BatchItemProcessor[T]) waitToEnqueue(ctx context.Context, item *TraceableItem[T]) error {
+	start := time.Now()
+	defer func() {
+		bvp.metrics.ObserveProducerBlockedDuration(bvp.label, time.Since(start))
+	}()
+
+	backoff := minQueueFullBackoff
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-bvp.stopCh:
+			return ErrShuttingDown
+		case <-timer.C:
+		}
+
+		if bvp.enqueue(item) {
+			return nil
+		}
+
+		backoff = min(backoff*2, maxQueueFullBackoff)
+		timer.Reset(backoff)
+	}
+}
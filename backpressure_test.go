@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_BlockOnQueueFull(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "block-on-queue-full-test", log,
+		WithMaxQueueSize(2),
+		WithMaxExportBatchSize(2),
+		WithBatchTimeout(10*time.Millisecond),
+		WithWorkers(1),
+		WithBlockOnQueueFull(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// The processor isn't started, so the queue fills after two items.
+	if err := proc.Write(ctx, ints(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+
+	if err := proc.Write(timeoutCtx, ints(1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the write to block until its context is done, got %v", err)
+	}
+
+	if got := counterValue(t, DefaultMetrics.itemsDropped.WithLabelValues("block-on-queue-full-test")); got != 0 {
+		t.Fatalf("expected nothing dropped, got %v", got)
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- proc.Write(ctx, ints(2))
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected the write to block, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Starting the processor frees queue space for the blocked write.
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 4 {
+		t.Fatalf("expected 4 items exported, got %d", got)
+	}
+}
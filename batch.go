@@ -2,29 +2,41 @@
 package processor
 
 import (
+	"container/list"
 	"context"
 	"errors"
 	"fmt"
 	"runtime"
+	"slices"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ItemExporter is an interface for exporting items.
 type ItemExporter[T any] interface {
 	// ExportItems exports a batch of items.
 	//
+	// The items belong to the caller, but with WithZeroCopyExport the slice
+	// holding them is reused for later batches once ExportItems returns, so
+	// exporters that keep it, e.g. to export asynchronously, must copy it.
+	//
 	// This function is called synchronously, so there is no concurrency
 	// safety requirement. However, due to the synchronous calling pattern,
 	// it is critical that all timeouts and cancellations contained in the
 	// passed context must be honored.
 	//
-	// Any retry logic must be contained in this function. The SDK that
-	// calls this function will not implement any retry logic. All errors
-	// returned by this function are considered unrecoverable and will be
-	// reported to a configured error Handler.
+	// Unless the processor is configured with WithRetry, it does not retry
+	// failed exports: any retry logic must be contained in this function.
+	// With WithRetry, a failed export is retried with the same items unless
+	// the error is, or wraps, a *PartialExportError or ErrExporterClosed, or
+	// the context is done. Errors that are not retried, and the error of the
+	// last attempt, are considered unrecoverable and will be reported to a
+	// configured error Handler.
 	ExportItems(ctx context.Context, items []*T) error
 
 	// Shutdown notifies the exporter of a pending halt to operations. The
@@ -34,13 +46,52 @@ type ItemExporter[T any] interface {
 	Shutdown(ctx context.Context) error
 }
 
+// ExporterStarter is an optional interface for exporters that set up
+// connections or other resources before exporting. The processor calls Start
+// from its own Start, so setup errors surface at startup rather than on the
+// first batch.
+type ExporterStarter interface {
+	// Start prepares the exporter. It is called once, before any batch is
+	// exported.
+	Start(ctx context.Context) error
+}
+
 const (
-	DefaultMaxQueueSize       = 51200
-	DefaultScheduleDelay      = 5000
-	DefaultExportTimeout      = 30000
-	DefaultMaxExportBatchSize = 512
-	DefaultShippingMethod     = ShippingMethodAsync
-	DefaultNumWorkers         = 5
+	DefaultMaxQueueSize        = 51200
+	DefaultScheduleDelay       = 5000
+	DefaultExportTimeout       = 30000
+	DefaultMaxExportBatchSize  = 512
+	DefaultShippingMethod      = ShippingMethodAsync
+	DefaultThroughputWindow    = 10000
+	DefaultTriggerInterval     = 100
+	DefaultHealthCheckInterval = 10000
+	DefaultPipelineDepth       = 1
+	DefaultQueueKind           = QueueKindChannel
+
+	DefaultDiskBufferFailureThreshold = 3
+	DefaultDiskBufferReplayInterval   = 5000
+)
+
+// DefaultNumWorkers was the fixed default worker count.
+//
+// Deprecated: the default worker count is derived from runtime.GOMAXPROCS and
+// the queue and batch sizes. Use WithWorkers to set it explicitly.
+const DefaultNumWorkers = 5
+
+// autoWorkers marks Workers as unset, so the default is derived once all
+// options are applied.
+const autoWorkers = -1
+
+var (
+	// ErrQueueFull is returned when an item is dropped because the queue is full.
+	ErrQueueFull = errors.New("queue is full")
+	// ErrQuotaExceeded is returned when an item is dropped because its
+	// producer already has its quota of items queued.
+	ErrQuotaExceeded = errors.New("producer quota exceeded")
+	// ErrShuttingDown is returned when an item is written after Shutdown was called.
+	ErrShuttingDown = errors.New("processor is shutting down")
+	// ErrNilItem is reported by WriteEach for nil items, which are dropped.
+	ErrNilItem = errors.New("item is nil")
 )
 
 // ShippingMethod is the method of shipping items for export.
@@ -52,6 +103,18 @@ const (
 	ShippingMethodSync    ShippingMethod = "sync"
 )
 
+// ShutdownOutcome describes how a call to Shutdown finished.
+type ShutdownOutcome string
+
+const (
+	// ShutdownOutcomeSuccess means the queue was drained and the exporter shut down cleanly.
+	ShutdownOutcomeSuccess ShutdownOutcome = "success"
+	// ShutdownOutcomeTimeout means the context expired before the queue was drained.
+	ShutdownOutcomeTimeout ShutdownOutcome = "timeout"
+	// ShutdownOutcomeError means the queue was drained but the exporter failed to shut down.
+	ShutdownOutcomeError ShutdownOutcome = "error"
+)
+
 // BatchItemProcessorOption is a functional option for the batch item processor.
 type BatchItemProcessorOption func(o *BatchItemProcessorOptions)
 
@@ -68,7 +131,7 @@ type BatchItemProcessorOptions struct {
 	BatchTimeout time.Duration
 
 	// ExportTimeout specifies the maximum duration for exporting items. If the timeout
-	// is reached, the export will be cancelled.
+	// is reached, the export will be cancelled. Each retry gets its own timeout.
 	// The default value of ExportTimeout is 30000 msec.
 	ExportTimeout time.Duration
 
@@ -80,26 +143,389 @@ type BatchItemProcessorOptions struct {
 	// of ShippingMethod is "async".
 	ShippingMethod ShippingMethod
 
+	// WriteCoalescingSize enables write coalescing when greater than zero. Async
+	// writes with fewer items than WriteCoalescingSize are buffered and enqueued
+	// together once the buffer holds WriteCoalescingSize items or
+	// WriteCoalescingDelay has passed since the first buffered item.
+	// Write coalescing is disabled by default.
+	WriteCoalescingSize int
+
+	// WriteCoalescingDelay is the maximum time a coalesced write is buffered
+	// before it is enqueued.
+	WriteCoalescingDelay time.Duration
+
+	// WriteAcceptance is what Write does with the rest of a write once the
+	// queue fills up. The default value of WriteAcceptance is "all". Set it
+	// with WithWriteAcceptance.
+	WriteAcceptance WriteAcceptance
+
+	// DropPolicy chooses which items are dropped when the queue is full.
+	// The default value of DropPolicy is "newest". Set it with
+	// WithDropPolicy.
+	DropPolicy DropPolicy
+
+	// BlockOnQueueFull makes writes wait for queue space instead of dropping
+	// items. Set it with WithBlockOnQueueFull.
+	BlockOnQueueFull bool
+
+	// CancelBehavior is what the processor does when the context passed
+	// to Start is cancelled. The default value of CancelBehavior is "none".
+	// Set it with WithCancelBehavior.
+	CancelBehavior CancelBehavior
+
+	// RetryMaxAttempts is the number of attempts made at exporting a batch,
+	// waiting RetryBackoff between them. Zero or one disables retries. Set
+	// them with WithRetry.
+	RetryMaxAttempts int
+	RetryBackoff     Backoff
+
+	// ExportRateLimit caps the items exported per second, allowing bursts
+	// of ExportRateBurst. Zero disables the limit. Set them with
+	// WithExportRateLimit.
+	ExportRateLimit float64
+	ExportRateBurst int
+
 	// Workers is the number of workers to process batches.
-	// The default value of Workers is 5.
+	// The default value of Workers is runtime.GOMAXPROCS, capped at the
+	// number of full batches that fit in the queue.
 	Workers int
 
-	// Metrics is the metrics instance to use.
-	Metrics *Metrics
+	// LazyWorkers defers starting workers until batches arrive, then adds
+	// workers one at a time while batches are waiting. Set it with
+	// WithLazyWorkers.
+	LazyWorkers bool
+
+	// DropSummary summarizes dropped items for the sampled drop log. Set it
+	// with WithDropLogging.
+	DropSummary any
+
+	// DropLogEvery logs one in this many dropped items.
+	DropLogEvery int
+
+	// DropSink receives dropped items. Set it with WithDropSink.
+	DropSink any
+
+	// DeadLetterHandler receives items whose export failed. Set it with
+	// WithDeadLetterHandler.
+	DeadLetterHandler any
+
+	// ExporterReplacer replaces an exporter found permanently closed. Set
+	// it with WithExporterReplacer.
+	ExporterReplacer any
+
+	// Overflow receives items the queue can't hold. Set it with
+	// WithOverflow.
+	Overflow any
+
+	// AdaptiveMinBatchTimeout and AdaptiveMaxBatchTimeout bound the batch
+	// timeout when it adapts to the arrival rate. Set them with
+	// WithAdaptiveBatchTimeout.
+	AdaptiveMinBatchTimeout time.Duration
+	AdaptiveMaxBatchTimeout time.Duration
+
+	// PipelineDepth is the number of batches assembled ahead for each worker
+	// while it exports. The default value of PipelineDepth is 1. Set it with
+	// WithExportPipelining.
+	PipelineDepth int
+
+	// QueueKind selects the queue implementation. The default value of
+	// QueueKind is "channel". Set it with WithQueue.
+	QueueKind QueueKind
+
+	// ConsistentHashing routes keys to workers with a HashRing whose
+	// partitions own HashRingReplicas points each. Set them with
+	// WithConsistentHashing.
+	ConsistentHashing bool
+	HashRingReplicas  int
+
+	// ReadinessTimeout bounds the exporter health check run by Start. Zero
+	// skips the check. Set it with WithReadinessCheck.
+	ReadinessTimeout time.Duration
+
+	// WaitStrategy is how workers wait for batches, spinning for
+	// SpinDuration with WaitStrategySpin. The default value of WaitStrategy
+	// is "block". Set them with WithWaitStrategy.
+	WaitStrategy WaitStrategy
+	SpinDuration time.Duration
+
+	// InvariantChecks asserts internal invariants, passing violations to
+	// InvariantReport. Set them with WithInvariantChecks.
+	InvariantChecks bool
+	InvariantReport func(err error)
+
+	// CheckpointMarker is a func(item *T) any reading an item's source
+	// position, passed to Checkpoint once it and every earlier item is
+	// exported. Set them with WithCheckpointer.
+	CheckpointMarker any
+	Checkpoint       func(ctx context.Context, marker any)
+
+	// WorkStealing lets idle workers export batches routed to busy workers.
+	// Set it with WithWorkStealing.
+	WorkStealing bool
+
+	// ZeroCopyExport reuses each worker's export slice. Set it with
+	// WithZeroCopyExport.
+	ZeroCopyExport bool
+
+	// TimerWheel drives the batch timeout from a wheel shared with other
+	// processors. Set it with WithTimerWheel.
+	TimerWheel *TimerWheel
+
+	// Stepper exports batches and drives the batch timeout in place of
+	// workers and the real clock, for tests. Set it with WithStepper.
+	Stepper *Stepper
+
+	// CapacityCheckInterval is how often the arrival rate is compared with
+	// the export capacity. Zero disables the check. Set it with
+	// WithCapacityAdvisor.
+	CapacityCheckInterval time.Duration
+
+	// DryRun discards batches instead of exporting them. Set it with
+	// WithDryRun.
+	DryRun bool
+
+	// QueueInspection tracks queued items for Peek. Set it with
+	// WithQueueInspection.
+	QueueInspection bool
+
+	// InspectSummary summarizes queued items for Peek.
+	InspectSummary any
+
+	// MaxProducerLabels enables producer labels on enqueue and drop metrics,
+	// bounding the number of distinct producers. Set it with
+	// WithProducerTracking.
+	MaxProducerLabels int
+
+	// WorkerMetrics enables per worker export metrics. Set it with
+	// WithWorkerMetrics.
+	WorkerMetrics bool
+
+	// ContextKeys are the context keys whose values are captured at write
+	// time and set on the export context. Set them with
+	// WithContextPropagation.
+	ContextKeys []any
+
+	// LaneFunc and LaneWeights split the queue in to weighted priority
+	// lanes. Set them with WithPriorityLanes.
+	LaneFunc    any
+	LaneWeights []int
+
+	// PriorityFunc makes the queue a priority queue. Set it with
+	// WithPriorityFunc.
+	PriorityFunc any
+
+	// LaneMaxWait promotes items that waited longer than this in a priority
+	// lane. Zero disables aging.
+	LaneMaxWait time.Duration
+
+	// DeadlineFunc gives items a deadline the batch builder flushes ahead
+	// of. Set it with WithDeadlineFunc.
+	DeadlineFunc any
+
+	// DeadlineLead is the minimum time ahead of an item's deadline at which
+	// its batch is flushed.
+	DeadlineLead time.Duration
+
+	// MaxBatchAge flushes a batch once its oldest item has been queued this
+	// long, however recently the batch timeout was reset. Zero disables it.
+	// Set it with WithMaxBatchAge.
+	MaxBatchAge time.Duration
+
+	// MaxQueueLatency is the target time within which admitted items are
+	// exported. Zero disables it. Set it with WithMaxQueueLatency.
+	MaxQueueLatency time.Duration
+
+	// TracerProvider enables export spans linked to the spans that wrote
+	// their items. Tracing is disabled when nil.
+	TracerProvider trace.TracerProvider
+
+	// ExporterFactory creates a dedicated exporter for each worker. Set it
+	// with WithExporterFactory.
+	ExporterFactory any
+
+	// WorkerIdleTimeout stops workers that haven't received a batch for this
+	// long. Stopped workers are started again on demand, so idle processors
+	// scale to zero goroutines. Zero keeps workers running.
+	WorkerIdleTimeout time.Duration
+
+	// Metrics is the metrics recorder to use. The default value of Metrics is
+	// DefaultMetrics.
+	Metrics MetricsRecorder
+
+	// ThroughputWindow is the rolling window over which export throughput is
+	// calculated. The default value of ThroughputWindow is 10000 msec.
+	ThroughputWindow time.Duration
+
+	// LabelGuard sanitizes and bounds the processor name used as a metric
+	// label. The default value of LabelGuard is DefaultLabelGuard.
+	LabelGuard *LabelGuard
+
+	// KeyFunc is an optional func(item *T) any deriving the key used by
+	// KeyGrouping, KeyOrdering and KeyDedup. Set it with WithKeyFunc.
+	KeyFunc any
+
+	// KeyGrouping splits batches by key before export.
+	KeyGrouping bool
+
+	// KeyOrdering routes items to workers by key, preserving per-key order.
+	KeyOrdering bool
+
+	// KeyDedup removes items with duplicate keys from each batch.
+	KeyDedup bool
+
+	// Triggers are custom flush conditions evaluated against the batch being
+	// assembled, in addition to MaxExportBatchSize and BatchTimeout. A batch is
+	// flushed as soon as any trigger fires.
+	Triggers []Trigger
+
+	// TriggerInterval is how often Triggers are evaluated while no items are
+	// arriving, so time-based triggers can fire. Triggers are also evaluated
+	// whenever an item is added to the batch.
+	// The default value of TriggerInterval is 100 msec.
+	TriggerInterval time.Duration
+
+	// DiskBufferDir enables the disk buffer when set. Batches that fail to
+	// export are written to this directory and replayed once the exporter
+	// recovers. Set it with WithDiskBuffer.
+	DiskBufferDir string
+
+	// DiskBufferCodec is the Codec[T] used to serialize buffered batches.
+	DiskBufferCodec any
+
+	// DiskBufferFailureThreshold is the number of consecutive export failures
+	// after which batches are written straight to disk without trying the
+	// exporter. The default value of DiskBufferFailureThreshold is 3.
+	DiskBufferFailureThreshold int
+
+	// DiskBufferReplayInterval is how often buffered batches are replayed.
+	// The default value of DiskBufferReplayInterval is 5000 msec.
+	DiskBufferReplayInterval time.Duration
+
+	// DiskBufferMaxBytes bounds the size of the disk buffer. Zero means unbounded.
+	DiskBufferMaxBytes int64
+
+	// HandoffPath is where queued items are handed to the next process
+	// generation on Shutdown, serialized with HandoffCodec, a Codec[T].
+	// Set them with WithHandoff.
+	HandoffPath  string
+	HandoffCodec any
+
+	// HealthCheckInterval is how often exporters implementing HealthChecker
+	// are probed. Zero disables probing.
+	// The default value of HealthCheckInterval is 10000 msec.
+	HealthCheckInterval time.Duration
+
+	// Sizer is an optional func(item *T) int that reports the size of an item
+	// in bytes. It is used to calculate byte throughput and the exported
+	// bytes metrics. Set it with WithSizer.
+	Sizer any
 }
 
-// Validate validates the options.
+// Validate validates the options. It reports every problem found, as a
+// *ValidationError, rather than stopping at the first.
 func (o *BatchItemProcessorOptions) Validate() error {
-	if o.MaxExportBatchSize > o.MaxQueueSize {
-		return errors.New("max export batch size cannot be greater than max queue size")
+	var problems []error
+
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(o.MaxExportBatchSize <= o.MaxQueueSize,
+		"max export batch size (%d) cannot be greater than max queue size (%d)", o.MaxExportBatchSize, o.MaxQueueSize)
+	check(o.Workers >= 1, "workers must be greater than 0, got %d", o.Workers)
+	check(o.MaxExportBatchSize >= 1, "max export batch size must be greater than 0, got %d", o.MaxExportBatchSize)
+	check(o.PipelineDepth >= 1, "pipeline depth must be greater than 0, got %d", o.PipelineDepth)
+	check(o.BatchTimeout >= 0, "batch timeout must not be negative, got %s", o.BatchTimeout)
+	check(o.ExportTimeout >= 0, "export timeout must not be negative, got %s", o.ExportTimeout)
+
+	if o.AdaptiveMaxBatchTimeout > 0 {
+		check(o.AdaptiveMinBatchTimeout > 0,
+			"adaptive min batch timeout must be greater than 0, got %s", o.AdaptiveMinBatchTimeout)
+		check(o.AdaptiveMinBatchTimeout <= o.AdaptiveMaxBatchTimeout,
+			"adaptive min batch timeout (%s) cannot be greater than the max (%s)",
+			o.AdaptiveMinBatchTimeout, o.AdaptiveMaxBatchTimeout)
+	}
+
+	if o.WriteCoalescingSize > 0 {
+		check(o.ShippingMethod == ShippingMethodAsync,
+			"write coalescing requires the async shipping method, got %s", o.ShippingMethod)
+		check(o.WriteCoalescingDelay > 0, "write coalescing delay must be greater than 0, got %s", o.WriteCoalescingDelay)
+		check(!o.BlockOnQueueFull, "write coalescing can't block on a full queue")
 	}
 
-	if o.Workers == 0 {
-		return errors.New("workers must be greater than 0")
+	check(!(o.KeyGrouping || o.KeyOrdering || o.KeyDedup) || o.KeyFunc != nil,
+		"key grouping, ordering and dedup require a key func")
+	check(!o.WorkStealing || o.KeyOrdering, "work stealing requires key ordering")
+	check(!o.ConsistentHashing || o.KeyOrdering, "consistent hashing requires key ordering")
+	check(o.WaitStrategy == WaitStrategyBlock || o.WaitStrategy == WaitStrategySpin,
+		"unknown wait strategy %q", o.WaitStrategy)
+	check(!o.BlockOnQueueFull || o.Overflow == nil, "blocking on a full queue leaves nothing to overflow")
+	check(o.DropPolicy == DropNewest || o.DropPolicy == DropOldest, "unknown drop policy %q", o.DropPolicy)
+
+	if o.DropPolicy == DropOldest {
+		check(o.QueueKind == QueueKindChannel, "the oldest drop policy can't be used with the %s queue", o.QueueKind)
+		check(o.LaneFunc == nil, "the oldest drop policy can't be used with priority lanes")
+		check(o.PriorityFunc == nil, "the oldest drop policy can't be used with a priority func")
+		check(o.Overflow == nil, "the oldest drop policy leaves nothing to overflow")
+		check(!o.BlockOnQueueFull, "the oldest drop policy can't block on a full queue")
+	}
+	check(o.WriteAcceptance == WriteAcceptanceAll || o.WriteAcceptance == WriteAcceptancePartial,
+		"unknown write acceptance %q", o.WriteAcceptance)
+	check(slices.Contains([]CancelBehavior{CancelBehaviorNone, CancelBehaviorDrain, CancelBehaviorStop}, o.CancelBehavior),
+		"unknown cancel behavior %q", o.CancelBehavior)
+	check(o.HashRingReplicas >= 0, "hash ring replicas must not be negative, got %d", o.HashRingReplicas)
+	check(len(o.Triggers) == 0 || o.TriggerInterval > 0,
+		"trigger interval must be greater than 0, got %s", o.TriggerInterval)
+
+	if o.DiskBufferDir != "" {
+		check(o.DiskBufferCodec != nil, "disk buffer requires a codec")
+		check(o.DiskBufferFailureThreshold >= 1,
+			"disk buffer failure threshold must be greater than 0, got %d", o.DiskBufferFailureThreshold)
+		check(o.DiskBufferReplayInterval > 0,
+			"disk buffer replay interval must be greater than 0, got %s", o.DiskBufferReplayInterval)
+	}
+
+	check(o.Stepper == nil || o.TimerWheel == nil, "a stepper can't be combined with a timer wheel")
+	check(o.HandoffPath == "" || o.HandoffCodec != nil, "handoff requires a codec")
+	check(o.CapacityCheckInterval >= 0,
+		"capacity check interval must not be negative, got %s", o.CapacityCheckInterval)
+	check(o.ReadinessTimeout >= 0, "readiness timeout must not be negative, got %s", o.ReadinessTimeout)
+	check(o.WorkerIdleTimeout >= 0, "worker idle timeout must not be negative, got %s", o.WorkerIdleTimeout)
+
+	if o.LaneFunc != nil {
+		if err := validateLaneWeights(o.LaneWeights); err != nil {
+			problems = append(problems, err)
+		}
+
+		check(o.QueueKind == QueueKindChannel, "priority lanes can't be used with the %s queue", o.QueueKind)
+	}
+
+	if o.PriorityFunc != nil {
+		check(o.QueueKind == QueueKindChannel, "a priority func can't be used with the %s queue", o.QueueKind)
+		check(o.LaneFunc == nil, "a priority func can't be used with priority lanes")
 	}
 
-	if o.MaxExportBatchSize < 1 {
-		return errors.New("max export batch size must be greater than 0")
+	check(slices.Contains([]QueueKind{QueueKindChannel, QueueKindRing, QueueKindSegments, QueueKindSharded}, o.QueueKind),
+		"unknown queue kind %q", o.QueueKind)
+
+	check(o.DropLogEvery >= 0, "drop log sample rate must not be negative, got %d", o.DropLogEvery)
+	check(o.MaxProducerLabels >= 0, "max producer labels must not be negative, got %d", o.MaxProducerLabels)
+	check(o.LaneMaxWait >= 0, "lane max wait must not be negative, got %s", o.LaneMaxWait)
+	check(o.DeadlineLead >= 0, "deadline lead must not be negative, got %s", o.DeadlineLead)
+	check(o.MaxBatchAge >= 0, "max batch age must not be negative, got %s", o.MaxBatchAge)
+	check(o.MaxQueueLatency >= 0, "max queue latency must not be negative, got %s", o.MaxQueueLatency)
+	check(o.RetryMaxAttempts >= 0, "retry max attempts must not be negative, got %d", o.RetryMaxAttempts)
+	check(o.RetryMaxAttempts <= 1 || o.RetryBackoff != nil, "retries require a backoff")
+	check(o.ExportRateLimit >= 0, "export rate limit must not be negative, got %v", o.ExportRateLimit)
+	check(o.ExportRateLimit == 0 || o.ExportRateBurst > 0,
+		"export rate burst must be greater than 0, got %d", o.ExportRateBurst)
+	check(o.ThroughputWindow >= time.Second,
+		"throughput window must be at least one second, got %s", o.ThroughputWindow)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
 	}
 
 	return nil
@@ -110,24 +536,127 @@ type BatchItemProcessor[T any] struct {
 	e ItemExporter[T]
 	o BatchItemProcessorOptions
 
-	log logrus.FieldLogger
-
-	queue   chan *TraceableItem[T]
-	batchCh chan []*TraceableItem[T]
-	name    string
+	// workerExporters holds a dedicated exporter per worker when an
+	// exporter factory is configured.
+	workerExporters []ItemExporter[T]
 
-	timer         *time.Timer
-	stopWait      sync.WaitGroup
-	stopOnce      sync.Once
-	stopCh        chan struct{}
-	stopWorkersCh chan struct{}
+	log logrus.FieldLogger
 
-	metrics *Metrics
+	queue     chan *TraceableItem[T]
+	lanes     *laneQueue[T]
+	staged    *stagedQueue[T]
+	priority  *PriorityQueue[*TraceableItem[T]]
+	cutCh     chan cutBatch[T]
+	batchCh   chan []*TraceableItem[T]
+	workerChs []chan []*TraceableItem[T]
+	name      string
+	label     string
+
+	timer          flushTimer
+	stopWait       sync.WaitGroup
+	workersMu      sync.Mutex
+	workerCtx      context.Context
+	workerRunning  []bool
+	activity       *workerActivity
+	buffers        *batchBuffers[T]
+	items          traceableItemPool[T]
+	values         valueChunkPool[T]
+	exportBuffers  [][]*T
+	workerLabels   []string
+	activeWorkers  int
+	workersStopped bool
+	stopOnce       sync.Once
+	stopCh         chan struct{}
+	stopWorkersCh  chan struct{}
+	builderDone    chan struct{}
+	flushCh        chan struct{}
+	started        atomic.Bool
+	metricsClaimed atomic.Bool
+
+	// pending counts items queued and not yet settled, for Drain and
+	// ForceFlush.
+	pending pendingItems
+
+	// discarding drops batches instead of exporting them, once Start's
+	// context is cancelled with CancelBehaviorStop.
+	discarding atomic.Bool
+
+	metrics       *recorder
+	throughput    *throughputMeter
+	arrivals      *throughputMeter
+	sizer         func(item *T) int
+	coalescer     *writeCoalescer[T]
+	keyFunc       func(item *T) any
+	ring          atomic.Pointer[HashRing]
+	invariants    *invariantChecker
+	checkpoints   *checkpointTracker[T]
+	stepper       *Stepper
+	events        eventBus
+	deadlineFunc  DeadlineFunc[T]
+	exportLatency latencyEstimate
+	tracer        trace.Tracer
+	diskBuffer    *diskBuffer[T]
+	handoffCodec  Codec[T]
+	handoffKept   handoffKept[T]
+	health        healthState
+	producers     producerRegistry[T]
+
+	producerLabels *LabelGuard
+
+	dropSummary func(item *T) string
+	drops       atomic.Uint64
+	dropSink    ItemExporter[T]
+	overflow    ItemWriter[T]
+	deadLetters DeadLetterHandler[T]
+	replacer    ExporterReplacer[T]
+	inspector   *queueIndex[T]
+	adaptive    *adaptiveTimeout
+
+	// flowBatchSize and pausedUntil hold the latest flow control hint
+	// from the exporter's sink.
+	flowBatchSize atomic.Int64
+	pausedUntil   atomic.Int64
+
+	// resumed is closed when paused exports resume. It is nil while
+	// exports aren't paused.
+	pauseMu sync.Mutex
+	resumed chan struct{}
+
+	exportRate *tokenBucket
+
+	// turns keeps per-key order across workers with work stealing.
+	turns *keyTurns
+
+	lifecycle exporterLifecycle[T]
 }
 
 // TraceableItem wraps an item with channels for synchronous processing.
 type TraceableItem[T any] struct {
-	item        *T
+	item *T
+	// enqueued is when the item was queued.
+	enqueued time.Time
+	wctx     *writeContext
+	span     *trace.SpanContext
+	producer *Producer[T]
+	// seq orders the item for checkpoints.
+	seq uint64
+	// round is the two-phase export the item's batch is part of, if the
+	// exporter is a TwoPhaseExporter.
+	round *preparedRound[T]
+	// producerLabel attributes the item's enqueue and drop metrics to a
+	// producer. It is empty when producers aren't tracked.
+	producerLabel string
+	// queued is the item's entry in the queue index, if queue inspection
+	// is on.
+	queued *list.Element
+	// generation is the generation of pending items the item counts
+	// towards, for ForceFlush.
+	generation uint32
+	// turn orders the export of the batch the item is first in among
+	// batches sharing its keys, with work stealing or a resizable ring.
+	turn uint64
+	// chunk holds the item's value if it was written with WriteValues.
+	chunk       *valueChunk[T]
 	errCh       chan error
 	completedCh chan struct{}
 }
@@ -151,19 +680,46 @@ func NewBatchItemProcessor[T any](
 	}
 
 	o := BatchItemProcessorOptions{
-		BatchTimeout:       time.Duration(DefaultScheduleDelay) * time.Millisecond,
-		ExportTimeout:      time.Duration(DefaultExportTimeout) * time.Millisecond,
-		MaxQueueSize:       maxQueueSize,
-		MaxExportBatchSize: maxExportBatchSize,
-		ShippingMethod:     DefaultShippingMethod,
-		Workers:            DefaultNumWorkers,
+		BatchTimeout:        time.Duration(DefaultScheduleDelay) * time.Millisecond,
+		ExportTimeout:       time.Duration(DefaultExportTimeout) * time.Millisecond,
+		MaxQueueSize:        maxQueueSize,
+		MaxExportBatchSize:  maxExportBatchSize,
+		ShippingMethod:      DefaultShippingMethod,
+		Workers:             autoWorkers,
+		ThroughputWindow:    time.Duration(DefaultThroughputWindow) * time.Millisecond,
+		TriggerInterval:     time.Duration(DefaultTriggerInterval) * time.Millisecond,
+		HealthCheckInterval: time.Duration(DefaultHealthCheckInterval) * time.Millisecond,
+
+		PipelineDepth: DefaultPipelineDepth,
+		QueueKind:     DefaultQueueKind,
+		WaitStrategy:  WaitStrategyBlock,
+
+		WriteAcceptance: WriteAcceptanceAll,
+		DropPolicy:      DropNewest,
+		CancelBehavior:  CancelBehaviorNone,
+
+		DiskBufferFailureThreshold: DefaultDiskBufferFailureThreshold,
+		DiskBufferReplayInterval:   time.Duration(DefaultDiskBufferReplayInterval) * time.Millisecond,
 	}
 
 	for _, opt := range options {
 		opt(&o)
 	}
 
-	if err := o.Validate(); err != nil {
+	if o.Workers == autoWorkers {
+		o.Workers = defaultWorkers(o.MaxQueueSize, o.MaxExportBatchSize)
+	}
+
+	if err := o.Validate(); err != nil || exporter == nil {
+		if exporter == nil {
+			err = joinValidationErrors(err, errors.New("exporter must not be nil"))
+		}
+
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
+
+	sizer, err := typedOption[func(item *T) int](o.Sizer, "sizer")
+	if err != nil {
 		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
 	}
 
@@ -172,376 +728,1614 @@ func NewBatchItemProcessor[T any](
 		metrics = DefaultMetrics
 	}
 
-	bvp := BatchItemProcessor[T]{
-		e:             exporter,
-		o:             o,
-		log:           log,
-		name:          name,
-		metrics:       metrics,
-		timer:         time.NewTimer(o.BatchTimeout),
-		queue:         make(chan *TraceableItem[T], o.MaxQueueSize),
-		batchCh:       make(chan []*TraceableItem[T], o.Workers),
-		stopCh:        make(chan struct{}),
-		stopWorkersCh: make(chan struct{}),
+	keyFunc, err := typedOption[func(item *T) any](o.KeyFunc, "key func")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
 	}
 
-	bvp.log.WithFields(
-		logrus.Fields{
-			"workers":               bvp.o.Workers,
-			"batch_timeout":         bvp.o.BatchTimeout,
-			"export_timeout":        bvp.o.ExportTimeout,
-			"max_export_batch_size": bvp.o.MaxExportBatchSize,
-			"max_queue_size":        bvp.o.MaxQueueSize,
-			"shipping_method":       bvp.o.ShippingMethod,
-		},
-	).Info("Batch item processor initialized")
+	deadlineFunc, err := typedOption[DeadlineFunc[T]](o.DeadlineFunc, "deadline func")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
 
-	return &bvp, nil
-}
+	checkpointMarker, err := typedOption[func(item *T) any](o.CheckpointMarker, "checkpoint marker")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
 
-// Start starts the batch item processor workers and batch builder.
-func (bvp *BatchItemProcessor[T]) Start(ctx context.Context) {
-	bvp.stopWait.Add(bvp.o.Workers)
+	dropSummary, err := typedOption[func(item *T) string](o.DropSummary, "drop summary")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
+
+	dropSink, err := typedOption[ItemExporter[T]](o.DropSink, "drop sink")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
 
-	bvp.metrics.SetWorkerCount(bvp.name, float64(bvp.o.Workers))
+	overflow, err := typedOption[ItemWriter[T]](o.Overflow, "overflow")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
 
-	bvp.log.Infof("Starting %d workers for %s", bvp.o.Workers, bvp.name)
+	deadLetters, err := typedOption[DeadLetterHandler[T]](o.DeadLetterHandler, "dead letter handler")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
 
-	for i := 0; i < bvp.o.Workers; i++ {
-		go func(num int) {
-			defer bvp.stopWait.Done()
-			bvp.worker(ctx, num)
-		}(i)
+	replacer, err := typedOption[ExporterReplacer[T]](o.ExporterReplacer, "exporter replacer")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
 	}
 
-	go func() {
-		bvp.batchBuilder(ctx)
-		bvp.log.Info("Batch builder exited")
-	}()
-}
+	inspectSummary, err := typedOption[func(item *T) string](o.InspectSummary, "inspect summary")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
 
-// Write writes items to the queue. If the Processor is configured to use
-// the sync shipping method, the items will be written to the queue and this
-// function will return when all items have been processed. If the Processor is
-// configured to use the async shipping method, the items will be written to
-// the queue and this function will return immediately.
-func (bvp *BatchItemProcessor[T]) Write(ctx context.Context, s []*T) error {
-	if len(s) == 0 {
-		return nil
+	laneFunc, err := typedOption[LaneFunc[T]](o.LaneFunc, "lane func")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
 	}
 
-	if bvp.e == nil {
-		return errors.New("exporter is nil")
+	// With priority lanes the queue only buffers the next batch, and items
+	// wait in their lanes instead.
+	queueSize := o.MaxQueueSize
+
+	var lanes *laneQueue[T]
+	if laneFunc != nil {
+		lanes = newLaneQueue(laneFunc, o.LaneWeights, o.MaxQueueSize, o.LaneMaxWait)
+		queueSize = o.MaxExportBatchSize
 	}
 
-	// Break our items up in to chunks that can be processed at
-	// one time by our workers. This is to prevent wasting
-	// resources sending items if we've failed an earlier batch.
-	batchSize := bvp.o.Workers * bvp.o.MaxExportBatchSize
-	for start := 0; start < len(s); start += batchSize {
-		end := start + batchSize
-		if end > len(s) {
-			end = len(s)
-		}
+	priorityFunc, err := typedOption[PriorityFunc[T]](o.PriorityFunc, "priority func")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
 
-		prepared := make([]*TraceableItem[T], 0, end-start)
+	// Other queue implementations, and the priority queue, stage items the
+	// same way.
+	var (
+		staged     *stagedQueue[T]
+		priorities *PriorityQueue[*TraceableItem[T]]
+	)
 
-		for _, i := range s[start:end] {
-			if i == nil {
-				bvp.metrics.IncItemsDroppedBy(bvp.name, float64(1))
+	if priorityFunc != nil {
+		priorities = NewPriorityQueue(o.MaxQueueSize, func(item *TraceableItem[T]) int {
+			return priorityFunc(item.item)
+		})
 
-				bvp.log.Warn("Attempted to write a nil item. This item has been dropped.")
+		staged = newStagedQueue[T](priorities)
+		queueSize = o.MaxExportBatchSize
+	}
 
-				continue
-			}
+	if o.QueueKind != QueueKindChannel {
+		q, err := NewQueue[*TraceableItem[T]](o.QueueKind, o.MaxQueueSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+		}
 
-			item := &TraceableItem[T]{
-				item: i,
-			}
+		staged = newStagedQueue(q)
+		queueSize = o.MaxExportBatchSize
+	}
 
-			if bvp.o.ShippingMethod == ShippingMethodSync {
-				item.errCh = make(chan error, 1)
-				item.completedCh = make(chan struct{}, 1)
-			}
+	var handoffCodec Codec[T]
 
-			prepared = append(prepared, item)
+	if o.HandoffPath != "" {
+		handoffCodec, err = typedOption[Codec[T]](o.HandoffCodec, "handoff codec")
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
 		}
+	}
 
-		for _, i := range prepared {
-			if err := bvp.enqueueOrDrop(ctx, i); err != nil {
-				return err
-			}
+	var buffer *diskBuffer[T]
+
+	if o.DiskBufferDir != "" {
+		codec, err := typedOption[Codec[T]](o.DiskBufferCodec, "disk buffer codec")
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
 		}
 
-		if bvp.o.ShippingMethod == ShippingMethodSync {
-			if err := bvp.waitForBatchCompletion(ctx, prepared); err != nil {
-				return err
-			}
+		buffer, err = newDiskBuffer(o.DiskBufferDir, codec, o.DiskBufferFailureThreshold, o.DiskBufferMaxBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open disk buffer: %w: %s", err, name)
 		}
 	}
 
-	return nil
-}
+	exporterFactory, err := typedOption[ExporterFactory[T]](o.ExporterFactory, "exporter factory")
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch item processor options: %w: %s", err, name)
+	}
 
-// exportWithTimeout exports items with a timeout.
-func (bvp *BatchItemProcessor[T]) exportWithTimeout(ctx context.Context, itemsBatch []*TraceableItem[T]) error {
-	if len(itemsBatch) == 0 {
-		return nil
+	if o.DryRun {
+		exporter = noopExporter[T]{}
+		exporterFactory = nil
 	}
 
-	bvp.metrics.IncWorkerExportInProgress(bvp.name)
-	defer bvp.metrics.DecWorkerExportInProgress(bvp.name)
+	workerExporters, err := newWorkerExporters(exporterFactory, o.Workers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worker exporters: %w: %s", err, name)
+	}
 
-	if bvp.o.ExportTimeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, bvp.o.ExportTimeout)
+	labels := o.LabelGuard
+	if labels == nil {
+		labels = DefaultLabelGuard
+	}
 
-		defer cancel()
+	var producerLabels *LabelGuard
+	if o.MaxProducerLabels > 0 {
+		producerLabels = NewLabelGuard(o.MaxProducerLabels)
 	}
 
-	// Since the batch processor filters out nil items upstream,
-	// we can optimize by pre-allocating the full slice size.
-	items := make([]*T, 0, len(itemsBatch))
+	var tracer trace.Tracer
+	if o.TracerProvider != nil {
+		tracer = o.TracerProvider.Tracer(tracerName)
+	}
 
-	for _, item := range itemsBatch {
-		if item == nil {
-			bvp.log.Warn("Attempted to export a nil item. This item has been dropped.")
+	bvp := BatchItemProcessor[T]{
+		e:               exporter,
+		o:               o,
+		log:             log,
+		name:            name,
+		label:           labels.Label(name),
+		tracer:          tracer,
+		producerLabels:  producerLabels,
+		dropSummary:     dropSummary,
+		dropSink:        dropSink,
+		overflow:        overflow,
+		deadLetters:     deadLetters,
+		replacer:        replacer,
+		metrics:         newRecorder(metrics),
+		workerExporters: workerExporters,
+		throughput:      newThroughputMeter(o.ThroughputWindow, time.Now()),
+		sizer:           sizer,
+		keyFunc:         keyFunc,
+		deadlineFunc:    deadlineFunc,
+		diskBuffer:      buffer,
+		handoffCodec:    handoffCodec,
+		timer:           newFlushTimer(o.TimerWheel, o.Stepper, o.BatchTimeout),
+		stepper:         o.Stepper,
+		queue:           make(chan *TraceableItem[T], queueSize),
+		lanes:           lanes,
+		staged:          staged,
+		priority:        priorities,
+		cutCh:           make(chan cutBatch[T], o.Workers),
+		batchCh:         make(chan []*TraceableItem[T], o.Workers*o.PipelineDepth),
+		stopCh:          make(chan struct{}),
+		stopWorkersCh:   make(chan struct{}),
+		builderDone:     make(chan struct{}),
+		flushCh:         make(chan struct{}, 1),
+		pending:         pendingItems{turn: make(chan struct{}, 1)},
+		workerRunning:   make([]bool, o.Workers),
+		activity:        newWorkerActivity(o.Workers),
+		buffers:         newBatchBuffers[T](o.Workers, o.MaxExportBatchSize),
+	}
 
-			continue
+	bvp.values.size = o.MaxExportBatchSize
+
+	if o.KeyOrdering {
+		bvp.workerChs = make([]chan []*TraceableItem[T], o.Workers)
+		for i := range bvp.workerChs {
+			bvp.workerChs[i] = make(chan []*TraceableItem[T], o.PipelineDepth)
 		}
 
-		items = append(items, item.item)
+		if o.ConsistentHashing {
+			bvp.ring.Store(NewHashRing(o.Workers, o.HashRingReplicas))
+			bvp.rebuildRingLocked()
+		}
+
+		if o.WorkStealing || resizableRing(&o) {
+			bvp.turns = newKeyTurns()
+		}
 	}
 
-	startTime := time.Now()
+	if checkpointMarker != nil && o.Checkpoint != nil {
+		bvp.checkpoints = newCheckpointTracker[T](checkpointMarker, o.Checkpoint)
+	}
 
-	err := bvp.e.ExportItems(ctx, items)
+	if o.InvariantChecks {
+		report := o.InvariantReport
+		if report == nil {
+			report = func(err error) {
+				log.WithError(err).Error("Invariant violated")
+			}
+		}
 
-	duration := time.Since(startTime)
+		bvp.invariants = &invariantChecker{report: report}
+	}
 
-	bvp.metrics.ObserveExportDuration(bvp.name, duration)
+	if o.AdaptiveMaxBatchTimeout > 0 {
+		bvp.adaptive = newAdaptiveTimeout(o.AdaptiveMinBatchTimeout, o.AdaptiveMaxBatchTimeout, o.BatchTimeout, o.MaxExportBatchSize)
+	}
 
-	if err != nil {
-		bvp.metrics.IncItemsFailedBy(bvp.name, float64(len(items)))
-	} else {
-		bvp.metrics.IncItemsExportedBy(bvp.name, float64(len(items)))
-		bvp.metrics.ObserveBatchSize(bvp.name, float64(len(items)))
+	if o.CapacityCheckInterval > 0 {
+		bvp.arrivals = newThroughputMeter(o.ThroughputWindow, time.Now())
 	}
 
-	for _, item := range itemsBatch {
-		if item.errCh != nil {
-			item.errCh <- err
-			close(item.errCh)
+	if o.ZeroCopyExport {
+		bvp.exportBuffers = make([][]*T, o.Workers)
+		for i := range bvp.exportBuffers {
+			bvp.exportBuffers[i] = make([]*T, 0, o.MaxExportBatchSize)
 		}
+	}
 
-		if item.completedCh != nil {
-			item.completedCh <- struct{}{}
-			close(item.completedCh)
-		}
+	if o.ExportRateLimit > 0 {
+		bvp.exportRate = newTokenBucket(o.ExportRateLimit, o.ExportRateBurst, time.Now())
 	}
 
-	return nil
-}
+	if o.WorkerMetrics {
+		bvp.workerLabels = make([]string, o.Workers)
+		for i := range bvp.workerLabels {
+			bvp.workerLabels[i] = strconv.Itoa(i)
+		}
+	}
 
-// Shutdown shuts down the batch item processor.
-func (bvp *BatchItemProcessor[T]) Shutdown(ctx context.Context) error {
-	var err error
+	// Resolve the processor's metrics up front, off the hot path.
+	if preloader, ok := metrics.(interface{ preload(name string) }); ok {
+		preloader.preload(bvp.label)
+	}
 
-	bvp.stopOnce.Do(func() {
-		wait := make(chan struct{})
-		go func() {
-			bvp.log.Info("Stopping processor")
+	if o.QueueInspection {
+		bvp.inspector = newQueueIndex(inspectSummary)
+	}
 
-			close(bvp.stopCh)
+	bvp.lifecycle.current.Store(&exporterRef[T]{e: exporter})
 
-			bvp.timer.Stop()
+	if o.WriteCoalescingSize > 0 {
+		bvp.coalescer = newWriteCoalescer(o.WriteCoalescingSize, o.WriteCoalescingDelay, bvp.enqueueCoalesced)
+	}
 
-			bvp.drainQueue()
+	bvp.log.WithFields(
+		logrus.Fields{
+			"workers":               bvp.o.Workers,
+			"batch_timeout":         bvp.o.BatchTimeout,
+			"export_timeout":        bvp.o.ExportTimeout,
+			"max_export_batch_size": bvp.o.MaxExportBatchSize,
+			"max_queue_size":        bvp.o.MaxQueueSize,
+			"shipping_method":       bvp.o.ShippingMethod,
+			"dry_run":               bvp.o.DryRun,
+		},
+	).Info("Batch item processor initialized")
 
-			close(bvp.stopWorkersCh)
+	return &bvp, nil
+}
 
-			bvp.stopWait.Wait()
+// Start starts the exporters, then the batch item processor workers and batch
+// builder. If an exporter implementing ExporterStarter fails to start, or the
+// readiness check set with WithReadinessCheck fails, the processor isn't
+// started and the error is returned. Start also fails with
+// ErrMetricsNameInUse if another running processor reports to the same
+// Metrics under the same name.
+func (bvp *BatchItemProcessor[T]) Start(ctx context.Context) error {
+	if err := bvp.claimMetrics(); err != nil {
+		return err
+	}
 
-			if bvp.e != nil {
-				if err = bvp.e.Shutdown(ctx); err != nil {
-					bvp.log.WithError(err).Error("failed to shutdown processor")
-				}
-			}
+	if err := bvp.start(ctx); err != nil {
+		bvp.releaseMetrics()
 
-			close(wait)
-		}()
+		return err
+	}
 
-		select {
-		case <-wait:
-		case <-ctx.Done():
-			err = ctx.Err()
-		}
-	})
+	bvp.metrics.SetProcessorInfo(bvp.label, bvp.o.MaxExportBatchSize, bvp.o.MaxQueueSize, bvp.o.Workers, bvp.o.BatchTimeout)
 
-	return err
+	return nil
 }
 
-// WithMaxQueueSize sets the maximum queue size.
-func WithMaxQueueSize(size int) BatchItemProcessorOption {
-	return func(o *BatchItemProcessorOptions) {
-		o.MaxQueueSize = size
+// claimMetrics claims the processor's name on its metrics recorder, if the
+// recorder tracks the processors running with it.
+func (bvp *BatchItemProcessor[T]) claimMetrics() error {
+	claimer, ok := bvp.metrics.MetricsRecorder.(interface{ claim(name string) error })
+	if !ok {
+		return nil
 	}
-}
 
-// WithMaxExportBatchSize sets the maximum export batch size.
-func WithMaxExportBatchSize(size int) BatchItemProcessorOption {
-	return func(o *BatchItemProcessorOptions) {
-		o.MaxExportBatchSize = size
+	if err := claimer.claim(bvp.label); err != nil {
+		return err
 	}
-}
 
-// WithBatchTimeout sets the batch timeout.
-func WithBatchTimeout(delay time.Duration) BatchItemProcessorOption {
-	return func(o *BatchItemProcessorOptions) {
-		o.BatchTimeout = delay
-	}
+	bvp.metricsClaimed.Store(true)
+
+	return nil
 }
 
-// WithExportTimeout sets the export timeout.
-func WithExportTimeout(timeout time.Duration) BatchItemProcessorOption {
-	return func(o *BatchItemProcessorOptions) {
-		o.ExportTimeout = timeout
+// releaseMetrics releases the processor's name claimed by claimMetrics.
+func (bvp *BatchItemProcessor[T]) releaseMetrics() {
+	if !bvp.metricsClaimed.CompareAndSwap(true, false) {
+		return
 	}
-}
 
-// WithShippingMethod sets the shipping method.
-func WithShippingMethod(method ShippingMethod) BatchItemProcessorOption {
-	return func(o *BatchItemProcessorOptions) {
-		o.ShippingMethod = method
+	if releaser, ok := bvp.metrics.MetricsRecorder.(interface{ release(name string) }); ok {
+		releaser.release(bvp.label)
 	}
 }
 
-// WithWorkers sets the number of workers.
-func WithWorkers(workers int) BatchItemProcessorOption {
-	return func(o *BatchItemProcessorOptions) {
-		o.Workers = workers
+// start implements Start once the processor's name is claimed.
+func (bvp *BatchItemProcessor[T]) start(ctx context.Context) error {
+	if err := bvp.startExporters(ctx); err != nil {
+		return err
 	}
-}
 
-// WithMetrics sets the metrics instance.
-func WithMetrics(metrics *Metrics) BatchItemProcessorOption {
-	return func(o *BatchItemProcessorOptions) {
-		o.Metrics = metrics
+	if bvp.o.ReadinessTimeout > 0 {
+		if err := bvp.checkReadiness(ctx); err != nil {
+			return err
+		}
 	}
-}
 
-func (bvp *BatchItemProcessor[T]) waitForBatchCompletion(
-	ctx context.Context,
-	items []*TraceableItem[T],
-) error {
-	for _, item := range items {
-		select {
-		case err := <-item.errCh:
-			if err != nil {
-				return err
-			}
-		case <-item.completedCh:
-			continue
-		case <-ctx.Done():
-			return ctx.Err()
+	// A sink unavailable at boot mustn't keep the processor from running,
+	// so handed off items not taken over are kept for the next start.
+	if bvp.handoffCodec != nil {
+		if err := bvp.takeHandoff(ctx); err != nil {
+			bvp.log.WithError(err).Error("Failed to take over handed off items, keeping them for the next start")
 		}
 	}
 
-	return nil
-}
+	bvp.started.Store(true)
 
-func (bvp *BatchItemProcessor[T]) batchBuilder(ctx context.Context) {
+	work := bvp.workContext(ctx)
+
+	bvp.startWorkers(work)
+
+	if bvp.lanes != nil {
+		go bvp.laneScheduler()
+	}
+
+	if bvp.staged != nil {
+		go bvp.queuePump()
+	}
+
+	go func() {
+		defer close(bvp.builderDone)
+
+		bvp.batchBuilder(work)
+		bvp.log.Info("Batch builder exited")
+	}()
+
+	if bvp.o.CancelBehavior != CancelBehaviorNone {
+		go bvp.shutdownOnCancel(ctx)
+	}
+
+	go bvp.throughputReporter()
+
+	if bvp.arrivals != nil {
+		go bvp.capacityAdvisor()
+	}
+
+	if bvp.diskBuffer != nil {
+		go bvp.diskBufferReplayer(ctx)
+	}
+
+	if bvp.o.HealthCheckInterval > 0 {
+		go bvp.healthProber(ctx)
+	}
+
+	return nil
+}
+
+// startExporters starts the exporter and any worker exporters.
+func (bvp *BatchItemProcessor[T]) startExporters(ctx context.Context) error {
+	bvp.connectFlowControl(bvp.e)
+
+	for _, exporter := range bvp.workerExporters {
+		bvp.connectFlowControl(exporter)
+	}
+
+	if err := startExporter(ctx, bvp.e); err != nil {
+		return fmt.Errorf("failed to start exporter: %w", err)
+	}
+
+	for i, exporter := range bvp.workerExporters {
+		if err := startExporter(ctx, exporter); err != nil {
+			return fmt.Errorf("failed to start exporter for worker %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// startExporter starts exporter if it implements ExporterStarter.
+func startExporter[T any](ctx context.Context, exporter ItemExporter[T]) error {
+	starter, ok := exporter.(ExporterStarter)
+	if !ok {
+		return nil
+	}
+
+	return starter.Start(ctx)
+}
+
+// Write writes items to the queue. If the Processor is configured to use
+// the sync shipping method, the items will be written to the queue and this
+// function will return when all items have been processed. If the Processor is
+// configured to use the async shipping method, the items will be written to
+// the queue and this function will return immediately.
+func (bvp *BatchItemProcessor[T]) Write(ctx context.Context, s []*T) error {
+	return bvp.write(ctx, s, nil)
+}
+
+// write implements Write, attributing the items to producer if it is set.
+func (bvp *BatchItemProcessor[T]) write(ctx context.Context, s []*T, producer *Producer[T]) error {
+	if len(s) == 0 {
+		return nil
+	}
+
+	if bvp.e == nil {
+		return errors.New("exporter is nil")
+	}
+
+	origin := bvp.captureOrigin(ctx, producer)
+
+	defer bvp.reportQueued()
+
+	// Tiny async writes are merged in to larger enqueue operations.
+	if bvp.coalescer != nil && len(s) < bvp.o.WriteCoalescingSize {
+		return bvp.coalescer.add(bvp.prepareItems(s, origin))
+	}
+
+	// Break our items up in to chunks that can be processed at
+	// one time by our workers. This is to prevent wasting
+	// resources sending items if we've failed an earlier batch.
+	batchSize := bvp.o.Workers * bvp.o.MaxExportBatchSize
+	for start := 0; start < len(s); start += batchSize {
+		end := start + batchSize
+		if end > len(s) {
+			end = len(s)
+		}
+
+		if bvp.o.ShippingMethod != ShippingMethodSync {
+			if n, err := bvp.enqueueEach(ctx, s[start:end], origin); err != nil {
+				if bvp.acceptsPartialWrites(err) {
+					return &PartialWriteError[T]{Remaining: s[start+n:]}
+				}
+
+				return err
+			}
+
+			continue
+		}
+
+		prepared := bvp.prepareItems(s[start:end], origin)
+
+		for j, i := range prepared {
+			if err := bvp.enqueueOrDrop(ctx, i); err != nil {
+				if !bvp.acceptsPartialWrites(err) {
+					return err
+				}
+
+				// Wait for the items that were accepted before handing
+				// back the rest.
+				if err := bvp.waitForBatchCompletion(ctx, prepared[:j]); err != nil {
+					return err
+				}
+
+				remaining := make([]*T, 0, len(prepared)-j+len(s)-end)
+				for _, item := range prepared[j:] {
+					remaining = append(remaining, item.item)
+				}
+
+				return &PartialWriteError[T]{Remaining: append(remaining, s[end:]...)}
+			}
+		}
+
+		if err := bvp.waitForBatchCompletion(ctx, prepared); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteValues writes items to the queue by value. The values are copied in to
+// contiguous buffers of up to a batch of items, reused once their items are
+// exported, so producers building small structs don't force a heap allocation
+// per item or per write. Exporters, and handlers given the items, receive
+// pointers in to those buffers, which must be treated as read-only and not
+// kept after returning. Otherwise WriteValues behaves like Write.
+func (bvp *BatchItemProcessor[T]) WriteValues(ctx context.Context, s []T) error {
+	if len(s) == 0 {
+		return nil
+	}
+
+	// Sync writers wait on their own items, and coalesced writes are
+	// buffered with those of Write, so only async writes reuse buffers.
+	if bvp.o.ShippingMethod == ShippingMethodSync || bvp.coalescer != nil {
+		values := make([]T, len(s))
+		copy(values, s)
+
+		items := make([]*T, len(values))
+		for i := range values {
+			items[i] = &values[i]
+		}
+
+		return bvp.Write(ctx, items)
+	}
+
+	if bvp.e == nil {
+		return errors.New("exporter is nil")
+	}
+
+	origin := bvp.captureOrigin(ctx, nil)
+
+	defer bvp.reportQueued()
+
+	for start := 0; start < len(s); start += bvp.values.size {
+		end := min(start+bvp.values.size, len(s))
+
+		chunk := bvp.values.get()
+		chunk.values = append(chunk.values, s[start:end]...)
+		chunk.refs.Store(int64(end - start))
+
+		for i := range chunk.values {
+			item := bvp.newTraceableItem(&chunk.values[i], origin)
+			item.chunk = chunk
+
+			if err := bvp.enqueueOrDrop(ctx, item); err != nil {
+				// The rest of the chunk's items are never queued.
+				chunk.release(len(chunk.values) - i)
+
+				if bvp.acceptsPartialWrites(err) {
+					remaining := make([]*T, 0, len(s)-start-i)
+					for _, value := range s[start+i:] {
+						remaining = append(remaining, &value)
+					}
+
+					return &PartialWriteError[T]{Remaining: remaining}
+				}
+
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WriteEach writes items to the queue like Write, but reports the outcome of
+// every item instead of stopping at the first failure. The returned slice has
+// one entry per item: nil if the item was accepted (and, with the sync shipping
+// method, exported), ErrNilItem, ErrQueueFull or ErrShuttingDown if it was
+// rejected, or the export error. The second return value is only set when the
+// call as a whole failed, for example because the context was cancelled while
+// waiting for sync exports; the per-item errors gathered so far are returned
+// alongside it.
+func (bvp *BatchItemProcessor[T]) WriteEach(ctx context.Context, s []*T) ([]error, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	if bvp.e == nil {
+		return nil, errors.New("exporter is nil")
+	}
+
+	errs := make([]error, len(s))
+	origin := bvp.captureOrigin(ctx, nil)
+
+	defer bvp.reportQueued()
+
+	batchSize := bvp.o.Workers * bvp.o.MaxExportBatchSize
+	for start := 0; start < len(s); start += batchSize {
+		end := start + batchSize
+		if end > len(s) {
+			end = len(s)
+		}
+
+		accepted := make([]*TraceableItem[T], 0, end-start)
+		indexes := make([]int, 0, end-start)
+
+		for idx := start; idx < end; idx++ {
+			if s[idx] == nil {
+				bvp.dropNilItem()
+
+				errs[idx] = ErrNilItem
+
+				continue
+			}
+
+			item := bvp.newTraceableItem(s[idx], origin)
+
+			if err := bvp.enqueueOrDrop(ctx, item); err != nil {
+				errs[idx] = err
+
+				continue
+			}
+
+			accepted = append(accepted, item)
+			indexes = append(indexes, idx)
+		}
+
+		if bvp.o.ShippingMethod != ShippingMethodSync {
+			continue
+		}
+
+		for j, item := range accepted {
+			select {
+			case err := <-item.errCh:
+				errs[indexes[j]] = err
+			case <-ctx.Done():
+				return errs, ctx.Err()
+			}
+		}
+	}
+
+	return errs, nil
+}
+
+// enqueueEach wraps and enqueues items one by one, without collecting them,
+// dropping any nil items. It is only used when shipping asynchronously, where
+// nothing waits on the items. On failure it returns the index of the item
+// that wasn't enqueued.
+func (bvp *BatchItemProcessor[T]) enqueueEach(ctx context.Context, s []*T, origin writeOrigin[T]) (int, error) {
+	for n, i := range s {
+		if i == nil {
+			bvp.dropNilItem()
+
+			continue
+		}
+
+		if err := bvp.enqueueOrDrop(ctx, bvp.newTraceableItem(i, origin)); err != nil {
+			return n, err
+		}
+	}
+
+	return len(s), nil
+}
+
+// dropNilItem records a nil item written to the processor.
+func (bvp *BatchItemProcessor[T]) dropNilItem() {
+	bvp.metrics.IncItemsDroppedBy(bvp.label, float64(1))
+
+	bvp.log.Warn("Attempted to write a nil item. This item has been dropped.")
+
+	bvp.publish(EventItemsDropped, Event{Items: 1, Reason: "nil item"})
+}
+
+// prepareItems wraps items for the queue, dropping any nil items.
+func (bvp *BatchItemProcessor[T]) prepareItems(s []*T, origin writeOrigin[T]) []*TraceableItem[T] {
+	prepared := make([]*TraceableItem[T], 0, len(s))
+
+	for _, i := range s {
+		if i == nil {
+			bvp.dropNilItem()
+
+			continue
+		}
+
+		prepared = append(prepared, bvp.newTraceableItem(i, origin))
+	}
+
+	return prepared
+}
+
+// writeOrigin is what items remember about the write that queued them.
+type writeOrigin[T any] struct {
+	wctx          *writeContext
+	span          *trace.SpanContext
+	producer      *Producer[T]
+	producerLabel string
+}
+
+// captureOrigin captures the origin of items written with ctx.
+func (bvp *BatchItemProcessor[T]) captureOrigin(ctx context.Context, producer *Producer[T]) writeOrigin[T] {
+	return writeOrigin[T]{
+		wctx:          bvp.captureContext(ctx),
+		span:          bvp.captureSpan(ctx),
+		producer:      producer,
+		producerLabel: bvp.producerLabel(ctx, producer),
+	}
+}
+
+// newTraceableItem wraps an item, adding completion channels when shipping
+// synchronously.
+func (bvp *BatchItemProcessor[T]) newTraceableItem(i *T, origin writeOrigin[T]) *TraceableItem[T] {
+	if bvp.o.ShippingMethod == ShippingMethodSync {
+		return &TraceableItem[T]{
+			item:          i,
+			wctx:          origin.wctx,
+			span:          origin.span,
+			producer:      origin.producer,
+			producerLabel: origin.producerLabel,
+			errCh:         make(chan error, 1),
+			completedCh:   make(chan struct{}, 1),
+		}
+	}
+
+	item := bvp.items.get()
+	item.item = i
+	item.wctx = origin.wctx
+	item.span = origin.span
+	item.producer = origin.producer
+	item.producerLabel = origin.producerLabel
+
+	return item
+}
+
+// exportWithTimeout exports a batch and returns the export error, after
+// passing it to any sync writers. The items are collected in to buf, if not
+// nil, rather than a new slice.
+func (bvp *BatchItemProcessor[T]) exportWithTimeout(
+	ctx context.Context,
+	exporter ItemExporter[T],
+	itemsBatch []*TraceableItem[T],
+	buf []*T,
+) error {
+	if len(itemsBatch) == 0 {
+		return nil
+	}
+
+	bvp.metrics.IncWorkerExportInProgress(bvp.label)
+	defer bvp.metrics.DecWorkerExportInProgress(bvp.label)
+
+	// Each attempt at the export is bounded by the export timeout, so a
+	// timed out attempt can still be retried.
+	ctx = batchContext(ctx, itemsBatch)
+
+	items := bvp.collectItems(itemsBatch, buf)
+
+	ctx, span := bvp.startExportSpan(ctx, itemsBatch)
+
+	var err error
+
+	if bvp.diskBuffer != nil {
+		err = bvp.exportOrBuffer(ctx, exporter, items)
+	} else {
+		err = bvp.export(ctx, exporter, items)
+	}
+
+	endSpan(span, err)
+
+	// Writers get their own item's error when only some items failed and
+	// the items still line up with the batch.
+	partial, _ := partialErrors(err, len(itemsBatch))
+	if len(items) != len(itemsBatch) {
+		partial = nil
+	}
+
+	signalWriters(itemsBatch, err, partial)
+
+	return err
+}
+
+// exportContext returns the context a batch is exported with in a single
+// attempt: the batch's write context, bounded by the export timeout.
+func (bvp *BatchItemProcessor[T]) exportContext(ctx context.Context, itemsBatch []*TraceableItem[T]) (context.Context, context.CancelFunc) {
+	return bvp.attemptContext(batchContext(ctx, itemsBatch))
+}
+
+// attemptContext bounds an attempt at an export by the export timeout.
+func (bvp *BatchItemProcessor[T]) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if bvp.o.ExportTimeout > 0 {
+		return context.WithTimeout(ctx, bvp.o.ExportTimeout)
+	}
+
+	return ctx, func() {}
+}
+
+// batchContext returns ctx carrying the write context of a batch.
+func batchContext[T any](ctx context.Context, itemsBatch []*TraceableItem[T]) context.Context {
+	// Batches are split by write context, so the first item's context
+	// applies to the whole batch.
+	if first := itemsBatch[0]; first != nil {
+		ctx = first.wctx.apply(ctx)
+	}
+
+	return ctx
+}
+
+// collectItems collects the items of a batch in to buf, if not nil, rather
+// than a new slice, removing duplicates if key dedup is on.
+func (bvp *BatchItemProcessor[T]) collectItems(itemsBatch []*TraceableItem[T], buf []*T) []*T {
+	// Since the batch processor filters out nil items upstream,
+	// we can optimize by pre-allocating the full slice size.
+	items := buf[:0]
+	if items == nil {
+		items = make([]*T, 0, len(itemsBatch))
+	}
+
+	for _, item := range itemsBatch {
+		if item == nil {
+			bvp.log.Warn("Attempted to export a nil item. This item has been dropped.")
+
+			continue
+		}
+
+		items = append(items, item.item)
+	}
+
+	return bvp.dedupItems(items)
+}
+
+// signalWriters passes the outcome of a batch to its sync writers, each
+// getting their own item's error if partial is not nil.
+func signalWriters[T any](itemsBatch []*TraceableItem[T], err error, partial *PartialExportError) {
+	for i, item := range itemsBatch {
+		if item.errCh != nil {
+			if partial != nil {
+				item.errCh <- partial.ItemErrors[i]
+			} else {
+				item.errCh <- err
+			}
+
+			close(item.errCh)
+		}
+
+		if item.completedCh != nil {
+			item.completedCh <- struct{}{}
+			close(item.completedCh)
+		}
+	}
+}
+
+// export calls the exporter and records the outcome.
+func (bvp *BatchItemProcessor[T]) export(ctx context.Context, exporter ItemExporter[T], items []*T) error {
+	if bvp.invariants != nil {
+		bvp.invariants.export(len(items))
+	}
+
+	err := bvp.exportWithRetry(ctx, exporter, items)
+
+	if partial, ok := partialErrors(err, len(items)); ok {
+		failed := partial.Failed()
+
+		bvp.metrics.IncItemsFailedBy(bvp.label, float64(failed))
+		bvp.metrics.IncItemsExportedBy(bvp.label, float64(len(items)-failed))
+	} else if err != nil {
+		bvp.metrics.IncItemsFailedBy(bvp.label, float64(len(items)))
+	} else {
+		bvp.metrics.IncItemsExportedBy(bvp.label, float64(len(items)))
+		bvp.metrics.ObserveBatchSize(bvp.label, float64(len(items)))
+
+		size := float64(bvp.sizeOf(items))
+
+		if bvp.sizer != nil {
+			bvp.metrics.IncBytesExportedBy(bvp.label, size)
+			bvp.metrics.ObserveBatchBytes(bvp.label, size)
+		}
+
+		bvp.throughput.add(time.Now(), float64(len(items)), size)
+	}
+
+	return err
+}
+
+// Shutdown shuts down the batch item processor. New writes are rejected, queued
+// items are batched and exported, in-flight exports complete, and only then is
+// the exporter shut down, so no batch is exported after the exporter's
+// Shutdown is called.
+func (bvp *BatchItemProcessor[T]) Shutdown(ctx context.Context) error {
+	var err error
+
+	bvp.stopOnce.Do(func() {
+		start := time.Now()
+
+		// Items held by the coalescer are queued first, so they are
+		// counted and drained with the rest.
+		if bvp.coalescer != nil {
+			bvp.coalescer.close()
+		}
+
+		// Items already cut in to batches, or being exported, are
+		// drained as well as those queued.
+		pending := int(bvp.pending.total())
+
+		var exporterErr error
+
+		wait := make(chan struct{})
+		go func() {
+			bvp.log.Info("Stopping processor")
+
+			close(bvp.stopCh)
+
+			bvp.timer.Stop()
+
+			// Shutdown runs in stages so nothing is exported once the
+			// exporter is shut down: the batch builder sends its last
+			// batches, then the workers export every pending batch and
+			// finish in-flight exports, and only then is the exporter
+			// shut down.
+			bvp.drainQueue()
+
+			if bvp.stepper != nil {
+				bvp.stepper.drain(bvp)
+			}
+
+			bvp.log.Info("Draining queue: waiting for workers to finish processing batches")
+
+			close(bvp.stopWorkersCh)
+
+			bvp.stopStartingWorkers()
+			bvp.stopWait.Wait()
+
+			bvp.log.Info("Draining queue: all items processed")
+
+			if bvp.invariants != nil {
+				bvp.invariants.drained()
+				bvp.invariants.exporterDown.Store(true)
+			}
+
+			if bvp.e != nil {
+				if exporterErr = bvp.exporter().Shutdown(ctx); exporterErr != nil {
+					bvp.log.WithError(exporterErr).Error("failed to shutdown processor")
+				}
+
+				if errors.Is(exporterErr, ErrExporterClosed) {
+					bvp.fail(exporterErr)
+				}
+			}
+
+			if bvp.dropSink != nil {
+				if err := bvp.dropSink.Shutdown(ctx); err != nil {
+					bvp.log.WithError(err).Error("failed to shutdown drop sink")
+
+					exporterErr = errors.Join(exporterErr, err)
+				}
+			}
+
+			if err := bvp.shutdownWorkerExporters(ctx); err != nil {
+				bvp.log.WithError(err).Error("failed to shutdown worker exporters")
+
+				exporterErr = errors.Join(exporterErr, err)
+			}
+
+			bvp.events.close()
+			bvp.releaseMetrics()
+
+			close(wait)
+		}()
+
+		outcome := ShutdownOutcomeSuccess
+		unfinished := 0
+
+		select {
+		case <-wait:
+			if exporterErr != nil {
+				err = exporterErr
+				outcome = ShutdownOutcomeError
+			}
+		case <-ctx.Done():
+			err = ctx.Err()
+			outcome = ShutdownOutcomeTimeout
+			// The items aren't discarded, and may still be exported
+			// once Shutdown has returned.
+			unfinished = int(bvp.pending.total())
+		}
+
+		bvp.recordShutdown(time.Since(start), pending, unfinished, outcome)
+	})
+
+	return err
+}
+
+// WithMaxQueueSize sets the maximum queue size.
+func WithMaxQueueSize(size int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.MaxQueueSize = size
+	}
+}
+
+// WithMaxExportBatchSize sets the maximum export batch size.
+func WithMaxExportBatchSize(size int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.MaxExportBatchSize = size
+	}
+}
+
+// WithBatchTimeout sets the batch timeout.
+func WithBatchTimeout(delay time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.BatchTimeout = delay
+	}
+}
+
+// WithExportTimeout sets the export timeout.
+func WithExportTimeout(timeout time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ExportTimeout = timeout
+	}
+}
+
+// WithShippingMethod sets the shipping method.
+func WithShippingMethod(method ShippingMethod) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ShippingMethod = method
+	}
+}
+
+// WithWorkers sets the number of workers.
+func WithWorkers(workers int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.Workers = workers
+	}
+}
+
+// WithExportPipelining lets the batch builder keep draining the queue and
+// assemble up to depth batches per worker while exports are in flight, so a
+// worker starts its next export as soon as the previous one returns. Deeper
+// pipelines help exporters dominated by network latency, at the cost of more
+// items held outside the queue.
+func WithExportPipelining(depth int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.PipelineDepth = depth
+	}
+}
+
+// WithMetrics sets the metrics recorder.
+func WithMetrics(metrics MetricsRecorder) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.Metrics = metrics
+	}
+}
+
+// WithWriteCoalescing merges async writes of fewer than size items in to
+// larger enqueue operations, buffering them for at most delay. The write
+// filling the buffer is told if its own items were dropped because the queue
+// is full. Other coalesced writes have returned before their items are
+// enqueued, so their drops are only logged and counted.
+func WithWriteCoalescing(size int, delay time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.WriteCoalescingSize = size
+		o.WriteCoalescingDelay = delay
+	}
+}
+
+// WithLabelGuard sets the label guard used to derive the processor's metric label.
+func WithLabelGuard(guard *LabelGuard) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.LabelGuard = guard
+	}
+}
+
+// WithThroughputWindow sets the rolling window used to calculate throughput.
+func WithThroughputWindow(window time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ThroughputWindow = window
+	}
+}
+
+// WithSizer sets the function used to report the size of an item in bytes.
+// Exported batches are then measured in bytes as well as items, in the
+// batch_bytes and bytes_exported_total metrics.
+func WithSizer[T any](sizer func(item *T) int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.Sizer = sizer
+	}
+}
+
+// typedOption asserts that an untyped generic option holds a value of type V.
+// A nil option yields the zero value of V.
+func typedOption[V any](option any, name string) (V, error) {
+	var zero V
+
+	if option == nil {
+		return zero, nil
+	}
+
+	v, ok := option.(V)
+	if !ok {
+		return zero, fmt.Errorf("%s must be a %T, got %T", name, zero, option)
+	}
+
+	return v, nil
+}
+
+func (bvp *BatchItemProcessor[T]) waitForBatchCompletion(
+	ctx context.Context,
+	items []*TraceableItem[T],
+) error {
+	for _, item := range items {
+		select {
+		case err := <-item.errCh:
+			if err != nil {
+				return err
+			}
+		case <-item.completedCh:
+			// The error is sent before completion, so it is ready, and
+			// must be checked as either may be selected.
+			if err := <-item.errCh; err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// batchBuilder decides when to cut batches. Cut batches are handed to the
+// dispatcher, so a slow export doesn't hold up flush decisions until every
+// worker is busy and the dispatcher is full.
+func (bvp *BatchItemProcessor[T]) batchBuilder(ctx context.Context) {
 	log := bvp.log.WithField("module", "batch_builder")
 
-	var batch []*TraceableItem[T]
+	dispatched := make(chan struct{})
+
+	go bvp.dispatcher(dispatched)
+
+	defer func() {
+		close(bvp.cutCh)
+		<-dispatched
+	}()
+
+	// Flushes while draining rearm the timer after Shutdown stopped it.
+	defer bvp.timer.Stop()
+
+	var (
+		batch        = bvp.buffers.get()
+		batchBytes   int
+		batchStarted time.Time
+		// handoff holds items received after Shutdown, when they are
+		// handed off rather than exported.
+		handoff []*TraceableItem[T]
+	)
+
+	var triggerTick <-chan time.Time
+
+	if len(bvp.o.Triggers) > 0 {
+		ticker := time.NewTicker(bvp.o.TriggerInterval)
+		defer ticker.Stop()
+
+		triggerTick = ticker.C
+	}
+
+	// The deadline timer fires when the batch must be flushed for its most
+	// urgent item to be exported in time, or before its oldest item exceeds
+	// the maximum batch age or queue latency. It is only armed while the
+	// batch holds such an item.
+	var (
+		flushBy       time.Time
+		flushByReason string
+		deadlineTimer = time.NewTimer(0)
+		deadlineC     <-chan time.Time
+	)
+
+	stopTimer(deadlineTimer)
+	defer deadlineTimer.Stop()
+
+	flushAt := func(at time.Time, reason string) {
+		if !flushBy.IsZero() && !at.Before(flushBy) {
+			return
+		}
+
+		flushBy = at
+		flushByReason = reason
+
+		resetTimer(deadlineTimer, time.Until(at))
+
+		deadlineC = deadlineTimer.C
+	}
+
+	flush := func(reason string) {
+		if bvp.adaptive != nil {
+			bvp.adaptive.sample(time.Now())
+		}
+
+		bvp.addStep(1)
+		bvp.cutCh <- cutBatch[T]{items: batch, reason: reason}
+
+		batch = bvp.buffers.get()
+		batchBytes = 0
+
+		// The batch builder owns the timer, so exports never hold back
+		// the next timeout.
+		resetFlushTimer(bvp.timer, bvp.batchTimeout())
+
+		if deadlineC != nil {
+			stopTimer(deadlineTimer)
+		}
+
+		flushBy = time.Time{}
+		deadlineC = nil
+	}
 
 	for {
 		select {
-		case <-bvp.stopWorkersCh:
-			log.Info("Stopping batch builder")
-			return
 		case item, ok := <-bvp.queue:
 			if !ok {
+				log.Info("Stopping batch builder")
+
+				if bvp.handoffCodec != nil {
+					batch = bvp.handOffRemaining(batch, handoff)
+				}
+
 				// Channel is closed, send any remaining items in the batch for processing
 				// before shutting down.
 				if len(batch) > 0 {
-					bvp.sendBatch(batch, "shutdown")
+					bvp.addStep(1)
+					bvp.cutCh <- cutBatch[T]{items: batch, reason: "shutdown"}
 				}
 
 				return
 			}
 
 			if item == nil {
-				bvp.metrics.IncItemsDroppedBy(bvp.name, float64(1))
+				bvp.metrics.IncItemsDroppedBy(bvp.label, float64(1))
 				bvp.log.Warn("Attempted to build a batch with a nil item. This item has been dropped.")
+				bvp.settleStep(1)
+
+				continue
+			}
+
+			if bvp.invariants != nil {
+				bvp.invariants.dequeue()
+			}
+
+			if item.producer != nil {
+				item.producer.release()
+			}
+
+			if bvp.inspector != nil {
+				bvp.inspector.untrack(item)
+			}
+
+			if bvp.handoffCodec != nil && bvp.stopping() {
+				handoff = append(handoff, item)
+				bvp.settleStep(1)
 
 				continue
 			}
 
+			if len(batch) == 0 {
+				batchStarted = time.Now()
+			}
+
+			if bvp.adaptive != nil {
+				bvp.adaptive.arrived()
+			}
+
 			batch = append(batch, item)
 
-			if len(batch) >= bvp.o.MaxExportBatchSize {
-				bvp.sendBatch(batch, "max_export_batch_size")
+			if bvp.sizer != nil {
+				batchBytes += bvp.sizer(item.item)
+			}
+
+			if at, ok := bvp.flushDeadline(item); ok {
+				flushAt(at, "deadline")
+			}
+
+			if bvp.o.MaxBatchAge > 0 {
+				flushAt(item.enqueued.Add(bvp.o.MaxBatchAge), "max_batch_age")
+			}
+
+			if at, ok := bvp.latencyDeadline(item); ok {
+				flushAt(at, "max_queue_latency")
+			}
+
+			if len(batch) >= bvp.maxBatchSize() {
+				flush("max_export_batch_size")
+			} else if bvp.triggered(len(batch), batchBytes, batchStarted) {
+				flush("trigger")
+			}
 
-				batch = []*TraceableItem[T]{}
+			bvp.settleStep(1)
+		case <-triggerTick:
+			if len(batch) > 0 && bvp.triggered(len(batch), batchBytes, batchStarted) {
+				flush("trigger")
+			}
+		case <-deadlineC:
+			flush(flushByReason)
+		case <-bvp.flushCh:
+			if len(batch) > 0 {
+				flush("requested")
 			}
-		case <-bvp.timer.C:
+		case <-bvp.timer.C():
 			if len(batch) > 0 {
-				bvp.sendBatch(batch, "timer")
-				batch = []*TraceableItem[T]{}
+				flush("timer")
 			} else {
-				bvp.timer.Reset(bvp.o.BatchTimeout)
+				if bvp.adaptive != nil {
+					bvp.adaptive.sample(time.Now())
+				}
+
+				bvp.timer.Reset(bvp.batchTimeout())
 			}
+
+			bvp.settleStep(1)
 		}
 	}
 }
 
-func (bvp *BatchItemProcessor[T]) sendBatch(batch []*TraceableItem[T], reason string) {
-	log := bvp.log.WithField("reason", reason)
-	log.Tracef("Creating a batch of %d items", len(batch))
+func (bvp *BatchItemProcessor[T]) worker(ctx context.Context, number int) {
+	var (
+		idleTimer *time.Timer
+		idle      <-chan time.Time
+	)
 
-	bvp.batchCh <- batch
+	if bvp.o.WorkerIdleTimeout > 0 {
+		idleTimer = time.NewTimer(bvp.o.WorkerIdleTimeout)
+		defer idleTimer.Stop()
 
-	log.Tracef("Batch sent to batch channel")
-}
+		idle = idleTimer.C
+	}
+
+	var steal <-chan time.Time
+
+	if bvp.o.WorkStealing {
+		ticker := time.NewTicker(workStealInterval)
+		defer ticker.Stop()
+
+		steal = ticker.C
+	}
 
-func (bvp *BatchItemProcessor[T]) worker(ctx context.Context, number int) {
 	for {
+		if bvp.o.WaitStrategy == WaitStrategySpin {
+			if batch, ok := bvp.spinForBatch(number); ok {
+				bvp.exportBatch(ctx, number, batch)
+
+				if idleTimer != nil {
+					resetTimer(idleTimer, bvp.o.WorkerIdleTimeout)
+				}
+
+				continue
+			}
+		}
+
 		select {
 		case <-bvp.stopWorkersCh:
 			bvp.log.Infof("Stopping worker %d", number)
 
+			bvp.exportRemaining(ctx, number)
+			bvp.workerExited(number)
+
 			return
 		case batch := <-bvp.batchCh:
-			bvp.timer.Reset(bvp.o.BatchTimeout)
+			bvp.exportBatch(ctx, number, batch)
+		case batch := <-bvp.workerCh(number):
+			bvp.exportBatch(ctx, number, batch)
+		case <-steal:
+			// Looking for work doesn't count as being busy.
+			if !bvp.stealBatches(ctx, number) {
+				continue
+			}
+		case <-idle:
+			if bvp.retireWorker(number) {
+				bvp.log.Debugf("Worker %d is idle, stopping until batches arrive", number)
 
-			if err := bvp.exportWithTimeout(ctx, batch); err != nil {
-				bvp.log.WithError(err).Error("failed to export items")
+				return
 			}
+		}
 
-			bvp.metrics.SetItemsQueued(bvp.name, float64(len(bvp.queue)))
+		if idleTimer != nil {
+			resetTimer(idleTimer, bvp.o.WorkerIdleTimeout)
 		}
 	}
 }
 
-func (bvp *BatchItemProcessor[T]) drainQueue() {
-	bvp.log.Info("Draining queue: waiting for the batch builder to process remaining items")
+// exportRemaining exports batches still waiting for the worker when it is
+// stopped, so none are lost on shutdown.
+func (bvp *BatchItemProcessor[T]) exportRemaining(ctx context.Context, number int) {
+	for {
+		select {
+		case batch := <-bvp.batchCh:
+			bvp.exportBatch(ctx, number, batch)
+		case batch := <-bvp.workerCh(number):
+			bvp.exportBatch(ctx, number, batch)
+		default:
+			if bvp.o.WorkStealing {
+				bvp.stealBatches(ctx, number)
+			}
+
+			return
+		}
+	}
+}
+
+// observeBatchWait records how long the oldest item of a batch waited
+// between being queued and the batch's export starting.
+func (bvp *BatchItemProcessor[T]) observeBatchWait(batch []*TraceableItem[T]) {
+	if len(batch) == 0 {
+		return
+	}
+
+	oldest := batch[0].enqueued
+
+	for _, item := range batch[1:] {
+		if item.enqueued.Before(oldest) {
+			oldest = item.enqueued
+		}
+	}
+
+	bvp.metrics.ObserveBatchWaitDuration(bvp.label, time.Since(oldest))
+}
+
+func (bvp *BatchItemProcessor[T]) exportBatch(ctx context.Context, number int, batch []*TraceableItem[T]) {
+	defer bvp.takeTurn(ctx, number, batch)()
+
+	if bvp.invariants != nil {
+		bvp.invariants.settle(len(batch), bvp.o.MaxExportBatchSize)
+	}
+
+	// Parts of a two-phase round are still prepared, so the round settles
+	// and aborts what was prepared.
+	if bvp.discarding.Load() && (len(batch) == 0 || batch[0].round == nil) {
+		bvp.discardBatch(ctx, batch)
+
+		return
+	}
+
+	if err := bvp.failure(); err != nil && (len(batch) == 0 || batch[0].round == nil) {
+		bvp.failBatch(ctx, batch, err)
+
+		return
+	}
+
+	bvp.waitFlowPause(ctx)
+	bvp.waitExportsResumed(ctx)
+	bvp.waitExportRate(ctx, len(batch))
+
+	batch, ok := bvp.shedLate(batch)
+	if !ok {
+		return
+	}
+
+	bvp.observeBatchWait(batch)
+
+	bvp.activity.begin(number, len(batch), time.Now())
+	defer bvp.activity.end(number)
+
+	if bvp.workerLabels != nil {
+		bvp.workerExportStarted(number)
+		defer bvp.workerExportFinished(number, time.Now())
+	}
+
+	if len(batch) > 0 && batch[0].round != nil {
+		bvp.prepareBatch(ctx, number, batch[0].round, batch)
+		bvp.releaseExportBuffer(number)
+
+		bvp.reportQueued()
+
+		return
+	}
+
+	start := time.Now()
+	current := bvp.lifecycle.current.Load()
+
+	err := bvp.exportWithTimeout(ctx, bvp.workerExporter(number), batch, bvp.exportBuffer(number))
+	if err != nil {
+		bvp.log.WithError(err).Error("failed to export items")
+	}
+
+	if errors.Is(err, ErrExporterClosed) {
+		bvp.exporterClosed(ctx, current, err)
+	}
+
+	bvp.publishExport(number, len(batch), time.Since(start), err)
+
+	bvp.settleBatch(ctx, batch, err)
+	bvp.releaseExportBuffer(number)
+
+	bvp.reportQueued()
+}
+
+// settleBatch records the outcome of an exported batch and recycles it.
+func (bvp *BatchItemProcessor[T]) settleBatch(ctx context.Context, batch []*TraceableItem[T], err error) {
+	if bvp.checkpoints != nil {
+		bvp.checkpoints.exported(ctx, batch, err)
+	}
+
+	if err != nil && bvp.deadLetters != nil {
+		bvp.deadLetter(ctx, batch, err)
+	}
+
+	bvp.settlePending(batch)
+
+	bvp.items.putAll(batch)
+	bvp.buffers.put(batch)
+}
+
+// enqueueCoalesced enqueues a group of coalesced items, doing the work shared
+// by their writes once for the group rather than once per write. It returns
+// the error dropping each item, or nil if none were dropped.
+func (bvp *BatchItemProcessor[T]) enqueueCoalesced(items []*TraceableItem[T]) []error {
+	if bvp.arrivals != nil {
+		bvp.arrivals.add(time.Now(), float64(len(items)), 0)
+	}
+
+	var (
+		errs    []error
+		dropped int
+	)
+
+	for i, item := range items {
+		if err := bvp.tryEnqueue(context.Background(), item); err != nil {
+			if errs == nil {
+				errs = make([]error, len(items))
+			}
+
+			errs[i] = err
+			dropped++
+		}
+	}
+
+	bvp.reportQueued()
+
+	if dropped > 0 {
+		bvp.log.WithField("dropped", dropped).Warn("Queue is full. Coalesced items have been dropped.")
+	}
+
+	return errs
+}
+
+func (bvp *BatchItemProcessor[T]) sizeOf(items []*T) int {
+	if bvp.sizer == nil {
+		return 0
+	}
+
+	size := 0
+
+	for _, item := range items {
+		size += bvp.sizer(item)
+	}
+
+	return size
+}
+
+func (bvp *BatchItemProcessor[T]) throughputReporter() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bvp.stopCh:
+			return
+		case now := <-ticker.C:
+			items, bytes := bvp.throughput.rates(now)
+
+			bvp.reportQueued()
 
-	// First wait for queue to be processed.
-	for len(bvp.queue) > 0 {
-		time.Sleep(10 * time.Millisecond)
+			bvp.metrics.SetItemsThroughput(bvp.label, items)
+			bvp.metrics.SetBytesThroughput(bvp.label, bytes)
+		}
 	}
+}
 
-	bvp.log.Info("Draining queue: waiting for workers to finish processing batches")
+func (bvp *BatchItemProcessor[T]) recordShutdown(
+	duration time.Duration,
+	pending, unfinished int,
+	outcome ShutdownOutcome,
+) {
+	drained := max(pending-unfinished, 0)
+
+	bvp.metrics.SetShutdownDuration(bvp.label, duration)
+	bvp.metrics.SetShutdownItemsDrained(bvp.label, float64(drained))
+	bvp.metrics.SetShutdownItemsUnfinished(bvp.label, float64(unfinished))
+	bvp.metrics.IncShutdowns(bvp.label, string(outcome))
+
+	bvp.log.WithFields(logrus.Fields{
+		"duration":   duration,
+		"drained":    drained,
+		"unfinished": unfinished,
+		"outcome":    outcome,
+	}).Info("Processor shutdown complete")
+}
+
+// batchesPending returns the number of batches waiting for a worker.
+func (bvp *BatchItemProcessor[T]) batchesPending() int {
+	pending := len(bvp.cutCh) + len(bvp.batchCh)
 
-	// Then wait for any in-flight batches.
-	for len(bvp.batchCh) > 0 {
-		time.Sleep(10 * time.Millisecond)
+	for _, ch := range bvp.workerChs {
+		pending += len(ch)
 	}
 
-	bvp.log.Info("Draining queue: all items processed")
+	return pending
+}
+
+// drainQueue closes the queue and waits for the batch builder to hand the
+// remaining items, including any partial batch, to the workers.
+func (bvp *BatchItemProcessor[T]) drainQueue() {
+	bvp.log.Info("Draining queue: waiting for the batch builder to process remaining items")
+
+	// With priority lanes or a staged queue, the goroutine moving items
+	// to the queue closes it once they are empty.
+	switch {
+	case bvp.lanes != nil:
+		bvp.lanes.close()
+	case bvp.staged != nil:
+		bvp.staged.close()
+	default:
+		close(bvp.queue)
+	}
 
-	close(bvp.queue)
+	if bvp.started.Load() {
+		<-bvp.builderDone
+	}
 }
 
 func recoverSendOnClosedChan() {
@@ -563,24 +2357,169 @@ func (bvp *BatchItemProcessor[T]) enqueueOrDrop(
 	ctx context.Context,
 	item *TraceableItem[T],
 ) error {
+	select {
+	case <-bvp.stopCh:
+		return ErrShuttingDown
+	default:
+	}
+
+	if err := bvp.failure(); err != nil {
+		return err
+	}
+
+	if bvp.arrivals != nil {
+		bvp.arrivals.add(time.Now(), 1, 0)
+	}
+
+	return bvp.tryEnqueue(ctx, item)
+}
+
+// tryEnqueue adds an item to the queue, dropping it if the queue is full. It
+// only blocks, until ctx is done, with WithBlockOnQueueFull.
+func (bvp *BatchItemProcessor[T]) tryEnqueue(ctx context.Context, item *TraceableItem[T]) error {
 	// This ensures the bvp.queue<- below does not panic as the
 	// processor shuts down.
 	defer recoverSendOnClosedChan()
 
-	select {
-	case <-bvp.stopCh:
-		return errors.New("processor is shutting down")
-	default:
+	// Once enqueued the item may be exported and recycled straight away,
+	// so it must not be touched after.
+	label := item.producerLabel
+
+	if item.producer != nil && !item.producer.reserve() {
+		bvp.drop(item, ErrQuotaExceeded)
+
+		return ErrQuotaExceeded
+	}
+
+	pushed := bvp.enqueue(item)
+
+	if !pushed && bvp.o.BlockOnQueueFull {
+		if err := bvp.waitToEnqueue(ctx, item); err != nil {
+			if item.producer != nil {
+				item.producer.release()
+			}
+
+			return err
+		}
+
+		pushed = true
+	}
+
+	// Evict until the item fits, as other writers may take the room made.
+	for !pushed && bvp.o.DropPolicy == DropOldest && bvp.evictOldest() {
+		pushed = bvp.enqueue(item)
+	}
+
+	for !pushed && bvp.priority != nil && bvp.shedLowerPriority(item) {
+		pushed = bvp.enqueue(item)
+	}
+
+	if !pushed {
+		if item.producer != nil {
+			item.producer.release()
+		}
+
+		if bvp.overflowItem(item) {
+			return nil
+		}
+
+		bvp.metrics.IncQueueFullItemsDroppedBy(bvp.label, string(DropNewest), float64(1))
+		bvp.drop(item, ErrQueueFull)
+
+		return ErrQueueFull
+	}
+
+	if label != "" {
+		bvp.metrics.IncProducerItemsEnqueuedBy(bvp.label, label, float64(1))
+	}
+
+	return nil
+}
+
+// enqueue adds an item to the queue, or its priority lane, without blocking.
+// It returns false if there is no room.
+func (bvp *BatchItemProcessor[T]) enqueue(item *TraceableItem[T]) (pushed bool) {
+	item.enqueued = time.Now()
+
+	if bvp.inspector != nil {
+		// Track before enqueueing, as the batch builder may dequeue the
+		// item straight away.
+		bvp.inspector.track(item)
+	}
+
+	if bvp.invariants != nil {
+		bvp.invariants.enqueued.Add(1)
+	}
+
+	if bvp.checkpoints != nil {
+		item.seq = bvp.checkpoints.assign()
+	}
+
+	bvp.addStep(1)
+	item.generation = bvp.pending.add()
+
+	// Undo the bookkeeping if the queue is full, or closed under us.
+	defer func() {
+		if pushed {
+			return
+		}
+
+		bvp.settleStep(1)
+		bvp.pending.settle(item.generation, 1)
+
+		if bvp.checkpoints != nil {
+			bvp.checkpoints.skip(item.seq)
+		}
+
+		if bvp.inspector != nil {
+			bvp.inspector.untrack(item)
+		}
+
+		if bvp.invariants != nil {
+			bvp.invariants.enqueued.Add(-1)
+		}
+	}()
+
+	return bvp.push(item)
+}
+
+// push adds an item to the queue, its priority lane or the staged queue
+// without blocking.
+func (bvp *BatchItemProcessor[T]) push(item *TraceableItem[T]) bool {
+	if bvp.lanes != nil {
+		return bvp.lanes.enqueue(item)
+	}
+
+	if bvp.staged != nil {
+		return bvp.staged.push(item)
 	}
 
 	select {
 	case bvp.queue <- item:
-		bvp.metrics.SetItemsQueued(bvp.name, float64(len(bvp.queue)))
-
-		return nil
+		return true
 	default:
-		bvp.metrics.IncItemsDroppedBy(bvp.name, float64(1))
+		return false
 	}
+}
+
+// queuedItems returns the number of items waiting to be batched.
+func (bvp *BatchItemProcessor[T]) queuedItems() int {
+	if bvp.lanes != nil {
+		return len(bvp.queue) + bvp.lanes.len()
+	}
+
+	if bvp.staged != nil {
+		return len(bvp.queue) + bvp.staged.q.Len()
+	}
+
+	return len(bvp.queue)
+}
+
+// reportQueued sets the queue gauges. It is called once per write rather
+// than per item to keep metric updates off the hot path.
+func (bvp *BatchItemProcessor[T]) reportQueued() {
+	queued := float64(bvp.queuedItems())
 
-	return errors.New("queue is full")
+	bvp.metrics.SetItemsQueued(bvp.label, queued)
+	bvp.metrics.SetItemsQueuedRatio(bvp.label, queued/float64(bvp.o.MaxQueueSize))
 }
@@ -2,6 +2,8 @@ package processor
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -11,6 +13,7 @@ import (
 
 // mockExporter is a test implementation of ItemExporter.
 type mockExporter[T any] struct {
+	mu            sync.Mutex
 	exportedItems []*T
 	exportCount   atomic.Int64
 	exportErr     error
@@ -27,7 +30,10 @@ func (m *mockExporter[T]) ExportItems(ctx context.Context, items []*T) error {
 	}
 
 	m.exportCount.Add(int64(len(items)))
+
+	m.mu.Lock()
 	m.exportedItems = append(m.exportedItems, items...)
+	m.mu.Unlock()
 
 	return m.exportErr
 }
@@ -160,3 +166,467 @@ func TestBatchItemProcessor_OptionsValidation(t *testing.T) {
 		t.Error("expected error for zero workers")
 	}
 }
+
+func TestBatchItemProcessor_ShutdownTimeout(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[string]{exportDelay: time.Second}
+
+	proc, err := NewBatchItemProcessor[string](
+		exporter,
+		"test_shutdown_timeout",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(1),
+		WithBatchTimeout(10*time.Millisecond),
+		WithExportTimeout(0),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	items := make([]*string, 10)
+	for i := range items {
+		s := "item"
+		items[i] = &s
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	timeouts := DefaultMetrics.shutdowns.WithLabelValues("test_shutdown_timeout", string(ShutdownOutcomeTimeout))
+	before := counterValue(t, timeouts)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	if err := proc.Shutdown(shutdownCtx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	if timeouts := counterValue(t, timeouts) - before; timeouts != 1 {
+		t.Errorf("expected 1 timed out shutdown, got %v", timeouts)
+	}
+
+	// The item being exported, and those batched behind it, count as
+	// unfinished as well as those still queued.
+	unfinished := gaugeValue(t, DefaultMetrics.shutdownItemsUnfinished.WithLabelValues("test_shutdown_timeout"))
+	if unfinished != 10 {
+		t.Errorf("expected 10 unfinished items reported, got %v", unfinished)
+	}
+}
+
+func TestBatchItemProcessor_WriteCoalescing(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(1000),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(50*time.Millisecond),
+		WithWriteCoalescing(8, 10*time.Millisecond),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	// Single item writes are buffered by the coalescer.
+	for i := 0; i < 21; i++ {
+		val := i
+		if err := proc.Write(ctx, []*int{&val}); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+
+	// Wait for the coalescing delay and batch timeout to trigger export.
+	time.Sleep(200 * time.Millisecond)
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if exporter.exportCount.Load() != 21 {
+		t.Errorf("expected 21 items exported, got %d", exporter.exportCount.Load())
+	}
+}
+
+func TestBatchItemProcessor_WriteEach(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(2),
+		WithMaxExportBatchSize(2),
+		WithBatchTimeout(10*time.Second),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	events, _ := proc.Subscribe(10)
+
+	// The processor isn't started, so only two items fit in the queue.
+	one, two, three := 1, 2, 3
+
+	errs, err := proc.WriteEach(ctx, []*int{&one, nil, &two, &three})
+	if err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	want := []error{nil, ErrNilItem, nil, ErrQueueFull}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Errorf("item %d: expected %v, got %v", i, want[i], errs[i])
+		}
+	}
+
+	// The nil item is dropped like on every other write path.
+	for {
+		select {
+		case e := <-events:
+			if e.Kind == EventItemsDropped && e.Reason == "nil item" {
+				return
+			}
+		default:
+			t.Fatal("expected the nil item published as dropped")
+		}
+	}
+}
+
+func TestBatchItemProcessor_WriteValues(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(1000),
+		WithMaxExportBatchSize(3),
+		WithShippingMethod(ShippingMethodSync),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	values := []int{1, 2, 3}
+
+	if err := proc.WriteValues(ctx, values); err != nil {
+		t.Fatalf("failed to write values: %v", err)
+	}
+
+	// The caller's slice is copied, so mutating it must not affect exported items.
+	values[0] = 100
+
+	if len(exporter.exportedItems) != 3 {
+		t.Fatalf("expected 3 items exported, got %d", len(exporter.exportedItems))
+	}
+
+	for i, item := range exporter.exportedItems {
+		if *item != i+1 {
+			t.Errorf("item %d: expected %d, got %d", i, i+1, *item)
+		}
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+}
+
+// copyingExporter records copies of the values it exports, as their pointers
+// may be reused once the export returns.
+type copyingExporter struct {
+	mu     sync.Mutex
+	values []int
+}
+
+func (e *copyingExporter) ExportItems(_ context.Context, items []*int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, item := range items {
+		e.values = append(e.values, *item)
+	}
+
+	return nil
+}
+
+func (e *copyingExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestBatchItemProcessor_WriteValuesAsync(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &copyingExporter{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(1000),
+		WithMaxExportBatchSize(4),
+		WithBatchTimeout(time.Millisecond),
+		WithWorkers(2),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	// Writes span several buffers, which are reused as batches export.
+	values := make([]int, 10)
+
+	for write := range 50 {
+		for i := range values {
+			values[i] = write*len(values) + i
+		}
+
+		if err := proc.WriteValues(ctx, values); err != nil {
+			t.Fatalf("failed to write values: %v", err)
+		}
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	seen := make(map[int]bool)
+	for _, value := range exporter.values {
+		seen[value] = true
+	}
+
+	if len(exporter.values) != 500 || len(seen) != 500 {
+		t.Fatalf("expected 500 distinct values exported, got %d of which %d distinct", len(exporter.values), len(seen))
+	}
+}
+
+// startingExporter records whether it was started before exporting.
+type startingExporter[T any] struct {
+	mockExporter[T]
+	started  atomic.Bool
+	startErr error
+}
+
+func (e *startingExporter[T]) Start(_ context.Context) error {
+	e.started.Store(true)
+
+	return e.startErr
+}
+
+func TestBatchItemProcessor_ExporterStart(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &startingExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log, WithWorkers(1))
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	if !exporter.started.Load() {
+		t.Error("expected exporter to be started")
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	// Setup errors are returned from Start.
+	failing := &startingExporter[int]{startErr: errors.New("connection refused")}
+
+	proc, err = NewBatchItemProcessor[int](failing, "test", log, WithWorkers(1))
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	if err := proc.Start(ctx); !errors.Is(err, failing.startErr) {
+		t.Errorf("expected %v, got %v", failing.startErr, err)
+	}
+}
+
+// orderingExporter fails exports made after Shutdown.
+type orderingExporter[T any] struct {
+	mockExporter[T]
+	shutdown    atomic.Bool
+	lateExports atomic.Int64
+}
+
+func (e *orderingExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	if e.shutdown.Load() {
+		e.lateExports.Add(1)
+	}
+
+	return e.mockExporter.ExportItems(ctx, items)
+}
+
+func (e *orderingExporter[T]) Shutdown(_ context.Context) error {
+	e.shutdown.Store(true)
+
+	return nil
+}
+
+func TestBatchItemProcessor_ShutdownFlushesBeforeExporterShutdown(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	for _, workers := range []int{1, 4} {
+		exporter := &orderingExporter[int]{}
+		exporter.exportDelay = 20 * time.Millisecond
+
+		proc, err := NewBatchItemProcessor[int](
+			exporter,
+			"test",
+			log,
+			WithMaxQueueSize(1000),
+			WithMaxExportBatchSize(10),
+			WithBatchTimeout(10*time.Second),
+			WithWorkers(workers),
+		)
+		if err != nil {
+			t.Fatalf("failed to create processor: %v", err)
+		}
+
+		ctx := context.Background()
+
+		if err := proc.Start(ctx); err != nil {
+			t.Fatalf("failed to start: %v", err)
+		}
+
+		// Full batches are in flight and the last batch is partial when
+		// Shutdown is called.
+		items := make([]*int, 95)
+		for i := range items {
+			val := i
+			items[i] = &val
+		}
+
+		if err := proc.Write(ctx, items); err != nil {
+			t.Fatalf("failed to write items: %v", err)
+		}
+
+		if err := proc.Shutdown(ctx); err != nil {
+			t.Fatalf("failed to shutdown: %v", err)
+		}
+
+		if got := exporter.exportCount.Load(); got != 95 {
+			t.Errorf("workers %d: expected 95 items exported, got %d", workers, got)
+		}
+
+		if got := exporter.lateExports.Load(); got != 0 {
+			t.Errorf("workers %d: expected no exports after exporter shutdown, got %d", workers, got)
+		}
+	}
+}
+
+func TestBatchItemProcessor_ExportPipelining(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	proc, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{exportDelay: 500 * time.Millisecond},
+		"test",
+		log,
+		WithMaxQueueSize(10),
+		WithMaxExportBatchSize(1),
+		WithWorkers(1),
+		WithExportPipelining(3),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	items := make([]*int, 4)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	// While the first batch exports, the other three are assembled ahead
+	// and the queue empties.
+	deadline := time.Now().Add(400 * time.Millisecond)
+	for proc.queuedItems() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if queued := proc.queuedItems(); queued != 0 {
+		t.Errorf("expected the queue to be drained while exporting, got %d items", queued)
+	}
+
+	if pending := proc.batchesPending(); pending != 3 {
+		t.Errorf("expected 3 batches assembled ahead, got %d", pending)
+	}
+}
+
+func TestBatchItemProcessor_WaitForBatchCompletionError(t *testing.T) {
+	var bvp BatchItemProcessor[int]
+
+	exportErr := errors.New("export failed")
+
+	// By the time a sync writer waits, its item's error and completion may
+	// both be ready, and the error must be returned whichever is selected.
+	for range 100 {
+		item := &TraceableItem[int]{
+			errCh:       make(chan error, 1),
+			completedCh: make(chan struct{}, 1),
+		}
+
+		item.errCh <- exportErr
+		item.completedCh <- struct{}{}
+
+		if err := bvp.waitForBatchCompletion(context.Background(), []*TraceableItem[int]{item}); !errors.Is(err, exportErr) {
+			t.Fatalf("expected the export error, got %v", err)
+		}
+	}
+}
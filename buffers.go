@@ -0,0 +1,159 @@
+package processor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// WithZeroCopyExport reuses each worker's export slice instead of allocating
+// one per batch. The slice passed to ExportItems is only valid until it
+// returns; exporters that keep it must copy it. The items themselves are
+// never reused.
+func WithZeroCopyExport() BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ZeroCopyExport = true
+	}
+}
+
+// batchBuffers recycles batch buffers between the batch builder and the
+// workers. There are two buffers per worker: while a worker exports one, the
+// builder fills the other, and they swap at flush time instead of a new batch
+// being allocated and grown for every flush.
+type batchBuffers[T any] struct {
+	free chan []*TraceableItem[T]
+	size int
+}
+
+func newBatchBuffers[T any](workers, size int) *batchBuffers[T] {
+	return &batchBuffers[T]{
+		free: make(chan []*TraceableItem[T], 2*workers),
+		size: size,
+	}
+}
+
+// get returns an empty buffer, allocating one if none are free.
+func (b *batchBuffers[T]) get() []*TraceableItem[T] {
+	select {
+	case buf := <-b.free:
+		return buf
+	default:
+		return make([]*TraceableItem[T], 0, b.size)
+	}
+}
+
+// put returns a buffer once nothing references it, discarding it if enough
+// are free. Buffers smaller than a full batch, left by splitting, aren't kept.
+func (b *batchBuffers[T]) put(buf []*TraceableItem[T]) {
+	if cap(buf) < b.size {
+		return
+	}
+
+	// Clear the items so exported items can be collected.
+	clear(buf[:cap(buf)])
+
+	select {
+	case b.free <- buf[:0]:
+	default:
+	}
+}
+
+// sameBuffer reports whether a and b share a backing array.
+func sameBuffer[T any](a, b []*TraceableItem[T]) bool {
+	return cap(a) > 0 && cap(b) > 0 && &a[:1][0] == &b[:1][0]
+}
+
+// exportBuffer returns the export slice of a worker, or nil if export slices
+// aren't reused.
+func (bvp *BatchItemProcessor[T]) exportBuffer(worker int) []*T {
+	if bvp.exportBuffers == nil {
+		return nil
+	}
+
+	return bvp.exportBuffers[worker]
+}
+
+// releaseExportBuffer clears a worker's export slice after an export, so the
+// exported items can be collected.
+func (bvp *BatchItemProcessor[T]) releaseExportBuffer(worker int) {
+	if bvp.exportBuffers == nil {
+		return
+	}
+
+	buf := bvp.exportBuffers[worker]
+	clear(buf[:cap(buf)])
+}
+
+// traceableItemPool recycles the wrappers of asynchronously shipped items
+// once they are exported, so writes don't allocate one per item. Synchronous
+// items aren't pooled, as writers wait on them.
+type traceableItemPool[T any] struct {
+	pool sync.Pool
+}
+
+func (p *traceableItemPool[T]) get() *TraceableItem[T] {
+	if item, ok := p.pool.Get().(*TraceableItem[T]); ok {
+		return item
+	}
+
+	return &TraceableItem[T]{}
+}
+
+// putAll recycles the asynchronous items of an exported batch, and the values
+// of items written by value.
+func (p *traceableItemPool[T]) putAll(batch []*TraceableItem[T]) {
+	for _, item := range batch {
+		if item == nil {
+			continue
+		}
+
+		if item.chunk != nil {
+			item.chunk.release(1)
+			item.chunk = nil
+		}
+
+		if item.errCh != nil {
+			continue
+		}
+
+		*item = TraceableItem[T]{}
+
+		p.pool.Put(item)
+	}
+}
+
+// valueChunk holds the values of items written with WriteValues next to each
+// other, up to a batch of them. It is recycled once all its items are.
+type valueChunk[T any] struct {
+	values []T
+	refs   atomic.Int64
+	pool   *valueChunkPool[T]
+}
+
+// release drops n of the chunk's items, recycling it once none are left.
+// Chunks whose items are dropped before export are left to the garbage
+// collector rather than recycled.
+func (c *valueChunk[T]) release(n int) {
+	if c.refs.Add(-int64(n)) != 0 {
+		return
+	}
+
+	clear(c.values)
+	c.values = c.values[:0]
+
+	c.pool.pool.Put(c)
+}
+
+// valueChunkPool recycles value chunks, so writing by value doesn't allocate.
+type valueChunkPool[T any] struct {
+	pool sync.Pool
+	size int
+}
+
+// get returns an empty chunk.
+func (p *valueChunkPool[T]) get() *valueChunk[T] {
+	if c, ok := p.pool.Get().(*valueChunk[T]); ok {
+		return c
+	}
+
+	return &valueChunk[T]{values: make([]T, 0, p.size), pool: p}
+}
@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchBuffers(t *testing.T) {
+	buffers := newBatchBuffers[int](1, 4)
+
+	buf := buffers.get()
+	if cap(buf) != 4 {
+		t.Fatalf("expected a buffer for a full batch, got capacity %d", cap(buf))
+	}
+
+	buf = append(buf, &TraceableItem[int]{}, &TraceableItem[int]{})
+
+	if !sameBuffer(buf[:1], buf) {
+		t.Error("expected a subslice to share the buffer")
+	}
+
+	buffers.put(buf)
+
+	recycled := buffers.get()
+	if len(recycled) != 0 || !sameBuffer(recycled, buf) {
+		t.Fatal("expected the buffer to be recycled empty")
+	}
+
+	if recycled[:2][0] != nil {
+		t.Error("expected recycled buffers to be cleared")
+	}
+
+	// Small buffers left by splitting batches aren't kept.
+	buffers.put(make([]*TraceableItem[int], 1))
+
+	if len(buffers.free) != 0 {
+		t.Error("expected a small buffer to be discarded")
+	}
+}
+
+// sliceExporter records the backing array of every exported slice.
+type sliceExporter struct {
+	mu     sync.Mutex
+	arrays []**int
+}
+
+func (e *sliceExporter) ExportItems(_ context.Context, items []*int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.arrays = append(e.arrays, &items[:1][0])
+
+	return nil
+}
+
+func (e *sliceExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestBatchItemProcessor_ZeroCopyExport(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &sliceExporter{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(2),
+		WithWorkers(1),
+		WithZeroCopyExport(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	for range 2 {
+		one, two := 1, 2
+
+		if err := proc.Write(ctx, []*int{&one, &two}); err != nil {
+			t.Fatalf("failed to write items: %v", err)
+		}
+	}
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+
+	if len(exporter.arrays) != 2 {
+		t.Fatalf("expected 2 exports, got %d", len(exporter.arrays))
+	}
+
+	if exporter.arrays[0] != exporter.arrays[1] {
+		t.Error("expected the export slice to be reused")
+	}
+}
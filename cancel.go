@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"context"
+	"errors"
+)
+
+// CancelBehavior is what the processor does when the context passed to
+// Start is cancelled.
+type CancelBehavior string
+
+const (
+	// CancelBehaviorNone only cancels exports in flight, and those started
+	// later. The processor keeps accepting writes until Shutdown.
+	CancelBehaviorNone CancelBehavior = "none"
+	// CancelBehaviorDrain shuts the processor down as Shutdown does: writes
+	// are rejected and queued items are exported before the exporter is
+	// shut down. Exports aren't cancelled with the context.
+	CancelBehaviorDrain CancelBehavior = "drain"
+	// CancelBehaviorStop shuts the processor down without exporting what's
+	// queued: exports in flight are cancelled, and queued items are dropped
+	// and their sync writers told so.
+	CancelBehaviorStop CancelBehavior = "stop"
+)
+
+// WithCancelBehavior sets what the processor does when the context passed to
+// Start is cancelled, tying its lifecycle to that of its owner. Either way
+// Shutdown may still be called, and waits for the processor to stop.
+func WithCancelBehavior(behavior CancelBehavior) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.CancelBehavior = behavior
+	}
+}
+
+// workContext returns the context exports run under: Start's context, or,
+// when its cancellation drains the processor, one that isn't cancelled so
+// the queued items can still be exported.
+func (bvp *BatchItemProcessor[T]) workContext(ctx context.Context) context.Context {
+	if bvp.o.CancelBehavior == CancelBehaviorDrain {
+		return context.WithoutCancel(ctx)
+	}
+
+	return ctx
+}
+
+// shutdownOnCancel shuts the processor down once Start's context is
+// cancelled, unless it is shut down first.
+func (bvp *BatchItemProcessor[T]) shutdownOnCancel(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-bvp.stopCh:
+		return
+	}
+
+	bvp.log.WithField("behavior", bvp.o.CancelBehavior).Info("Start context cancelled, shutting down")
+
+	if bvp.o.CancelBehavior == CancelBehaviorStop {
+		bvp.discarding.Store(true)
+	}
+
+	if err := bvp.Shutdown(context.WithoutCancel(ctx)); err != nil {
+		bvp.log.WithError(err).Error("failed to shutdown processor")
+	}
+}
+
+// discardBatch drops a batch without exporting it, once the processor is
+// stopping with CancelBehaviorStop.
+func (bvp *BatchItemProcessor[T]) discardBatch(ctx context.Context, batch []*TraceableItem[T]) {
+	bvp.metrics.IncItemsDroppedBy(bvp.label, float64(len(batch)))
+
+	bvp.publish(EventItemsDropped, Event{Items: len(batch), Reason: "processor stopped"})
+
+	err := errors.Join(ErrShuttingDown, context.Cause(ctx))
+
+	signalWriters(batch, err, nil)
+	bvp.settleBatch(ctx, batch, err)
+}
@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_CancelBehaviorDrain(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{exportDelay: 10 * time.Millisecond}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(1),
+		WithCancelBehavior(CancelBehaviorDrain),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Write(context.Background(), ints(25)); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	// Shutdown waits for the drain started by the cancellation.
+	if err := proc.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 25 {
+		t.Fatalf("expected every queued item exported, got %d", got)
+	}
+
+	if err := proc.Write(context.Background(), ints(1)); !errors.Is(err, ErrShuttingDown) {
+		t.Fatalf("expected writes rejected once drained, got %v", err)
+	}
+}
+
+func TestBatchItemProcessor_CancelBehaviorStop(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{exportDelay: time.Hour}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxExportBatchSize(5),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(1),
+		WithCancelBehavior(CancelBehaviorStop),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, _ := proc.Subscribe(100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first batch is stuck exporting while the rest wait.
+	if err := proc.Write(context.Background(), ints(15)); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	dropped := 0
+
+	// The channel is closed once the processor has shut down.
+	for e := range events {
+		if e.Kind == EventItemsDropped {
+			dropped += e.Items
+		}
+	}
+
+	if got := exporter.exportCount.Load(); got != 0 {
+		t.Fatalf("expected nothing exported, got %d", got)
+	}
+
+	if dropped == 0 {
+		t.Fatal("expected queued items dropped")
+	}
+
+	if err := proc.Write(context.Background(), ints(1)); !errors.Is(err, ErrShuttingDown) {
+		t.Fatalf("expected writes rejected once stopped, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// WithCapacityAdvisor checks every interval whether the processor can keep up
+// with the rate items are written at. The export capacity is estimated from
+// the recent export duration as workers × max batch size / export duration.
+// The ratio of the arrival rate to the capacity is reported through the
+// capacity_utilization metric, and a warning is logged while it is above one,
+// before the queue overflows.
+func WithCapacityAdvisor(interval time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.CapacityCheckInterval = interval
+	}
+}
+
+// capacityAdvice is the outcome of a capacity check.
+type capacityAdvice struct {
+	arrivalRate float64
+	capacity    float64
+	utilization float64
+	// queueWait is how long queued items wait at the current capacity, by
+	// Little's law.
+	queueWait time.Duration
+	// timeToFull estimates when the queue overflows at the current rates.
+	// It is zero if the queue isn't filling up.
+	timeToFull time.Duration
+}
+
+// adviseCapacity compares the arrival rate with the estimated export
+// capacity. It returns false until an export duration has been observed.
+func adviseCapacity(arrivalRate float64, exportDuration time.Duration, workers, batchSize, queued, queueSize int) (capacityAdvice, bool) {
+	if exportDuration <= 0 {
+		return capacityAdvice{}, false
+	}
+
+	capacity := float64(workers*batchSize) / exportDuration.Seconds()
+
+	advice := capacityAdvice{
+		arrivalRate: arrivalRate,
+		capacity:    capacity,
+		utilization: arrivalRate / capacity,
+		queueWait:   time.Duration(float64(queued) / capacity * float64(time.Second)),
+	}
+
+	if arrivalRate > capacity {
+		advice.timeToFull = time.Duration(float64(queueSize-queued) / (arrivalRate - capacity) * float64(time.Second))
+	}
+
+	return advice, true
+}
+
+func (bvp *BatchItemProcessor[T]) capacityAdvisor() {
+	ticker := time.NewTicker(bvp.o.CapacityCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bvp.stopCh:
+			return
+		case now := <-ticker.C:
+			bvp.checkCapacity(now)
+		}
+	}
+}
+
+func (bvp *BatchItemProcessor[T]) checkCapacity(now time.Time) {
+	arrivalRate, _ := bvp.arrivals.rates(now)
+
+	advice, ok := adviseCapacity(
+		arrivalRate,
+		bvp.exportLatency.get(),
+		bvp.o.Workers,
+		bvp.o.MaxExportBatchSize,
+		bvp.queuedItems(),
+		bvp.o.MaxQueueSize,
+	)
+	if !ok {
+		return
+	}
+
+	bvp.metrics.SetCapacityUtilization(bvp.label, advice.utilization)
+
+	if advice.utilization <= 1 {
+		return
+	}
+
+	bvp.log.WithFields(logrus.Fields{
+		"arrival_rate":    advice.arrivalRate,
+		"export_capacity": advice.capacity,
+		"utilization":     advice.utilization,
+		"queue_wait":      advice.queueWait,
+		"time_to_full":    advice.timeToFull,
+		"workers":         bvp.o.Workers,
+		"batch_size":      bvp.o.MaxExportBatchSize,
+	}).Warn("Items are written faster than they can be exported. The queue will overflow unless load drops or more workers or larger batches are configured.")
+}
@@ -0,0 +1,41 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdviseCapacity(t *testing.T) {
+	// Two workers exporting batches of 100 in 100ms export 2000 items a
+	// second.
+	advice, ok := adviseCapacity(4000, 100*time.Millisecond, 2, 100, 1000, 5000)
+	if !ok {
+		t.Fatal("expected advice once an export duration is known")
+	}
+
+	if advice.capacity != 2000 {
+		t.Errorf("expected a capacity of 2000, got %v", advice.capacity)
+	}
+
+	if advice.utilization != 2 {
+		t.Errorf("expected a utilization of 2, got %v", advice.utilization)
+	}
+
+	if advice.queueWait != 500*time.Millisecond {
+		t.Errorf("expected a queue wait of 500ms, got %s", advice.queueWait)
+	}
+
+	// The queue gains 2000 items a second with 4000 free.
+	if advice.timeToFull != 2*time.Second {
+		t.Errorf("expected the queue to fill in 2s, got %s", advice.timeToFull)
+	}
+
+	advice, _ = adviseCapacity(1000, 100*time.Millisecond, 2, 100, 0, 5000)
+	if advice.utilization != 0.5 || advice.timeToFull != 0 {
+		t.Errorf("expected a half utilized processor that keeps up, got %+v", advice)
+	}
+
+	if _, ok := adviseCapacity(1000, 0, 2, 100, 0, 5000); ok {
+		t.Error("expected no advice before an export duration is known")
+	}
+}
@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"context"
+	"sync"
+)
+
+// WithCheckpointer reports progress to sources that resume from a position,
+// such as a log offset or WAL position. marker reads an item's position, and
+// checkpoint is called with the marker of the latest written item once it
+// and every item written before it has been exported, so committing the
+// marker never skips an item still in the processor. Markers are reported
+// in the order items were enqueued; with concurrent writers that order is
+// only meaningful if the markers agree with it.
+//
+// Checkpoints stop advancing at the first item whose export fails, so a
+// source resuming from the last checkpoint reads it again. Calls to
+// checkpoint are never concurrent, and markers are skipped while a call is
+// running, so checkpoint always sees the newest marker.
+func WithCheckpointer[T any, M any](marker func(item *T) M, checkpoint func(ctx context.Context, marker M)) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.CheckpointMarker = func(item *T) any {
+			return marker(item)
+		}
+		o.Checkpoint = func(ctx context.Context, m any) {
+			checkpoint(ctx, m.(M))
+		}
+	}
+}
+
+// checkpointTracker settles enqueued items by sequence number and reports the
+// marker of the highest contiguous settled item.
+type checkpointTracker[T any] struct {
+	marker     func(item *T) any
+	checkpoint func(ctx context.Context, marker any)
+
+	mu sync.Mutex
+	// seq is the next sequence number to assign and next the lowest one
+	// not yet settled.
+	seq  uint64
+	next uint64
+	// settled holds items settled out of order. A nil item was never
+	// enqueued and carries no marker.
+	settled map[uint64]*T
+	latest  *T
+	pending bool
+	stalled bool
+
+	reportMu sync.Mutex
+}
+
+func newCheckpointTracker[T any](marker func(item *T) any, checkpoint func(ctx context.Context, marker any)) *checkpointTracker[T] {
+	return &checkpointTracker[T]{
+		marker:     marker,
+		checkpoint: checkpoint,
+		settled:    make(map[uint64]*T),
+	}
+}
+
+// assign returns the sequence number of an item about to be enqueued.
+func (c *checkpointTracker[T]) assign() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seq := c.seq
+	c.seq++
+
+	return seq
+}
+
+// skip settles a sequence number whose item wasn't enqueued.
+func (c *checkpointTracker[T]) skip(seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.settle(seq, nil)
+}
+
+// exported settles the items of an exported batch and reports the new
+// checkpoint, if any. A failed export stalls the checkpoint for good.
+func (c *checkpointTracker[T]) exported(ctx context.Context, batch []*TraceableItem[T], err error) {
+	c.mu.Lock()
+
+	if err != nil {
+		c.stalled = true
+		clear(c.settled)
+	}
+
+	for _, item := range batch {
+		if item != nil {
+			c.settle(item.seq, item.item)
+		}
+	}
+
+	c.mu.Unlock()
+
+	c.report(ctx)
+}
+
+// settle records a settled item and advances past every contiguous settled
+// item. It must be called with the lock held.
+func (c *checkpointTracker[T]) settle(seq uint64, item *T) {
+	if c.stalled {
+		return
+	}
+
+	if seq != c.next {
+		c.settled[seq] = item
+
+		return
+	}
+
+	for {
+		if item != nil {
+			c.latest = item
+			c.pending = true
+		}
+
+		c.next++
+
+		var ok bool
+
+		if item, ok = c.settled[c.next]; !ok {
+			return
+		}
+
+		delete(c.settled, c.next)
+	}
+}
+
+// report calls the checkpoint with the newest marker not yet reported.
+func (c *checkpointTracker[T]) report(ctx context.Context) {
+	if !c.reportMu.TryLock() {
+		// The running report picks up the newest marker when it's done.
+		return
+	}
+
+	for {
+		c.mu.Lock()
+
+		latest, pending := c.latest, c.pending
+		c.pending = false
+
+		c.mu.Unlock()
+
+		if !pending {
+			c.reportMu.Unlock()
+
+			// Catch a marker settled after the check but before the
+			// unlock, whose reporter found the lock taken.
+			c.mu.Lock()
+			pending = c.pending
+			c.mu.Unlock()
+
+			if !pending || !c.reportMu.TryLock() {
+				return
+			}
+
+			continue
+		}
+
+		c.checkpoint(ctx, c.marker(latest))
+	}
+}
@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_Checkpointer(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{exportDelay: time.Millisecond}
+
+	var (
+		mu      sync.Mutex
+		markers []int
+	)
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxQueueSize(1000),
+		WithMaxExportBatchSize(10),
+		WithWorkers(4),
+		WithCheckpointer(func(item *int) int { return *item }, func(_ context.Context, marker int) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			markers = append(markers, marker)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	items := make([]*int, 200)
+	for i := range items {
+		items[i] = &i
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(markers) == 0 || markers[len(markers)-1] != 199 {
+		t.Fatalf("expected the last checkpoint to be the last item, got %v", markers)
+	}
+
+	for i := 1; i < len(markers); i++ {
+		if markers[i] <= markers[i-1] {
+			t.Fatalf("expected checkpoints to advance in order, got %v", markers)
+		}
+	}
+}
+
+func TestBatchItemProcessor_CheckpointerStallsOnFailure(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{exportErr: errors.New("export failed")}
+
+	var checkpoints int
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxExportBatchSize(1),
+		WithCheckpointer(func(item *int) int { return *item }, func(_ context.Context, _ int) {
+			checkpoints++
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	item := 1
+
+	if err := proc.Write(ctx, []*int{&item}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if checkpoints != 0 {
+		t.Fatalf("expected no checkpoints after a failed export, got %d", checkpoints)
+	}
+}
+
+func TestCheckpointTracker(t *testing.T) {
+	ctx := context.Background()
+
+	var markers []int
+
+	c := newCheckpointTracker[int](func(item *int) any { return *item }, func(_ context.Context, marker any) {
+		markers = append(markers, marker.(int))
+	})
+
+	batch := func(values ...int) []*TraceableItem[int] {
+		items := make([]*TraceableItem[int], len(values))
+		for i := range values {
+			items[i] = &TraceableItem[int]{item: &values[i], seq: c.assign()}
+		}
+
+		return items
+	}
+
+	first, second, third := batch(0, 1), batch(2), batch(3)
+
+	// A later batch settling first doesn't move the checkpoint.
+	c.exported(ctx, second, nil)
+
+	if len(markers) != 0 {
+		t.Fatalf("expected no checkpoint before the first batch, got %v", markers)
+	}
+
+	// Items that never made it in to the queue don't hold it back.
+	c.skip(c.assign())
+
+	c.exported(ctx, first, nil)
+
+	if len(markers) != 1 || markers[0] != 2 {
+		t.Fatalf("expected a checkpoint at 2, got %v", markers)
+	}
+
+	// A failed export stalls the checkpoint for good.
+	c.exported(ctx, third, errors.New("export failed"))
+	c.exported(ctx, batch(4), nil)
+
+	if len(markers) != 1 {
+		t.Fatalf("expected the checkpoint to stall after a failure, got %v", markers)
+	}
+}
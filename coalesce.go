@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"sync"
+	"time"
+)
+
+// writeCoalescer buffers items from small writes and hands them off in
+// larger groups, either once size items are buffered or delay has passed
+// since the first item was buffered.
+type writeCoalescer[T any] struct {
+	mu      sync.Mutex
+	size    int
+	delay   time.Duration
+	pending []*TraceableItem[T]
+	timer   *time.Timer
+	// enqueue enqueues items, returning the error enqueueing each item, or
+	// nil if all were enqueued.
+	enqueue func(items []*TraceableItem[T]) []error
+
+	// closed is set by the final flush on shutdown, after which writes are
+	// refused rather than buffered for a flush that will never come.
+	closed bool
+}
+
+func newWriteCoalescer[T any](
+	size int,
+	delay time.Duration,
+	enqueue func(items []*TraceableItem[T]) []error,
+) *writeCoalescer[T] {
+	return &writeCoalescer[T]{
+		size:    size,
+		delay:   delay,
+		pending: make([]*TraceableItem[T], 0, size),
+		enqueue: enqueue,
+	}
+}
+
+// add buffers items, enqueueing the buffer if it is full. It returns the
+// first error enqueueing items, if the buffer was enqueued, and
+// ErrShuttingDown once the coalescer is closed. Errors enqueueing the items
+// of earlier writes aren't returned, as they aren't the caller's.
+func (c *writeCoalescer[T]) add(items []*TraceableItem[T]) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrShuttingDown
+	}
+
+	start := len(c.pending)
+	c.pending = append(c.pending, items...)
+
+	if len(c.pending) >= c.size {
+		errs := c.flushLocked()
+		if errs == nil {
+			return nil
+		}
+
+		for _, err := range errs[start:] {
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.delay, c.flush)
+	}
+
+	return nil
+}
+
+// flush enqueues any buffered items once the delay has passed. Their writes
+// have returned, so items that can't be enqueued are only logged and counted
+// as dropped.
+func (c *writeCoalescer[T]) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flushLocked()
+}
+
+// close enqueues any buffered items for the last time and refuses later
+// writes.
+func (c *writeCoalescer[T]) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	c.flushLocked()
+}
+
+// flushLocked enqueues any buffered items, returning the error enqueueing
+// each, or nil if all were enqueued. Enqueueing under c.mu keeps coalesced
+// items in write order. The caller must hold c.mu.
+func (c *writeCoalescer[T]) flushLocked() []error {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+
+	if len(c.pending) == 0 {
+		return nil
+	}
+
+	errs := c.enqueue(c.pending)
+
+	c.pending = make([]*TraceableItem[T], 0, c.size)
+
+	return errs
+}
@@ -0,0 +1,159 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_WriteCoalescingQueueFull(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "test", log,
+		WithMaxQueueSize(2),
+		WithMaxExportBatchSize(2),
+		WithWriteCoalescing(4, 5*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// The processor isn't started, so only two items fit in the queue. The
+	// write filling the buffer is told its group was partly dropped.
+	for i := range 3 {
+		if err := proc.Write(ctx, ints(1)); err != nil {
+			t.Fatalf("write %d: expected the item buffered, got %v", i, err)
+		}
+	}
+
+	if err := proc.Write(ctx, ints(1)); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected the flushing write to get ErrQueueFull, got %v", err)
+	}
+
+	// Items flushed by the delay are only counted as dropped. Their write
+	// has returned, and the next write's items aren't affected.
+	if err := proc.Write(ctx, ints(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := proc.Write(ctx, ints(1)); err != nil {
+		t.Fatalf("expected the next write not to get an earlier write's error, got %v", err)
+	}
+}
+
+func TestWriteCoalescer_FlushErrors(t *testing.T) {
+	errDropped := errors.New("dropped")
+
+	// Only the first item of each flush fits.
+	c := newWriteCoalescer(3, time.Hour, func(items []*TraceableItem[int]) []error {
+		errs := make([]error, len(items))
+		for i := 1; i < len(items); i++ {
+			errs[i] = errDropped
+		}
+
+		return errs
+	})
+
+	// The flushing write isn't told about another write's dropped items.
+	if err := c.add(make([]*TraceableItem[int], 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.add(make([]*TraceableItem[int], 1)); !errors.Is(err, errDropped) {
+		t.Fatalf("expected the flushing write told its item was dropped, got %v", err)
+	}
+
+	c.close()
+
+	c = newWriteCoalescer(3, time.Hour, func(items []*TraceableItem[int]) []error {
+		errs := make([]error, len(items))
+		errs[0] = errDropped
+
+		return errs
+	})
+
+	if err := c.add(make([]*TraceableItem[int], 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.add(make([]*TraceableItem[int], 2)); err != nil {
+		t.Fatalf("expected the flushing write's own items enqueued, got %v", err)
+	}
+}
+
+func TestBatchItemProcessor_WriteCoalescingShutdown(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{}
+	name := "write-coalescing-shutdown-test"
+
+	proc, err := NewBatchItemProcessor[int](exporter, name, log,
+		WithWriteCoalescing(10, time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		if err := proc.Write(ctx, ints(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Items still held by the coalescer are drained and counted.
+	if got := exporter.exportCount.Load(); got != 3 {
+		t.Fatalf("expected 3 items exported, got %d", got)
+	}
+
+	if got := gaugeValue(t, DefaultMetrics.shutdownItemsDrained.WithLabelValues(name)); got != 3 {
+		t.Fatalf("expected 3 items counted as drained, got %v", got)
+	}
+}
+
+func TestWriteCoalescer_Close(t *testing.T) {
+	var enqueued []*TraceableItem[int]
+
+	c := newWriteCoalescer(10, time.Hour, func(items []*TraceableItem[int]) []error {
+		enqueued = append(enqueued, items...)
+
+		return nil
+	})
+
+	if err := c.add(make([]*TraceableItem[int], 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	c.close()
+
+	if len(enqueued) != 3 {
+		t.Fatalf("expected buffered items enqueued on close, got %d", len(enqueued))
+	}
+
+	// Writes racing the final flush are refused rather than lost.
+	if err := c.add(make([]*TraceableItem[int], 1)); !errors.Is(err, ErrShuttingDown) {
+		t.Fatalf("expected ErrShuttingDown once closed, got %v", err)
+	}
+
+	if len(enqueued) != 3 {
+		t.Fatalf("expected nothing enqueued once closed, got %d", len(enqueued))
+	}
+}
@@ -0,0 +1,35 @@
+package processor
+
+import (
+	"encoding/json"
+)
+
+// Codec serializes batches of items, for features that persist items outside
+// of memory.
+type Codec[T any] interface {
+	// Encode serializes a batch of items.
+	Encode(items []*T) ([]byte, error)
+	// Decode deserializes a batch previously produced by Encode.
+	Decode(data []byte) ([]*T, error)
+}
+
+// JSONCodec encodes batches as a JSON array.
+type JSONCodec[T any] struct{}
+
+var _ Codec[struct{}] = JSONCodec[struct{}]{}
+
+// Encode serializes items as a JSON array.
+func (JSONCodec[T]) Encode(items []*T) ([]byte, error) {
+	return json.Marshal(items)
+}
+
+// Decode deserializes a JSON array of items.
+func (JSONCodec[T]) Decode(data []byte) ([]*T, error) {
+	var items []*T
+
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,38 @@
+package processor
+
+import (
+	"context"
+)
+
+// DeadLetterHandler receives items that could not be exported, with the
+// error that failed them, so they can be persisted and replayed later.
+type DeadLetterHandler[T any] func(ctx context.Context, items []*T, err error)
+
+// WithDeadLetterHandler hands items whose export failed for good, after any
+// retries set with WithRetry, to handler. Only the failed items of a batch
+// that partly failed are handed over. The handler is called from the worker
+// that exported the batch, before the worker moves on, with a context that
+// isn't cancelled with the export. The items must not be kept after it
+// returns, as they may be recycled.
+func WithDeadLetterHandler[T any](handler DeadLetterHandler[T]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DeadLetterHandler = handler
+	}
+}
+
+// deadLetter hands the failed items of a batch to the dead letter handler.
+func (bvp *BatchItemProcessor[T]) deadLetter(ctx context.Context, batch []*TraceableItem[T], err error) {
+	failed := make([]*T, 0, len(batch))
+
+	partial, ok := partialErrors(err, len(batch))
+
+	for i, item := range batch {
+		if ok && partial.ItemErrors[i] == nil {
+			continue
+		}
+
+		failed = append(failed, item.item)
+	}
+
+	bvp.deadLetters(context.WithoutCancel(ctx), failed, err)
+}
@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_DeadLetterHandler(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	var (
+		mu       sync.Mutex
+		received []int
+		errs     []error
+	)
+
+	handler := func(_ context.Context, items []*int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, item := range items {
+			received = append(received, *item)
+		}
+
+		errs = append(errs, err)
+	}
+
+	// Only the odd items of the batch fail.
+	proc, err := NewBatchItemProcessor[int](ExporterFromV2[int](&oddExporter{}), "test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(4),
+		WithDeadLetterHandler(handler),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Write(ctx, ints(4)); !errors.Is(err, errOdd) {
+		t.Fatalf("expected the odd items to fail, got %v", err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !slices.Equal(received, []int{1, 3}) {
+		t.Fatalf("expected the failed items dead lettered, got %v", received)
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], errOdd) {
+		t.Fatalf("expected one dead letter with the export error, got %v", errs)
+	}
+}
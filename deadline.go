@@ -0,0 +1,79 @@
+package processor
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DeadlineFunc returns the time by which an item must be exported. A zero
+// time means the item has no deadline.
+type DeadlineFunc[T any] func(item *T) time.Time
+
+// WithDeadlineFunc gives items a deadline. The batch builder flushes a batch
+// early enough for its most urgent item to be exported by its deadline,
+// instead of waiting for the batch timeout. Batches are flushed ahead of the
+// deadline by the recent export duration, or by the lead set with
+// WithDeadlineLead if that is longer.
+func WithDeadlineFunc[T any](deadline DeadlineFunc[T]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DeadlineFunc = deadline
+	}
+}
+
+// WithDeadlineLead sets the minimum time ahead of an item's deadline at which
+// its batch is flushed, leaving room for queueing before export.
+func WithDeadlineLead(lead time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DeadlineLead = lead
+	}
+}
+
+// WithMaxBatchAge flushes a batch once its oldest item has been queued for
+// age. Unlike the batch timeout, which runs from the previous flush, the age
+// runs from when each item was queued, bounding how long a slow trickle of
+// items waits for its batch to be cut.
+func WithMaxBatchAge(age time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.MaxBatchAge = age
+	}
+}
+
+// latencyEstimate is an exponentially weighted moving average of export
+// durations.
+type latencyEstimate struct {
+	nanos atomic.Int64
+}
+
+func (l *latencyEstimate) observe(d time.Duration) {
+	for {
+		old := l.nanos.Load()
+
+		next := int64(d)
+		if old > 0 {
+			next = old + (int64(d)-old)/8
+		}
+
+		if l.nanos.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (l *latencyEstimate) get() time.Duration {
+	return time.Duration(l.nanos.Load())
+}
+
+// flushDeadline returns when the batch holding item must be flushed for the
+// item to meet its deadline.
+func (bvp *BatchItemProcessor[T]) flushDeadline(item *TraceableItem[T]) (time.Time, bool) {
+	if bvp.deadlineFunc == nil {
+		return time.Time{}, false
+	}
+
+	deadline := bvp.deadlineFunc(item.item)
+	if deadline.IsZero() {
+		return time.Time{}, false
+	}
+
+	return deadline.Add(-max(bvp.o.DeadlineLead, bvp.exportLatency.get())), true
+}
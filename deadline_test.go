@@ -0,0 +1,139 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type deadlineItem struct {
+	deadline time.Time
+}
+
+func TestBatchItemProcessor_DeadlineFlush(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[deadlineItem]{}
+
+	proc, err := NewBatchItemProcessor[deadlineItem](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(10*time.Second),
+		WithWorkers(1),
+		WithDeadlineFunc(func(item *deadlineItem) time.Time {
+			return item.deadline
+		}),
+		WithDeadlineLead(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	// Items without a deadline wait for the batch timeout, until an urgent
+	// item joins the batch.
+	relaxed := &deadlineItem{}
+	if err := proc.Write(ctx, []*deadlineItem{relaxed}); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if got := exporter.exportCount.Load(); got != 0 {
+		t.Fatalf("expected no items exported yet, got %d", got)
+	}
+
+	urgent := &deadlineItem{deadline: time.Now().Add(100 * time.Millisecond)}
+	if err := proc.Write(ctx, []*deadlineItem{urgent}); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	for exporter.exportCount.Load() < 2 && time.Now().Before(urgent.deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if got := exporter.exportCount.Load(); got != 2 {
+		t.Errorf("expected 2 items exported by the deadline, got %d", got)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+}
+
+func TestBatchItemProcessor_MaxBatchAge(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(10*time.Second),
+		WithWorkers(1),
+		WithMaxBatchAge(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	events, _ := proc.Subscribe(10)
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	written := time.Now()
+
+	if err := proc.Write(ctx, ints(1)); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	// A later item doesn't hold back the oldest one.
+	time.Sleep(20 * time.Millisecond)
+
+	if err := proc.Write(ctx, ints(1)); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case e := <-events:
+			if e.Kind != EventBatchCreated {
+				continue
+			}
+
+			if e.Reason != "max_batch_age" || e.Items != 2 {
+				t.Fatalf("expected a batch of 2 cut for its age, got %+v", e)
+			}
+
+			if waited := time.Since(written); waited > time.Second {
+				t.Fatalf("expected the batch cut well before the batch timeout, waited %s", waited)
+			}
+
+			return
+		case <-timeout:
+			t.Fatal("expected the batch cut once its oldest item was too old")
+		}
+	}
+}
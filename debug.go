@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"sync"
+	"time"
+)
+
+// Worker states reported by DebugSnapshot.
+const (
+	WorkerStateStopped   = "stopped"
+	WorkerStateIdle      = "idle"
+	WorkerStateExporting = "exporting"
+)
+
+// DebugSnapshot is a JSON-serializable dump of a processor's internal state,
+// meant for admin endpoints.
+type DebugSnapshot struct {
+	Processor  string               `json:"processor"`
+	Queue      QueueSnapshot        `json:"queue"`
+	Workers    []WorkerSnapshot     `json:"workers"`
+	DiskBuffer *DiskBufferSnapshot  `json:"disk_buffer,omitempty"`
+	Health     *HealthCheckSnapshot `json:"health,omitempty"`
+}
+
+// QueueSnapshot describes queue occupancy.
+type QueueSnapshot struct {
+	Queued   int `json:"queued"`
+	Capacity int `json:"capacity"`
+}
+
+// WorkerSnapshot describes what a worker is doing.
+type WorkerSnapshot struct {
+	ID    int    `json:"id"`
+	State string `json:"state"`
+	// BatchSize is the size of the batch being exported, if exporting.
+	BatchSize int `json:"batch_size,omitempty"`
+	// Duration is how long the current export has been running.
+	Duration time.Duration `json:"duration_ns,omitempty"`
+}
+
+// DiskBufferSnapshot summarizes batches waiting in the disk buffer to be
+// replayed.
+type DiskBufferSnapshot struct {
+	Batches  int   `json:"batches"`
+	Bytes    int64 `json:"bytes"`
+	Degraded bool  `json:"degraded"`
+}
+
+// HealthCheckSnapshot is the result of the latest exporter health probe.
+type HealthCheckSnapshot struct {
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// workerActivity records the batch each worker is exporting.
+type workerActivity struct {
+	mu      sync.Mutex
+	sizes   []int
+	started []time.Time
+}
+
+func newWorkerActivity(workers int) *workerActivity {
+	return &workerActivity{
+		sizes:   make([]int, workers),
+		started: make([]time.Time, workers),
+	}
+}
+
+// begin records a worker starting to export a batch.
+func (a *workerActivity) begin(worker, size int, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sizes[worker] = size
+	a.started[worker] = now
+}
+
+// end records a worker finishing an export.
+func (a *workerActivity) end(worker int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sizes[worker] = 0
+	a.started[worker] = time.Time{}
+}
+
+// get returns the batch a worker is exporting, with a zero start time if idle.
+func (a *workerActivity) get(worker int) (int, time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.sizes[worker], a.started[worker]
+}
+
+// DebugSnapshot returns a snapshot of the processor's internal state: queue
+// occupancy, what each worker is doing, batches waiting in the disk buffer
+// and the latest exporter health probe.
+func (bvp *BatchItemProcessor[T]) DebugSnapshot() DebugSnapshot {
+	now := time.Now()
+
+	snapshot := DebugSnapshot{
+		Processor: bvp.name,
+		Queue: QueueSnapshot{
+			Queued:   bvp.queuedItems(),
+			Capacity: bvp.o.MaxQueueSize,
+		},
+		Workers: make([]WorkerSnapshot, bvp.o.Workers),
+	}
+
+	bvp.workersMu.Lock()
+	running := append([]bool(nil), bvp.workerRunning...)
+	bvp.workersMu.Unlock()
+
+	for i := range snapshot.Workers {
+		worker := WorkerSnapshot{ID: i, State: WorkerStateStopped}
+
+		size, started := bvp.activity.get(i)
+
+		switch {
+		case !started.IsZero():
+			worker.State = WorkerStateExporting
+			worker.BatchSize = size
+			worker.Duration = now.Sub(started)
+		case running[i]:
+			worker.State = WorkerStateIdle
+		}
+
+		snapshot.Workers[i] = worker
+	}
+
+	if bvp.diskBuffer != nil {
+		buffered := &DiskBufferSnapshot{
+			Bytes:    bvp.diskBuffer.bytes(),
+			Degraded: bvp.diskBuffer.degraded(),
+		}
+
+		if paths, err := bvp.diskBuffer.files(); err == nil {
+			buffered.Batches = len(paths)
+		}
+
+		snapshot.DiskBuffer = buffered
+	}
+
+	if bvp.probingHealth() {
+		checked, err := bvp.health.get()
+
+		health := &HealthCheckSnapshot{
+			Healthy:   !checked.IsZero() && err == nil,
+			CheckedAt: checked,
+		}
+
+		if err != nil {
+			health.Error = err.Error()
+		}
+
+		snapshot.Health = health
+	}
+
+	return snapshot
+}
@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_DebugSnapshot(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	proc, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{exportDelay: 500 * time.Millisecond},
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(5),
+		WithWorkers(2),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	items := make([]*int, 5)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	var snapshot DebugSnapshot
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		snapshot = proc.DebugSnapshot()
+		if snapshot.Workers[0].State == WorkerStateExporting || snapshot.Workers[1].State == WorkerStateExporting {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	states := map[string]int{}
+	for _, worker := range snapshot.Workers {
+		states[worker.State]++
+
+		if worker.State == WorkerStateExporting && worker.BatchSize != 5 {
+			t.Errorf("expected exporting batch of 5, got %d", worker.BatchSize)
+		}
+	}
+
+	if states[WorkerStateExporting] != 1 || states[WorkerStateIdle] != 1 {
+		t.Errorf("expected one exporting and one idle worker, got %v", states)
+	}
+
+	if snapshot.Queue.Capacity != 100 {
+		t.Errorf("expected queue capacity 100, got %d", snapshot.Queue.Capacity)
+	}
+
+	if _, err := json.Marshal(snapshot); err != nil {
+		t.Errorf("failed to marshal snapshot: %v", err)
+	}
+}
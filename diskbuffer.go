@@ -0,0 +1,397 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDiskBufferFull is returned when a batch doesn't fit in the disk buffer.
+var ErrDiskBufferFull = errors.New("disk buffer is full")
+
+const diskBufferExt = ".batch"
+
+// diskBuffer persists batches that could not be exported, one file per batch,
+// so they can be replayed once the exporter recovers. Files are named by a
+// monotonically increasing sequence number so they replay in order, and
+// batches left behind by a previous process are picked up on startup.
+type diskBuffer[T any] struct {
+	dir       string
+	codec     Codec[T]
+	threshold int
+	maxBytes  int64
+
+	mu       sync.Mutex
+	seq      uint64
+	size     int64
+	failures int
+}
+
+func newDiskBuffer[T any](dir string, codec Codec[T], threshold int, maxBytes int64) (*diskBuffer[T], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create disk buffer directory: %w", err)
+	}
+
+	b := &diskBuffer[T]{
+		dir:       dir,
+		codec:     codec,
+		threshold: threshold,
+		maxBytes:  maxBytes,
+	}
+
+	paths, err := b.files()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat buffered batch: %w", err)
+		}
+
+		b.size += info.Size()
+	}
+
+	if len(paths) > 0 {
+		last := strings.TrimSuffix(filepath.Base(paths[len(paths)-1]), diskBufferExt)
+		if b.seq, err = strconv.ParseUint(last, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid buffered batch name: %s", paths[len(paths)-1])
+		}
+	}
+
+	return b, nil
+}
+
+// degraded reports whether exports have failed often enough that batches
+// should go straight to disk.
+func (b *diskBuffer[T]) degraded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.failures >= b.threshold
+}
+
+// recordFailure records a failed export.
+func (b *diskBuffer[T]) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+}
+
+// markDegraded switches straight to disk without waiting for exports to fail.
+func (b *diskBuffer[T]) markDegraded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		b.failures = b.threshold
+	}
+}
+
+// recordSuccess records a successful export, leaving degraded mode.
+func (b *diskBuffer[T]) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+}
+
+// bytes returns the total size of the buffered batches.
+func (b *diskBuffer[T]) bytes() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.size
+}
+
+// write persists a batch.
+func (b *diskBuffer[T]) write(items []*T) error {
+	payload, err := b.codec.Encode(items)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	data, err := Envelope{Codec: CodecName(b.codec), Payload: payload}.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxBytes > 0 && b.size+int64(len(data)) > b.maxBytes {
+		return ErrDiskBufferFull
+	}
+
+	b.seq++
+
+	path := filepath.Join(b.dir, fmt.Sprintf("%020d%s", b.seq, diskBufferExt))
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write batch: %w", err)
+	}
+
+	// Renaming makes the batch visible to the replayer atomically.
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write batch: %w", err)
+	}
+
+	b.size += int64(len(data))
+
+	return nil
+}
+
+// oldest returns the path of the oldest buffered batch.
+func (b *diskBuffer[T]) oldest() (string, bool, error) {
+	paths, err := b.files()
+	if err != nil || len(paths) == 0 {
+		return "", false, err
+	}
+
+	return paths[0], true, nil
+}
+
+// read loads a buffered batch.
+func (b *diskBuffer[T]) read(path string) ([]*T, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read buffered batch: %w", err)
+	}
+
+	// Batches buffered before envelopes were introduced hold the bare
+	// payload.
+	if IsEnvelope(data) {
+		var envelope Envelope
+
+		if err := envelope.UnmarshalBinary(data); err != nil {
+			return nil, fmt.Errorf("failed to decode buffered batch: %w", err)
+		}
+
+		if name := CodecName(b.codec); envelope.Codec != name {
+			return nil, fmt.Errorf("buffered batch was encoded with codec %q, not %q", envelope.Codec, name)
+		}
+
+		data = envelope.Payload
+	}
+
+	items, err := b.codec.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode buffered batch: %w", err)
+	}
+
+	return items, nil
+}
+
+// remove deletes a buffered batch.
+func (b *diskBuffer[T]) remove(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat buffered batch: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove buffered batch: %w", err)
+	}
+
+	b.mu.Lock()
+	b.size -= info.Size()
+	b.mu.Unlock()
+
+	return nil
+}
+
+// files returns the buffered batches, oldest first.
+func (b *diskBuffer[T]) files() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(b.dir, "*"+diskBufferExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list buffered batches: %w", err)
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// WithDiskBuffer diverts batches that fail to export to files in dir, encoded
+// with codec, instead of dropping them. After
+// DiskBufferFailureThreshold consecutive failures the processor stops trying
+// the exporter and writes every batch straight to disk. Buffered batches are
+// replayed every DiskBufferReplayInterval; the first successful replay takes
+// the processor out of degraded mode. Batches still on disk at shutdown are
+// replayed by the next processor using the same directory. Replayed batches
+// may be exported out of order relative to newer batches.
+//
+// With the sync shipping method, Write reports buffered items as successful.
+func WithDiskBuffer[T any](dir string, codec Codec[T]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DiskBufferDir = dir
+		o.DiskBufferCodec = codec
+	}
+}
+
+// WithDiskBufferFailureThreshold sets how many consecutive export failures
+// switch the processor to writing batches straight to disk.
+func WithDiskBufferFailureThreshold(failures int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DiskBufferFailureThreshold = failures
+	}
+}
+
+// WithDiskBufferReplayInterval sets how often buffered batches are replayed.
+func WithDiskBufferReplayInterval(interval time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DiskBufferReplayInterval = interval
+	}
+}
+
+// WithDiskBufferMaxBytes bounds the size of the disk buffer. Batches that
+// don't fit fail as if there was no disk buffer.
+func WithDiskBufferMaxBytes(size int64) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DiskBufferMaxBytes = size
+	}
+}
+
+// exportOrBuffer exports items, falling back to the disk buffer on failure.
+// Only the items that failed are buffered, so items already exported by a
+// partly failed export aren't replayed.
+func (bvp *BatchItemProcessor[T]) exportOrBuffer(ctx context.Context, exporter ItemExporter[T], items []*T) error {
+	if !bvp.diskBuffer.degraded() {
+		err := bvp.export(ctx, exporter, items)
+		if err == nil {
+			bvp.diskBuffer.recordSuccess()
+
+			return nil
+		}
+
+		bvp.diskBuffer.recordFailure()
+
+		if bvp.diskBuffer.degraded() {
+			bvp.log.WithError(err).Warn("Exports keep failing. Buffering batches to disk until the exporter recovers.")
+		}
+
+		items = failedItems(items, err)
+	}
+
+	if err := bvp.diskBuffer.write(items); err != nil {
+		return fmt.Errorf("failed to buffer batch to disk: %w", err)
+	}
+
+	bvp.metrics.IncItemsBufferedBy(bvp.label, float64(len(items)))
+	bvp.metrics.SetDiskBufferBytes(bvp.label, float64(bvp.diskBuffer.bytes()))
+
+	return nil
+}
+
+// failedItems returns the items of an export that failed with err: those
+// with an item error if it only partly failed, and all of them otherwise.
+func failedItems[T any](items []*T, err error) []*T {
+	partial, ok := partialErrors(err, len(items))
+	if !ok {
+		return items
+	}
+
+	failed := make([]*T, 0, partial.Failed())
+
+	for i, itemErr := range partial.ItemErrors {
+		if itemErr != nil {
+			failed = append(failed, items[i])
+		}
+	}
+
+	return failed
+}
+
+func (bvp *BatchItemProcessor[T]) diskBufferReplayer(ctx context.Context) {
+	ticker := time.NewTicker(bvp.o.DiskBufferReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bvp.stopCh:
+			return
+		case <-ticker.C:
+			bvp.replayDiskBuffer(ctx)
+		}
+	}
+}
+
+// replayDiskBuffer exports buffered batches, oldest first, until the buffer
+// is empty or an export fails.
+func (bvp *BatchItemProcessor[T]) replayDiskBuffer(ctx context.Context) {
+	log := bvp.log.WithField("module", "disk_buffer")
+
+	// Don't replay in to an exporter that is known to be unhealthy.
+	if bvp.Healthy() != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-bvp.stopCh:
+			return
+		default:
+		}
+
+		path, ok, err := bvp.diskBuffer.oldest()
+		if err != nil {
+			log.WithError(err).Error("Failed to find buffered batches")
+
+			return
+		}
+
+		if !ok {
+			return
+		}
+
+		items, err := bvp.diskBuffer.read(path)
+		if err != nil {
+			// A batch that can't be decoded will never replay, so drop it
+			// rather than blocking the batches behind it.
+			log.WithError(err).WithField("path", path).Error("Dropping unreadable buffered batch")
+
+			if err := bvp.diskBuffer.remove(path); err != nil {
+				log.WithError(err).Error("Failed to remove buffered batch")
+
+				return
+			}
+
+			continue
+		}
+
+		if err := bvp.exportWithDeadline(ctx, items); err != nil {
+			bvp.diskBuffer.recordFailure()
+
+			log.WithError(err).Debug("Exporter has not recovered. Keeping buffered batches.")
+
+			return
+		}
+
+		bvp.diskBuffer.recordSuccess()
+
+		if err := bvp.diskBuffer.remove(path); err != nil {
+			log.WithError(err).Error("Failed to remove replayed batch")
+
+			return
+		}
+
+		bvp.metrics.IncItemsReplayedBy(bvp.label, float64(len(items)))
+		bvp.metrics.SetDiskBufferBytes(bvp.label, float64(bvp.diskBuffer.bytes()))
+	}
+}
+
+// exportWithDeadline exports items with the exporter in use, each attempt
+// bounded by the export timeout.
+func (bvp *BatchItemProcessor[T]) exportWithDeadline(ctx context.Context, items []*T) error {
+	return bvp.export(ctx, bvp.exporter(), items)
+}
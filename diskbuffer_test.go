@@ -0,0 +1,259 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// toggleExporter fails every export while down is set.
+type toggleExporter[T any] struct {
+	down     atomic.Bool
+	attempts atomic.Int64
+
+	mu    sync.Mutex
+	items []*T
+}
+
+func (e *toggleExporter[T]) ExportItems(_ context.Context, items []*T) error {
+	e.attempts.Add(1)
+
+	if e.down.Load() {
+		return errors.New("exporter is down")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.items = append(e.items, items...)
+
+	return nil
+}
+
+func (e *toggleExporter[T]) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func (e *toggleExporter[T]) exported() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.items)
+}
+
+func TestBatchItemProcessor_DiskBuffer(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &toggleExporter[int]{}
+	exporter.down.Store(true)
+
+	dir := t.TempDir()
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(2),
+		WithShippingMethod(ShippingMethodSync),
+		WithWorkers(1),
+		WithDiskBuffer[int](dir, JSONCodec[int]{}),
+		WithDiskBufferFailureThreshold(2),
+		WithDiskBufferReplayInterval(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	items := make([]*int, 10)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	// Buffered items are reported as successful.
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	if got := exporter.attempts.Load(); got > 3 {
+		t.Errorf("expected exports to stop once degraded, got %d attempts", got)
+	}
+
+	exporter.down.Store(false)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for exporter.exported() < 10 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := exporter.exported(); got != 10 {
+		t.Errorf("expected 10 items replayed, got %d", got)
+	}
+
+	if remaining, _ := proc.diskBuffer.files(); len(remaining) != 0 {
+		t.Errorf("expected disk buffer to be empty, got %d batches", len(remaining))
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+}
+
+func TestDiskBuffer_Resume(t *testing.T) {
+	dir := t.TempDir()
+
+	buffer, err := newDiskBuffer[int](dir, JSONCodec[int]{}, 1, 0)
+	if err != nil {
+		t.Fatalf("failed to open disk buffer: %v", err)
+	}
+
+	one, two := 1, 2
+
+	if err := buffer.write([]*int{&one}); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	// A new buffer in the same directory continues the sequence.
+	resumed, err := newDiskBuffer[int](dir, JSONCodec[int]{}, 1, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen disk buffer: %v", err)
+	}
+
+	if err := resumed.write([]*int{&two}); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	path, ok, err := resumed.oldest()
+	if err != nil || !ok {
+		t.Fatalf("expected a buffered batch, got %v", err)
+	}
+
+	items, err := resumed.read(path)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	if len(items) != 1 || *items[0] != 1 {
+		t.Errorf("expected the oldest batch first, got %v", items)
+	}
+
+	if resumed.bytes() != buffer.bytes()*2 {
+		t.Errorf("expected size to include existing batches, got %d", resumed.bytes())
+	}
+}
+
+func TestDiskBuffer_ReadsLegacyBatches(t *testing.T) {
+	dir := t.TempDir()
+
+	// Batches buffered before envelopes hold the bare codec output.
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001.batch"), []byte("[1,2]"), 0o600); err != nil {
+		t.Fatalf("failed to write legacy batch: %v", err)
+	}
+
+	buffer, err := newDiskBuffer[int](dir, JSONCodec[int]{}, 1, 0)
+	if err != nil {
+		t.Fatalf("failed to open disk buffer: %v", err)
+	}
+
+	path, ok, err := buffer.oldest()
+	if err != nil || !ok {
+		t.Fatalf("expected a buffered batch, got %v", err)
+	}
+
+	items, err := buffer.read(path)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	if len(items) != 2 || *items[0] != 1 || *items[1] != 2 {
+		t.Errorf("expected [1 2], got %v", items)
+	}
+}
+
+// partialExporter fails the odd items of its first export, exporting the
+// rest, and exports everything after.
+type partialExporter struct {
+	toggleExporter[int]
+	failed atomic.Bool
+}
+
+func (e *partialExporter) ExportItems(ctx context.Context, items []*int) error {
+	if e.failed.Swap(true) {
+		return e.toggleExporter.ExportItems(ctx, items)
+	}
+
+	partial := &PartialExportError{ItemErrors: make([]error, len(items))}
+
+	var exported []*int
+
+	for i, item := range items {
+		if i%2 == 1 {
+			partial.ItemErrors[i] = errors.New("export failed")
+
+			continue
+		}
+
+		exported = append(exported, item)
+	}
+
+	if err := e.toggleExporter.ExportItems(ctx, exported); err != nil {
+		return err
+	}
+
+	return partial
+}
+
+func TestBatchItemProcessor_DiskBufferPartialExport(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &partialExporter{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxExportBatchSize(4),
+		WithShippingMethod(ShippingMethodSync),
+		WithWorkers(1),
+		WithDiskBuffer[int](t.TempDir(), JSONCodec[int]{}),
+		WithDiskBufferReplayInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	if err := proc.Write(ctx, ints(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for exporter.exported() < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Only the failed items are buffered and replayed, so none are exported
+	// twice.
+	time.Sleep(50 * time.Millisecond)
+
+	if got := exporter.exported(); got != 4 {
+		t.Fatalf("expected each of the 4 items exported once, got %d exports", got)
+	}
+}
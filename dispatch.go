@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"reflect"
+)
+
+// cutBatch is a batch cut by the batch builder, waiting to be dispatched.
+type cutBatch[T any] struct {
+	items  []*TraceableItem[T]
+	reason string
+}
+
+// dispatcher routes batches cut by the batch builder to the workers until the
+// batch builder stops.
+func (bvp *BatchItemProcessor[T]) dispatcher(done chan<- struct{}) {
+	defer close(done)
+
+	for cut := range bvp.cutCh {
+		bvp.sendBatch(cut.items, cut.reason)
+		bvp.settleStep(1)
+	}
+}
+
+func (bvp *BatchItemProcessor[T]) sendBatch(batch []*TraceableItem[T], reason string) {
+	log := bvp.log.WithField("reason", reason)
+	log.Tracef("Creating a batch of %d items", len(batch))
+
+	bvp.publish(EventBatchCreated, Event{Items: len(batch), Reason: reason})
+
+	routed := bvp.routeBatch(batch)
+	forwarded := false
+
+	if bvp.turns != nil {
+		bvp.queueTurns(routed)
+	}
+
+	if exporter, ok := bvp.twoPhaseExporter(); ok {
+		newPreparedRound(exporter, routed)
+	}
+
+	for _, r := range routed {
+		// The worker recycles the buffer it was sent once exported.
+		forwarded = forwarded || sameBuffer(r.items, batch)
+
+		if bvp.invariants != nil {
+			bvp.invariants.dispatched.Add(int64(len(r.items)))
+		}
+	}
+
+	if bvp.stepper != nil {
+		bvp.parkBatches(routed)
+	} else {
+		bvp.deliver(routed)
+	}
+
+	// Routing copied the items in to new batches, so the buffer is free.
+	if !forwarded {
+		bvp.buffers.put(batch)
+	}
+
+	log.Tracef("Batch sent to batch channel")
+}
+
+// deliver sends routed batches to their workers in whatever order the workers
+// take them, so a worker busy with a slow export doesn't hold up batches
+// routed to the others. Batches for the same worker keep their order.
+func (bvp *BatchItemProcessor[T]) deliver(routed []routedBatch[T]) {
+	if len(routed) == 1 {
+		bvp.batchChFor(routed[0].worker) <- routed[0].items
+		bvp.ensureWorkers(routed[0].worker)
+
+		return
+	}
+
+	var (
+		workers []int
+		pending = make(map[int][]*routedBatch[T])
+	)
+
+	for i := range routed {
+		worker := routed[i].worker
+		if _, ok := pending[worker]; !ok {
+			workers = append(workers, worker)
+		}
+
+		pending[worker] = append(pending[worker], &routed[i])
+	}
+
+	cases := make([]reflect.SelectCase, len(workers))
+
+	for len(workers) > 0 {
+		// Offer the next batch of every worker, making sure each has a
+		// worker running before waiting on it.
+		cases = cases[:len(workers)]
+
+		for i, worker := range workers {
+			bvp.ensureWorkers(worker)
+
+			cases[i] = reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(bvp.batchChFor(worker)),
+				Send: reflect.ValueOf(pending[worker][0].items),
+			}
+		}
+
+		chosen, _, _ := reflect.Select(cases)
+		worker := workers[chosen]
+
+		bvp.ensureWorkers(worker)
+
+		if pending[worker] = pending[worker][1:]; len(pending[worker]) == 0 {
+			workers = append(workers[:chosen], workers[chosen+1:]...)
+		}
+	}
+}
+
+// batchChFor returns the channel of batches for the given worker, or the
+// shared batch channel for batches any worker can export.
+func (bvp *BatchItemProcessor[T]) batchChFor(worker int) chan []*TraceableItem[T] {
+	if worker < 0 {
+		return bvp.batchCh
+	}
+
+	return bvp.workerChs[worker]
+}
@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// gateExporter holds exports of the slow item until the gate is opened, and
+// reports the other batches it exports.
+type gateExporter struct {
+	slow     string
+	gate     chan struct{}
+	exported chan []*string
+}
+
+func (g *gateExporter) ExportItems(_ context.Context, items []*string) error {
+	if slices.ContainsFunc(items, func(item *string) bool { return *item == g.slow }) {
+		<-g.gate
+
+		return nil
+	}
+
+	g.exported <- items
+
+	return nil
+}
+
+func (g *gateExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestBatchItemProcessor_SlowWorkerDoesNotBlockOthers(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	slow := "slow"
+	fast := ""
+
+	for i := 0; fast == ""; i++ {
+		if key := fmt.Sprintf("fast-%d", i); PartitionOf(key, 2) != PartitionOf(slow, 2) {
+			fast = key
+		}
+	}
+
+	exporter := &gateExporter{
+		slow:     slow,
+		gate:     make(chan struct{}),
+		exported: make(chan []*string, 1),
+	}
+
+	proc, err := NewBatchItemProcessor[string](exporter, "test", log,
+		WithMaxExportBatchSize(2),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(2),
+		WithKeyFunc(func(item *string) string { return *item }),
+		WithKeyOrdering(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		close(exporter.gate)
+
+		_ = proc.Shutdown(ctx)
+	}()
+
+	// The slow worker's export hangs and its next batch fills its channel.
+	for range 2 {
+		if err := proc.Write(ctx, []*string{&slow, &slow}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// This batch is split between the slow and the fast worker.
+	if err := proc.Write(ctx, []*string{&slow, &fast}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case items := <-exporter.exported:
+		if len(items) != 1 || *items[0] != fast {
+			t.Fatalf("expected the fast worker to export its item, got %d items", len(items))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fast worker's batch was held up by the slow worker")
+	}
+}
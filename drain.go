@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// drainPollInterval is how often Drain and ForceFlush check whether the
+// items they wait for are settled, asking the batch builder to flush each
+// time.
+const drainPollInterval = 10 * time.Millisecond
+
+// pendingItems counts queued items that aren't settled yet. Items count
+// towards one of two generations, so ForceFlush can start a new generation
+// and wait for the items queued before it while later ones count towards
+// the other.
+type pendingItems struct {
+	generation atomic.Uint32
+	counts     [2]atomic.Int64
+
+	// turn serializes ForceFlush calls, so a generation is only reused
+	// once the flush waiting on it is done.
+	turn chan struct{}
+}
+
+// add counts an item towards the current generation, returning it.
+func (p *pendingItems) add() uint32 {
+	g := p.generation.Load() & 1
+
+	p.counts[g].Add(1)
+
+	return g
+}
+
+// settle stops counting n items of generation g.
+func (p *pendingItems) settle(g uint32, n int64) {
+	p.counts[g].Add(-n)
+}
+
+// total returns the number of pending items of both generations.
+func (p *pendingItems) total() int64 {
+	return p.counts[0].Load() + p.counts[1].Load()
+}
+
+// settlePending stops counting a batch of items as pending.
+func (bvp *BatchItemProcessor[T]) settlePending(items []*TraceableItem[T]) {
+	var counts [2]int64
+
+	for _, item := range items {
+		counts[item.generation]++
+	}
+
+	for g, n := range counts {
+		if n > 0 {
+			bvp.pending.settle(uint32(g), n)
+		}
+	}
+}
+
+// Drain flushes the batch being built and waits until every queued item has
+// been exported, or failed to, without shutting the processor down. Writes
+// are accepted throughout, so Drain suits barriers such as checkpoints
+// where everything written so far must be out before moving on. Items
+// written while it waits are drained too, so writers that never pause keep
+// it waiting until ctx is done; ForceFlush only waits for earlier items.
+func (bvp *BatchItemProcessor[T]) Drain(ctx context.Context) error {
+	if bvp.stopping() {
+		return ErrShuttingDown
+	}
+
+	if bvp.coalescer != nil {
+		bvp.coalescer.flush()
+	}
+
+	return bvp.waitFlushed(ctx, bvp.pending.total)
+}
+
+// ForceFlush flushes the batch being built and waits until every item
+// queued before the call has been exported, or failed to, without shutting
+// the processor down. Unlike Drain it doesn't wait for items written after
+// it was called, so it returns under a steady stream of writes.
+func (bvp *BatchItemProcessor[T]) ForceFlush(ctx context.Context) error {
+	if bvp.stopping() {
+		return ErrShuttingDown
+	}
+
+	select {
+	case bvp.pending.turn <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	defer func() { <-bvp.pending.turn }()
+
+	// Coalesced items were written before the call, so they count towards
+	// the generation being flushed.
+	if bvp.coalescer != nil {
+		bvp.coalescer.flush()
+	}
+
+	g := (bvp.pending.generation.Add(1) - 1) & 1
+
+	return bvp.waitFlushed(ctx, bvp.pending.counts[g].Load)
+}
+
+// waitFlushed asks the batch builder to flush until remaining reports no
+// items left.
+func (bvp *BatchItemProcessor[T]) waitFlushed(ctx context.Context, remaining func() int64) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for remaining() > 0 {
+		bvp.requestFlush()
+
+		select {
+		case <-ticker.C:
+		case <-bvp.stopCh:
+			return ErrShuttingDown
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// requestFlush asks the batch builder to cut the batch being built, unless
+// a request is already waiting.
+func (bvp *BatchItemProcessor[T]) requestFlush() {
+	select {
+	case bvp.flushCh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,123 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_Drain(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxExportBatchSize(100),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(2),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	// The processor keeps accepting writes between drains.
+	for round := int64(1); round <= 2; round++ {
+		if err := proc.Write(ctx, ints(5)); err != nil {
+			t.Fatalf("failed to write items: %v", err)
+		}
+
+		if err := proc.Drain(ctx); err != nil {
+			t.Fatalf("failed to drain: %v", err)
+		}
+
+		if got := exporter.exportCount.Load(); got != 5*round {
+			t.Fatalf("expected %d items exported once drained, got %d", 5*round, got)
+		}
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if err := proc.Drain(ctx); !errors.Is(err, ErrShuttingDown) {
+		t.Fatalf("expected draining a stopped processor to fail, got %v", err)
+	}
+}
+
+func TestBatchItemProcessor_ForceFlush(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxExportBatchSize(100),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(2),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	if err := proc.Write(ctx, ints(5)); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	// Writes made during the flush don't hold it up.
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+
+	go func() {
+		defer close(stopped)
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = proc.Write(ctx, ints(1))
+
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	if err := proc.ForceFlush(ctx); err != nil {
+		t.Fatalf("failed to force flush: %v", err)
+	}
+
+	if got := exporter.exportCount.Load(); got < 5 {
+		t.Fatalf("expected at least the 5 earlier items exported, got %d", got)
+	}
+}
@@ -0,0 +1,93 @@
+package processor
+
+import "fmt"
+
+// DropPolicy chooses which items are dropped when the queue is full.
+type DropPolicy string
+
+const (
+	// DropNewest drops the items being written, keeping those already
+	// queued.
+	DropNewest DropPolicy = "newest"
+	// DropOldest evicts the longest queued items to make room for those
+	// being written, so the freshest data wins.
+	DropOldest DropPolicy = "oldest"
+)
+
+// ErrEvicted is returned to sync writers whose items were evicted from the
+// full queue to make room for others, under DropOldest or by higher priority
+// items. It matches ErrQueueFull.
+var ErrEvicted = fmt.Errorf("%w: evicted to make room", ErrQueueFull)
+
+// WithDropPolicy sets which items are dropped when the queue is full. Either
+// way dropped items are counted in items_dropped_total, and in
+// queue_full_items_dropped_total under the policy that chose them.
+//
+// DropOldest requires the channel queue, and can't be combined with priority
+// lanes, an overflow writer or WithBlockOnQueueFull.
+func WithDropPolicy(policy DropPolicy) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DropPolicy = policy
+	}
+}
+
+// evictOldest takes the oldest item off the queue and drops it, making room
+// for a newer one. It returns false if the queue was empty.
+func (bvp *BatchItemProcessor[T]) evictOldest() bool {
+	var item *TraceableItem[T]
+
+	select {
+	case queued, ok := <-bvp.queue:
+		if !ok {
+			return false
+		}
+
+		item = queued
+	default:
+		return false
+	}
+
+	if item == nil {
+		bvp.settleStep(1)
+		bvp.metrics.IncQueueFullItemsDroppedBy(bvp.label, string(DropOldest), float64(1))
+		bvp.metrics.IncItemsDroppedBy(bvp.label, float64(1))
+
+		return true
+	}
+
+	bvp.evict(item, string(DropOldest))
+
+	return true
+}
+
+// evict drops a queued item to make room for another, counting it under
+// policy.
+func (bvp *BatchItemProcessor[T]) evict(item *TraceableItem[T], policy string) {
+	bvp.settleStep(1)
+	bvp.metrics.IncQueueFullItemsDroppedBy(bvp.label, policy, float64(1))
+
+	if bvp.invariants != nil {
+		bvp.invariants.dequeue()
+	}
+
+	if item.producer != nil {
+		item.producer.release()
+	}
+
+	if bvp.inspector != nil {
+		bvp.inspector.untrack(item)
+	}
+
+	if bvp.checkpoints != nil {
+		bvp.checkpoints.skip(item.seq)
+	}
+
+	bvp.pending.settle(item.generation, 1)
+
+	bvp.drop(item, ErrEvicted)
+
+	evicted := []*TraceableItem[T]{item}
+
+	signalWriters(evicted, ErrEvicted, nil)
+	bvp.items.putAll(evicted)
+}
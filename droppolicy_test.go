@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_DropPolicy(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	tests := []struct {
+		policy DropPolicy
+		want   []int
+	}{
+		{policy: DropNewest, want: []int{0, 1}},
+		{policy: DropOldest, want: []int{2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			name := "drop-policy-" + string(tt.policy)
+			exporter := &mockExporter[int]{}
+
+			proc, err := NewBatchItemProcessor[int](exporter, name, log,
+				WithMaxQueueSize(2),
+				WithMaxExportBatchSize(2),
+				WithBatchTimeout(10*time.Millisecond),
+				WithWorkers(1),
+				WithDropPolicy(tt.policy),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+
+			// The processor isn't started, so only two items fit in the
+			// queue.
+			for _, item := range ints(4) {
+				_ = proc.Write(ctx, []*int{item})
+			}
+
+			if err := proc.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := proc.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			exporter.mu.Lock()
+			defer exporter.mu.Unlock()
+
+			if len(exporter.exportedItems) != len(tt.want) {
+				t.Fatalf("expected %d items exported, got %d", len(tt.want), len(exporter.exportedItems))
+			}
+
+			for i, item := range exporter.exportedItems {
+				if *item != tt.want[i] {
+					t.Fatalf("expected items %v exported, got %d at %d", tt.want, *item, i)
+				}
+			}
+
+			if got := counterValue(t, DefaultMetrics.itemsDropped.WithLabelValues(name)); got != 2 {
+				t.Fatalf("expected 2 items dropped, got %v", got)
+			}
+
+			if got := counterValue(t, DefaultMetrics.itemsDroppedByPolicy.WithLabelValues(name, string(tt.policy))); got != 2 {
+				t.Fatalf("expected 2 items dropped by the %s policy, got %v", tt.policy, got)
+			}
+		})
+	}
+}
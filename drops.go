@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"context"
+)
+
+// DefaultDropLogEvery is the default sample rate of the drop log: one in this
+// many dropped items is logged.
+const DefaultDropLogEvery = 1000
+
+// WithDropLogging logs a sample of dropped items, one in every, using summary
+// to describe them, so operators can see what is being lost and not only how
+// much. The first drop is always logged. An every of zero uses
+// DefaultDropLogEvery.
+func WithDropLogging[T any](summary func(item *T) string, every int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DropSummary = summary
+		o.DropLogEvery = every
+	}
+}
+
+// WithDropSink diverts dropped items to sink, for example a local file or a
+// per-key counter, so they can be analysed later. The sink is called
+// synchronously from the writing goroutine with each dropped item, so it must
+// be fast; its errors are logged and otherwise ignored. Items are still
+// counted as dropped. The sink is shut down with the processor.
+func WithDropSink[T any](sink ItemExporter[T]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DropSink = sink
+	}
+}
+
+// drop records an item rejected by the queue.
+func (bvp *BatchItemProcessor[T]) drop(item *TraceableItem[T], reason error) {
+	bvp.metrics.IncItemsDroppedBy(bvp.label, float64(1))
+
+	if item.producerLabel != "" {
+		bvp.metrics.IncProducerItemsDroppedBy(bvp.label, item.producerLabel, float64(1))
+	}
+
+	bvp.logDrop(item, reason)
+
+	bvp.publish(EventItemsDropped, Event{Items: 1, Reason: reason.Error()})
+
+	if bvp.dropSink != nil {
+		if err := bvp.dropSink.ExportItems(context.Background(), []*T{item.item}); err != nil {
+			bvp.log.WithError(err).Warn("Failed to export dropped item to the drop sink")
+		}
+	}
+}
+
+// logDrop logs the item if it is sampled by the drop log.
+func (bvp *BatchItemProcessor[T]) logDrop(item *TraceableItem[T], reason error) {
+	if bvp.dropSummary == nil {
+		return
+	}
+
+	every := uint64(bvp.o.DropLogEvery)
+	if every == 0 {
+		every = DefaultDropLogEvery
+	}
+
+	n := bvp.drops.Add(1)
+	if (n-1)%every != 0 {
+		return
+	}
+
+	log := bvp.log.WithField("reason", reason.Error()).WithField("dropped", n)
+
+	if item.producerLabel != "" {
+		log = log.WithField("producer", item.producerLabel)
+	}
+
+	log.WithField("item", bvp.dropSummary(item.item)).Warn("Dropped item (sampled)")
+}
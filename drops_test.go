@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestBatchItemProcessor_DropLogging(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	log.SetLevel(logrus.WarnLevel)
+
+	proc, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{},
+		"test",
+		log,
+		WithMaxQueueSize(1),
+		WithMaxExportBatchSize(1),
+		WithWorkers(1),
+		WithDropLogging(func(item *int) string {
+			return "item " + strconv.Itoa(*item)
+		}, 2),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	// The processor isn't started, so only the first item fits in the queue.
+	items := make([]*int, 5)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	if _, err := proc.WriteEach(context.Background(), items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	var logged []string
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Dropped item (sampled)" {
+			logged = append(logged, entry.Data["item"].(string))
+		}
+	}
+
+	// Four items are dropped and one in two is logged, starting with the first.
+	want := []string{"item 1", "item 3"}
+
+	if len(logged) != len(want) {
+		t.Fatalf("expected %d logged drops, got %d", len(want), len(logged))
+	}
+
+	for i := range want {
+		if logged[i] != want[i] {
+			t.Errorf("drop %d: expected %q, got %q", i, want[i], logged[i])
+		}
+	}
+}
+
+func TestBatchItemProcessor_DropSink(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	sink := &shutdownExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{},
+		"test",
+		log,
+		WithMaxQueueSize(1),
+		WithMaxExportBatchSize(1),
+		WithWorkers(1),
+		WithDropSink[int](sink),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	items := make([]*int, 3)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	ctx := context.Background()
+
+	// The processor isn't started, so only the first item fits in the queue.
+	if _, err := proc.WriteEach(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	if len(sink.exportedItems) != 2 {
+		t.Fatalf("expected 2 items in the drop sink, got %d", len(sink.exportedItems))
+	}
+
+	for i, item := range sink.exportedItems {
+		if *item != i+1 {
+			t.Errorf("dropped item %d: expected %d, got %d", i, i+1, *item)
+		}
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if !sink.shutdown.Load() {
+		t.Error("expected drop sink to be shut down")
+	}
+}
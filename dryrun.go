@@ -0,0 +1,27 @@
+package processor
+
+import (
+	"context"
+)
+
+// WithDryRun runs the whole pipeline, including metrics and logs, but
+// discards batches instead of exporting them. The exporter and any exporter
+// factory are never called, so sizing and throughput can be validated before
+// pointing the processor at a real sink. Drop sinks and overflows are still
+// written to.
+func WithDryRun() BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.DryRun = true
+	}
+}
+
+// noopExporter discards every batch.
+type noopExporter[T any] struct{}
+
+func (noopExporter[T]) ExportItems(_ context.Context, _ []*T) error {
+	return nil
+}
+
+func (noopExporter[T]) Shutdown(_ context.Context) error {
+	return nil
+}
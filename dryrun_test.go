@@ -0,0 +1,56 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_DryRun(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(5),
+		WithWorkers(1),
+		WithDryRun(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	items := make([]*int, 10)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	exported := counterValue(t, DefaultMetrics.itemsExported.WithLabelValues("test"))
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 0 {
+		t.Errorf("expected the exporter not to be called, got %d items", got)
+	}
+
+	if got := counterValue(t, DefaultMetrics.itemsExported.WithLabelValues("test")) - exported; got != 10 {
+		t.Errorf("expected 10 items counted as exported, got %v", got)
+	}
+}
@@ -0,0 +1,245 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// EnvelopeVersion is the envelope format version written by this package.
+// Readers accept every version up to and including it.
+const EnvelopeVersion = 1
+
+// envelopeMagic starts every envelope.
+var envelopeMagic = [4]byte{'B', 'P', 'E', 'V'}
+
+var (
+	// ErrNotEnvelope is returned when data doesn't start with the envelope
+	// magic bytes.
+	ErrNotEnvelope = errors.New("not a batch envelope")
+	// ErrUnsupportedEnvelopeVersion is returned for envelopes written by a
+	// newer version of the format.
+	ErrUnsupportedEnvelopeVersion = errors.New("unsupported batch envelope version")
+)
+
+// Envelope is the stable wire format for persisted and transmitted batches,
+// so data written by one release of this package stays readable by later
+// ones. All integers are big endian:
+//
+//	magic    [4]byte "BPEV"
+//	version  uint16
+//	codec    uint16 length, then the codec id
+//	metadata uint16 count, then per entry a uint16 length and key, and a
+//	         uint32 length and value, sorted by key
+//	payload  uint32 length, then the payload
+type Envelope struct {
+	// Version is the format version. It is set to EnvelopeVersion when
+	// writing.
+	Version uint16
+	// Codec identifies the codec the payload was encoded with.
+	Codec string
+	// Metadata holds arbitrary key value pairs about the batch.
+	Metadata map[string]string
+	// Payload is the encoded batch.
+	Payload []byte
+}
+
+// NamedCodec is an optional interface for codecs, identifying them in
+// envelopes.
+type NamedCodec interface {
+	// Name returns a stable identifier for the codec.
+	Name() string
+}
+
+// CodecName returns the envelope codec id of codec: its Name if it
+// implements NamedCodec, or an empty string.
+func CodecName[T any](codec Codec[T]) string {
+	if named, ok := codec.(NamedCodec); ok {
+		return named.Name()
+	}
+
+	return ""
+}
+
+// Name identifies JSONCodec in envelopes.
+func (JSONCodec[T]) Name() string {
+	return "json"
+}
+
+// MarshalBinary encodes the envelope.
+func (e Envelope) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := WriteEnvelope(&buf, e); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes an envelope.
+func (e *Envelope) UnmarshalBinary(data []byte) error {
+	decoded, err := ReadEnvelope(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	*e = decoded
+
+	return nil
+}
+
+// IsEnvelope reports whether data starts with the envelope magic bytes.
+func IsEnvelope(data []byte) bool {
+	return len(data) >= len(envelopeMagic) && bytes.Equal(data[:len(envelopeMagic)], envelopeMagic[:])
+}
+
+// WriteEnvelope writes an envelope to w. Envelopes are self-delimiting, so
+// several can be written to the same stream.
+func WriteEnvelope(w io.Writer, e Envelope) error {
+	if len(e.Codec) > math.MaxUint16 || len(e.Metadata) > math.MaxUint16 || len(e.Payload) > math.MaxUint32 {
+		return errors.New("batch envelope field is too large")
+	}
+
+	keys := make([]string, 0, len(e.Metadata))
+
+	for key, value := range e.Metadata {
+		if len(key) > math.MaxUint16 || len(value) > math.MaxUint32 {
+			return errors.New("batch envelope metadata is too large")
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+
+	bw.Write(envelopeMagic[:])
+	writeUint16(bw, EnvelopeVersion)
+	writeUint16(bw, uint16(len(e.Codec)))
+	bw.WriteString(e.Codec)
+	writeUint16(bw, uint16(len(e.Metadata)))
+
+	for _, key := range keys {
+		value := e.Metadata[key]
+
+		writeUint16(bw, uint16(len(key)))
+		bw.WriteString(key)
+		writeUint32(bw, uint32(len(value)))
+		bw.WriteString(value)
+	}
+
+	writeUint32(bw, uint32(len(e.Payload)))
+	bw.Write(e.Payload)
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write batch envelope: %w", err)
+	}
+
+	return nil
+}
+
+// ReadEnvelope reads the next envelope from r. It returns io.EOF if r is
+// exhausted before an envelope starts.
+func ReadEnvelope(r io.Reader) (Envelope, error) {
+	var magic [4]byte
+
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Envelope{}, io.EOF
+		}
+
+		return Envelope{}, fmt.Errorf("failed to read batch envelope: %w", err)
+	}
+
+	if magic != envelopeMagic {
+		return Envelope{}, ErrNotEnvelope
+	}
+
+	d := envelopeDecoder{r: r}
+
+	e := Envelope{Version: d.uint16()}
+
+	if d.err == nil && (e.Version == 0 || e.Version > EnvelopeVersion) {
+		return Envelope{}, fmt.Errorf("%w: %d", ErrUnsupportedEnvelopeVersion, e.Version)
+	}
+
+	e.Codec = string(d.bytes(int(d.uint16())))
+
+	if count := int(d.uint16()); count > 0 && d.err == nil {
+		e.Metadata = make(map[string]string, count)
+
+		for range count {
+			key := string(d.bytes(int(d.uint16())))
+			e.Metadata[key] = string(d.bytes(int(d.uint32())))
+		}
+	}
+
+	e.Payload = d.bytes(int(d.uint32()))
+
+	if d.err != nil {
+		return Envelope{}, fmt.Errorf("failed to read batch envelope: %w", d.err)
+	}
+
+	return e, nil
+}
+
+// envelopeDecoder reads envelope fields, keeping the first error.
+type envelopeDecoder struct {
+	r   io.Reader
+	err error
+}
+
+func (d *envelopeDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+
+	buf := make([]byte, n)
+
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		d.err = err
+
+		return nil
+	}
+
+	return buf
+}
+
+func (d *envelopeDecoder) uint16() uint16 {
+	buf := d.bytes(2)
+	if buf == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint16(buf)
+}
+
+func (d *envelopeDecoder) uint32() uint32 {
+	buf := d.bytes(4)
+	if buf == nil {
+		return 0
+	}
+
+	return binary.BigEndian.Uint32(buf)
+}
+
+func writeUint16(w *bufio.Writer, v uint16) {
+	var buf [2]byte
+
+	binary.BigEndian.PutUint16(buf[:], v)
+	w.Write(buf[:])
+}
+
+func writeUint32(w *bufio.Writer, v uint32) {
+	var buf [4]byte
+
+	binary.BigEndian.PutUint32(buf[:], v)
+	w.Write(buf[:])
+}
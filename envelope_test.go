@@ -0,0 +1,72 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	envelopes := []Envelope{
+		{Codec: "json", Metadata: map[string]string{"b": "2", "a": "1"}, Payload: []byte("[1,2]")},
+		{Codec: "proto", Payload: []byte{}},
+	}
+
+	var stream bytes.Buffer
+
+	for _, e := range envelopes {
+		if err := WriteEnvelope(&stream, e); err != nil {
+			t.Fatalf("failed to write envelope: %v", err)
+		}
+	}
+
+	for i, want := range envelopes {
+		got, err := ReadEnvelope(&stream)
+		if err != nil {
+			t.Fatalf("envelope %d: failed to read: %v", i, err)
+		}
+
+		if got.Version != EnvelopeVersion || got.Codec != want.Codec || !bytes.Equal(got.Payload, want.Payload) {
+			t.Errorf("envelope %d: expected %+v, got %+v", i, want, got)
+		}
+
+		if len(got.Metadata) != len(want.Metadata) {
+			t.Fatalf("envelope %d: expected metadata %v, got %v", i, want.Metadata, got.Metadata)
+		}
+
+		for key, value := range want.Metadata {
+			if got.Metadata[key] != value {
+				t.Errorf("envelope %d: expected %s=%s, got %s", i, key, value, got.Metadata[key])
+			}
+		}
+	}
+
+	if _, err := ReadEnvelope(&stream); !errors.Is(err, io.EOF) {
+		t.Errorf("expected EOF after the last envelope, got %v", err)
+	}
+}
+
+func TestEnvelope_Invalid(t *testing.T) {
+	var e Envelope
+
+	if err := e.UnmarshalBinary([]byte("[1,2]")); !errors.Is(err, ErrNotEnvelope) {
+		t.Errorf("expected ErrNotEnvelope, got %v", err)
+	}
+
+	data, err := Envelope{Codec: "json"}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if err := e.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("expected an error for a truncated envelope")
+	}
+
+	// Bump the version past the one this package writes.
+	data[5] = EnvelopeVersion + 1
+
+	if err := e.UnmarshalBinary(data); !errors.Is(err, ErrUnsupportedEnvelopeVersion) {
+		t.Errorf("expected ErrUnsupportedEnvelopeVersion, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package processor
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventKind identifies what happened in a processor event.
+type EventKind string
+
+const (
+	// EventBatchCreated is published when the batch builder cuts a batch.
+	// Reason says why it was cut.
+	EventBatchCreated EventKind = "batch_created"
+	// EventExportSucceeded is published when a batch is exported.
+	EventExportSucceeded EventKind = "export_succeeded"
+	// EventExportFailed is published when a batch, or part of it, fails to
+	// export.
+	EventExportFailed EventKind = "export_failed"
+	// EventItemsDropped is published when items are dropped before they
+	// are queued. Reason says why.
+	EventItemsDropped EventKind = "items_dropped"
+	// EventWorkerStarted is published when a worker starts.
+	EventWorkerStarted EventKind = "worker_started"
+	// EventWorkerStopped is published when a worker stops, on shutdown or
+	// once idle.
+	EventWorkerStopped EventKind = "worker_stopped"
+)
+
+// Event is something that happened in a processor, for tooling observing it
+// through Subscribe. Fields that don't apply to the kind are zero.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+	// Processor is the name of the processor.
+	Processor string
+	// Items is the number of items in the batch or dropped.
+	Items int
+	// Worker is the worker exporting the batch, or the worker that
+	// started or stopped.
+	Worker int
+	// Reason is why a batch was cut or items were dropped.
+	Reason string
+	// Duration is how long the export took.
+	Duration time.Duration
+	// Err is why the export failed.
+	Err error
+	// Missed is the number of events the subscriber missed before this
+	// one because its channel was full.
+	Missed uint64
+}
+
+// eventBus fans events out to subscribers without blocking the processor.
+type eventBus struct {
+	subscribers atomic.Int32
+
+	mu     sync.RWMutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+type subscription struct {
+	ch     chan Event
+	missed atomic.Uint64
+}
+
+// Subscribe returns a channel receiving the processor's events, buffered to
+// hold size of them, and a function ending the subscription. Events are
+// never waited for: while the channel is full new events are missed, and
+// counted in the next event delivered. The channel is closed when the
+// subscription ends or the processor has shut down.
+func (bvp *BatchItemProcessor[T]) Subscribe(size int) (<-chan Event, func()) {
+	return bvp.events.subscribe(size)
+}
+
+func (b *eventBus) subscribe(size int) (<-chan Event, func()) {
+	sub := &subscription{ch: make(chan Event, max(size, 1))}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(sub.ch)
+
+		return sub.ch, func() {}
+	}
+
+	if b.subs == nil {
+		b.subs = make(map[*subscription]struct{})
+	}
+
+	b.subs[sub] = struct{}{}
+	b.subscribers.Add(1)
+
+	return sub.ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subs[sub]; !ok {
+			return
+		}
+
+		delete(b.subs, sub)
+		b.subscribers.Add(-1)
+		close(sub.ch)
+	}
+}
+
+// active reports whether anyone is subscribed, so events are only built
+// when they'll be delivered.
+func (b *eventBus) active() bool {
+	return b.subscribers.Load() > 0
+}
+
+func (b *eventBus) publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		e.Missed = sub.missed.Load()
+
+		select {
+		case sub.ch <- e:
+			sub.missed.Add(-e.Missed)
+		default:
+			sub.missed.Add(1)
+		}
+	}
+}
+
+// close ends every subscription.
+func (b *eventBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+
+	for sub := range b.subs {
+		close(sub.ch)
+	}
+
+	b.subs = nil
+	b.subscribers.Store(0)
+}
+
+// publish publishes an event if anyone is subscribed.
+func (bvp *BatchItemProcessor[T]) publish(kind EventKind, e Event) {
+	if !bvp.events.active() {
+		return
+	}
+
+	e.Kind = kind
+	e.Time = time.Now()
+	e.Processor = bvp.name
+
+	bvp.events.publish(e)
+}
+
+// publishExport publishes the outcome of exporting a batch.
+func (bvp *BatchItemProcessor[T]) publishExport(worker, items int, duration time.Duration, err error) {
+	kind := EventExportSucceeded
+	if err != nil {
+		kind = EventExportFailed
+	}
+
+	bvp.publish(kind, Event{Worker: worker, Items: items, Duration: duration, Err: err})
+}
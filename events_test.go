@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_Subscribe(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxExportBatchSize(5),
+		WithWorkers(1),
+		WithShippingMethod(ShippingMethodSync),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, _ := proc.Subscribe(100)
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Write(ctx, ints(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	exporter.mu.Lock()
+	exporter.exportErr = errors.New("export failed")
+	exporter.mu.Unlock()
+
+	if err := proc.Write(ctx, ints(5)); err == nil {
+		t.Fatal("expected the export to fail")
+	}
+
+	if err := proc.Write(ctx, []*int{nil}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[EventKind]int)
+
+	// The channel is closed once the processor has shut down.
+	for e := range events {
+		if e.Processor != "test" {
+			t.Fatalf("expected events from the test processor, got %q", e.Processor)
+		}
+
+		switch e.Kind {
+		case EventBatchCreated:
+			if e.Items != 5 || e.Reason != "max_export_batch_size" {
+				t.Fatalf("unexpected batch event %+v", e)
+			}
+		case EventExportFailed:
+			if e.Err == nil {
+				t.Fatal("expected a failed export event to carry the error")
+			}
+		}
+
+		counts[e.Kind]++
+	}
+
+	want := map[EventKind]int{
+		EventWorkerStarted:   1,
+		EventWorkerStopped:   1,
+		EventBatchCreated:    2,
+		EventExportSucceeded: 1,
+		EventExportFailed:    1,
+		EventItemsDropped:    1,
+	}
+
+	for kind, n := range want {
+		if counts[kind] != n {
+			t.Fatalf("expected %d %s events, got %v", n, kind, counts)
+		}
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	var bus eventBus
+
+	if bus.active() {
+		t.Fatal("expected no subscribers")
+	}
+
+	events, cancel := bus.subscribe(1)
+
+	// Events published while the channel is full are missed and counted.
+	for range 3 {
+		bus.publish(Event{Kind: EventBatchCreated})
+	}
+
+	<-events
+	bus.publish(Event{Kind: EventExportSucceeded})
+
+	if e := <-events; e.Kind != EventExportSucceeded || e.Missed != 2 {
+		t.Fatalf("expected an export event after 2 missed, got %+v", e)
+	}
+
+	cancel()
+	cancel()
+
+	if _, ok := <-events; ok {
+		t.Fatal("expected the channel closed once unsubscribed")
+	}
+
+	if bus.active() {
+		t.Fatal("expected no subscribers once unsubscribed")
+	}
+
+	bus.close()
+
+	closed, _ := bus.subscribe(1)
+
+	if _, ok := <-closed; ok {
+		t.Fatal("expected subscriptions to a closed bus to be closed")
+	}
+}
@@ -0,0 +1,4 @@
+// Package exporters groups the item exporters shipped with the batch item
+// processor. Each exporter lives in its own subpackage so importing the
+// processor core never pulls in the dependencies of sinks that aren't used.
+package exporters
@@ -0,0 +1,91 @@
+package grpcstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// codecName is the content subtype the sink service is called with.
+const codecName = "batchenvelope"
+
+func init() {
+	encoding.RegisterCodec(messageCodec{})
+}
+
+// batchMessage carries one batch: a big endian uint64 sequence number
+// followed by the envelope.
+type batchMessage struct {
+	seq      uint64
+	envelope []byte
+}
+
+// feedbackMessage carries the result of a batch and the sink's hint: a big
+// endian uint64 sequence number, uint32 batch size and int64 pause in
+// nanoseconds, followed by the error message, empty on success.
+type feedbackMessage struct {
+	seq       uint64
+	batchSize uint32
+	pause     time.Duration
+	err       string
+}
+
+const feedbackHeaderSize = 8 + 4 + 8
+
+var errMessageTooShort = errors.New("message is too short")
+
+// messageCodec encodes the sink service's messages.
+type messageCodec struct{}
+
+func (messageCodec) Name() string {
+	return codecName
+}
+
+func (messageCodec) Marshal(v any) ([]byte, error) {
+	switch m := v.(type) {
+	case *batchMessage:
+		buf := make([]byte, 8, 8+len(m.envelope))
+		binary.BigEndian.PutUint64(buf, m.seq)
+
+		return append(buf, m.envelope...), nil
+	case *feedbackMessage:
+		buf := make([]byte, feedbackHeaderSize, feedbackHeaderSize+len(m.err))
+		binary.BigEndian.PutUint64(buf, m.seq)
+		binary.BigEndian.PutUint32(buf[8:], m.batchSize)
+		binary.BigEndian.PutUint64(buf[12:], uint64(m.pause))
+
+		return append(buf, m.err...), nil
+	default:
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+}
+
+func (messageCodec) Unmarshal(data []byte, v any) error {
+	switch m := v.(type) {
+	case *batchMessage:
+		if len(data) < 8 {
+			return errMessageTooShort
+		}
+
+		m.seq = binary.BigEndian.Uint64(data)
+		m.envelope = append([]byte(nil), data[8:]...)
+
+		return nil
+	case *feedbackMessage:
+		if len(data) < feedbackHeaderSize {
+			return errMessageTooShort
+		}
+
+		m.seq = binary.BigEndian.Uint64(data)
+		m.batchSize = binary.BigEndian.Uint32(data[8:])
+		m.pause = time.Duration(binary.BigEndian.Uint64(data[12:]))
+		m.err = string(data[feedbackHeaderSize:])
+
+		return nil
+	default:
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+}
@@ -0,0 +1,300 @@
+// Package grpcstream provides an exporter that streams batches to a gRPC
+// sink, and the sink service itself. The sink answers every batch with its
+// result and a flow control hint, a batch size and a pause, which the
+// processor applies to the batches that follow, closing the feedback loop
+// between a collector and its producers.
+//
+// The service is defined without generated code: batches travel as
+// processor.Envelope bytes using the "batchenvelope" content subtype, so
+// both ends only need this package.
+package grpcstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"google.golang.org/grpc"
+)
+
+const (
+	serviceName = "batchprocessor.v1.BatchSink"
+	methodName  = "/" + serviceName + "/Export"
+)
+
+var (
+	// ErrRejected is returned by ExportItems when the sink's handler failed
+	// the batch. The handler's message is included in the error.
+	ErrRejected = errors.New("batch rejected by sink")
+	// ErrStreamClosed is returned for exports still waiting when the
+	// exporter shuts down.
+	ErrStreamClosed = errors.New("export stream closed")
+)
+
+var streamDesc = grpc.StreamDesc{
+	StreamName:    "Export",
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// Exporter streams batches to a sink over one long lived stream, opened on
+// the first export and reopened after it fails. Exports from several workers
+// share the stream and wait for their own result.
+type Exporter[T any] struct {
+	conn  grpc.ClientConnInterface
+	codec processor.Codec[T]
+	apply func(processor.FlowControl)
+
+	mu      sync.Mutex
+	stream  *exportStream
+	seq     uint64
+	pending map[uint64]chan error
+}
+
+var (
+	_ processor.ItemExporter[struct{}] = (*Exporter[struct{}])(nil)
+	_ processor.FlowControlled         = (*Exporter[struct{}])(nil)
+)
+
+// exportStream is an open stream and the means to tear it down.
+type exportStream struct {
+	grpc.ClientStream
+
+	sendMu sync.Mutex
+	cancel context.CancelFunc
+}
+
+// New creates an exporter streaming batches encoded with codec over conn.
+func New[T any](conn grpc.ClientConnInterface, codec processor.Codec[T]) *Exporter[T] {
+	return &Exporter[T]{
+		conn:    conn,
+		codec:   codec,
+		pending: make(map[uint64]chan error),
+	}
+}
+
+// SetFlowControl is called by the processor with the function applying the
+// sink's hints.
+func (e *Exporter[T]) SetFlowControl(apply func(processor.FlowControl)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.apply = apply
+}
+
+// ExportItems sends items to the sink and waits for its result.
+func (e *Exporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	payload, err := e.codec.Encode(items)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	envelope, err := processor.Envelope{
+		Codec:   processor.CodecName(e.codec),
+		Payload: payload,
+	}.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	stream, seq, result, err := e.register()
+	if err != nil {
+		return err
+	}
+
+	stream.sendMu.Lock()
+	err = stream.SendMsg(&batchMessage{seq: seq, envelope: envelope})
+	stream.sendMu.Unlock()
+
+	if err != nil {
+		e.unregister(seq)
+		e.reset(stream, err)
+
+		return fmt.Errorf("failed to send batch: %w", err)
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		e.unregister(seq)
+
+		return ctx.Err()
+	}
+}
+
+// register opens the stream if needed and allocates a sequence number to
+// wait for a result on.
+func (e *Exporter[T]) register() (*exportStream, uint64, chan error, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stream == nil {
+		// The stream outlives any one export, so it isn't bound to the
+		// export's context.
+		ctx, cancel := context.WithCancel(context.Background())
+
+		stream, err := e.conn.NewStream(ctx, &streamDesc, methodName, grpc.CallContentSubtype(codecName))
+		if err != nil {
+			cancel()
+
+			return nil, 0, nil, fmt.Errorf("failed to open export stream: %w", err)
+		}
+
+		e.stream = &exportStream{ClientStream: stream, cancel: cancel}
+
+		go e.receive(e.stream)
+	}
+
+	e.seq++
+
+	result := make(chan error, 1)
+	e.pending[e.seq] = result
+
+	return e.stream, e.seq, result, nil
+}
+
+func (e *Exporter[T]) unregister(seq uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.pending, seq)
+}
+
+// receive delivers results to waiting exports and applies the sink's hints,
+// until the stream fails.
+func (e *Exporter[T]) receive(stream *exportStream) {
+	for {
+		var fb feedbackMessage
+
+		if err := stream.RecvMsg(&fb); err != nil {
+			e.reset(stream, err)
+
+			return
+		}
+
+		e.mu.Lock()
+		apply := e.apply
+		result, ok := e.pending[fb.seq]
+		delete(e.pending, fb.seq)
+		e.mu.Unlock()
+
+		if apply != nil {
+			apply(processor.FlowControl{BatchSize: int(fb.batchSize), Pause: fb.pause})
+		}
+
+		if !ok {
+			continue
+		}
+
+		if fb.err != "" {
+			result <- fmt.Errorf("%w: %s", ErrRejected, fb.err)
+		} else {
+			result <- nil
+		}
+	}
+}
+
+// reset tears down a failed stream, failing the exports waiting on it, so
+// the next export opens a new one.
+func (e *Exporter[T]) reset(stream *exportStream, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stream != stream {
+		return
+	}
+
+	stream.cancel()
+	e.stream = nil
+
+	for seq, result := range e.pending {
+		result <- fmt.Errorf("export stream failed: %w", err)
+		delete(e.pending, seq)
+	}
+}
+
+// Shutdown closes the stream. Exports still waiting fail with
+// ErrStreamClosed.
+func (e *Exporter[T]) Shutdown(_ context.Context) error {
+	e.mu.Lock()
+	stream := e.stream
+	e.mu.Unlock()
+
+	if stream == nil {
+		return nil
+	}
+
+	stream.sendMu.Lock()
+	err := stream.CloseSend()
+	stream.sendMu.Unlock()
+
+	e.reset(stream, ErrStreamClosed)
+
+	return err
+}
+
+// Handler handles a batch received by the sink. It returns the flow control
+// hint sent back with the result; the zero hint asks for full batches
+// without pausing.
+type Handler func(ctx context.Context, envelope processor.Envelope) (processor.FlowControl, error)
+
+// RegisterSink registers the sink service on s, handling batches with
+// handle. Batches from one stream are handled in order.
+func RegisterSink(s grpc.ServiceRegistrar, handle Handler) {
+	desc := streamDesc
+	desc.Handler = func(_ any, stream grpc.ServerStream) error {
+		return serveStream(stream, handle)
+	}
+
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*any)(nil),
+		Streams:     []grpc.StreamDesc{desc},
+	}, struct{}{})
+}
+
+func serveStream(stream grpc.ServerStream, handle Handler) error {
+	ctx := stream.Context()
+
+	for {
+		var batch batchMessage
+
+		if err := stream.RecvMsg(&batch); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		hint, err := handleBatch(ctx, batch, handle)
+
+		fb := feedbackMessage{
+			seq:       batch.seq,
+			batchSize: uint32(max(hint.BatchSize, 0)),
+			pause:     hint.Pause,
+		}
+
+		if err != nil {
+			fb.err = err.Error()
+		}
+
+		if err := stream.SendMsg(&fb); err != nil {
+			return err
+		}
+	}
+}
+
+func handleBatch(ctx context.Context, batch batchMessage, handle Handler) (processor.FlowControl, error) {
+	var envelope processor.Envelope
+
+	if err := envelope.UnmarshalBinary(batch.envelope); err != nil {
+		return processor.FlowControl{}, err
+	}
+
+	return handle(ctx, envelope)
+}
@@ -0,0 +1,150 @@
+package grpcstream
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type event struct {
+	Slot uint64 `json:"slot"`
+}
+
+func dial(t *testing.T, handle Handler) *grpc.ClientConn {
+	t.Helper()
+
+	l := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+
+	RegisterSink(s, handle)
+
+	go func() {
+		_ = s.Serve(l)
+	}()
+
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///sink",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestExporter(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		mu    sync.Mutex
+		slots []uint64
+	)
+
+	conn := dial(t, func(_ context.Context, envelope processor.Envelope) (processor.FlowControl, error) {
+		items, err := processor.JSONCodec[event]{}.Decode(envelope.Payload)
+		if err != nil {
+			return processor.FlowControl{}, err
+		}
+
+		if items[0].Slot == 0 {
+			return processor.FlowControl{}, errors.New("slot zero")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, item := range items {
+			slots = append(slots, item.Slot)
+		}
+
+		// Ask for smaller batches and a short pause as more arrive.
+		return processor.FlowControl{BatchSize: 10 / len(slots), Pause: time.Millisecond}, nil
+	})
+
+	exporter := New[event](conn, processor.JSONCodec[event]{})
+
+	var hints []processor.FlowControl
+
+	exporter.SetFlowControl(func(fc processor.FlowControl) {
+		hints = append(hints, fc)
+	})
+
+	if err := exporter.ExportItems(ctx, []*event{{Slot: 1}, {Slot: 2}}); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	if err := exporter.ExportItems(ctx, []*event{{Slot: 3}}); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	if err := exporter.ExportItems(ctx, []*event{{Slot: 0}}); !errors.Is(err, ErrRejected) {
+		t.Fatalf("expected the batch to be rejected, got %v", err)
+	}
+
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(slots) != 3 {
+		t.Fatalf("expected 3 items at the sink, got %v", slots)
+	}
+
+	// Hints are applied before the result is delivered.
+	if len(hints) != 3 || hints[0].BatchSize != 5 || hints[1].BatchSize != 3 || hints[0].Pause != time.Millisecond {
+		t.Fatalf("expected hints for batch sizes 5 and 3, got %+v", hints)
+	}
+}
+
+func TestExporter_ReopensStream(t *testing.T) {
+	ctx := context.Background()
+
+	conn := dial(t, func(_ context.Context, _ processor.Envelope) (processor.FlowControl, error) {
+		return processor.FlowControl{}, nil
+	})
+
+	exporter := New[event](conn, processor.JSONCodec[event]{})
+
+	if err := exporter.ExportItems(ctx, []*event{{Slot: 1}}); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	// Cancel the stream under the exporter.
+	exporter.mu.Lock()
+	exporter.stream.cancel()
+	exporter.mu.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		err := exporter.ExportItems(ctx, []*event{{Slot: 2}})
+		if err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the exporter to reopen the stream, got %v", err)
+		}
+	}
+
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+}
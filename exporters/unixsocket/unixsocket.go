@@ -0,0 +1,316 @@
+// Package unixsocket provides an exporter that ships batches over a Unix
+// domain socket to a local sidecar or agent, and the receiver the sidecar
+// runs, so batches handed off locally survive restarts of the application.
+//
+// Each batch is sent as a frame: a big endian uint32 length followed by a
+// processor.Envelope. The receiver answers every frame with a single status
+// byte once it has handled the batch, so an export only succeeds once the
+// sidecar has taken responsibility for it.
+package unixsocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/ethpandaops/go-batch-processor/registry"
+)
+
+// DefaultMaxFrameSize is the largest frame Serve accepts by default.
+const DefaultMaxFrameSize = 64 << 20
+
+// Status bytes sent by the receiver after each frame.
+const (
+	statusOK     byte = 0
+	statusFailed byte = 1
+)
+
+var (
+	// ErrRejected is returned by ExportItems when the receiver's handler
+	// failed the batch.
+	ErrRejected = errors.New("batch rejected by receiver")
+	// ErrFrameTooLarge is returned by Serve for frames larger than its
+	// limit.
+	ErrFrameTooLarge = errors.New("frame is too large")
+)
+
+// Exporter sends batches over a Unix socket. It dials lazily and redials
+// after any error, so the sidecar can restart independently.
+type Exporter[T any] struct {
+	path  string
+	codec processor.Codec[T]
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+var _ processor.ItemExporter[struct{}] = (*Exporter[struct{}])(nil)
+
+// New creates an exporter sending batches encoded with codec to the socket
+// at path.
+func New[T any](path string, codec processor.Codec[T]) *Exporter[T] {
+	return &Exporter[T]{path: path, codec: codec}
+}
+
+// ExportItems sends items as one frame and waits for the receiver's status.
+func (e *Exporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	payload, err := e.codec.Encode(items)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	frame, err := encodeFrame(processor.Envelope{
+		Codec:   processor.CodecName(e.codec),
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	conn, err := e.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := roundTrip(ctx, conn, frame)
+	if err != nil {
+		// The stream may be mid-frame, so start over on a new connection.
+		e.closeConn()
+
+		return fmt.Errorf("failed to send batch to %s: %w", e.path, err)
+	}
+
+	if status != statusOK {
+		return ErrRejected
+	}
+
+	return nil
+}
+
+// dial returns the open connection, dialing one if needed. It must be called
+// with the lock held.
+func (e *Exporter[T]) dial(ctx context.Context) (net.Conn, error) {
+	if e.conn != nil {
+		return e.conn, nil
+	}
+
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "unix", e.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", e.path, err)
+	}
+
+	e.conn = conn
+
+	return conn, nil
+}
+
+// closeConn closes the connection. It must be called with the lock held.
+func (e *Exporter[T]) closeConn() error {
+	if e.conn == nil {
+		return nil
+	}
+
+	err := e.conn.Close()
+	e.conn = nil
+
+	return err
+}
+
+// Shutdown closes the connection.
+func (e *Exporter[T]) Shutdown(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.closeConn()
+}
+
+// roundTrip writes a frame and reads the status, bounded by ctx's deadline
+// and aborted if ctx is cancelled.
+func roundTrip(ctx context.Context, conn net.Conn, frame []byte) (byte, error) {
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := conn.Write(frame); err != nil {
+		return 0, err
+	}
+
+	var status [1]byte
+
+	if _, err := io.ReadFull(conn, status[:]); err != nil {
+		return 0, err
+	}
+
+	return status[0], nil
+}
+
+func encodeFrame(e processor.Envelope) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.Write(make([]byte, 4))
+
+	if err := processor.WriteEnvelope(&buf, e); err != nil {
+		return nil, err
+	}
+
+	frame := buf.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+
+	return frame, nil
+}
+
+// Handler handles a batch received by Serve. Returning an error fails the
+// export on the sending side.
+type Handler func(ctx context.Context, envelope processor.Envelope) error
+
+// ServeOption configures Serve.
+type ServeOption func(*serveOptions)
+
+type serveOptions struct {
+	maxFrameSize int
+}
+
+// WithMaxFrameSize sets the largest frame Serve accepts. Connections sending
+// larger frames are closed.
+func WithMaxFrameSize(size int) ServeOption {
+	return func(o *serveOptions) {
+		o.maxFrameSize = size
+	}
+}
+
+// Serve accepts connections on l and passes every batch received to handle,
+// until ctx is done or l fails. Each connection is served by its own
+// goroutine, handling its batches in order. Serve closes l and waits for
+// its connections before returning.
+func Serve(ctx context.Context, l net.Listener, handle Handler, opts ...ServeOption) error {
+	o := serveOptions{maxFrameSize: DefaultMaxFrameSize}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = l.Close()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			serveConn(ctx, conn, handle, o)
+		}()
+	}
+}
+
+// serveConn handles frames from one connection until it fails or ctx is
+// done.
+func serveConn(ctx context.Context, conn net.Conn, handle Handler, o serveOptions) {
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stop()
+	defer conn.Close()
+
+	for {
+		envelope, err := readFrame(conn, o.maxFrameSize)
+		if err != nil {
+			return
+		}
+
+		status := statusOK
+		if err := handle(ctx, envelope); err != nil {
+			status = statusFailed
+		}
+
+		if _, err := conn.Write([]byte{status}); err != nil {
+			return
+		}
+	}
+}
+
+func readFrame(r io.Reader, maxFrameSize int) (processor.Envelope, error) {
+	var size [4]byte
+
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return processor.Envelope{}, err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if int64(n) > int64(maxFrameSize) {
+		return processor.Envelope{}, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, n)
+	}
+
+	frame := make([]byte, n)
+
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return processor.Envelope{}, err
+	}
+
+	var envelope processor.Envelope
+
+	if err := envelope.UnmarshalBinary(frame); err != nil {
+		return processor.Envelope{}, err
+	}
+
+	return envelope, nil
+}
+
+// Config configures an Exporter built through a registry. Batches are
+// encoded as JSON.
+type Config struct {
+	// Path is the socket to send batches to.
+	Path string `json:"path"`
+}
+
+// Register registers the exporter under the name "unixsocket".
+func Register[T any](r *registry.Registry[processor.ItemExporter[T]]) error {
+	return r.Register("unixsocket", func(config map[string]any) (processor.ItemExporter[T], error) {
+		var cfg Config
+
+		if err := registry.DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		if cfg.Path == "" {
+			return nil, errors.New("unixsocket exporter path is required")
+		}
+
+		return New[T](cfg.Path, processor.JSONCodec[T]{}), nil
+	})
+}
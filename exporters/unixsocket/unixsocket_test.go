@@ -0,0 +1,137 @@
+package unixsocket
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/ethpandaops/go-batch-processor/registry"
+)
+
+type event struct {
+	Slot uint64 `json:"slot"`
+}
+
+// receiver serves a socket, collecting the slots of received batches.
+type receiver struct {
+	mu     sync.Mutex
+	slots  []uint64
+	reject bool
+
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func listen(t *testing.T, path string) *receiver {
+	t.Helper()
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &receiver{cancel: cancel, done: make(chan error, 1)}
+
+	go func() {
+		r.done <- Serve(ctx, l, r.handle)
+	}()
+
+	t.Cleanup(r.stop)
+
+	return r
+}
+
+func (r *receiver) handle(_ context.Context, envelope processor.Envelope) error {
+	items, err := processor.JSONCodec[event]{}.Decode(envelope.Payload)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.reject {
+		return errors.New("rejected")
+	}
+
+	for _, item := range items {
+		r.slots = append(r.slots, item.Slot)
+	}
+
+	return nil
+}
+
+func (r *receiver) stop() {
+	r.cancel()
+	<-r.done
+	r.done <- nil
+}
+
+func TestExporter(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sidecar.sock")
+
+	r := listen(t, path)
+	exporter := New[event](path, processor.JSONCodec[event]{})
+
+	if err := exporter.ExportItems(ctx, []*event{{Slot: 1}, {Slot: 2}}); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	r.mu.Lock()
+	r.reject = true
+	r.mu.Unlock()
+
+	if err := exporter.ExportItems(ctx, []*event{{Slot: 3}}); !errors.Is(err, ErrRejected) {
+		t.Fatalf("expected the batch to be rejected, got %v", err)
+	}
+
+	// The exporter redials once the sidecar restarts.
+	r.stop()
+
+	if err := exporter.ExportItems(ctx, []*event{{Slot: 4}}); err == nil {
+		t.Fatal("expected exports to fail while the sidecar is down")
+	}
+
+	restarted := listen(t, path)
+
+	if err := exporter.ExportItems(ctx, []*event{{Slot: 5}}); err != nil {
+		t.Fatalf("failed to export after the sidecar restarted: %v", err)
+	}
+
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if len(r.slots) != 2 || r.slots[0] != 1 || r.slots[1] != 2 {
+		t.Errorf("expected slots [1 2], got %v", r.slots)
+	}
+
+	restarted.mu.Lock()
+	defer restarted.mu.Unlock()
+
+	if len(restarted.slots) != 1 || restarted.slots[0] != 5 {
+		t.Errorf("expected slots [5] after the restart, got %v", restarted.slots)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := registry.NewExporterRegistry[event]()
+
+	if err := Register(r); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	if _, err := r.Build("unixsocket", map[string]any{}); err == nil {
+		t.Fatal("expected a missing path to be rejected")
+	}
+
+	if _, err := r.Build("unixsocket", map[string]any{"path": "/tmp/sidecar.sock"}); err != nil {
+		t.Fatalf("failed to build: %v", err)
+	}
+}
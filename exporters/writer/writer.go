@@ -0,0 +1,96 @@
+// Package writer provides an exporter that writes items as JSON lines to an
+// io.Writer.
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/ethpandaops/go-batch-processor/registry"
+)
+
+// Exporter writes each item as a line of JSON.
+type Exporter[T any] struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+var _ processor.ItemExporter[struct{}] = (*Exporter[struct{}])(nil)
+
+// New creates an exporter writing to w. If w is an io.Closer it is closed on
+// Shutdown.
+func New[T any](w io.Writer) *Exporter[T] {
+	return &Exporter[T]{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+// ExportItems writes items as JSON lines.
+func (e *Exporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := e.enc.Encode(item); err != nil {
+			return fmt.Errorf("failed to write item: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Shutdown closes the underlying writer if it is an io.Closer.
+func (e *Exporter[T]) Shutdown(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if closer, ok := e.w.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// Config configures an Exporter built through a registry.
+type Config struct {
+	// Path is the file to append to. An empty path writes to stdout.
+	Path string `json:"path"`
+}
+
+// Register registers the exporter under the name "writer".
+func Register[T any](r *registry.Registry[processor.ItemExporter[T]]) error {
+	return r.Register("writer", func(config map[string]any) (processor.ItemExporter[T], error) {
+		var cfg Config
+
+		if err := registry.DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		if cfg.Path == "" {
+			return New[T](nopCloser{os.Stdout}), nil
+		}
+
+		f, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", cfg.Path, err)
+		}
+
+		return New[T](f), nil
+	})
+}
+
+// nopCloser prevents Shutdown from closing stdout.
+type nopCloser struct {
+	io.Writer
+}
@@ -0,0 +1,61 @@
+package writer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethpandaops/go-batch-processor/registry"
+)
+
+type event struct {
+	Slot uint64 `json:"slot"`
+}
+
+func TestExporter(t *testing.T) {
+	var buf bytes.Buffer
+
+	exporter := New[event](&buf)
+
+	if err := exporter.ExportItems(context.Background(), []*event{{Slot: 1}, {Slot: 2}}); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	if got, want := buf.String(), "{\"slot\":1}\n{\"slot\":2}\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := registry.NewExporterRegistry[event]()
+
+	if err := Register(r); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	exporter, err := r.Build("writer", map[string]any{"path": path})
+	if err != nil {
+		t.Fatalf("failed to build: %v", err)
+	}
+
+	if err := exporter.ExportItems(context.Background(), []*event{{Slot: 7}}); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	if got, want := string(b), "{\"slot\":7}\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,165 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ItemExporterV2 is the full exporter lifecycle in one interface, replacing
+// ItemExporter and its optional ExporterStarter and HealthChecker
+// interfaces. Exporters report per item results, so a batch can partly
+// succeed. Use ExporterFromV2 to pass one to NewBatchItemProcessor and
+// ExporterV2From to wrap an existing ItemExporter.
+type ItemExporterV2[T any] interface {
+	// Start prepares the exporter. It is called once, before any batch is
+	// exported.
+	Start(ctx context.Context) error
+
+	// ExportItems exports a batch of items, with the same ownership and
+	// concurrency rules as ItemExporter.ExportItems.
+	ExportItems(ctx context.Context, items []*T) ExportResult
+
+	// HealthCheck returns an error if the exporter can't currently export.
+	HealthCheck(ctx context.Context) error
+
+	// Shutdown notifies the exporter of a pending halt to operations.
+	Shutdown(ctx context.Context) error
+}
+
+// ExportResult is the outcome of exporting a batch.
+type ExportResult struct {
+	// Err fails the whole batch.
+	Err error
+	// ItemErrors holds an error per item, nil for items that were
+	// exported. It is ignored if Err is set and may be nil if every item
+	// was exported.
+	ItemErrors []error
+}
+
+// Error returns the result as a single error: Err, a *PartialExportError if
+// only some items failed, or nil.
+func (r ExportResult) Error() error {
+	if r.Err != nil {
+		return r.Err
+	}
+
+	for _, err := range r.ItemErrors {
+		if err != nil {
+			return &PartialExportError{ItemErrors: r.ItemErrors}
+		}
+	}
+
+	return nil
+}
+
+// PartialExportError reports a batch where only some items failed to export.
+// With the sync shipping method each writer gets its own item's error.
+type PartialExportError struct {
+	// ItemErrors holds an error per item, nil for items that were
+	// exported.
+	ItemErrors []error
+}
+
+func (e *PartialExportError) Error() string {
+	return fmt.Sprintf("%d of %d items failed to export: %v", e.Failed(), len(e.ItemErrors), errors.Join(e.ItemErrors...))
+}
+
+// Unwrap returns the item errors, so errors.Is and errors.As match each of
+// them.
+func (e *PartialExportError) Unwrap() []error {
+	return e.ItemErrors
+}
+
+// Failed returns the number of items that failed.
+func (e *PartialExportError) Failed() int {
+	failed := 0
+
+	for _, err := range e.ItemErrors {
+		if err != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// ExporterFromV2 adapts an ItemExporterV2 to an ItemExporter implementing
+// ExporterStarter and HealthChecker, for NewBatchItemProcessor.
+func ExporterFromV2[T any](exporter ItemExporterV2[T]) ItemExporter[T] {
+	return &v2Exporter[T]{exporter: exporter}
+}
+
+type v2Exporter[T any] struct {
+	exporter ItemExporterV2[T]
+}
+
+func (e *v2Exporter[T]) Start(ctx context.Context) error {
+	return e.exporter.Start(ctx)
+}
+
+func (e *v2Exporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	return e.exporter.ExportItems(ctx, items).Error()
+}
+
+func (e *v2Exporter[T]) HealthCheck(ctx context.Context) error {
+	return e.exporter.HealthCheck(ctx)
+}
+
+func (e *v2Exporter[T]) Shutdown(ctx context.Context) error {
+	return e.exporter.Shutdown(ctx)
+}
+
+// ExporterV2From adapts an ItemExporter to an ItemExporterV2. Start and
+// HealthCheck call the exporter's ExporterStarter and HealthChecker
+// implementations, and succeed if it has none. A *PartialExportError
+// returned by ExportItems becomes per item results.
+func ExporterV2From[T any](exporter ItemExporter[T]) ItemExporterV2[T] {
+	if v2, ok := exporter.(*v2Exporter[T]); ok {
+		return v2.exporter
+	}
+
+	return &v1Exporter[T]{exporter: exporter}
+}
+
+type v1Exporter[T any] struct {
+	exporter ItemExporter[T]
+}
+
+func (e *v1Exporter[T]) Start(ctx context.Context) error {
+	return startExporter(ctx, e.exporter)
+}
+
+func (e *v1Exporter[T]) ExportItems(ctx context.Context, items []*T) ExportResult {
+	err := e.exporter.ExportItems(ctx, items)
+
+	if partial, ok := partialErrors(err, len(items)); ok {
+		return ExportResult{ItemErrors: partial.ItemErrors}
+	}
+
+	return ExportResult{Err: err}
+}
+
+func (e *v1Exporter[T]) HealthCheck(ctx context.Context) error {
+	checker, ok := e.exporter.(HealthChecker)
+	if !ok {
+		return nil
+	}
+
+	return checker.HealthCheck(ctx)
+}
+
+func (e *v1Exporter[T]) Shutdown(ctx context.Context) error {
+	return e.exporter.Shutdown(ctx)
+}
+
+// partialErrors returns the item errors of a *PartialExportError for a batch
+// of n items.
+func partialErrors(err error, n int) (*PartialExportError, bool) {
+	var partial *PartialExportError
+	if !errors.As(err, &partial) || len(partial.ItemErrors) != n {
+		return nil, false
+	}
+
+	return partial, true
+}
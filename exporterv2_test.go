@@ -0,0 +1,137 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+var errOdd = errors.New("odd item")
+
+// oddExporter fails odd items.
+type oddExporter struct {
+	started  atomic.Bool
+	shutdown atomic.Bool
+}
+
+func (e *oddExporter) Start(_ context.Context) error {
+	e.started.Store(true)
+
+	return nil
+}
+
+func (e *oddExporter) ExportItems(_ context.Context, items []*int) ExportResult {
+	errs := make([]error, len(items))
+
+	for i, item := range items {
+		if *item%2 == 1 {
+			errs[i] = errOdd
+		}
+	}
+
+	return ExportResult{ItemErrors: errs}
+}
+
+func (e *oddExporter) HealthCheck(_ context.Context) error {
+	return nil
+}
+
+func (e *oddExporter) Shutdown(_ context.Context) error {
+	e.shutdown.Store(true)
+
+	return nil
+}
+
+func TestBatchItemProcessor_ExporterV2(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &oddExporter{}
+
+	proc, err := NewBatchItemProcessor[int](ExporterFromV2[int](exporter), "test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(10),
+		WithReadinessCheck(DefaultSpinDuration),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !exporter.started.Load() {
+		t.Fatal("expected the exporter to be started")
+	}
+
+	items := make([]*int, 4)
+	for i := range items {
+		items[i] = &i
+	}
+
+	exportedBefore := counterValue(t, DefaultMetrics.itemsExported.WithLabelValues("test"))
+	failedBefore := counterValue(t, DefaultMetrics.itemsFailed.WithLabelValues("test"))
+
+	errs, err := proc.WriteEach(ctx, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, err := range errs {
+		if want := i%2 == 1; errors.Is(err, errOdd) != want {
+			t.Errorf("item %d: expected failure %v, got %v", i, want, err)
+		}
+	}
+
+	if got := counterValue(t, DefaultMetrics.itemsExported.WithLabelValues("test")) - exportedBefore; got != 2 {
+		t.Errorf("expected 2 items counted as exported, got %v", got)
+	}
+
+	if got := counterValue(t, DefaultMetrics.itemsFailed.WithLabelValues("test")) - failedBefore; got != 2 {
+		t.Errorf("expected 2 items counted as failed, got %v", got)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !exporter.shutdown.Load() {
+		t.Fatal("expected the exporter to be shut down")
+	}
+}
+
+func TestExporterV2From(t *testing.T) {
+	ctx := context.Background()
+
+	v1 := &healthExporter[int]{}
+	v1.down.Store(true)
+
+	v2 := ExporterV2From[int](v1)
+
+	if err := v2.Start(ctx); err != nil {
+		t.Fatalf("expected exporters without Start to start, got %v", err)
+	}
+
+	if err := v2.HealthCheck(ctx); err == nil {
+		t.Fatal("expected the v1 health check to be used")
+	}
+
+	odd := &oddExporter{}
+
+	if got := ExporterV2From(ExporterFromV2[int](odd)); got != odd {
+		t.Fatal("expected adapting an adapted exporter back to unwrap it")
+	}
+
+	one, two := 1, 2
+
+	result := ExporterV2From(ExporterFromV2[int](&oddExporter{})).ExportItems(ctx, []*int{&one, &two})
+	if len(result.ItemErrors) != 2 || !errors.Is(result.ItemErrors[0], errOdd) || result.ItemErrors[1] != nil {
+		t.Fatalf("expected per item results, got %+v", result)
+	}
+}
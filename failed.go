@@ -0,0 +1,162 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrExporterClosed is returned, wrapped, by exporters that are permanently
+// closed and will never export again, for example because their client was
+// shut down. Retrying such an exporter would spin forever, so the processor
+// replaces it if it can, and otherwise enters the failed state.
+var ErrExporterClosed = errors.New("exporter permanently closed")
+
+// ErrProcessorFailed is returned by writes, and Healthy, once the processor
+// has failed. It wraps the error that failed it.
+var ErrProcessorFailed = errors.New("processor failed")
+
+// State is the lifecycle state of a processor.
+type State string
+
+const (
+	// StateCreated is the state of a processor that hasn't been started.
+	StateCreated State = "created"
+	// StateRunning is the state of a started processor.
+	StateRunning State = "running"
+	// StateStopped is the state of a processor that has been shut down.
+	StateStopped State = "stopped"
+	// StateFailed is the terminal state of a processor whose exporter was
+	// found permanently closed and couldn't be replaced. Writes fail with
+	// ErrProcessorFailed, and queued items fail without being exported.
+	StateFailed State = "failed"
+)
+
+// ExporterReplacer returns a new exporter to replace one found permanently
+// closed with err.
+type ExporterReplacer[T any] func(ctx context.Context, err error) (ItemExporter[T], error)
+
+// WithExporterReplacer sets a function called when an export fails with
+// ErrExporterClosed, to replace the exporter rather than enter the failed
+// state. The replacement is started if it implements ExporterStarter, and
+// the closed exporter is shut down. HealthChecker is probed on the
+// replacement, but other optional interfaces are still those of the original
+// exporter. Exporters made per worker with WithExporterFactory aren't
+// replaced.
+func WithExporterReplacer[T any](replace ExporterReplacer[T]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ExporterReplacer = replace
+	}
+}
+
+// exporterRef holds the exporter in use, so it can be replaced while
+// workers export with it.
+type exporterRef[T any] struct {
+	e ItemExporter[T]
+}
+
+// exporterLifecycle tracks the exporter in use and whether the processor has
+// failed.
+type exporterLifecycle[T any] struct {
+	current atomic.Pointer[exporterRef[T]]
+	failed  atomic.Pointer[error]
+
+	// replaceMu serializes replacements, so an exporter found closed by
+	// several workers at once is replaced once.
+	replaceMu sync.Mutex
+}
+
+// State returns the lifecycle state of the processor.
+func (bvp *BatchItemProcessor[T]) State() State {
+	switch {
+	case bvp.failure() != nil:
+		return StateFailed
+	case bvp.stopping():
+		return StateStopped
+	case bvp.started.Load():
+		return StateRunning
+	default:
+		return StateCreated
+	}
+}
+
+// failure returns the error that failed the processor, or nil if it hasn't.
+func (bvp *BatchItemProcessor[T]) failure() error {
+	if err := bvp.lifecycle.failed.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
+
+// fail moves the processor in to the failed state, if it isn't already.
+func (bvp *BatchItemProcessor[T]) fail(err error) {
+	err = fmt.Errorf("%w: %w", ErrProcessorFailed, err)
+
+	if bvp.lifecycle.failed.CompareAndSwap(nil, &err) {
+		bvp.log.WithError(err).Error("Processor failed, queued items will not be exported")
+	}
+}
+
+// exporter returns the exporter in use.
+func (bvp *BatchItemProcessor[T]) exporter() ItemExporter[T] {
+	return bvp.lifecycle.current.Load().e
+}
+
+// exporterClosed handles an export by closed failing with ErrExporterClosed,
+// replacing the exporter if a replacer is set and failing the processor
+// otherwise.
+func (bvp *BatchItemProcessor[T]) exporterClosed(ctx context.Context, closed *exporterRef[T], err error) {
+	l := &bvp.lifecycle
+
+	l.replaceMu.Lock()
+	defer l.replaceMu.Unlock()
+
+	// Another worker already handled it.
+	if bvp.failure() != nil || l.current.Load() != closed {
+		return
+	}
+
+	if bvp.replacer == nil || bvp.workerExporters != nil {
+		bvp.fail(err)
+
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+
+	replacement, replaceErr := bvp.replacer(ctx, err)
+	if replaceErr == nil && replacement == nil {
+		replaceErr = errors.New("no replacement exporter")
+	}
+
+	if replaceErr == nil {
+		replaceErr = startExporter(ctx, replacement)
+	}
+
+	if replaceErr != nil {
+		bvp.fail(errors.Join(err, fmt.Errorf("failed to replace exporter: %w", replaceErr)))
+
+		return
+	}
+
+	bvp.connectFlowControl(replacement)
+	l.current.Store(&exporterRef[T]{e: replacement})
+
+	bvp.log.WithError(err).Warn("Replaced permanently closed exporter")
+
+	if shutdownErr := closed.e.Shutdown(ctx); shutdownErr != nil {
+		bvp.log.WithError(shutdownErr).Warn("Failed to shut down closed exporter")
+	}
+}
+
+// failBatch fails a batch without exporting it once the processor has
+// failed.
+func (bvp *BatchItemProcessor[T]) failBatch(ctx context.Context, batch []*TraceableItem[T], err error) {
+	bvp.metrics.IncItemsFailedBy(bvp.label, float64(len(batch)))
+
+	signalWriters(batch, err, nil)
+	bvp.settleBatch(ctx, batch, err)
+}
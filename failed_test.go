@@ -0,0 +1,138 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// closedExporter is permanently closed.
+type closedExporter struct {
+	shutdown atomic.Bool
+}
+
+func (e *closedExporter) ExportItems(_ context.Context, _ []*int) error {
+	return fmt.Errorf("connection shut down: %w", ErrExporterClosed)
+}
+
+func (e *closedExporter) Shutdown(_ context.Context) error {
+	e.shutdown.Store(true)
+
+	return nil
+}
+
+func TestBatchItemProcessor_FailedState(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	proc, err := NewBatchItemProcessor[int](&closedExporter{}, "test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state := proc.State(); state != StateCreated {
+		t.Fatalf("expected the created state, got %s", state)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := proc.State(); state != StateRunning {
+		t.Fatalf("expected the running state, got %s", state)
+	}
+
+	if err := proc.Write(ctx, ints(5)); !errors.Is(err, ErrExporterClosed) {
+		t.Fatalf("expected the exporter closed, got %v", err)
+	}
+
+	if state := proc.State(); state != StateFailed {
+		t.Fatalf("expected the failed state, got %s", state)
+	}
+
+	if err := proc.Healthy(); !errors.Is(err, ErrProcessorFailed) || !errors.Is(err, ErrExporterClosed) {
+		t.Fatalf("expected the failure reported as unhealthy, got %v", err)
+	}
+
+	if err := proc.Write(ctx, ints(5)); !errors.Is(err, ErrProcessorFailed) {
+		t.Fatalf("expected writes to a failed processor to fail, got %v", err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := proc.State(); state != StateFailed {
+		t.Fatalf("expected the failed state to be terminal, got %s", state)
+	}
+}
+
+func TestBatchItemProcessor_ExporterReplacer(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	closed := &closedExporter{}
+	replacement := &mockExporter[int]{}
+
+	var replaced atomic.Int32
+
+	proc, err := NewBatchItemProcessor[int](closed, "test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(5),
+		WithExporterReplacer(func(_ context.Context, err error) (ItemExporter[int], error) {
+			if !errors.Is(err, ErrExporterClosed) {
+				t.Errorf("expected the replacer given the closed error, got %v", err)
+			}
+
+			replaced.Add(1)
+
+			return replacement, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Write(ctx, ints(5)); !errors.Is(err, ErrExporterClosed) {
+		t.Fatalf("expected the exporter closed, got %v", err)
+	}
+
+	if err := proc.Write(ctx, ints(5)); err != nil {
+		t.Fatalf("expected the replacement to export, got %v", err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := proc.State(); state != StateStopped {
+		t.Fatalf("expected the stopped state, got %s", state)
+	}
+
+	if got := replaced.Load(); got != 1 {
+		t.Fatalf("expected the exporter replaced once, got %d", got)
+	}
+
+	if !closed.shutdown.Load() {
+		t.Fatal("expected the closed exporter shut down")
+	}
+
+	if got := replacement.exportCount.Load(); got != 5 {
+		t.Fatalf("expected 5 items exported by the replacement, got %d", got)
+	}
+}
@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"context"
+	"time"
+)
+
+// FlowControl is a pacing hint sent by a sink, letting a collector slow down
+// or reshape the load its producers send it.
+type FlowControl struct {
+	// BatchSize is the batch size the sink asks for. It is capped at
+	// MaxExportBatchSize, and zero restores MaxExportBatchSize.
+	BatchSize int
+	// Pause holds back exports for this long. Items keep queueing while
+	// exports are paused.
+	Pause time.Duration
+}
+
+// FlowControlled is an optional interface for exporters whose sink sends
+// flow control hints. The processor calls SetFlowControl once, before
+// starting the exporter, with a function that applies hints; the exporter
+// calls it whenever its sink sends one.
+type FlowControlled interface {
+	SetFlowControl(apply func(FlowControl))
+}
+
+// connectFlowControl hands the exporter the processor's flow control, if it
+// takes hints.
+func (bvp *BatchItemProcessor[T]) connectFlowControl(exporter ItemExporter[T]) {
+	if controlled, ok := exporter.(FlowControlled); ok {
+		controlled.SetFlowControl(bvp.applyFlowControl)
+	}
+}
+
+// applyFlowControl applies a hint from the exporter's sink.
+func (bvp *BatchItemProcessor[T]) applyFlowControl(fc FlowControl) {
+	bvp.flowBatchSize.Store(int64(min(max(fc.BatchSize, 0), bvp.o.MaxExportBatchSize)))
+
+	if fc.Pause > 0 {
+		until := time.Now().Add(fc.Pause).UnixNano()
+
+		for {
+			current := bvp.pausedUntil.Load()
+			if current >= until || bvp.pausedUntil.CompareAndSwap(current, until) {
+				break
+			}
+		}
+	}
+}
+
+// maxBatchSize returns the size the batch builder cuts full batches at.
+func (bvp *BatchItemProcessor[T]) maxBatchSize() int {
+	if size := bvp.flowBatchSize.Load(); size > 0 {
+		return int(size)
+	}
+
+	return bvp.o.MaxExportBatchSize
+}
+
+// waitFlowPause blocks while the sink has asked for exports to pause, or
+// until ctx is done.
+func (bvp *BatchItemProcessor[T]) waitFlowPause(ctx context.Context) {
+	wait := time.Until(time.Unix(0, bvp.pausedUntil.Load()))
+	if wait <= 0 {
+		return
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// hintExporter asks for smaller batches after its first export.
+type hintExporter struct {
+	sizeExporter[int]
+	apply func(FlowControl)
+}
+
+func (e *hintExporter) SetFlowControl(apply func(FlowControl)) {
+	e.apply = apply
+}
+
+func (e *hintExporter) ExportItems(ctx context.Context, items []*int) error {
+	err := e.sizeExporter.ExportItems(ctx, items)
+
+	e.apply(FlowControl{BatchSize: 2, Pause: 20 * time.Millisecond})
+
+	return err
+}
+
+func TestBatchItemProcessor_FlowControl(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &hintExporter{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	items := make([]*int, 14)
+	for i := range items {
+		items[i] = &i
+	}
+
+	start := time.Now()
+
+	if err := proc.Write(ctx, items[:10]); err != nil {
+		t.Fatal(err)
+	}
+
+	for exporter.exportCount.Load() < 10 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := proc.Write(ctx, items[10:]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if exporter.sizes[0] != 10 {
+		t.Fatalf("expected a full first batch, got sizes %v", exporter.sizes)
+	}
+
+	for _, size := range exporter.sizes[1:] {
+		if size > 2 {
+			t.Fatalf("expected batches of at most 2 after the hint, got sizes %v", exporter.sizes)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected exports to pause after the hint, took %v", elapsed)
+	}
+}
@@ -0,0 +1,184 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// ErrHandedOff is returned to sync writers whose items were handed off to
+// the next process generation rather than exported.
+var ErrHandedOff = errors.New("items handed off to the next process")
+
+// WithHandoff hands queued items to the next process generation across a
+// restart, such as a binary upgrade. On Shutdown, items not yet in a cut
+// batch are written to path with codec instead of being exported; batches
+// already cut are exported as usual. On Start, items found at path are
+// exported ahead of anything written, and the file is removed, so a rolling
+// restart neither loses nor duplicates queued items.
+//
+// The replacement must start its processor once the old one has shut down.
+// If the handoff can't be written, items are exported on Shutdown instead.
+func WithHandoff[T any](path string, codec Codec[T]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.HandoffPath = path
+		o.HandoffCodec = codec
+	}
+}
+
+// stopping reports whether Shutdown has been called.
+func (bvp *BatchItemProcessor[T]) stopping() bool {
+	select {
+	case <-bvp.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// handoffKept holds what is kept of the previous process's handoff when Start
+// couldn't take it over.
+type handoffKept[T any] struct {
+	mu sync.Mutex
+	// items are the handed off items not exported, written again with
+	// the items handed off on Shutdown.
+	items []*T
+	// unread is set if the handoff couldn't be read, so it can't be
+	// written again without losing it.
+	unread bool
+}
+
+// handOff writes items to the handoff file, telling their sync writers. It
+// returns false, leaving the items untouched, if they couldn't be written.
+func (bvp *BatchItemProcessor[T]) handOff(items []*TraceableItem[T]) bool {
+	bvp.handoffKept.mu.Lock()
+	defer bvp.handoffKept.mu.Unlock()
+
+	if bvp.handoffKept.unread {
+		bvp.log.Warn("Previous handoff couldn't be read, exporting queued items instead of overwriting it")
+
+		return false
+	}
+
+	plain := slices.Clone(bvp.handoffKept.items)
+	for _, item := range items {
+		plain = append(plain, item.item)
+	}
+
+	if err := bvp.writeHandoff(plain); err != nil {
+		bvp.log.WithError(err).Error("Failed to hand off queued items, exporting them instead")
+
+		return false
+	}
+
+	bvp.log.WithField("items", len(items)).Info("Handed off queued items")
+
+	signalWriters(items, ErrHandedOff, nil)
+
+	bvp.settlePending(items)
+	bvp.items.putAll(items)
+
+	return true
+}
+
+// handOffRemaining hands off the batch being built and the items received
+// since Shutdown, returning what's left to cut as the last batch. If they
+// can't be handed off, all but the last batch of them are cut for export.
+func (bvp *BatchItemProcessor[T]) handOffRemaining(batch, received []*TraceableItem[T]) []*TraceableItem[T] {
+	pending := append(slices.Clone(batch), received...)
+
+	if len(pending) == 0 || bvp.handOff(pending) {
+		return batch[:0]
+	}
+
+	batch = batch[:0]
+
+	for i, item := range pending {
+		batch = append(batch, item)
+
+		if len(batch) >= bvp.o.MaxExportBatchSize && i < len(pending)-1 {
+			bvp.addStep(1)
+			bvp.cutCh <- cutBatch[T]{items: batch, reason: "shutdown"}
+
+			batch = bvp.buffers.get()
+		}
+	}
+
+	return batch
+}
+
+// writeHandoff writes items to the handoff file, replacing it atomically.
+func (bvp *BatchItemProcessor[T]) writeHandoff(items []*T) error {
+	data, err := bvp.handoffCodec.Encode(items)
+	if err != nil {
+		return fmt.Errorf("failed to encode handoff: %w", err)
+	}
+
+	tmp := bvp.o.HandoffPath + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write handoff: %w", err)
+	}
+
+	// Renaming makes the handoff visible to the next process atomically.
+	if err := os.Rename(tmp, bvp.o.HandoffPath); err != nil {
+		return fmt.Errorf("failed to write handoff: %w", err)
+	}
+
+	return nil
+}
+
+// takeHandoff exports the items handed off by the previous process, in
+// batches, and removes the handoff file. If an export fails the items not
+// yet exported are left in the file for the next start.
+func (bvp *BatchItemProcessor[T]) takeHandoff(ctx context.Context) error {
+	bvp.handoffKept.mu.Lock()
+	defer bvp.handoffKept.mu.Unlock()
+
+	bvp.handoffKept.items, bvp.handoffKept.unread = nil, false
+
+	data, err := os.ReadFile(bvp.o.HandoffPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		bvp.handoffKept.unread = true
+
+		return fmt.Errorf("failed to read handoff: %w", err)
+	}
+
+	items, err := bvp.handoffCodec.Decode(data)
+	if err != nil {
+		bvp.handoffKept.unread = true
+
+		return fmt.Errorf("failed to decode handoff: %w", err)
+	}
+
+	bvp.log.WithField("items", len(items)).Info("Taking over handed off items")
+
+	for len(items) > 0 {
+		n := min(len(items), bvp.o.MaxExportBatchSize)
+
+		if err := bvp.exportWithDeadline(ctx, items[:n]); err != nil {
+			bvp.handoffKept.items = items
+
+			if writeErr := bvp.writeHandoff(items); writeErr != nil {
+				err = errors.Join(err, writeErr)
+			}
+
+			return fmt.Errorf("failed to export handed off items: %w", err)
+		}
+
+		items = items[n:]
+	}
+
+	if err := os.Remove(bvp.o.HandoffPath); err != nil {
+		return fmt.Errorf("failed to remove handoff: %w", err)
+	}
+
+	return nil
+}
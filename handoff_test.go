@@ -0,0 +1,183 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_Handoff(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	path := filepath.Join(t.TempDir(), "handoff.json")
+	ctx := context.Background()
+
+	stepper := NewStepper(time.Unix(0, 0))
+
+	newProc := func(exporter ItemExporter[int]) *BatchItemProcessor[int] {
+		proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+			WithMaxExportBatchSize(10),
+			WithStepper(stepper),
+			WithHandoff(path, JSONCodec[int]{}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return proc
+	}
+
+	// The old generation exports full batches and hands off the rest.
+	old := &mockExporter[int]{}
+	proc := newProc(old)
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Write(ctx, ints(10)); err != nil {
+		t.Fatal(err)
+	}
+
+	stepper.RunUntilIdle()
+
+	if err := proc.Write(ctx, ints(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := old.exportCount.Load(); got != 10 {
+		t.Fatalf("expected only the full batch exported, got %d items", got)
+	}
+
+	// A failed export doesn't keep the processor from starting, and
+	// leaves the handoff for the next start, along with the items it hands
+	// off itself.
+	failing := &mockExporter[int]{exportErr: errors.New("export failed")}
+	proc = newProc(failing)
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("expected the processor started despite the failed handoff, got %v", err)
+	}
+
+	if err := proc.Write(ctx, ints(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new generation exports the handed off items on start.
+	replacement := &mockExporter[int]{}
+	proc = newProc(replacement)
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	if got := replacement.exportCount.Load(); got != 8 {
+		t.Fatalf("expected the 8 handed off items exported on start, got %d", got)
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the handoff removed once taken over, got %v", err)
+	}
+}
+
+func TestBatchItemProcessor_HandoffUnreadable(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	path := filepath.Join(t.TempDir(), "handoff.json")
+	ctx := context.Background()
+
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stepper := NewStepper(time.Unix(0, 0))
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxExportBatchSize(10),
+		WithStepper(stepper),
+		WithHandoff(path, JSONCodec[int]{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("expected the processor started despite the unreadable handoff, got %v", err)
+	}
+
+	if err := proc.Write(ctx, ints(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queued items are exported rather than handed off over the handoff
+	// that couldn't be read, which is kept for the next start.
+	if got := exporter.exportCount.Load(); got != 5 {
+		t.Fatalf("expected the queued items exported, got %d", got)
+	}
+
+	if data, err := os.ReadFile(path); err != nil || string(data) != "not json" {
+		t.Fatalf("expected the unreadable handoff kept, got %q, %v", data, err)
+	}
+}
+
+func TestBatchItemProcessor_HandoffSyncWriters(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithBatchTimeout(time.Hour),
+		WithHandoff(filepath.Join(t.TempDir(), "handoff.json"), JSONCodec[int]{}),
+		WithInvariantChecks(func(err error) { t.Error(err) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- proc.Write(ctx, ints(3))
+	}()
+
+	// Wait for the batch builder to take the items.
+	for proc.invariants.dequeued.Load() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; !errors.Is(err, ErrHandedOff) {
+		t.Fatalf("expected the writer told its items were handed off, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package processor
+
+import (
+	"slices"
+	"sort"
+)
+
+// DefaultHashRingReplicas is the number of points each partition owns on a
+// hash ring when none is given.
+const DefaultHashRingReplicas = 128
+
+// HashRing maps keys to partitions by consistent hashing. Each partition owns
+// a number of points on the ring and a key belongs to the partition owning
+// the first point at or after the key's hash. Adding or removing a partition
+// moves only about 1/n of the keys, where PartitionOf moves nearly all of
+// them.
+type HashRing struct {
+	partitions int
+	points     []uint64
+	owners     []int
+}
+
+// NewHashRing returns a hash ring over partitions, each owning replicas
+// points. Non-positive replicas use DefaultHashRingReplicas.
+func NewHashRing(partitions, replicas int) *HashRing {
+	members := make([]int, max(partitions, 1))
+	for p := range members {
+		members[p] = p
+	}
+
+	return newHashRing(members, replicas)
+}
+
+// newHashRing returns a hash ring over the given partitions. A partition owns
+// the same points whichever others are on the ring, so adding or removing one
+// moves only its own keys.
+func newHashRing(members []int, replicas int) *HashRing {
+	if replicas <= 0 {
+		replicas = DefaultHashRingReplicas
+	}
+
+	type point struct {
+		hash  uint64
+		owner int
+	}
+
+	points := make([]point, 0, len(members)*replicas)
+
+	for _, p := range members {
+		for r := range replicas {
+			points = append(points, point{hash: mixHash(uint64(p)<<32 | uint64(r)), owner: p})
+		}
+	}
+
+	slices.SortFunc(points, func(a, b point) int {
+		switch {
+		case a.hash < b.hash:
+			return -1
+		case a.hash > b.hash:
+			return 1
+		default:
+			return a.owner - b.owner
+		}
+	})
+
+	r := &HashRing{
+		partitions: len(members),
+		points:     make([]uint64, len(points)),
+		owners:     make([]int, len(points)),
+	}
+
+	for i, p := range points {
+		r.points[i] = p.hash
+		r.owners[i] = p.owner
+	}
+
+	return r
+}
+
+// Partitions returns the number of partitions on the ring.
+func (r *HashRing) Partitions() int {
+	return r.partitions
+}
+
+// locate returns the partition owning hash.
+func (r *HashRing) locate(hash uint64) int {
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.owners[i]
+}
+
+// RingPartitionOf returns the partition the key maps to on the ring.
+func RingPartitionOf[K comparable](r *HashRing, key K) int {
+	return r.locate(hashKey(key))
+}
+
+// WithConsistentHashing routes keys to workers with a HashRing instead of
+// PartitionOf when WithKeyOrdering is set, so changing the worker count
+// moves only the keys of the added or removed workers and downstream
+// connections held per partition stay warm. Each worker owns replicas points
+// on the ring; zero uses DefaultHashRingReplicas.
+//
+// With WithLazyWorkers or WithWorkerIdleTimeout the ring holds only the
+// running workers and is rebuilt as they start and stop, so keys follow the
+// workers as they scale. A moved key's batches still export in order: one
+// waits for the key's earlier batches on its old worker to finish.
+func WithConsistentHashing(replicas int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ConsistentHashing = true
+		o.HashRingReplicas = replicas
+	}
+}
+
+// resizableRing reports whether the workers keys are routed to by consistent
+// hashing start and stop while running, so the ring must follow them.
+func resizableRing(o *BatchItemProcessorOptions) bool {
+	return o.KeyOrdering && o.ConsistentHashing && (o.LazyWorkers || o.WorkerIdleTimeout > 0)
+}
+
+// rebuildRingLocked rebuilds the ring over the running workers, or the first
+// worker while none are, so routing starts it. The caller must hold
+// bvp.workersMu.
+func (bvp *BatchItemProcessor[T]) rebuildRingLocked() {
+	if !resizableRing(&bvp.o) {
+		return
+	}
+
+	var members []int
+
+	for num, running := range bvp.workerRunning {
+		if running {
+			members = append(members, num)
+		}
+	}
+
+	if len(members) == 0 {
+		members = []int{0}
+	}
+
+	bvp.ring.Store(newHashRing(members, bvp.o.HashRingReplicas))
+}
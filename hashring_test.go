@@ -0,0 +1,49 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashRing_BalanceAndMovement(t *testing.T) {
+	const keys = 20000
+
+	small, large := NewHashRing(8, 0), NewHashRing(9, 0)
+	counts := make([]int, small.Partitions())
+	ringMoved, moduloMoved := 0, 0
+
+	for i := range keys {
+		key := fmt.Sprintf("key-%d", i)
+		p := RingPartitionOf(small, key)
+
+		if p != RingPartitionOf(small, key) {
+			t.Fatalf("key %s moved between lookups", key)
+		}
+
+		counts[p]++
+
+		if RingPartitionOf(large, key) != p {
+			ringMoved++
+		}
+
+		if PartitionOf(key, 9) != PartitionOf(key, 8) {
+			moduloMoved++
+		}
+	}
+
+	// Each partition should hold roughly an eighth of the keys.
+	for p, count := range counts {
+		if count < keys/8/2 || count > keys/8*2 {
+			t.Errorf("partition %d holds %d of %d keys", p, count, keys)
+		}
+	}
+
+	// Adding a ninth partition should move about a ninth of the keys.
+	if ringMoved > keys/5 {
+		t.Errorf("expected about %d keys to move, %d did", keys/9, ringMoved)
+	}
+
+	if moduloMoved < keys/2 {
+		t.Errorf("expected modulo partitioning to move most keys, %d did", moduloMoved)
+	}
+}
@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HealthChecker is an optional interface for exporters that can report
+// whether their sink is reachable without exporting anything. When the
+// exporters in use implement it, the processor probes them every
+// HealthCheckInterval: the current exporter, including one installed by an
+// ExporterReplacer, or each worker's exporter with WithExporterFactory. It
+// reports the result through Healthy and the exporter_healthy metric, and
+// uses it to enter and leave disk buffering early instead of waiting for
+// exports to fail or succeed.
+type HealthChecker interface {
+	// HealthCheck returns an error if the exporter can't currently export.
+	HealthCheck(ctx context.Context) error
+}
+
+// errHealthUnknown is reported by Healthy before the first probe completes.
+var errHealthUnknown = errors.New("exporter health has not been checked yet")
+
+// healthState holds the result of the latest health probe.
+type healthState struct {
+	mu      sync.RWMutex
+	err     error
+	checked time.Time
+}
+
+func (h *healthState) set(err error, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.err = err
+	h.checked = now
+}
+
+func (h *healthState) get() (time.Time, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.checked, h.err
+}
+
+// WithHealthCheckInterval sets how often exporters implementing HealthChecker
+// are probed. A zero interval disables probing.
+func WithHealthCheckInterval(interval time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.HealthCheckInterval = interval
+	}
+}
+
+// Healthy returns the result of the latest exporter health probe, or
+// ErrProcessorFailed once the processor has failed. Otherwise it always
+// returns nil if the exporters in use don't implement HealthChecker or
+// probing is disabled.
+func (bvp *BatchItemProcessor[T]) Healthy() error {
+	if err := bvp.failure(); err != nil {
+		return err
+	}
+
+	if !bvp.probingHealth() {
+		return nil
+	}
+
+	checked, err := bvp.health.get()
+	if checked.IsZero() {
+		return errHealthUnknown
+	}
+
+	return err
+}
+
+// probingHealth reports whether the exporters in use are being probed.
+func (bvp *BatchItemProcessor[T]) probingHealth() bool {
+	return bvp.o.HealthCheckInterval > 0 && len(bvp.healthCheckers()) > 0
+}
+
+// healthCheckers returns the HealthCheckers of the exporters in use: the
+// worker exporters if workers have their own, or the current exporter, which
+// may have replaced the one the processor was created with.
+func (bvp *BatchItemProcessor[T]) healthCheckers() []namedHealthChecker {
+	if bvp.workerExporters == nil {
+		if checker, ok := bvp.exporter().(HealthChecker); ok {
+			return []namedHealthChecker{{name: "exporter", HealthChecker: checker}}
+		}
+
+		return nil
+	}
+
+	var checkers []namedHealthChecker
+
+	for i, exporter := range bvp.workerExporters {
+		if checker, ok := exporter.(HealthChecker); ok {
+			checkers = append(checkers, namedHealthChecker{name: fmt.Sprintf("worker %d exporter", i), HealthChecker: checker})
+		}
+	}
+
+	return checkers
+}
+
+// namedHealthChecker is a HealthChecker named for the errors it reports.
+type namedHealthChecker struct {
+	HealthChecker
+	name string
+}
+
+// checkHealth health checks the exporters in use, returning the errors of
+// those that failed.
+func (bvp *BatchItemProcessor[T]) checkHealth(ctx context.Context) error {
+	var errs []error
+
+	for _, checker := range bvp.healthCheckers() {
+		if err := checker.HealthCheck(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", checker.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// healthProber probes the exporters in use every HealthCheckInterval. The
+// exporters are looked up for every probe, as a replacement may implement
+// HealthChecker even if the exporter it replaced didn't.
+func (bvp *BatchItemProcessor[T]) healthProber(ctx context.Context) {
+	ticker := time.NewTicker(bvp.o.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if len(bvp.healthCheckers()) > 0 {
+			bvp.probeHealth(ctx)
+		}
+
+		select {
+		case <-bvp.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (bvp *BatchItemProcessor[T]) probeHealth(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, bvp.o.HealthCheckInterval)
+	defer cancel()
+
+	err := bvp.checkHealth(ctx)
+
+	checked, previous := bvp.health.get()
+	bvp.health.set(err, time.Now())
+
+	bvp.metrics.SetExporterHealthy(bvp.label, err == nil)
+
+	if err != nil && (previous == nil || checked.IsZero()) {
+		bvp.log.WithError(err).Warn("Exporter health check failed")
+	} else if err == nil && previous != nil {
+		bvp.log.Info("Exporter health check recovered")
+	}
+
+	if bvp.diskBuffer == nil {
+		return
+	}
+
+	if err != nil {
+		bvp.diskBuffer.markDegraded()
+	} else {
+		bvp.diskBuffer.recordSuccess()
+	}
+}
+
+// ErrExporterNotReady is returned by Start when the readiness check fails.
+var ErrExporterNotReady = errors.New("exporter is not ready")
+
+// WithReadinessCheck makes Start health check exporters implementing
+// HealthChecker, waiting up to timeout, and fail with ErrExporterNotReady
+// instead of starting if the check fails. A misconfigured sink then fails at
+// boot rather than on the first batch. Exporters started by Start are left
+// for Shutdown to shut down.
+func WithReadinessCheck(timeout time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ReadinessTimeout = timeout
+	}
+}
+
+// checkReadiness runs the readiness check, recording the result as the
+// first health probe.
+func (bvp *BatchItemProcessor[T]) checkReadiness(ctx context.Context) error {
+	if len(bvp.healthCheckers()) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, bvp.o.ReadinessTimeout)
+	defer cancel()
+
+	err := bvp.checkHealth(ctx)
+
+	bvp.health.set(err, time.Now())
+	bvp.metrics.SetExporterHealthy(bvp.label, err == nil)
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrExporterNotReady, err)
+	}
+
+	return nil
+}
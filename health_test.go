@@ -0,0 +1,220 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// healthExporter is a toggleExporter that also reports its health.
+type healthExporter[T any] struct {
+	toggleExporter[T]
+}
+
+func (e *healthExporter[T]) HealthCheck(_ context.Context) error {
+	if e.down.Load() {
+		return errors.New("exporter is down")
+	}
+
+	return nil
+}
+
+func TestBatchItemProcessor_HealthCheck(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &healthExporter[int]{}
+	exporter.down.Store(true)
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(1),
+		WithShippingMethod(ShippingMethodSync),
+		WithWorkers(1),
+		WithHealthCheckInterval(10*time.Millisecond),
+		WithDiskBuffer[int](t.TempDir(), JSONCodec[int]{}),
+		WithDiskBufferReplayInterval(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	if err := proc.Healthy(); err == nil {
+		t.Error("expected health to be unknown before the first probe")
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := proc.Healthy(); err == nil {
+		t.Error("expected exporter to be unhealthy")
+	}
+
+	// The failed probe puts the processor in degraded mode, so the exporter
+	// isn't tried at all.
+	one := 1
+	if err := proc.Write(ctx, []*int{&one}); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	if got := exporter.attempts.Load(); got != 0 {
+		t.Errorf("expected no export attempts while unhealthy, got %d", got)
+	}
+
+	exporter.down.Store(false)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if err := proc.Healthy(); err != nil {
+		t.Errorf("expected exporter to be healthy, got %v", err)
+	}
+
+	if got := exporter.exported(); got != 1 {
+		t.Errorf("expected buffered item to be replayed, got %d", got)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+}
+
+func TestBatchItemProcessor_ReadinessCheck(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &healthExporter[int]{}
+	exporter.down.Store(true)
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log, WithReadinessCheck(time.Second))
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); !errors.Is(err, ErrExporterNotReady) {
+		t.Fatalf("expected start to fail with ErrExporterNotReady, got %v", err)
+	}
+
+	exporter.down.Store(false)
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("expected start to succeed once the exporter is ready, got %v", err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+}
+
+// closedHealthExporter is permanently closed and reports as much.
+type closedHealthExporter struct {
+	closedExporter
+}
+
+func (e *closedHealthExporter) HealthCheck(_ context.Context) error {
+	return ErrExporterClosed
+}
+
+func TestBatchItemProcessor_HealthCheckReplacedExporter(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	replacement := &healthExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](&closedHealthExporter{}, "test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(5),
+		WithHealthCheckInterval(10*time.Millisecond),
+		WithExporterReplacer(func(_ context.Context, _ error) (ItemExporter[int], error) {
+			return replacement, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := proc.Healthy(); !errors.Is(err, ErrExporterClosed) {
+		t.Fatalf("expected the closed exporter reported unhealthy, got %v", err)
+	}
+
+	if err := proc.Write(ctx, ints(5)); !errors.Is(err, ErrExporterClosed) {
+		t.Fatalf("expected the exporter closed, got %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	// The replacement is probed, not the exporter it replaced.
+	if err := proc.Healthy(); err != nil {
+		t.Fatalf("expected the replacement reported healthy, got %v", err)
+	}
+
+	replacement.down.Store(true)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := proc.Healthy(); err == nil {
+		t.Fatal("expected the replacement reported unhealthy")
+	}
+}
+
+func TestBatchItemProcessor_HealthCheckWorkerExporters(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	workerExporters := []*healthExporter[int]{{}, {}}
+
+	proc, err := NewBatchItemProcessor[int](&healthExporter[int]{}, "test", log,
+		WithWorkers(2),
+		WithHealthCheckInterval(10*time.Millisecond),
+		WithExporterFactory(func(id int) (ItemExporter[int], error) {
+			return workerExporters[id], nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := proc.Healthy(); err != nil {
+		t.Fatalf("expected the worker exporters reported healthy, got %v", err)
+	}
+
+	// The exporters the workers export with are probed, not the unused
+	// exporter the processor was created with.
+	workerExporters[1].down.Store(true)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := proc.Healthy(); err == nil || !strings.Contains(err.Error(), "worker 1 exporter") {
+		t.Fatalf("expected worker 1's exporter reported unhealthy, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxPeekItems bounds the number of items returned by Peek.
+const MaxPeekItems = 100
+
+// QueuedItem summarizes an item waiting to be batched.
+type QueuedItem struct {
+	// Summary describes the item, as returned by the summary func given to
+	// WithQueueInspection.
+	Summary string
+	// Age is how long the item has been queued.
+	Age time.Duration
+	// Producer is the producer that wrote the item, if any.
+	Producer string
+}
+
+// WithQueueInspection tracks queued items in order so Peek can report the
+// oldest of them, summarized by summary. A nil summary formats items with
+// %+v. Tracking takes a lock per enqueued and dequeued item, so it's meant for
+// debugging stuck pipelines rather than being left on everywhere.
+func WithQueueInspection[T any](summary func(item *T) string) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.QueueInspection = true
+		o.InspectSummary = summary
+	}
+}
+
+// queueIndex tracks queued items in enqueue order.
+type queueIndex[T any] struct {
+	summary func(item *T) string
+
+	mu    sync.Mutex
+	items *list.List
+}
+
+func newQueueIndex[T any](summary func(item *T) string) *queueIndex[T] {
+	if summary == nil {
+		summary = func(item *T) string {
+			return fmt.Sprintf("%+v", *item)
+		}
+	}
+
+	return &queueIndex[T]{
+		summary: summary,
+		items:   list.New(),
+	}
+}
+
+// track records an item as queued. It must be called before the item can be
+// dequeued.
+func (q *queueIndex[T]) track(item *TraceableItem[T]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	item.queued = q.items.PushBack(item)
+}
+
+// untrack removes an item once it's dequeued or failed to enqueue.
+func (q *queueIndex[T]) untrack(item *TraceableItem[T]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if item.queued != nil {
+		q.items.Remove(item.queued)
+		item.queued = nil
+	}
+}
+
+// peek summarizes up to n of the oldest queued items.
+func (q *queueIndex[T]) peek(n int, now time.Time) []QueuedItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	peeked := make([]QueuedItem, 0, min(n, q.items.Len()))
+
+	for e := q.items.Front(); e != nil && len(peeked) < n; e = e.Next() {
+		item, _ := e.Value.(*TraceableItem[T])
+
+		peeked = append(peeked, QueuedItem{
+			Summary:  q.summary(item.item),
+			Age:      now.Sub(item.enqueued),
+			Producer: item.producerLabel,
+		})
+	}
+
+	return peeked
+}
+
+// Peek returns summaries of up to n of the oldest queued items, oldest first,
+// without dequeuing them. n is capped at MaxPeekItems. It returns nil unless
+// the processor was created with WithQueueInspection.
+func (bvp *BatchItemProcessor[T]) Peek(n int) []QueuedItem {
+	if bvp.inspector == nil || n <= 0 {
+		return nil
+	}
+
+	return bvp.inspector.peek(min(n, MaxPeekItems), time.Now())
+}
@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_Peek(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	proc, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{},
+		"test",
+		log,
+		WithMaxQueueSize(10),
+		WithMaxExportBatchSize(10),
+		WithWorkers(1),
+		WithQueueInspection(func(item *int) string {
+			return "item " + strconv.Itoa(*item)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	items := make([]*int, 3)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	ctx := context.Background()
+
+	// The processor isn't started, so the items stay queued.
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	peeked := proc.Peek(2)
+	want := []string{"item 0", "item 1"}
+
+	if len(peeked) != len(want) {
+		t.Fatalf("expected %d peeked items, got %d", len(want), len(peeked))
+	}
+
+	for i := range want {
+		if peeked[i].Summary != want[i] {
+			t.Errorf("peeked item %d: expected %q, got %q", i, want[i], peeked[i].Summary)
+		}
+	}
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if peeked := proc.Peek(10); len(peeked) != 0 {
+		t.Errorf("expected no queued items after shutdown, got %d", len(peeked))
+	}
+}
+
+func TestBatchItemProcessor_PeekWithoutInspection(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "test", log)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	val := 1
+	if err := proc.Write(context.Background(), []*int{&val}); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	if peeked := proc.Peek(10); peeked != nil {
+		t.Errorf("expected nil without queue inspection, got %v", peeked)
+	}
+}
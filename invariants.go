@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrInvariantViolation is reported when WithInvariantChecks catches the
+// processor breaking one of its internal invariants.
+var ErrInvariantViolation = errors.New("batch item processor invariant violated")
+
+// WithInvariantChecks asserts the processor's internal invariants as it runs
+// and passes violations, wrapping ErrInvariantViolation, to report. It checks
+// that every queued item is batched and every batched item settled by
+// shutdown, that no batch exceeds the max export batch size, and that
+// nothing is exported once the exporter is shut down. The checks cost a few
+// atomic operations per item and are meant for tests and debug builds. A nil
+// report logs violations.
+func WithInvariantChecks(report func(err error)) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.InvariantChecks = true
+		o.InvariantReport = report
+	}
+}
+
+// invariantChecker tracks the counts the invariants are checked against.
+type invariantChecker struct {
+	report func(err error)
+
+	// enqueued is raised before an item is enqueued, so it never trails
+	// dequeued, and lowered again if the queue is full.
+	enqueued   atomic.Int64
+	dequeued   atomic.Int64
+	dispatched atomic.Int64
+	settled    atomic.Int64
+
+	exporterDown atomic.Bool
+}
+
+func (c *invariantChecker) violated(format string, args ...any) {
+	c.report(fmt.Errorf("%w: %s", ErrInvariantViolation, fmt.Sprintf(format, args...)))
+}
+
+// dequeue records an item taken off the queue by the batch builder.
+func (c *invariantChecker) dequeue() {
+	if dequeued, enqueued := c.dequeued.Add(1), c.enqueued.Load(); dequeued > enqueued {
+		c.violated("%d items dequeued but only %d enqueued", dequeued, enqueued)
+	}
+}
+
+// settle records a batch a worker finished with.
+func (c *invariantChecker) settle(size, limit int) {
+	if size == 0 || size > limit {
+		c.violated("exported a batch of %d items, outside 1 to %d", size, limit)
+	}
+
+	if settled, dispatched := c.settled.Add(int64(size)), c.dispatched.Load(); settled > dispatched {
+		c.violated("%d items settled but only %d dispatched", settled, dispatched)
+	}
+}
+
+// export checks that an export doesn't happen after the exporter shut down.
+func (c *invariantChecker) export(size int) {
+	if c.exporterDown.Load() {
+		c.violated("exported %d items after the exporter was shut down", size)
+	}
+}
+
+// drained checks that every queued item was batched and every dispatched
+// item settled once the workers have stopped.
+func (c *invariantChecker) drained() {
+	if enqueued, dequeued := c.enqueued.Load(), c.dequeued.Load(); enqueued != dequeued {
+		c.violated("%d items enqueued but %d dequeued by shutdown", enqueued, dequeued)
+	}
+
+	if dispatched, settled := c.dispatched.Load(), c.settled.Load(); dispatched != settled {
+		c.violated("%d items dispatched but %d settled by shutdown", dispatched, settled)
+	}
+}
@@ -0,0 +1,131 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// violations collects reported invariant violations.
+type violations struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (v *violations) report(err error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.errs = append(v.errs, err)
+}
+
+func (v *violations) get() []error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return append([]error(nil), v.errs...)
+}
+
+func TestBatchItemProcessor_InvariantChecks(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	configs := map[string][]BatchItemProcessorOption{
+		"default": nil,
+		"sync":    {WithShippingMethod(ShippingMethodSync)},
+		"key ordering": {
+			WithKeyFunc(func(item *int) int { return *item % 7 }),
+			WithKeyOrdering(),
+			WithKeyGrouping(),
+			WithWorkStealing(),
+		},
+		"priority lanes": {WithPriorityLanes(func(item *int) int { return *item % 3 }, 80, 15, 5)},
+		"sharded queue":  {WithQueue(QueueKindSharded)},
+	}
+
+	for name, opts := range configs {
+		t.Run(name, func(t *testing.T) {
+			var v violations
+
+			proc, err := NewBatchItemProcessor[int](&mockExporter[int]{exportDelay: time.Millisecond}, "test", log,
+				append([]BatchItemProcessorOption{
+					WithMaxQueueSize(1000),
+					WithMaxExportBatchSize(10),
+					WithBatchTimeout(5 * time.Millisecond),
+					WithWorkers(4),
+					WithInvariantChecks(v.report),
+				}, opts...)...,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+
+			if err := proc.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+
+			for p := range 4 {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					for i := range 50 {
+						item := p*1000 + i
+
+						_ = proc.Write(ctx, []*int{&item})
+					}
+				}()
+			}
+
+			wg.Wait()
+
+			if err := proc.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if errs := v.get(); len(errs) > 0 {
+				t.Fatalf("expected no violations, got %v", errs)
+			}
+		})
+	}
+}
+
+func TestInvariantChecker_ReportsViolations(t *testing.T) {
+	var v violations
+
+	c := &invariantChecker{report: v.report}
+
+	c.enqueued.Add(1)
+	c.dequeue()
+	c.dequeue()
+
+	c.dispatched.Add(5)
+	c.settle(20, 10)
+
+	c.exporterDown.Store(true)
+	c.export(1)
+
+	c.drained()
+
+	errs := v.get()
+	// Dequeuing too much, the oversized batch, settling more than was
+	// dispatched, the late export and both counts at shutdown.
+	if len(errs) != 6 {
+		t.Fatalf("expected 6 violations, got %d: %v", len(errs), errs)
+	}
+
+	for _, err := range errs {
+		if !errors.Is(err, ErrInvariantViolation) {
+			t.Errorf("expected %v to wrap ErrInvariantViolation", err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+)
+
+// KeyFunc derives a comparable key from an item. Keys drive every feature that
+// needs to relate items to each other, such as deduplication, grouping and
+// partitioning, so they all agree on which items belong together.
+type KeyFunc[T any, K comparable] func(item *T) K
+
+// KeyByField returns a KeyFunc that reads a key from the item through accessor.
+// Nil items map to the zero key.
+func KeyByField[T any, K comparable](accessor func(item *T) K) KeyFunc[T, K] {
+	return func(item *T) K {
+		var zero K
+
+		if item == nil {
+			return zero
+		}
+
+		return accessor(item)
+	}
+}
+
+// KeyByHash returns a KeyFunc that hashes the bytes produced by encode with
+// 64-bit FNV-1a. Nil items, and items encode returns nil for, map to the zero
+// key.
+func KeyByHash[T any](encode func(item *T) []byte) KeyFunc[T, uint64] {
+	return func(item *T) uint64 {
+		if item == nil {
+			return 0
+		}
+
+		b := encode(item)
+		if b == nil {
+			return 0
+		}
+
+		h := fnv.New64a()
+		_, _ = h.Write(b)
+
+		return h.Sum64()
+	}
+}
+
+// KeyByJSONHash returns a KeyFunc that hashes the JSON encoding of the item,
+// keying items by content. Items that fail to encode map to the zero key, so
+// they are grouped, and deduplicated, together; key items that may not encode
+// with KeyByField or KeyByHash instead.
+func KeyByJSONHash[T any]() KeyFunc[T, uint64] {
+	return KeyByHash(func(item *T) []byte {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return nil
+		}
+
+		return b
+	})
+}
+
+// Group splits items by key. Keys are returned in the order they were first
+// seen and each group keeps the relative order of its items.
+func (f KeyFunc[T, K]) Group(items []*T) ([]K, map[K][]*T) {
+	keys := make([]K, 0)
+	groups := make(map[K][]*T)
+
+	for _, item := range items {
+		key := f(item)
+
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		groups[key] = append(groups[key], item)
+	}
+
+	return keys, groups
+}
+
+// Dedup returns items with every item whose key was already seen removed,
+// keeping the first occurrence of each key.
+func (f KeyFunc[T, K]) Dedup(items []*T) []*T {
+	seen := make(map[K]struct{}, len(items))
+	unique := make([]*T, 0, len(items))
+
+	for _, item := range items {
+		key := f(item)
+
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		unique = append(unique, item)
+	}
+
+	return unique
+}
+
+// Partition returns the partition in [0, n) the item's key maps to. Equal
+// keys always map to the same partition.
+func (f KeyFunc[T, K]) Partition(item *T, n int) int {
+	return PartitionOf(f(item), n)
+}
+
+// PartitionOf returns the partition in [0, n) the key maps to.
+func PartitionOf[K comparable](key K, n int) int {
+	if n <= 1 {
+		return 0
+	}
+
+	return int(hashKey(key) % uint64(n))
+}
+
+// hashKey hashes a comparable key, avoiding formatting for common key types.
+func hashKey[K comparable](key K) uint64 {
+	switch k := any(key).(type) {
+	case uint64:
+		return mixHash(k)
+	case int64:
+		return mixHash(uint64(k))
+	case int:
+		return mixHash(uint64(k))
+	case uint32:
+		return mixHash(uint64(k))
+	case int32:
+		return mixHash(uint64(k))
+	}
+
+	h := fnv.New64a()
+
+	if s, ok := any(key).(string); ok {
+		_, _ = h.Write([]byte(s))
+	} else {
+		_, _ = fmt.Fprintf(h, "%#v", key)
+	}
+
+	return h.Sum64()
+}
+
+// mixHash spreads integer keys across the full range so sequential keys don't
+// land in sequential partitions (splitmix64 finalizer).
+func mixHash(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
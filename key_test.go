@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"testing"
+)
+
+type keyedItem struct {
+	ID    string
+	Value int
+}
+
+func TestKeyFunc_GroupAndDedup(t *testing.T) {
+	key := KeyByField(func(item *keyedItem) string { return item.ID })
+
+	items := []*keyedItem{
+		{ID: "b", Value: 1},
+		{ID: "a", Value: 2},
+		{ID: "b", Value: 3},
+	}
+
+	keys, groups := key.Group(items)
+	if len(keys) != 2 || keys[0] != "b" || keys[1] != "a" {
+		t.Fatalf("expected keys [b a], got %v", keys)
+	}
+
+	if len(groups["b"]) != 2 || groups["b"][1].Value != 3 {
+		t.Errorf("expected group b to hold items 1 and 3 in order, got %v", groups["b"])
+	}
+
+	unique := key.Dedup(items)
+	if len(unique) != 2 || unique[0].Value != 1 || unique[1].Value != 2 {
+		t.Errorf("expected first occurrence of each key, got %v", unique)
+	}
+}
+
+func TestKeyFunc_HashAndPartition(t *testing.T) {
+	key := KeyByJSONHash[keyedItem]()
+
+	a := &keyedItem{ID: "a", Value: 1}
+	b := &keyedItem{ID: "a", Value: 1}
+	c := &keyedItem{ID: "a", Value: 2}
+
+	if key(a) != key(b) {
+		t.Error("expected equal content to hash equally")
+	}
+
+	if key(a) == key(c) {
+		t.Error("expected different content to hash differently")
+	}
+
+	for i := 0; i < 100; i++ {
+		p := PartitionOf(i, 7)
+		if p < 0 || p >= 7 {
+			t.Fatalf("partition %d out of range", p)
+		}
+
+		if p != PartitionOf(i, 7) {
+			t.Fatal("expected partitioning to be deterministic")
+		}
+	}
+}
+
+func TestKeyByJSONHash_EncodeFailure(t *testing.T) {
+	key := KeyByJSONHash[func()]()
+
+	f := func() {}
+
+	// Functions can't be encoded as JSON.
+	if got := key(&f); got != 0 {
+		t.Fatalf("expected the zero key for an item that fails to encode, got %d", got)
+	}
+
+	if got := KeyByJSONHash[keyedItem]()(&keyedItem{}); got == 0 {
+		t.Fatal("expected encodable items not to get the zero key")
+	}
+}
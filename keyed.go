@@ -0,0 +1,138 @@
+package processor
+
+// routedBatch is a batch bound for a specific worker. A worker of -1 means the
+// batch can be exported by any worker.
+type routedBatch[T any] struct {
+	items  []*TraceableItem[T]
+	worker int
+}
+
+// WithKeyFunc sets the key shared by all key-driven features: grouping
+// (WithKeyGrouping), per-key ordering (WithKeyOrdering) and deduplication
+// (WithKeyDedup). On its own the key has no effect.
+func WithKeyFunc[T any, K comparable](key KeyFunc[T, K]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.KeyFunc = func(item *T) any {
+			return key(item)
+		}
+	}
+}
+
+// WithKeyGrouping splits every batch by key before export, so each call to
+// ExportItems only contains items sharing a key.
+func WithKeyGrouping() BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.KeyGrouping = true
+	}
+}
+
+// WithKeyOrdering partitions batches across workers by key, so all items with
+// the same key are exported by the same worker in the order they were written.
+func WithKeyOrdering() BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.KeyOrdering = true
+	}
+}
+
+// WithKeyDedup removes items whose key already appears earlier in the same
+// batch before export. Removed items are reported as successfully exported.
+func WithKeyDedup() BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.KeyDedup = true
+	}
+}
+
+// itemKey returns the KeyFunc over queued items.
+func (bvp *BatchItemProcessor[T]) itemKey() KeyFunc[TraceableItem[T], any] {
+	return func(item *TraceableItem[T]) any {
+		return bvp.keyFunc(item.item)
+	}
+}
+
+// routeBatch splits a batch by write context, then according to the key
+// grouping and ordering options.
+func (bvp *BatchItemProcessor[T]) routeBatch(batch []*TraceableItem[T]) []routedBatch[T] {
+	if !hasWriteContext(batch) {
+		return bvp.routeByKey(batch)
+	}
+
+	var routed []routedBatch[T]
+
+	for _, group := range splitByWriteContext(batch) {
+		routed = append(routed, bvp.routeByKey(group)...)
+	}
+
+	return routed
+}
+
+// routeByKey splits a batch according to the key grouping and ordering options.
+func (bvp *BatchItemProcessor[T]) routeByKey(batch []*TraceableItem[T]) []routedBatch[T] {
+	if bvp.keyFunc == nil || (!bvp.o.KeyGrouping && !bvp.o.KeyOrdering) {
+		return []routedBatch[T]{{items: batch, worker: -1}}
+	}
+
+	key := bvp.itemKey()
+
+	if bvp.o.KeyGrouping {
+		keys, groups := key.Group(batch)
+		routed := make([]routedBatch[T], 0, len(keys))
+
+		for _, k := range keys {
+			worker := -1
+			if bvp.o.KeyOrdering {
+				worker = bvp.workerFor(k)
+			}
+
+			routed = append(routed, routedBatch[T]{items: groups[k], worker: worker})
+		}
+
+		return routed
+	}
+
+	partition := func(item *TraceableItem[T]) int {
+		return bvp.workerFor(key(item))
+	}
+
+	workers, partitions := KeyFunc[TraceableItem[T], int](partition).Group(batch)
+	routed := make([]routedBatch[T], 0, len(workers))
+
+	for _, worker := range workers {
+		routed = append(routed, routedBatch[T]{items: partitions[worker], worker: worker})
+	}
+
+	return routed
+}
+
+// workerFor returns the worker a key is routed to.
+func (bvp *BatchItemProcessor[T]) workerFor(key any) int {
+	if ring := bvp.ring.Load(); ring != nil {
+		return RingPartitionOf(ring, key)
+	}
+
+	return PartitionOf(key, bvp.o.Workers)
+}
+
+// workerCh returns the channel of batches routed to the given worker, or nil
+// if batches aren't partitioned.
+func (bvp *BatchItemProcessor[T]) workerCh(number int) chan []*TraceableItem[T] {
+	if bvp.workerChs == nil {
+		return nil
+	}
+
+	return bvp.workerChs[number]
+}
+
+// dedupItems removes items whose key already appeared in the batch.
+func (bvp *BatchItemProcessor[T]) dedupItems(items []*T) []*T {
+	if bvp.keyFunc == nil || !bvp.o.KeyDedup {
+		return items
+	}
+
+	unique := KeyFunc[T, any](bvp.keyFunc).Dedup(items)
+
+	if removed := len(items) - len(unique); removed > 0 {
+		bvp.metrics.IncItemsDeduplicatedBy(bvp.label, float64(removed))
+	}
+
+	return unique
+}
@@ -0,0 +1,268 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recordingExporter records every batch it receives.
+type recordingExporter[T any] struct {
+	mu      sync.Mutex
+	batches [][]*T
+	delay   time.Duration
+}
+
+func (r *recordingExporter[T]) ExportItems(_ context.Context, items []*T) error {
+	time.Sleep(r.delay)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches = append(r.batches, items)
+
+	return nil
+}
+
+func (r *recordingExporter[T]) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func (r *recordingExporter[T]) recorded() [][]*T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([][]*T(nil), r.batches...)
+}
+
+func TestBatchItemProcessor_KeyGroupingAndDedup(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &recordingExporter[keyedItem]{}
+
+	proc, err := NewBatchItemProcessor[keyedItem](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(6),
+		WithShippingMethod(ShippingMethodSync),
+		WithWorkers(1),
+		WithKeyFunc(KeyByField(func(item *keyedItem) string { return item.ID })),
+		WithKeyGrouping(),
+		WithKeyDedup(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	items := []*keyedItem{
+		{ID: "a", Value: 1},
+		{ID: "b", Value: 2},
+		{ID: "a", Value: 3},
+		{ID: "c", Value: 4},
+		{ID: "b", Value: 5},
+		{ID: "c", Value: 6},
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	batches := exporter.recorded()
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d", len(batches))
+	}
+
+	for _, batch := range batches {
+		if len(batch) != 1 {
+			t.Errorf("expected duplicates to be removed, got batch of %d", len(batch))
+		}
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+}
+
+func TestBatchItemProcessor_KeyOrdering(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	for name, opts := range map[string][]BatchItemProcessorOption{
+		"modulo":             nil,
+		"consistent hashing": {WithConsistentHashing(0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			exporter := &recordingExporter[keyedItem]{delay: time.Millisecond}
+
+			proc, err := NewBatchItemProcessor[keyedItem](
+				exporter,
+				"test",
+				log,
+				append([]BatchItemProcessorOption{
+					WithMaxQueueSize(1000),
+					WithMaxExportBatchSize(5),
+					WithBatchTimeout(10 * time.Millisecond),
+					WithWorkers(4),
+					WithKeyFunc(KeyByField(func(item *keyedItem) string { return item.ID })),
+					WithKeyOrdering(),
+				}, opts...)...,
+			)
+			if err != nil {
+				t.Fatalf("failed to create processor: %v", err)
+			}
+
+			ctx := context.Background()
+			proc.Start(ctx)
+
+			keys := []string{"a", "b", "c", "d", "e", "f"}
+
+			for i := 0; i < 300; i++ {
+				item := &keyedItem{ID: keys[i%len(keys)], Value: i}
+
+				if err := proc.Write(ctx, []*keyedItem{item}); err != nil {
+					t.Fatalf("failed to write item: %v", err)
+				}
+			}
+
+			time.Sleep(200 * time.Millisecond)
+
+			if err := proc.Shutdown(ctx); err != nil {
+				t.Fatalf("failed to shutdown: %v", err)
+			}
+
+			last := make(map[string]int)
+			total := 0
+
+			for _, batch := range exporter.recorded() {
+				for _, item := range batch {
+					if prev, ok := last[item.ID]; ok && item.Value < prev {
+						t.Fatalf("key %s exported out of order: %d after %d", item.ID, item.Value, prev)
+					}
+
+					last[item.ID] = item.Value
+					total++
+				}
+			}
+
+			if total != 300 {
+				t.Errorf("expected 300 items exported, got %d", total)
+			}
+		})
+	}
+}
+
+func TestBatchItemProcessor_ConsistentHashingFollowsWorkers(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &recordingExporter[keyedItem]{delay: 20 * time.Millisecond}
+
+	proc, err := NewBatchItemProcessor[keyedItem](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(1000),
+		WithMaxExportBatchSize(5),
+		WithBatchTimeout(10*time.Millisecond),
+		WithWorkers(4),
+		WithLazyWorkers(),
+		WithWorkerIdleTimeout(50*time.Millisecond),
+		WithKeyFunc(KeyByField(func(item *keyedItem) string { return item.ID })),
+		WithKeyOrdering(),
+		WithConsistentHashing(0),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	keys := make([]string, 32)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	// routedTo returns the workers the keys are routed to, failing if any
+	// isn't running.
+	routedTo := func() map[int]struct{} {
+		proc.workersMu.Lock()
+		defer proc.workersMu.Unlock()
+
+		workers := make(map[int]struct{})
+
+		for _, key := range keys {
+			worker := proc.workerFor(key)
+			if proc.activeWorkers > 0 && !proc.workerRunning[worker] {
+				t.Fatalf("key %s routed to stopped worker %d", key, worker)
+			}
+
+			workers[worker] = struct{}{}
+		}
+
+		return workers
+	}
+
+	items := make([]*keyedItem, 400)
+	for i := range items {
+		items[i] = &keyedItem{ID: keys[i%len(keys)], Value: i}
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	// Slow exports leave batches waiting, so the pool scales up.
+	time.Sleep(100 * time.Millisecond)
+
+	// Workers started as the pool scaled up take keys.
+	if got := len(routedTo()); got < 2 {
+		t.Errorf("expected keys routed to the workers the pool scaled up to, got %d workers", got)
+	}
+
+	// Once the pool scales down, keys follow the workers left.
+	deadline := time.Now().Add(5 * time.Second)
+	for proc.runningWorkers() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := proc.runningWorkers(); got != 0 {
+		t.Fatalf("expected idle workers to stop, got %d running", got)
+	}
+
+	if workers := routedTo(); len(workers) != 1 {
+		t.Errorf("expected keys routed to the one worker started next, got %d workers", len(workers))
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	last := make(map[string]int)
+	total := 0
+
+	for _, batch := range exporter.recorded() {
+		for _, item := range batch {
+			if prev, ok := last[item.ID]; ok && item.Value < prev {
+				t.Fatalf("key %s exported out of order: %d after %d", item.ID, item.Value, prev)
+			}
+
+			last[item.ID] = item.Value
+			total++
+		}
+	}
+
+	if total != 400 {
+		t.Errorf("expected 400 items exported, got %d", total)
+	}
+}
@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultMaxProcessorLabels is the default number of distinct processor
+	// names a LabelGuard admits before falling back to OverflowLabel.
+	DefaultMaxProcessorLabels = 1000
+	// MaxProcessorLabelLength is the maximum length of a processor label.
+	MaxProcessorLabelLength = 128
+	// OverflowLabel is the label used once a LabelGuard is full.
+	OverflowLabel = "overflow"
+	// UnknownLabel is the label used for empty processor names.
+	UnknownLabel = "unknown"
+)
+
+// DefaultLabelGuard is the label guard shared by processors that don't
+// configure their own.
+var DefaultLabelGuard = NewLabelGuard(DefaultMaxProcessorLabels)
+
+// LabelGuard sanitizes processor names before they are used as metric labels
+// and bounds the number of distinct values it hands out. Once the bound is
+// reached, new names are mapped to OverflowLabel so creating processors per
+// connection or per tenant can't explode metric cardinality. Distinct names
+// that sanitize to the same label, such as "a b" and "a/b", are told apart
+// with a numeric suffix, so they don't report under one label.
+//
+// A LabelGuard is safe for concurrent use and is intended to be shared by all
+// processors reporting to the same metrics recorder.
+type LabelGuard struct {
+	mu  sync.Mutex
+	max int
+	// labels maps each admitted name to its label.
+	labels map[string]string
+	// taken holds the labels handed out.
+	taken map[string]struct{}
+}
+
+// NewLabelGuard creates a label guard admitting up to maxValues distinct labels.
+// A maxValues of zero or less disables the bound, only sanitizing names.
+func NewLabelGuard(maxValues int) *LabelGuard {
+	return &LabelGuard{
+		max:    maxValues,
+		labels: make(map[string]string),
+		taken:  map[string]struct{}{OverflowLabel: {}},
+	}
+}
+
+// Label returns the metric label to use for the given processor name. The
+// same name always gets the same label.
+func (g *LabelGuard) Label(name string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if label, ok := g.labels[name]; ok {
+		return label
+	}
+
+	if g.max > 0 && len(g.labels) >= g.max {
+		return OverflowLabel
+	}
+
+	base := sanitizeLabel(name)
+	label := base
+
+	for n := 2; ; n++ {
+		if _, ok := g.taken[label]; !ok {
+			break
+		}
+
+		suffix := "_" + strconv.Itoa(n)
+		label = base[:min(len(base), MaxProcessorLabelLength-len(suffix))] + suffix
+	}
+
+	g.labels[name] = label
+	g.taken[label] = struct{}{}
+
+	return label
+}
+
+// sanitizeLabel restricts a label to [a-zA-Z0-9_.:-] and MaxProcessorLabelLength
+// characters, replacing anything else with an underscore.
+func sanitizeLabel(name string) string {
+	if name == "" {
+		return UnknownLabel
+	}
+
+	if len(name) > MaxProcessorLabelLength {
+		name = name[:MaxProcessorLabelLength]
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '_', r == '.', r == ':', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelGuard_Sanitize(t *testing.T) {
+	g := NewLabelGuard(0)
+
+	tests := map[string]string{
+		"":                    UnknownLabel,
+		"my-processor":        "my-processor",
+		"conn 10.0.0.1:30303": "conn_10.0.0.1:30303",
+		"tenant/äbc":          "tenant__bc",
+	}
+
+	for name, want := range tests {
+		if got := g.Label(name); got != want {
+			t.Errorf("Label(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	if got := g.Label(strings.Repeat("a", 500)); len(got) != MaxProcessorLabelLength {
+		t.Errorf("expected label truncated to %d, got %d", MaxProcessorLabelLength, len(got))
+	}
+}
+
+func TestLabelGuard_Overflow(t *testing.T) {
+	g := NewLabelGuard(2)
+
+	if got := g.Label("a"); got != "a" {
+		t.Errorf("expected a, got %q", got)
+	}
+
+	if got := g.Label("b"); got != "b" {
+		t.Errorf("expected b, got %q", got)
+	}
+
+	if got := g.Label("c"); got != OverflowLabel {
+		t.Errorf("expected %q, got %q", OverflowLabel, got)
+	}
+
+	// Already admitted labels keep working once the guard is full.
+	if got := g.Label("a"); got != "a" {
+		t.Errorf("expected a, got %q", got)
+	}
+}
+
+func TestLabelGuard_Collisions(t *testing.T) {
+	g := NewLabelGuard(0)
+
+	if got := g.Label("a b"); got != "a_b" {
+		t.Errorf("expected a_b, got %q", got)
+	}
+
+	// Distinct names sanitized to the same label are told apart.
+	if got := g.Label("a/b"); got != "a_b_2" {
+		t.Errorf("expected a_b_2, got %q", got)
+	}
+
+	if got := g.Label("a b"); got != "a_b" {
+		t.Errorf("expected a name to keep its label, got %q", got)
+	}
+
+	if got := g.Label(OverflowLabel); got == OverflowLabel {
+		t.Errorf("expected a processor named %q kept apart from the overflow label", OverflowLabel)
+	}
+
+	if got := g.Label(strings.Repeat("a", 200) + "/"); len(got) != MaxProcessorLabelLength {
+		t.Errorf("expected a suffixed label truncated to %d, got %d", MaxProcessorLabelLength, len(got))
+	}
+}
@@ -0,0 +1,232 @@
+package processor
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// LaneFunc returns the priority lane of an item. Lanes are numbered from zero,
+// in the order their weights are given to WithPriorityLanes; out of range
+// lanes are clamped.
+type LaneFunc[T any] func(item *T) int
+
+// WithPriorityLanes splits the queue in to priority lanes, one per weight.
+// Each lane holds up to MaxQueueSize items. The batch builder takes items from
+// the lanes in proportion to their weights, so with weights 80, 15 and 5 lower
+// lanes still make progress under sustained load on the first. Idle lanes
+// don't hold back the others.
+func WithPriorityLanes[T any](lane LaneFunc[T], weights ...int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.LaneFunc = lane
+		o.LaneWeights = weights
+	}
+}
+
+// WithLaneAging promotes items that have waited longer than maxWait in a
+// priority lane ahead of the weighted order, oldest first. This bounds how
+// long items in light lanes can wait when heavier lanes are saturated. Zero
+// disables aging.
+func WithLaneAging(maxWait time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.LaneMaxWait = maxWait
+	}
+}
+
+// laneQueue holds queued items in weighted lanes. Producers enqueue in to a
+// lane; a single scheduler goroutine dequeues with smooth weighted round
+// robin.
+type laneQueue[T any] struct {
+	laneOf  LaneFunc[T]
+	lanes   []chan *TraceableItem[T]
+	weights []int
+	current []int
+	maxWait time.Duration
+
+	// heads holds the next item of each lane, taken off the lane so its
+	// age can be checked. Only the scheduler touches heads; held counts
+	// them for len.
+	heads []*TraceableItem[T]
+	held  atomic.Int64
+
+	// signal wakes the scheduler when items are enqueued in to empty lanes.
+	signal chan struct{}
+}
+
+func validateLaneWeights(weights []int) error {
+	if len(weights) == 0 {
+		return errors.New("priority lanes need at least one weight")
+	}
+
+	for _, weight := range weights {
+		if weight < 1 {
+			return errors.New("priority lane weights must be positive")
+		}
+	}
+
+	return nil
+}
+
+func newLaneQueue[T any](laneOf LaneFunc[T], weights []int, size int, maxWait time.Duration) *laneQueue[T] {
+	q := &laneQueue[T]{
+		laneOf:  laneOf,
+		lanes:   make([]chan *TraceableItem[T], len(weights)),
+		weights: weights,
+		current: make([]int, len(weights)),
+		maxWait: maxWait,
+		heads:   make([]*TraceableItem[T], len(weights)),
+		signal:  make(chan struct{}, 1),
+	}
+
+	for i := range q.lanes {
+		q.lanes[i] = make(chan *TraceableItem[T], size)
+	}
+
+	return q
+}
+
+// lane returns the lane index of an item.
+func (q *laneQueue[T]) lane(item *TraceableItem[T]) int {
+	return min(max(q.laneOf(item.item), 0), len(q.lanes)-1)
+}
+
+// enqueue adds an item to its lane without blocking. It returns false if the
+// lane is full. Like the plain queue it panics once the lanes are closed.
+func (q *laneQueue[T]) enqueue(item *TraceableItem[T]) bool {
+	select {
+	case q.lanes[q.lane(item)] <- item:
+	default:
+		return false
+	}
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// len returns the number of items queued in all lanes.
+func (q *laneQueue[T]) len() int {
+	n := int(q.held.Load())
+
+	for _, lane := range q.lanes {
+		n += len(lane)
+	}
+
+	return n
+}
+
+// close stops the lanes accepting items. Queued items are still dequeued.
+func (q *laneQueue[T]) close() {
+	for _, lane := range q.lanes {
+		close(lane)
+	}
+
+	close(q.signal)
+}
+
+// next picks the lane to dequeue from, returning -1 if all are empty. Heads
+// that waited longer than maxWait go first, oldest first; otherwise lanes are
+// picked with smooth weighted round robin over the non-empty lanes. It must
+// only be called by the scheduler.
+func (q *laneQueue[T]) next(now time.Time) int {
+	for i := range q.lanes {
+		q.fill(i)
+	}
+
+	if lane := q.aged(now); lane >= 0 {
+		return lane
+	}
+
+	best, total := -1, 0
+
+	for i, head := range q.heads {
+		if head == nil {
+			continue
+		}
+
+		q.current[i] += q.weights[i]
+		total += q.weights[i]
+
+		if best < 0 || q.current[i] > q.current[best] {
+			best = i
+		}
+	}
+
+	if best >= 0 {
+		q.current[best] -= total
+	}
+
+	return best
+}
+
+// aged returns the lane whose head waited longest past maxWait, or -1.
+func (q *laneQueue[T]) aged(now time.Time) int {
+	if q.maxWait <= 0 {
+		return -1
+	}
+
+	oldest := -1
+
+	for i, head := range q.heads {
+		if head == nil || now.Sub(head.enqueued) < q.maxWait {
+			continue
+		}
+
+		if oldest < 0 || head.enqueued.Before(q.heads[oldest].enqueued) {
+			oldest = i
+		}
+	}
+
+	return oldest
+}
+
+// fill takes the next item off a lane in to its head, if the head is empty.
+func (q *laneQueue[T]) fill(lane int) {
+	if q.heads[lane] != nil {
+		return
+	}
+
+	select {
+	case item, ok := <-q.lanes[lane]:
+		if ok {
+			q.heads[lane] = item
+			q.held.Add(1)
+		}
+	default:
+	}
+}
+
+// take removes and returns the head of a lane.
+func (q *laneQueue[T]) take(lane int) *TraceableItem[T] {
+	item := q.heads[lane]
+
+	q.heads[lane] = nil
+	q.held.Add(-1)
+
+	return item
+}
+
+// laneScheduler moves items from the lanes to the queue in weighted order,
+// closing the queue once the lanes are closed and empty. The queue only holds
+// about one batch, so lane order decides what is batched next.
+func (bvp *BatchItemProcessor[T]) laneScheduler() {
+	defer close(bvp.queue)
+
+	q := bvp.lanes
+
+	for {
+		lane := q.next(time.Now())
+		if lane < 0 {
+			if _, ok := <-q.signal; !ok && q.len() == 0 {
+				return
+			}
+
+			continue
+		}
+
+		bvp.queue <- q.take(lane)
+	}
+}
@@ -0,0 +1,144 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLaneQueue_Weights(t *testing.T) {
+	lane := func(item *int) int { return *item }
+	q := newLaneQueue[int](lane, []int{80, 15, 5}, 100, 0)
+
+	for l := 0; l < 3; l++ {
+		for i := 0; i < 100; i++ {
+			val := l
+			q.enqueue(&TraceableItem[int]{item: &val})
+		}
+	}
+
+	counts := make([]int, 3)
+
+	for i := 0; i < 100; i++ {
+		l := q.next(time.Now())
+		q.take(l)
+
+		counts[l]++
+	}
+
+	want := []int{80, 15, 5}
+	for l := range want {
+		if counts[l] != want[l] {
+			t.Errorf("lane %d: expected %d items, got %d", l, want[l], counts[l])
+		}
+	}
+
+	// Once the busy lane is drained, the remaining lanes share by weight.
+	for len(q.lanes[0]) > 0 {
+		<-q.lanes[0]
+	}
+
+	q.take(0)
+
+	counts = make([]int, 3)
+
+	for i := 0; i < 40; i++ {
+		l := q.next(time.Now())
+		q.take(l)
+
+		counts[l]++
+	}
+
+	if counts[1] != 30 || counts[2] != 10 {
+		t.Errorf("expected 30/10 split between lanes 1 and 2, got %d/%d", counts[1], counts[2])
+	}
+}
+
+func TestLaneQueue_Aging(t *testing.T) {
+	lane := func(item *int) int { return *item }
+	q := newLaneQueue[int](lane, []int{100, 1}, 100, time.Second)
+
+	start := time.Now()
+
+	// The light lane's item was queued first.
+	for i, l := range []int{1, 0, 0, 0} {
+		val := l
+		q.enqueue(&TraceableItem[int]{item: &val, enqueued: start.Add(time.Duration(i) * time.Millisecond)})
+	}
+
+	if l := q.next(start); l != 0 {
+		t.Fatalf("expected the heavy lane before items age, got lane %d", l)
+	}
+
+	q.take(0)
+
+	// Once it waited past the max wait it is promoted.
+	if l := q.next(start.Add(time.Second)); l != 1 {
+		t.Fatalf("expected the aged item's lane, got lane %d", l)
+	}
+}
+
+func TestBatchItemProcessor_PriorityLanes(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(10*time.Second),
+		WithWorkers(1),
+		WithPriorityLanes(func(item *int) int { return *item % 2 }, 3, 1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// Each lane holds MaxQueueSize items.
+	items := make([]*int, 200)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	if got := proc.Stats().ItemsQueued; got != 200 {
+		t.Fatalf("expected 200 items queued, got %d", got)
+	}
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 200 {
+		t.Fatalf("expected 200 items exported, got %d", got)
+	}
+
+	// The first exports favour the heavier lane.
+	even := 0
+
+	for _, item := range exporter.exportedItems[:40] {
+		if *item%2 == 0 {
+			even++
+		}
+	}
+
+	if even < 25 {
+		t.Errorf("expected the heavier lane to dominate early exports, got %d of 40", even)
+	}
+}
@@ -0,0 +1,91 @@
+package processor
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrQueueLatencyExceeded is returned to sync writers whose items were shed
+// because they couldn't be exported within the maximum queue latency.
+var ErrQueueLatencyExceeded = errors.New("maximum queue latency exceeded")
+
+// WithMaxQueueLatency bounds the time between an item being written and its
+// export, for near-real-time pipelines with latency objectives. The batch
+// builder flushes a batch early enough for its oldest item to be exported
+// within latency, ahead of it by the recent export duration, whatever the
+// batch timeout.
+//
+// Items that have already waited longer than latency when their batch
+// reaches a worker, because the workers are behind or exports are paused,
+// are shed rather than exported late. They are counted as dropped and in
+// queue_latency_items_shed_total, and sync writers get
+// ErrQueueLatencyExceeded. Batches of a two-phase round are never shed.
+func WithMaxQueueLatency(latency time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.MaxQueueLatency = latency
+	}
+}
+
+// latencyDeadline returns when the batch holding item must be flushed for the
+// item to be exported within the maximum queue latency.
+func (bvp *BatchItemProcessor[T]) latencyDeadline(item *TraceableItem[T]) (time.Time, bool) {
+	if bvp.o.MaxQueueLatency <= 0 {
+		return time.Time{}, false
+	}
+
+	return item.enqueued.Add(bvp.o.MaxQueueLatency - bvp.exportLatency.get()), true
+}
+
+// shedLate sheds the items of batch that waited longer than the maximum queue
+// latency, returning those left to export. It returns false, recycling the
+// batch, if none are left.
+func (bvp *BatchItemProcessor[T]) shedLate(batch []*TraceableItem[T]) ([]*TraceableItem[T], bool) {
+	if bvp.o.MaxQueueLatency <= 0 || len(batch) == 0 || batch[0].round != nil {
+		return batch, true
+	}
+
+	var (
+		now  = time.Now()
+		kept = batch[:0]
+		shed []*TraceableItem[T]
+	)
+
+	for _, item := range batch {
+		if now.Sub(item.enqueued) <= bvp.o.MaxQueueLatency {
+			kept = append(kept, item)
+
+			continue
+		}
+
+		shed = append(shed, item)
+	}
+
+	if len(shed) == 0 {
+		return batch, true
+	}
+
+	bvp.metrics.IncQueueLatencyItemsShedBy(bvp.label, float64(len(shed)))
+
+	for _, item := range shed {
+		if bvp.checkpoints != nil {
+			bvp.checkpoints.skip(item.seq)
+		}
+
+		bvp.drop(item, ErrQueueLatencyExceeded)
+	}
+
+	bvp.log.WithField("items", len(shed)).Warn("Shed items that exceeded the maximum queue latency")
+
+	signalWriters(shed, ErrQueueLatencyExceeded, nil)
+
+	bvp.settlePending(shed)
+	bvp.items.putAll(shed)
+
+	if len(kept) == 0 {
+		bvp.buffers.put(kept)
+
+		return nil, false
+	}
+
+	return kept, true
+}
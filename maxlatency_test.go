@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_MaxQueueLatencyFlush(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "test", log,
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(10*time.Second),
+		WithWorkers(1),
+		WithMaxQueueLatency(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, _ := proc.Subscribe(10)
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	written := time.Now()
+
+	if err := proc.Write(ctx, ints(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case e := <-events:
+			if e.Kind != EventBatchCreated {
+				continue
+			}
+
+			if e.Reason != "max_queue_latency" || e.Items != 2 {
+				t.Fatalf("expected a batch of 2 cut for the queue latency, got %+v", e)
+			}
+
+			if waited := time.Since(written); waited > time.Second {
+				t.Fatalf("expected the batch cut well before the batch timeout, waited %s", waited)
+			}
+
+			return
+		case <-timeout:
+			t.Fatal("expected the batch cut within the queue latency")
+		}
+	}
+}
+
+func TestBatchItemProcessor_MaxQueueLatencyShed(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{}
+	name := "max-queue-latency-shed-test"
+
+	proc, err := NewBatchItemProcessor[int](exporter, name, log,
+		WithMaxExportBatchSize(5),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(1),
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxQueueLatency(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	shed := DefaultMetrics.itemsShedLate.WithLabelValues(name)
+	shedBefore := counterValue(t, shed)
+
+	// Items held back past the latency are shed rather than exported late.
+	proc.PauseExports()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- proc.Write(ctx, ints(5))
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	proc.ResumeExports()
+
+	if err := <-done; !errors.Is(err, ErrQueueLatencyExceeded) {
+		t.Fatalf("expected the writer told its items were shed, got %v", err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 0 {
+		t.Fatalf("expected no shed items exported, got %d", got)
+	}
+
+	if got := counterValue(t, shed) - shedBefore; got != 5 {
+		t.Fatalf("expected 5 items counted as shed, got %v", got)
+	}
+
+	// Items exported in time are unaffected.
+	if err := proc.Write(ctx, ints(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 5 {
+		t.Fatalf("expected 5 items exported, got %d", got)
+	}
+}
@@ -0,0 +1,37 @@
+package processor
+
+import (
+	"context"
+	"maps"
+)
+
+// Metadata is request-scoped information attached to items by
+// WriteWithMetadata. Exporters and middleware read it from the export context
+// with MetadataFromContext.
+type Metadata map[string]string
+
+type metadataKey struct{}
+
+// WriteWithMetadata writes items like Write, attaching md to them. Every
+// export only holds items written with equal metadata, and md is available
+// from the export context via MetadataFromContext. md is copied, so the caller
+// may reuse it. Metadata is not kept for batches buffered to disk.
+func (bvp *BatchItemProcessor[T]) WriteWithMetadata(ctx context.Context, s []*T, md Metadata) error {
+	return bvp.Write(contextWithMetadata(ctx, md), s)
+}
+
+// MetadataFromContext returns the metadata of the items being exported, or
+// nil if they were written without metadata.
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+
+	return md
+}
+
+func contextWithMetadata(ctx context.Context, md Metadata) context.Context {
+	if len(md) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, metadataKey{}, maps.Clone(md))
+}
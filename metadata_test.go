@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// metadataExporter records the metadata of every exported batch.
+type metadataExporter struct {
+	mockExporter[int]
+	mu      sync.Mutex
+	batches []Metadata
+}
+
+func (e *metadataExporter) ExportItems(ctx context.Context, items []*int) error {
+	e.mu.Lock()
+	e.batches = append(e.batches, MetadataFromContext(ctx))
+	e.mu.Unlock()
+
+	return e.mockExporter.ExportItems(ctx, items)
+}
+
+func TestBatchItemProcessor_WriteWithMetadata(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &metadataExporter{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(10*time.Second),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	md := Metadata{"request_id": "1"}
+	one, two, three := 1, 2, 3
+
+	if err := proc.WriteWithMetadata(ctx, []*int{&one}, md); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	// The metadata is copied at write time.
+	md["request_id"] = "2"
+
+	if err := proc.WriteWithMetadata(ctx, []*int{&two}, md); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	if err := proc.Write(ctx, []*int{&three}); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	want := []string{"1", "2", ""}
+
+	if len(exporter.batches) != len(want) {
+		t.Fatalf("expected %d batches, got %d", len(want), len(exporter.batches))
+	}
+
+	for i, id := range want {
+		if got := exporter.batches[i]["request_id"]; got != id {
+			t.Errorf("batch %d: expected request id %q, got %q", i, id, got)
+		}
+	}
+}
@@ -1,26 +1,130 @@
 package processor
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// MetricsRecorder records metrics about a batch item processor. Every method
+// receives the name of the processor so one recorder can be shared by many
+// processors. The metrics of optional features are recorded through extension
+// interfaces, such as DiskBufferMetricsRecorder, which a recorder implements
+// only if it supports them.
+type MetricsRecorder interface {
+	SetItemsQueued(name string, count float64)
+	IncItemsDroppedBy(name string, count float64)
+	IncItemsExportedBy(name string, count float64)
+	IncItemsFailedBy(name string, count float64)
+	ObserveExportDuration(name string, duration time.Duration)
+	ObserveBatchSize(name string, size float64)
+	SetWorkerCount(name string, count float64)
+	IncWorkerExportInProgress(name string)
+	DecWorkerExportInProgress(name string)
+	SetItemsThroughput(name string, rate float64)
+	SetBytesThroughput(name string, rate float64)
+	SetShutdownDuration(name string, duration time.Duration)
+	SetShutdownItemsDrained(name string, count float64)
+	SetShutdownItemsUnfinished(name string, count float64)
+	IncShutdowns(name, outcome string)
+}
+
+// ErrMetricsNameInUse is returned by Start when another running processor
+// reports to the same Metrics under the same name.
+var ErrMetricsNameInUse = errors.New("processor name already in use by metrics")
+
 // DefaultMetrics is the default metrics instance using "batch" namespace.
 var DefaultMetrics = NewMetrics("batch")
 
-// Metrics holds Prometheus metrics for the batch processor.
+// Metrics is a MetricsRecorder backed by Prometheus. It is safe for
+// concurrent use and meant to be shared by many processors, each reporting
+// under its own name: Start fails with ErrMetricsNameInUse while another
+// running processor reports to the same Metrics under the same name, since
+// their metrics would be merged. Names mapped to OverflowLabel are exempt.
 type Metrics struct {
-	itemsQueued            *prometheus.GaugeVec
-	itemsDropped           *prometheus.CounterVec
-	itemsFailed            *prometheus.CounterVec
-	itemsExported          *prometheus.CounterVec
-	exportDuration         *prometheus.HistogramVec
-	batchSize              *prometheus.HistogramVec
-	workerCount            *prometheus.GaugeVec
-	workerExportInProgress *prometheus.GaugeVec
+	itemsQueued             *prometheus.GaugeVec
+	itemsDropped            *prometheus.CounterVec
+	itemsFailed             *prometheus.CounterVec
+	itemsExported           *prometheus.CounterVec
+	exportDuration          *prometheus.HistogramVec
+	batchSize               *prometheus.HistogramVec
+	workerCount             *prometheus.GaugeVec
+	workerExportInProgress  *prometheus.GaugeVec
+	itemsThroughput         *prometheus.GaugeVec
+	bytesThroughput         *prometheus.GaugeVec
+	shutdownDuration        *prometheus.GaugeVec
+	shutdownItemsDrained    *prometheus.GaugeVec
+	shutdownItemsUnfinished *prometheus.GaugeVec
+	shutdowns               *prometheus.CounterVec
+	itemsDeduplicated       *prometheus.CounterVec
+	itemsBuffered           *prometheus.CounterVec
+	itemsReplayed           *prometheus.CounterVec
+	diskBufferBytes         *prometheus.GaugeVec
+	exporterHealthy         *prometheus.GaugeVec
+	producerItemsDropped    *prometheus.CounterVec
+	producerItemsEnqueued   *prometheus.CounterVec
+	itemsOverflowed         *prometheus.CounterVec
+	capacityUtilization     *prometheus.GaugeVec
+	producerBlockedDuration *prometheus.HistogramVec
+	batchesStolen           *prometheus.CounterVec
+	sharedExporterWait      *prometheus.HistogramVec
+	exportRetries           *prometheus.CounterVec
+	itemsQueuedRatio        *prometheus.GaugeVec
+	batchWaitDuration       *prometheus.HistogramVec
+	batchBytes              *prometheus.HistogramVec
+	bytesExported           *prometheus.CounterVec
+	itemsDroppedByPolicy    *prometheus.CounterVec
+	workerExporting         *prometheus.GaugeVec
+	workerExportDuration    *prometheus.HistogramVec
+	processorInfo           *prometheus.GaugeVec
+	itemsShedLate           *prometheus.CounterVec
+
+	// processors caches each processor's hot path metrics by name.
+	processors sync.Map
+
+	// vecs holds every metric vector, for registration and Preload.
+	vecs []*prometheus.MetricVec
+
+	// running holds the names of the running processors reporting here.
+	runningMu sync.Mutex
+	running   map[string]struct{}
+}
+
+// processorMetrics holds the children of one processor's hot path metrics,
+// so they aren't looked up by label values on every update.
+type processorMetrics struct {
+	itemsQueued            prometheus.Gauge
+	itemsQueuedRatio       prometheus.Gauge
+	itemsDropped           prometheus.Counter
+	itemsExported          prometheus.Counter
+	itemsFailed            prometheus.Counter
+	exportDuration         prometheus.Observer
+	batchSize              prometheus.Observer
+	workerExportInProgress prometheus.Gauge
 }
 
+var (
+	_ MetricsRecorder               = (*Metrics)(nil)
+	_ KeyMetricsRecorder            = (*Metrics)(nil)
+	_ DiskBufferMetricsRecorder     = (*Metrics)(nil)
+	_ HealthMetricsRecorder         = (*Metrics)(nil)
+	_ ProducerMetricsRecorder       = (*Metrics)(nil)
+	_ OverflowMetricsRecorder       = (*Metrics)(nil)
+	_ CapacityMetricsRecorder       = (*Metrics)(nil)
+	_ WorkStealingMetricsRecorder   = (*Metrics)(nil)
+	_ SharedExporterMetricsRecorder = (*Metrics)(nil)
+	_ RetryMetricsRecorder          = (*Metrics)(nil)
+	_ BatchMetricsRecorder          = (*Metrics)(nil)
+	_ DropPolicyMetricsRecorder     = (*Metrics)(nil)
+	_ WorkerMetricsRecorder         = (*Metrics)(nil)
+	_ InfoMetricsRecorder           = (*Metrics)(nil)
+	_ QueueLatencyMetricsRecorder   = (*Metrics)(nil)
+)
+
 // NewMetrics creates a new Metrics instance with the given namespace.
 func NewMetrics(namespace string) *Metrics {
 	if namespace != "" {
@@ -72,48 +176,306 @@ func NewMetrics(namespace string) *Metrics {
 			Namespace: namespace,
 			Help:      "Number of workers currently exporting",
 		}, []string{"processor"}),
+		itemsThroughput: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "throughput_items_per_second",
+			Namespace: namespace,
+			Help:      "Number of items exported per second over the throughput window",
+		}, []string{"processor"}),
+		bytesThroughput: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "throughput_bytes_per_second",
+			Namespace: namespace,
+			Help:      "Number of bytes exported per second over the throughput window",
+		}, []string{"processor"}),
+		shutdownDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "shutdown_duration_seconds",
+			Namespace: namespace,
+			Help:      "Duration of the last shutdown in seconds",
+		}, []string{"processor"}),
+		shutdownItemsDrained: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "shutdown_items_drained",
+			Namespace: namespace,
+			Help:      "Number of items, queued, batched or being exported, drained during the last shutdown",
+		}, []string{"processor"}),
+		shutdownItemsUnfinished: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "shutdown_items_unfinished",
+			Namespace: namespace,
+			Help:      "Number of items, queued, batched or being exported, not yet exported when the last shutdown timed out",
+		}, []string{"processor"}),
+		shutdowns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "shutdowns_total",
+			Namespace: namespace,
+			Help:      "Number of shutdowns by outcome",
+		}, []string{"processor", "outcome"}),
+		itemsDeduplicated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "items_deduplicated_total",
+			Namespace: namespace,
+			Help:      "Number of items removed from batches as duplicates",
+		}, []string{"processor"}),
+		itemsBuffered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "items_buffered_total",
+			Namespace: namespace,
+			Help:      "Number of items written to the disk buffer",
+		}, []string{"processor"}),
+		itemsReplayed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "items_replayed_total",
+			Namespace: namespace,
+			Help:      "Number of items replayed from the disk buffer",
+		}, []string{"processor"}),
+		diskBufferBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "disk_buffer_bytes",
+			Namespace: namespace,
+			Help:      "Size of the batches held in the disk buffer in bytes",
+		}, []string{"processor"}),
+		exporterHealthy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "exporter_healthy",
+			Namespace: namespace,
+			Help:      "Whether the latest exporter health check passed (1) or failed (0)",
+		}, []string{"processor"}),
+		producerItemsDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "producer_items_dropped_total",
+			Namespace: namespace,
+			Help:      "Number of items dropped by producer",
+		}, []string{"processor", "producer"}),
+		producerItemsEnqueued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "producer_items_enqueued_total",
+			Namespace: namespace,
+			Help:      "Number of items enqueued by producer",
+		}, []string{"processor", "producer"}),
+		itemsOverflowed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "items_overflowed_total",
+			Namespace: namespace,
+			Help:      "Number of items written to the overflow because the queue was full",
+		}, []string{"processor"}),
+		capacityUtilization: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "capacity_utilization",
+			Namespace: namespace,
+			Help:      "Arrival rate as a fraction of the estimated export capacity",
+		}, []string{"processor"}),
+		producerBlockedDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "producer_blocked_duration_seconds",
+			Namespace: namespace,
+			Help:      "Time writers spent blocked waiting for queue space in seconds",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"processor"}),
+		batchesStolen: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "batches_stolen_total",
+			Namespace: namespace,
+			Help:      "Number of batches exported by a worker other than the one they were routed to",
+		}, []string{"processor"}),
+		sharedExporterWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "shared_exporter_wait_duration_seconds",
+			Namespace: namespace,
+			Help:      "Time batches waited for a shared exporter slot in seconds",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"processor"}),
+		exportRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "export_retries_total",
+			Namespace: namespace,
+			Help:      "Number of export attempts retried after a failure",
+		}, []string{"processor"}),
+		itemsQueuedRatio: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "items_queued_ratio",
+			Namespace: namespace,
+			Help:      "Items queued as a fraction of the queue capacity",
+		}, []string{"processor"}),
+		batchWaitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "batch_wait_duration_seconds",
+			Namespace: namespace,
+			Help:      "Time from the oldest item of a batch being queued to the batch's export starting in seconds",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"processor"}),
+		batchBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "batch_bytes",
+			Namespace: namespace,
+			Help:      "Size of exported batches in bytes, as measured by the sizer",
+			Buckets:   prometheus.ExponentialBuckets(1024, 4, 10),
+		}, []string{"processor"}),
+		bytesExported: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "bytes_exported_total",
+			Namespace: namespace,
+			Help:      "Number of bytes exported, as measured by the sizer",
+		}, []string{"processor"}),
+		itemsDroppedByPolicy: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "queue_full_items_dropped_total",
+			Namespace: namespace,
+			Help:      "Number of items dropped because the queue was full, by the drop policy that chose them",
+		}, []string{"processor", "policy"}),
+		workerExporting: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "worker_exporting",
+			Namespace: namespace,
+			Help:      "Whether each worker is exporting a batch (1) or not (0)",
+		}, []string{"processor", "worker"}),
+		workerExportDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:      "worker_export_duration_seconds",
+			Namespace: namespace,
+			Help:      "Duration of exporting a batch in seconds, by worker",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+		}, []string{"processor", "worker"}),
+		processorInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:      "processor_info",
+			Namespace: namespace,
+			Help:      "Configuration of each running processor, in its labels. Always 1",
+		}, []string{"processor", "max_export_batch_size", "max_queue_size", "workers", "batch_timeout"}),
+		itemsShedLate: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:      "queue_latency_items_shed_total",
+			Namespace: namespace,
+			Help:      "Number of items shed because they couldn't be exported within the maximum queue latency",
+		}, []string{"processor"}),
 	}
 
-	prometheus.MustRegister(m.itemsQueued)
-	prometheus.MustRegister(m.itemsDropped)
-	prometheus.MustRegister(m.itemsFailed)
-	prometheus.MustRegister(m.itemsExported)
-	prometheus.MustRegister(m.exportDuration)
-	prometheus.MustRegister(m.batchSize)
-	prometheus.MustRegister(m.workerCount)
-	prometheus.MustRegister(m.workerExportInProgress)
+	m.vecs = []*prometheus.MetricVec{
+		m.itemsQueued.MetricVec,
+		m.itemsDropped.MetricVec,
+		m.itemsFailed.MetricVec,
+		m.itemsExported.MetricVec,
+		m.exportDuration.MetricVec,
+		m.batchSize.MetricVec,
+		m.workerCount.MetricVec,
+		m.workerExportInProgress.MetricVec,
+		m.itemsThroughput.MetricVec,
+		m.bytesThroughput.MetricVec,
+		m.shutdownDuration.MetricVec,
+		m.shutdownItemsDrained.MetricVec,
+		m.shutdownItemsUnfinished.MetricVec,
+		m.shutdowns.MetricVec,
+		m.itemsDeduplicated.MetricVec,
+		m.itemsBuffered.MetricVec,
+		m.itemsReplayed.MetricVec,
+		m.diskBufferBytes.MetricVec,
+		m.exporterHealthy.MetricVec,
+		m.producerItemsDropped.MetricVec,
+		m.producerItemsEnqueued.MetricVec,
+		m.itemsOverflowed.MetricVec,
+		m.capacityUtilization.MetricVec,
+		m.producerBlockedDuration.MetricVec,
+		m.batchesStolen.MetricVec,
+		m.sharedExporterWait.MetricVec,
+		m.exportRetries.MetricVec,
+		m.itemsQueuedRatio.MetricVec,
+		m.batchWaitDuration.MetricVec,
+		m.batchBytes.MetricVec,
+		m.bytesExported.MetricVec,
+		m.itemsDroppedByPolicy.MetricVec,
+		m.workerExporting.MetricVec,
+		m.workerExportDuration.MetricVec,
+		m.processorInfo.MetricVec,
+		m.itemsShedLate.MetricVec,
+	}
+
+	for _, vec := range m.vecs {
+		prometheus.MustRegister(vec)
+	}
 
 	return m
 }
 
+// processor returns the cached hot path metrics of a processor.
+func (m *Metrics) processor(name string) *processorMetrics {
+	if cached, ok := m.processors.Load(name); ok {
+		return cached.(*processorMetrics)
+	}
+
+	cached, _ := m.processors.LoadOrStore(name, &processorMetrics{
+		itemsQueued:            m.itemsQueued.WithLabelValues(name),
+		itemsQueuedRatio:       m.itemsQueuedRatio.WithLabelValues(name),
+		itemsDropped:           m.itemsDropped.WithLabelValues(name),
+		itemsExported:          m.itemsExported.WithLabelValues(name),
+		itemsFailed:            m.itemsFailed.WithLabelValues(name),
+		exportDuration:         m.exportDuration.WithLabelValues(name),
+		batchSize:              m.batchSize.WithLabelValues(name),
+		workerExportInProgress: m.workerExportInProgress.WithLabelValues(name),
+	})
+
+	return cached.(*processorMetrics)
+}
+
+// preload resolves a processor's hot path metrics when it is created.
+func (m *Metrics) preload(name string) {
+	m.processor(name)
+}
+
+// Preload creates the metrics of processors with the given names ahead of
+// their first use, so they are exported from the start and the first
+// updates don't pay for creating them. Names are turned in to labels by
+// guard, which must be the LabelGuard the processors use; nil is
+// DefaultLabelGuard, as it is for processors. Metrics with labels besides the
+// processor name, such as per producer ones, are still created on first use.
+func (m *Metrics) Preload(guard *LabelGuard, names ...string) {
+	if guard == nil {
+		guard = DefaultLabelGuard
+	}
+
+	for _, name := range names {
+		label := guard.Label(name)
+
+		m.preload(label)
+
+		for _, vec := range m.vecs {
+			//nolint:errcheck // Vectors with more labels than the processor's are skipped.
+			vec.GetMetricWithLabelValues(label)
+		}
+	}
+}
+
+// claim records a running processor reporting under name, failing if
+// another one already is.
+func (m *Metrics) claim(name string) error {
+	if name == OverflowLabel {
+		return nil
+	}
+
+	m.runningMu.Lock()
+	defer m.runningMu.Unlock()
+
+	if _, ok := m.running[name]; ok {
+		return fmt.Errorf("%w: %s", ErrMetricsNameInUse, name)
+	}
+
+	if m.running == nil {
+		m.running = make(map[string]struct{})
+	}
+
+	m.running[name] = struct{}{}
+
+	return nil
+}
+
+// release records a processor reporting under name having stopped.
+func (m *Metrics) release(name string) {
+	m.runningMu.Lock()
+	defer m.runningMu.Unlock()
+
+	delete(m.running, name)
+}
+
 // SetItemsQueued sets the number of items queued for the given processor.
 func (m *Metrics) SetItemsQueued(name string, count float64) {
-	m.itemsQueued.WithLabelValues(name).Set(count)
+	m.processor(name).itemsQueued.Set(count)
 }
 
 // IncItemsDroppedBy increments the number of items dropped by the given count.
 func (m *Metrics) IncItemsDroppedBy(name string, count float64) {
-	m.itemsDropped.WithLabelValues(name).Add(count)
+	m.processor(name).itemsDropped.Add(count)
 }
 
 // IncItemsExportedBy increments the number of items exported by the given count.
 func (m *Metrics) IncItemsExportedBy(name string, count float64) {
-	m.itemsExported.WithLabelValues(name).Add(count)
+	m.processor(name).itemsExported.Add(count)
 }
 
 // IncItemsFailedBy increments the number of items failed by the given count.
 func (m *Metrics) IncItemsFailedBy(name string, count float64) {
-	m.itemsFailed.WithLabelValues(name).Add(count)
+	m.processor(name).itemsFailed.Add(count)
 }
 
 // ObserveExportDuration records the duration of an export operation.
 func (m *Metrics) ObserveExportDuration(name string, duration time.Duration) {
-	m.exportDuration.WithLabelValues(name).Observe(duration.Seconds())
+	m.processor(name).exportDuration.Observe(duration.Seconds())
 }
 
 // ObserveBatchSize records the size of a processed batch.
 func (m *Metrics) ObserveBatchSize(name string, size float64) {
-	m.batchSize.WithLabelValues(name).Observe(size)
+	m.processor(name).batchSize.Observe(size)
 }
 
 // SetWorkerCount sets the number of active workers for the given processor.
@@ -123,10 +485,159 @@ func (m *Metrics) SetWorkerCount(name string, count float64) {
 
 // IncWorkerExportInProgress increments the number of workers currently exporting.
 func (m *Metrics) IncWorkerExportInProgress(name string) {
-	m.workerExportInProgress.WithLabelValues(name).Inc()
+	m.processor(name).workerExportInProgress.Inc()
 }
 
 // DecWorkerExportInProgress decrements the number of workers currently exporting.
 func (m *Metrics) DecWorkerExportInProgress(name string) {
-	m.workerExportInProgress.WithLabelValues(name).Dec()
+	m.processor(name).workerExportInProgress.Dec()
+}
+
+// SetItemsThroughput sets the number of items exported per second for the given processor.
+func (m *Metrics) SetItemsThroughput(name string, rate float64) {
+	m.itemsThroughput.WithLabelValues(name).Set(rate)
+}
+
+// SetBytesThroughput sets the number of bytes exported per second for the given processor.
+func (m *Metrics) SetBytesThroughput(name string, rate float64) {
+	m.bytesThroughput.WithLabelValues(name).Set(rate)
+}
+
+// SetShutdownDuration sets the duration of the last shutdown for the given processor.
+func (m *Metrics) SetShutdownDuration(name string, duration time.Duration) {
+	m.shutdownDuration.WithLabelValues(name).Set(duration.Seconds())
+}
+
+// SetShutdownItemsDrained sets the number of items drained during the last shutdown.
+func (m *Metrics) SetShutdownItemsDrained(name string, count float64) {
+	m.shutdownItemsDrained.WithLabelValues(name).Set(count)
+}
+
+// SetShutdownItemsUnfinished sets the number of items not yet exported when the last shutdown timed out.
+func (m *Metrics) SetShutdownItemsUnfinished(name string, count float64) {
+	m.shutdownItemsUnfinished.WithLabelValues(name).Set(count)
+}
+
+// IncShutdowns increments the number of shutdowns with the given outcome.
+func (m *Metrics) IncShutdowns(name, outcome string) {
+	m.shutdowns.WithLabelValues(name, outcome).Inc()
+}
+
+// IncItemsDeduplicatedBy increments the number of items removed as duplicates by the given count.
+func (m *Metrics) IncItemsDeduplicatedBy(name string, count float64) {
+	m.itemsDeduplicated.WithLabelValues(name).Add(count)
+}
+
+// IncItemsBufferedBy increments the number of items written to the disk buffer by the given count.
+func (m *Metrics) IncItemsBufferedBy(name string, count float64) {
+	m.itemsBuffered.WithLabelValues(name).Add(count)
+}
+
+// IncItemsReplayedBy increments the number of items replayed from the disk buffer by the given count.
+func (m *Metrics) IncItemsReplayedBy(name string, count float64) {
+	m.itemsReplayed.WithLabelValues(name).Add(count)
+}
+
+// SetDiskBufferBytes sets the size of the disk buffer for the given processor.
+func (m *Metrics) SetDiskBufferBytes(name string, size float64) {
+	m.diskBufferBytes.WithLabelValues(name).Set(size)
+}
+
+// SetExporterHealthy sets whether the latest exporter health check passed.
+func (m *Metrics) SetExporterHealthy(name string, healthy bool) {
+	m.exporterHealthy.WithLabelValues(name).Set(boolToFloat(healthy))
+}
+
+// IncProducerItemsDroppedBy increments the number of items dropped for the given producer.
+func (m *Metrics) IncProducerItemsDroppedBy(name, producer string, count float64) {
+	m.producerItemsDropped.WithLabelValues(name, producer).Add(count)
+}
+
+// IncProducerItemsEnqueuedBy increments the number of items enqueued for the given producer.
+func (m *Metrics) IncProducerItemsEnqueuedBy(name, producer string, count float64) {
+	m.producerItemsEnqueued.WithLabelValues(name, producer).Add(count)
+}
+
+// IncItemsOverflowedBy increments the number of items written to the overflow by the given count.
+func (m *Metrics) IncItemsOverflowedBy(name string, count float64) {
+	m.itemsOverflowed.WithLabelValues(name).Add(count)
+}
+
+// SetCapacityUtilization sets the arrival rate as a fraction of the estimated export capacity.
+func (m *Metrics) SetCapacityUtilization(name string, utilization float64) {
+	m.capacityUtilization.WithLabelValues(name).Set(utilization)
+}
+
+// ObserveProducerBlockedDuration records how long a writer was blocked waiting for queue space.
+func (m *Metrics) ObserveProducerBlockedDuration(name string, duration time.Duration) {
+	m.producerBlockedDuration.WithLabelValues(name).Observe(duration.Seconds())
+}
+
+// IncBatchesStolen increments the number of batches stolen by idle workers.
+func (m *Metrics) IncBatchesStolen(name string) {
+	m.batchesStolen.WithLabelValues(name).Inc()
+}
+
+// ObserveSharedExporterWait records how long a batch waited for a slot on a shared exporter.
+func (m *Metrics) ObserveSharedExporterWait(name string, duration time.Duration) {
+	m.sharedExporterWait.WithLabelValues(name).Observe(duration.Seconds())
+}
+
+// IncExportRetries increments the number of export attempts retried after a failure.
+func (m *Metrics) IncExportRetries(name string) {
+	m.exportRetries.WithLabelValues(name).Inc()
+}
+
+// SetItemsQueuedRatio sets the items queued as a fraction of the queue capacity for the given processor.
+func (m *Metrics) SetItemsQueuedRatio(name string, ratio float64) {
+	m.processor(name).itemsQueuedRatio.Set(ratio)
+}
+
+// ObserveBatchWaitDuration records how long a batch's oldest item waited between being queued and the batch's export starting.
+func (m *Metrics) ObserveBatchWaitDuration(name string, duration time.Duration) {
+	m.batchWaitDuration.WithLabelValues(name).Observe(duration.Seconds())
+}
+
+// ObserveBatchBytes records the size in bytes of an exported batch.
+func (m *Metrics) ObserveBatchBytes(name string, bytes float64) {
+	m.batchBytes.WithLabelValues(name).Observe(bytes)
+}
+
+// IncBytesExportedBy increments the number of bytes exported.
+func (m *Metrics) IncBytesExportedBy(name string, bytes float64) {
+	m.bytesExported.WithLabelValues(name).Add(bytes)
+}
+
+// IncQueueFullItemsDroppedBy increments the number of items dropped because the queue was full under the given drop policy.
+func (m *Metrics) IncQueueFullItemsDroppedBy(name, policy string, count float64) {
+	m.itemsDroppedByPolicy.WithLabelValues(name, policy).Add(count)
+}
+
+// SetWorkerExporting sets whether the given worker is exporting a batch.
+func (m *Metrics) SetWorkerExporting(name, worker string, exporting bool) {
+	m.workerExporting.WithLabelValues(name, worker).Set(boolToFloat(exporting))
+}
+
+// ObserveWorkerExportDuration records how long the given worker took to export a batch.
+func (m *Metrics) ObserveWorkerExportDuration(name, worker string, duration time.Duration) {
+	m.workerExportDuration.WithLabelValues(name, worker).Observe(duration.Seconds())
+}
+
+// SetProcessorInfo reports the configuration of the given processor, replacing any reported before.
+func (m *Metrics) SetProcessorInfo(name string, batchSize, queueSize, workers int, batchTimeout time.Duration) {
+	m.processorInfo.DeletePartialMatch(prometheus.Labels{"processor": name})
+	m.processorInfo.WithLabelValues(name, strconv.Itoa(batchSize), strconv.Itoa(queueSize), strconv.Itoa(workers), batchTimeout.String()).Set(1)
+}
+
+// IncQueueLatencyItemsShedBy increments the number of items shed because they couldn't be exported within the maximum queue latency.
+func (m *Metrics) IncQueueLatencyItemsShedBy(name string, count float64) {
+	m.itemsShedLate.WithLabelValues(name).Add(count)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
 }
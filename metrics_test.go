@@ -0,0 +1,432 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+
+	return m.GetCounter().GetValue()
+}
+
+func gaugeValue(t *testing.T, g prometheus.Gauge) float64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := g.Write(&m); err != nil {
+		t.Fatalf("failed to read gauge: %v", err)
+	}
+
+	return m.GetGauge().GetValue()
+}
+
+func TestMetrics_CachesProcessorChildren(t *testing.T) {
+	m := DefaultMetrics
+
+	if m.processor("cache-test") != m.processor("cache-test") {
+		t.Fatal("expected the processor's metrics to be cached")
+	}
+
+	before := counterValue(t, m.itemsExported.WithLabelValues("cache-test"))
+
+	m.IncItemsExportedBy("cache-test", 3)
+
+	if got := counterValue(t, m.itemsExported.WithLabelValues("cache-test")) - before; got != 3 {
+		t.Fatalf("expected the cached child to update the vector, got %v", got)
+	}
+}
+
+func TestBatchItemProcessor_QueuedRatio(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "queued-ratio-test", log,
+		WithMaxQueueSize(10),
+		WithMaxExportBatchSize(5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The processor isn't started, so the items stay queued.
+	if err := proc.Write(context.Background(), ints(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := gaugeValue(t, DefaultMetrics.itemsQueuedRatio.WithLabelValues("queued-ratio-test")); got != 0.4 {
+		t.Fatalf("expected the queue 40%% full, got %v", got)
+	}
+}
+
+func TestBatchItemProcessor_BatchWaitDuration(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "batch-wait-test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(50*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	// The batch isn't full, so it waits for the batch timeout.
+	if err := proc.Write(ctx, ints(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	var m dto.Metric
+
+	if err := DefaultMetrics.batchWaitDuration.WithLabelValues("batch-wait-test").(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("failed to read histogram: %v", err)
+	}
+
+	if got := m.GetHistogram().GetSampleCount(); got != 1 {
+		t.Fatalf("expected 1 batch observed, got %d", got)
+	}
+
+	if got := m.GetHistogram().GetSampleSum(); got < 0.04 {
+		t.Fatalf("expected the batch to have waited for the timeout, got %vs", got)
+	}
+}
+
+func TestBatchItemProcessor_BytesExported(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "bytes-exported-test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(4),
+		WithSizer(func(_ *int) int { return 100 }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	if err := proc.Write(ctx, ints(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := counterValue(t, DefaultMetrics.bytesExported.WithLabelValues("bytes-exported-test")); got != 400 {
+		t.Fatalf("expected 400 bytes exported, got %v", got)
+	}
+}
+
+func TestMetrics_ProcessorNameInUse(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	ctx := context.Background()
+
+	newProc := func() *BatchItemProcessor[int] {
+		proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "name-in-use-test", log)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return proc
+	}
+
+	first := newProc()
+
+	if err := first.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	second := newProc()
+
+	if err := second.Start(ctx); !errors.Is(err, ErrMetricsNameInUse) {
+		t.Fatalf("expected the name in use, got %v", err)
+	}
+
+	// Shutting down a processor that never started leaves the name claimed.
+	if err := second.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newProc().Start(ctx); !errors.Is(err, ErrMetricsNameInUse) {
+		t.Fatalf("expected the name still in use, got %v", err)
+	}
+
+	if err := first.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	third := newProc()
+
+	if err := third.Start(ctx); err != nil {
+		t.Fatalf("expected the name free once shut down, got %v", err)
+	}
+
+	if err := third.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchItemProcessor_MetricsNameCollision(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	ctx := context.Background()
+
+	// Distinct names sanitized to the same label both start.
+	for _, name := range []string{"collision test", "collision/test"} {
+		proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, name, log)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := proc.Start(ctx); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		defer proc.Shutdown(ctx)
+	}
+}
+
+func TestMetrics_Preload(t *testing.T) {
+	m := DefaultMetrics
+
+	m.Preload(nil, "preload test")
+
+	// labelled reports whether the collector has a child labelled value.
+	labelled := func(c prometheus.Collector, value string) bool {
+		ch := make(chan prometheus.Metric, 100)
+		c.Collect(ch)
+		close(ch)
+
+		for metric := range ch {
+			var d dto.Metric
+			if err := metric.Write(&d); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, label := range d.GetLabel() {
+				if label.GetValue() == value {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	// Names are sanitized as processor labels are.
+	for _, c := range []prometheus.Collector{m.itemsDropped, m.exportRetries, m.batchWaitDuration} {
+		if !labelled(c, "preload_test") {
+			t.Fatalf("expected %v preloaded", c)
+		}
+	}
+}
+
+func TestBatchItemProcessor_WorkerMetrics(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "worker-metrics-test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(2),
+		WithWorkers(2),
+		WithWorkerMetrics(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Write(ctx, ints(6)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var batches uint64
+
+	for _, worker := range []string{"0", "1"} {
+		var m dto.Metric
+
+		if err := DefaultMetrics.workerExportDuration.WithLabelValues("worker-metrics-test", worker).(prometheus.Metric).Write(&m); err != nil {
+			t.Fatalf("failed to read histogram: %v", err)
+		}
+
+		batches += m.GetHistogram().GetSampleCount()
+
+		if got := gaugeValue(t, DefaultMetrics.workerExporting.WithLabelValues("worker-metrics-test", worker)); got != 0 {
+			t.Fatalf("expected worker %s idle after shutdown, got %v", worker, got)
+		}
+	}
+
+	if batches != 3 {
+		t.Fatalf("expected 3 batches observed across workers, got %d", batches)
+	}
+}
+
+func TestBatchItemProcessor_ProcessorInfo(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	ctx := context.Background()
+
+	for _, workers := range []int{1, 2} {
+		proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "processor-info-test", log,
+			WithMaxQueueSize(100),
+			WithMaxExportBatchSize(10),
+			WithBatchTimeout(time.Second),
+			WithWorkers(workers),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := proc.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := proc.Shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info := DefaultMetrics.processorInfo
+
+	if got := gaugeValue(t, info.WithLabelValues("processor-info-test", "10", "100", "2", "1s")); got != 1 {
+		t.Fatalf("expected the latest configuration reported, got %v", got)
+	}
+
+	// A restarted processor replaces the configuration reported before.
+	if info.DeleteLabelValues("processor-info-test", "10", "100", "1", "1s") {
+		t.Fatal("expected the earlier configuration no longer reported")
+	}
+}
+
+// baseRecorder implements only MetricsRecorder, counting exported items.
+type baseRecorder struct {
+	exported atomic.Int64
+}
+
+func (r *baseRecorder) SetItemsQueued(string, float64)              {}
+func (r *baseRecorder) IncItemsDroppedBy(string, float64)           {}
+func (r *baseRecorder) IncItemsFailedBy(string, float64)            {}
+func (r *baseRecorder) ObserveExportDuration(string, time.Duration) {}
+func (r *baseRecorder) ObserveBatchSize(string, float64)            {}
+func (r *baseRecorder) SetWorkerCount(string, float64)              {}
+func (r *baseRecorder) IncWorkerExportInProgress(string)            {}
+func (r *baseRecorder) DecWorkerExportInProgress(string)            {}
+func (r *baseRecorder) SetItemsThroughput(string, float64)          {}
+func (r *baseRecorder) SetBytesThroughput(string, float64)          {}
+func (r *baseRecorder) SetShutdownDuration(string, time.Duration)   {}
+func (r *baseRecorder) SetShutdownItemsDrained(string, float64)     {}
+func (r *baseRecorder) SetShutdownItemsUnfinished(string, float64)  {}
+func (r *baseRecorder) IncShutdowns(string, string)                 {}
+
+func (r *baseRecorder) IncItemsExportedBy(_ string, count float64) {
+	r.exported.Add(int64(count))
+}
+
+// retryRecorder also implements RetryMetricsRecorder.
+type retryRecorder struct {
+	baseRecorder
+	retries atomic.Int64
+}
+
+func (r *retryRecorder) IncExportRetries(string) {
+	r.retries.Add(1)
+}
+
+func TestBatchItemProcessor_MetricsExtensions(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	backoff := BackoffConfig{InitialInterval: time.Millisecond, Multiplier: 2}
+
+	for name, metrics := range map[string]MetricsRecorder{
+		"base only":  &baseRecorder{},
+		"with retry": &retryRecorder{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Features recording metrics through extension interfaces work
+			// with a recorder that doesn't implement them.
+			proc, err := NewBatchItemProcessor[int](&flakyExporter{failures: 1}, "test", log,
+				WithShippingMethod(ShippingMethodSync),
+				WithMaxExportBatchSize(4),
+				WithMetrics(metrics),
+				WithRetry(3, backoff),
+				WithSizer(func(_ *int) int { return 100 }),
+				WithWorkerMetrics(true),
+				WithMaxQueueLatency(time.Minute),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+
+			if err := proc.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := proc.Write(ctx, ints(4)); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := proc.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var base *baseRecorder
+
+			switch m := metrics.(type) {
+			case *baseRecorder:
+				base = m
+			case *retryRecorder:
+				base = &m.baseRecorder
+
+				if got := m.retries.Load(); got != 1 {
+					t.Fatalf("expected 1 retry recorded, got %d", got)
+				}
+			}
+
+			if got := base.exported.Load(); got != 4 {
+				t.Fatalf("expected 4 exported items recorded, got %d", got)
+			}
+		})
+	}
+}
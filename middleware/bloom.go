@@ -0,0 +1,355 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+const (
+	// DefaultBloomCapacity is the default number of keys in each generation
+	// of a BloomDedup filter.
+	DefaultBloomCapacity = 1_000_000
+	// DefaultBloomFalsePositiveRate is the default fraction of new items a
+	// full BloomDedup generation mistakes for exported ones.
+	DefaultBloomFalsePositiveRate = 0.001
+	// DefaultBloomSaveInterval is the default minimum time between saves of
+	// a BloomDedup filter.
+	DefaultBloomSaveInterval = 10 * time.Second
+)
+
+// bloomMagic starts every saved filter, followed by its format version.
+const (
+	bloomMagic   = "BPBF"
+	bloomVersion = 1
+)
+
+// BloomDedupOption configures BloomDedup.
+type BloomDedupOption func(*bloomOptions)
+
+type bloomOptions struct {
+	capacity          int
+	falsePositiveRate float64
+	saveInterval      time.Duration
+}
+
+// WithBloomCapacity sets the number of keys in each generation of the
+// filter. The filter remembers between one and two generations of keys.
+func WithBloomCapacity(n int) BloomDedupOption {
+	return func(o *bloomOptions) {
+		o.capacity = n
+	}
+}
+
+// WithBloomFalsePositiveRate sets the fraction of new items a full
+// generation mistakes for exported ones.
+func WithBloomFalsePositiveRate(rate float64) BloomDedupOption {
+	return func(o *bloomOptions) {
+		o.falsePositiveRate = rate
+	}
+}
+
+// WithBloomSaveInterval sets the minimum time between saves of the filter.
+func WithBloomSaveInterval(interval time.Duration) BloomDedupOption {
+	return func(o *bloomOptions) {
+		o.saveInterval = interval
+	}
+}
+
+// BloomDedup drops items whose key is in a Bloom filter of recently exported
+// items saved at path, so duplicates replayed after a crash, from a WAL or
+// Kafka, are mostly suppressed toward the sink. Key items by content with
+// processor.KeyByJSONHash or by ID with processor.KeyByHash.
+//
+// The filter keeps two generations of keys; once the current one is full it
+// replaces the previous one. It is loaded from path, if it exists, and saved
+// there after an export at most once per save interval and on Shutdown, so
+// items exported after the last save before a crash may be exported again.
+// A saved filter sized differently from the options is discarded. Failed
+// saves are retried with the next export; Shutdown returns the error of
+// its own.
+//
+// A false positive drops an item that was never exported, at up to the
+// configured rate, so only use it where that loss is acceptable.
+func BloomDedup[T any](key processor.KeyFunc[T, uint64], path string, opts ...BloomDedupOption) (Middleware[T], error) {
+	o := bloomOptions{
+		capacity:          DefaultBloomCapacity,
+		falsePositiveRate: DefaultBloomFalsePositiveRate,
+		saveInterval:      DefaultBloomSaveInterval,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.capacity <= 0 {
+		return nil, fmt.Errorf("bloom capacity must be positive, got %d", o.capacity)
+	}
+
+	if o.falsePositiveRate <= 0 || o.falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("bloom false positive rate must be in (0, 1), got %v", o.falsePositiveRate)
+	}
+
+	filter := newGenerationalBloom(o.capacity, o.falsePositiveRate)
+
+	if err := filter.load(path); err != nil {
+		return nil, err
+	}
+
+	return func(next processor.ItemExporter[T]) processor.ItemExporter[T] {
+		return &bloomDedupExporter[T]{
+			next:         next,
+			key:          key,
+			path:         path,
+			saveInterval: o.saveInterval,
+			filter:       filter,
+			lastSave:     time.Now(),
+		}
+	}, nil
+}
+
+type bloomDedupExporter[T any] struct {
+	next         processor.ItemExporter[T]
+	key          processor.KeyFunc[T, uint64]
+	path         string
+	saveInterval time.Duration
+
+	filter *generationalBloom
+
+	saveMu   sync.Mutex
+	lastSave time.Time
+}
+
+func (e *bloomDedupExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	keys, fresh := e.filterItems(items)
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	if err := e.next.ExportItems(ctx, fresh); err != nil {
+		return err
+	}
+
+	e.filter.addAll(keys)
+
+	if e.saveMu.TryLock() {
+		defer e.saveMu.Unlock()
+
+		if time.Since(e.lastSave) >= e.saveInterval && e.filter.save(e.path) == nil {
+			e.lastSave = time.Now()
+		}
+	}
+
+	return nil
+}
+
+// filterItems returns the items not in the filter, without duplicates, and
+// their keys.
+func (e *bloomDedupExporter[T]) filterItems(items []*T) ([]uint64, []*T) {
+	keys := make([]uint64, 0, len(items))
+	fresh := make([]*T, 0, len(items))
+	seen := make(map[uint64]struct{}, len(items))
+
+	for _, item := range items {
+		k := e.key(item)
+
+		if _, ok := seen[k]; ok {
+			continue
+		}
+
+		seen[k] = struct{}{}
+
+		if e.filter.has(k) {
+			continue
+		}
+
+		keys = append(keys, k)
+		fresh = append(fresh, item)
+	}
+
+	return keys, fresh
+}
+
+// Shutdown saves the filter and shuts down the wrapped exporter.
+func (e *bloomDedupExporter[T]) Shutdown(ctx context.Context) error {
+	e.saveMu.Lock()
+	saveErr := e.filter.save(e.path)
+	e.saveMu.Unlock()
+
+	return errors.Join(saveErr, e.next.Shutdown(ctx))
+}
+
+// generationalBloom is a pair of Bloom filters, the current one taking new
+// keys and the previous one still matched until the current one fills up.
+type generationalBloom struct {
+	capacity int
+	bits     uint64
+	hashes   uint32
+
+	mu       sync.RWMutex
+	current  *bloomFilter
+	previous *bloomFilter
+}
+
+type bloomFilter struct {
+	words []uint64
+	count uint64
+}
+
+func newGenerationalBloom(capacity int, falsePositiveRate float64) *generationalBloom {
+	// The optimal size and number of hashes for the capacity and rate.
+	bits := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	bits = (bits + 63) / 64 * 64
+	hashes := uint32(max(1, math.Round(float64(bits)/float64(capacity)*math.Ln2)))
+
+	g := &generationalBloom{
+		capacity: capacity,
+		bits:     bits,
+		hashes:   hashes,
+	}
+
+	g.current = g.newFilter()
+	g.previous = g.newFilter()
+
+	return g
+}
+
+func (g *generationalBloom) newFilter() *bloomFilter {
+	return &bloomFilter{words: make([]uint64, g.bits/64)}
+}
+
+// positions calls fn with each bit position of key, by double hashing.
+func (g *generationalBloom) positions(key uint64, fn func(bit uint64)) {
+	h1 := mix64(key)
+	h2 := mix64(h1) | 1
+
+	for i := range uint64(g.hashes) {
+		fn((h1 + i*h2) % g.bits)
+	}
+}
+
+func (g *generationalBloom) has(key uint64) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	inCurrent, inPrevious := true, true
+
+	g.positions(key, func(bit uint64) {
+		inCurrent = inCurrent && g.current.words[bit/64]&(1<<(bit%64)) != 0
+		inPrevious = inPrevious && g.previous.words[bit/64]&(1<<(bit%64)) != 0
+	})
+
+	return inCurrent || inPrevious
+}
+
+func (g *generationalBloom) addAll(keys []uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, key := range keys {
+		if g.current.count >= uint64(g.capacity) {
+			g.previous = g.current
+			g.current = g.newFilter()
+		}
+
+		g.positions(key, func(bit uint64) {
+			g.current.words[bit/64] |= 1 << (bit % 64)
+		})
+
+		g.current.count++
+	}
+}
+
+// save writes the filter to path, replacing it atomically.
+func (g *generationalBloom) save(path string) error {
+	var buf bytes.Buffer
+
+	g.mu.RLock()
+
+	buf.WriteString(bloomMagic)
+	_ = binary.Write(&buf, binary.LittleEndian, []uint64{bloomVersion, g.bits, uint64(g.hashes)})
+
+	for _, f := range []*bloomFilter{g.current, g.previous} {
+		_ = binary.Write(&buf, binary.LittleEndian, f.count)
+		_ = binary.Write(&buf, binary.LittleEndian, f.words)
+	}
+
+	g.mu.RUnlock()
+
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to save bloom filter: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save bloom filter: %w", err)
+	}
+
+	return nil
+}
+
+// load reads a filter saved at path, if there is one sized like g.
+func (g *generationalBloom) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to load bloom filter: %w", err)
+	}
+
+	r := bytes.NewReader(data)
+
+	magic := make([]byte, len(bloomMagic))
+	header := make([]uint64, 3)
+
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != bloomMagic {
+		return fmt.Errorf("failed to load bloom filter: %s is not a saved filter", path)
+	}
+
+	if err := binary.Read(r, binary.LittleEndian, header); err != nil {
+		return fmt.Errorf("failed to load bloom filter: %w", err)
+	}
+
+	if header[0] != bloomVersion {
+		return fmt.Errorf("failed to load bloom filter: unsupported version %d", header[0])
+	}
+
+	if header[1] != g.bits || header[2] != uint64(g.hashes) {
+		return nil
+	}
+
+	for _, f := range []*bloomFilter{g.current, g.previous} {
+		if err := binary.Read(r, binary.LittleEndian, &f.count); err != nil {
+			return fmt.Errorf("failed to load bloom filter: %w", err)
+		}
+
+		if err := binary.Read(r, binary.LittleEndian, f.words); err != nil {
+			return fmt.Errorf("failed to load bloom filter: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// mix64 spreads a key across the full range (splitmix64 finalizer).
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
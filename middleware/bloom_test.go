@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+func TestBloomDedup(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "exported.bloom")
+	key := processor.KeyByJSONHash[int]()
+
+	dedup, err := BloomDedup(key, path, WithBloomCapacity(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner := &batchExporter{}
+	exporter := Chain[int](inner, dedup)
+
+	for _, batch := range [][]*int{ints(1, 2, 2), ints(2, 3)} {
+		if err := exporter.ExportItems(ctx, batch); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Failed items aren't remembered.
+	inner.err = errors.New("export failed")
+
+	if err := exporter.ExportItems(ctx, ints(4)); !errors.Is(err, inner.err) {
+		t.Fatalf("expected the exporter's error, got %v", err)
+	}
+
+	inner.err = nil
+
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inner.batches) != 3 || len(inner.batches[0]) != 2 || len(inner.batches[1]) != 1 {
+		t.Fatalf("expected batches [1 2] [3] [4], got %v", inner.batches)
+	}
+
+	// After a restart the saved filter still suppresses exported items.
+	dedup, err = BloomDedup(key, path, WithBloomCapacity(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner = &batchExporter{}
+	exporter = Chain[int](inner, dedup)
+
+	if err := exporter.ExportItems(ctx, ints(1, 2, 3, 4, 5)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(inner.batches) != 1 || len(inner.batches[0]) != 2 || inner.batches[0][0] != 4 || inner.batches[0][1] != 5 {
+		t.Fatalf("expected only [4 5] exported after the restart, got %v", inner.batches)
+	}
+
+	// A filter sized differently is discarded.
+	dedup, err = BloomDedup(key, path, WithBloomCapacity(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner = &batchExporter{}
+
+	if err := Chain[int](inner, dedup).ExportItems(ctx, ints(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(inner.batches) != 1 {
+		t.Fatalf("expected the resized filter to start empty, got %v", inner.batches)
+	}
+}
+
+func TestBloomDedup_Generations(t *testing.T) {
+	filter := newGenerationalBloom(100, 0.001)
+
+	keys := make([]uint64, 250)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+
+	filter.addAll(keys)
+
+	// The oldest generation was dropped, the last two are remembered.
+	for _, k := range keys[200:] {
+		if !filter.has(k) {
+			t.Fatalf("expected key %d to be remembered", k)
+		}
+	}
+
+	forgotten := 0
+
+	for _, k := range keys[:100] {
+		if !filter.has(k) {
+			forgotten++
+		}
+	}
+
+	if forgotten < 90 {
+		t.Fatalf("expected the oldest generation to be forgotten, %d of 100 keys were", forgotten)
+	}
+}
+
+func TestBloomDedup_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exported.bloom")
+
+	if err := os.WriteFile(path, []byte("not a filter"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := BloomDedup(processor.KeyByJSONHash[int](), path); err == nil {
+		t.Fatal("expected an error loading a corrupt filter")
+	}
+}
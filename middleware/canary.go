@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultCanaryMinBatches is the default number of canary batches exported
+// before the canary's error rate is judged.
+const DefaultCanaryMinBatches = 20
+
+var canaryActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name:      "canary_active",
+	Namespace: "batch_processor",
+	Help:      "Whether batches are being routed to the canary exporter",
+}, []string{"canary"})
+
+func init() {
+	prometheus.MustRegister(canaryActive)
+}
+
+// CanaryConfig configures Canary.
+type CanaryConfig struct {
+	// Name labels the canary_active metric.
+	Name string
+	// Fraction of batches, between 0 and 1, routed to the canary.
+	Fraction float64
+	// Threshold is how much higher, as a fraction of batches, the canary's
+	// error rate may be than the primary's before rolling back.
+	Threshold float64
+	// MinBatches is the number of canary batches exported before its error
+	// rate is judged. Defaults to DefaultCanaryMinBatches.
+	MinBatches int
+	// OnRollback is called once if the canary is rolled back, with both
+	// error rates.
+	OnRollback func(canaryRate, primaryRate float64)
+}
+
+// Canary routes a fraction of batches to canary instead of the wrapped
+// exporter. Batches the canary fails to export are retried on the wrapped
+// exporter, so they aren't lost. Once the canary's error rate exceeds the
+// wrapped exporter's by more than the threshold, it's rolled back: every
+// batch goes to the wrapped exporter again, canary_active drops to zero and
+// OnRollback is called.
+func Canary[T any](canary processor.ItemExporter[T], cfg CanaryConfig) Middleware[T] {
+	if cfg.MinBatches <= 0 {
+		cfg.MinBatches = DefaultCanaryMinBatches
+	}
+
+	return func(next processor.ItemExporter[T]) processor.ItemExporter[T] {
+		canaryActive.WithLabelValues(cfg.Name).Set(1)
+
+		return &canaryExporter[T]{
+			next:   next,
+			canary: canary,
+			cfg:    cfg,
+			active: true,
+		}
+	}
+}
+
+type canaryExporter[T any] struct {
+	next   processor.ItemExporter[T]
+	canary processor.ItemExporter[T]
+	cfg    CanaryConfig
+
+	mu             sync.Mutex
+	active         bool
+	canaryBatches  int
+	canaryErrors   int
+	primaryBatches int
+	primaryErrors  int
+}
+
+func (e *canaryExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	if e.routeToCanary() {
+		err := e.canary.ExportItems(ctx, items)
+
+		e.recordCanary(err)
+
+		if err == nil {
+			return nil
+		}
+	}
+
+	err := e.next.ExportItems(ctx, items)
+
+	e.recordPrimary(err)
+
+	return err
+}
+
+func (e *canaryExporter[T]) routeToCanary() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.active && rand.Float64() < e.cfg.Fraction
+}
+
+func (e *canaryExporter[T]) recordPrimary(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.primaryBatches++
+
+	if err != nil {
+		e.primaryErrors++
+	}
+}
+
+// recordCanary records a canary export and rolls the canary back if its
+// error rate is too high.
+func (e *canaryExporter[T]) recordCanary(err error) {
+	e.mu.Lock()
+
+	e.canaryBatches++
+
+	if err != nil {
+		e.canaryErrors++
+	}
+
+	if !e.active || e.canaryBatches < e.cfg.MinBatches {
+		e.mu.Unlock()
+
+		return
+	}
+
+	canaryRate := float64(e.canaryErrors) / float64(e.canaryBatches)
+
+	primaryRate := 0.0
+	if e.primaryBatches > 0 {
+		primaryRate = float64(e.primaryErrors) / float64(e.primaryBatches)
+	}
+
+	if canaryRate-primaryRate <= e.cfg.Threshold {
+		e.mu.Unlock()
+
+		return
+	}
+
+	e.active = false
+	e.mu.Unlock()
+
+	canaryActive.WithLabelValues(e.cfg.Name).Set(0)
+
+	if e.cfg.OnRollback != nil {
+		e.cfg.OnRollback(canaryRate, primaryRate)
+	}
+}
+
+func (e *canaryExporter[T]) Shutdown(ctx context.Context) error {
+	return errors.Join(e.next.Shutdown(ctx), e.canary.Shutdown(ctx))
+}
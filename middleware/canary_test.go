@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCanary(t *testing.T) {
+	ctx := context.Background()
+
+	primary := &countingExporter{}
+	canary := &countingExporter{err: errors.New("canary failed")}
+
+	var rolledBack int
+
+	exporter := Chain[int](primary, Canary[int](canary, CanaryConfig{
+		Name:       "test",
+		Fraction:   1,
+		Threshold:  0.1,
+		MinBatches: 5,
+		OnRollback: func(canaryRate, primaryRate float64) {
+			rolledBack++
+
+			if canaryRate != 1 || primaryRate != 0 {
+				t.Errorf("expected rates 1 and 0, got %v and %v", canaryRate, primaryRate)
+			}
+		},
+	}))
+
+	for range 10 {
+		if err := exporter.ExportItems(ctx, nil); err != nil {
+			t.Fatalf("expected failed canary batches to fall back, got %v", err)
+		}
+	}
+
+	if rolledBack != 1 {
+		t.Fatalf("expected one rollback, got %d", rolledBack)
+	}
+
+	if got := canary.calls.Load(); got != 5 {
+		t.Errorf("expected 5 canary exports before rolling back, got %d", got)
+	}
+
+	if got := primary.calls.Load(); got != 10 {
+		t.Errorf("expected every batch to reach the primary, got %d", got)
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+// Comparison is the outcome of exporting a batch to both the primary and the
+// candidate exporter.
+type Comparison[T any] struct {
+	Items []*T
+
+	PrimaryDuration   time.Duration
+	CandidateDuration time.Duration
+	PrimaryErr        error
+	CandidateErr      error
+
+	// Diverged reports whether the candidate's result differs from the
+	// primary's.
+	Diverged bool
+}
+
+// LatencyDelta returns how much slower the candidate was than the primary.
+func (c Comparison[T]) LatencyDelta() time.Duration {
+	return c.CandidateDuration - c.PrimaryDuration
+}
+
+// CompareOption configures Compare.
+type CompareOption func(*compareOptions)
+
+type compareOptions struct {
+	equal func(primary, candidate error) bool
+}
+
+// WithResponseComparison decides whether two export results match. By
+// default they match if both exports succeeded or both failed.
+func WithResponseComparison(equal func(primary, candidate error) bool) CompareOption {
+	return func(o *compareOptions) {
+		o.equal = equal
+	}
+}
+
+// Compare exports each batch to both the wrapped exporter and candidate,
+// concurrently, and reports the latency and results of both to report, for
+// migrating between sinks. Only the wrapped exporter's result is returned.
+// Exporters must not modify items.
+func Compare[T any](candidate processor.ItemExporter[T], report func(Comparison[T]), opts ...CompareOption) Middleware[T] {
+	o := compareOptions{
+		equal: func(primary, candidate error) bool {
+			return (primary == nil) == (candidate == nil)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next processor.ItemExporter[T]) processor.ItemExporter[T] {
+		return &compareExporter[T]{
+			next:      next,
+			candidate: candidate,
+			report:    report,
+			equal:     o.equal,
+		}
+	}
+}
+
+type compareExporter[T any] struct {
+	next      processor.ItemExporter[T]
+	candidate processor.ItemExporter[T]
+	report    func(Comparison[T])
+	equal     func(primary, candidate error) bool
+}
+
+func (e *compareExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	c := Comparison[T]{Items: items}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		start := time.Now()
+		c.CandidateErr = e.candidate.ExportItems(ctx, items)
+		c.CandidateDuration = time.Since(start)
+	}()
+
+	start := time.Now()
+	c.PrimaryErr = e.next.ExportItems(ctx, items)
+	c.PrimaryDuration = time.Since(start)
+
+	wg.Wait()
+
+	c.Diverged = !e.equal(c.PrimaryErr, c.CandidateErr)
+
+	e.report(c)
+
+	return c.PrimaryErr
+}
+
+func (e *compareExporter[T]) Shutdown(ctx context.Context) error {
+	return errors.Join(e.next.Shutdown(ctx), e.candidate.Shutdown(ctx))
+}
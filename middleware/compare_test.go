@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	ctx := context.Background()
+	candidateErr := errors.New("candidate failed")
+
+	var comparisons []Comparison[int]
+
+	report := func(c Comparison[int]) {
+		comparisons = append(comparisons, c)
+	}
+
+	candidate := &countingExporter{}
+	exporter := Chain[int](&countingExporter{}, Compare[int](candidate, report))
+
+	if err := exporter.ExportItems(ctx, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	candidate.err = candidateErr
+
+	if err := exporter.ExportItems(ctx, nil); err != nil {
+		t.Fatalf("expected the candidate error not to be returned, got %v", err)
+	}
+
+	if len(comparisons) != 2 {
+		t.Fatalf("expected 2 comparisons, got %d", len(comparisons))
+	}
+
+	if comparisons[0].Diverged {
+		t.Error("expected matching results not to diverge")
+	}
+
+	if !comparisons[1].Diverged || !errors.Is(comparisons[1].CandidateErr, candidateErr) {
+		t.Errorf("expected the candidate failure to diverge, got %+v", comparisons[1])
+	}
+
+	// A custom comparison can treat any result as a match.
+	comparisons = nil
+	exporter = Chain[int](&countingExporter{}, Compare[int](candidate, report, WithResponseComparison(func(_, _ error) bool {
+		return true
+	})))
+
+	if err := exporter.ExportItems(ctx, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if comparisons[0].Diverged {
+		t.Error("expected the custom comparison to match")
+	}
+}
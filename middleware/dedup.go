@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+// DefaultDedupTTL is how long Dedup remembers exported items when given a
+// non-positive TTL.
+const DefaultDedupTTL = 5 * time.Minute
+
+// Dedup drops items whose key matches an item exported successfully within
+// the last ttl, so overlapping replays from upstream after a reconnect
+// aren't exported twice. Key items by content with
+// processor.KeyByJSONHash. Items are only remembered once their batch is
+// exported, so batches exported concurrently may still share items, and a
+// batch left empty isn't passed to the wrapped exporter at all.
+func Dedup[T any, K comparable](key processor.KeyFunc[T, K], ttl time.Duration) Middleware[T] {
+	if ttl <= 0 {
+		ttl = DefaultDedupTTL
+	}
+
+	return func(next processor.ItemExporter[T]) processor.ItemExporter[T] {
+		return &dedupExporter[T, K]{
+			next:     next,
+			key:      key,
+			ttl:      ttl,
+			exported: make(map[K]time.Time),
+		}
+	}
+}
+
+type dedupExporter[T any, K comparable] struct {
+	next processor.ItemExporter[T]
+	key  processor.KeyFunc[T, K]
+	ttl  time.Duration
+
+	mu        sync.Mutex
+	exported  map[K]time.Time
+	lastSweep time.Time
+}
+
+func (e *dedupExporter[T, K]) ExportItems(ctx context.Context, items []*T) error {
+	keys, fresh := e.filter(items)
+	if len(fresh) == 0 {
+		return nil
+	}
+
+	if err := e.next.ExportItems(ctx, fresh); err != nil {
+		return err
+	}
+
+	e.remember(keys)
+
+	return nil
+}
+
+// filter returns the items not exported within the TTL, without duplicates,
+// and their keys.
+func (e *dedupExporter[T, K]) filter(items []*T) ([]K, []*T) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	keys := make([]K, 0, len(items))
+	fresh := make([]*T, 0, len(items))
+	seen := make(map[K]struct{}, len(items))
+
+	for _, item := range items {
+		k := e.key(item)
+
+		if _, ok := seen[k]; ok {
+			continue
+		}
+
+		seen[k] = struct{}{}
+
+		if at, ok := e.exported[k]; ok && now.Sub(at) < e.ttl {
+			continue
+		}
+
+		keys = append(keys, k)
+		fresh = append(fresh, item)
+	}
+
+	return keys, fresh
+}
+
+// remember records keys as exported now, sweeping expired keys at most once
+// per TTL.
+func (e *dedupExporter[T, K]) remember(keys []K) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+
+	for _, k := range keys {
+		e.exported[k] = now
+	}
+
+	if now.Sub(e.lastSweep) < e.ttl {
+		return
+	}
+
+	e.lastSweep = now
+
+	for k, at := range e.exported {
+		if now.Sub(at) >= e.ttl {
+			delete(e.exported, k)
+		}
+	}
+}
+
+func (e *dedupExporter[T, K]) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
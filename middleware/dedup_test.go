@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+// batchExporter records the items of every export.
+type batchExporter struct {
+	batches [][]int
+	err     error
+}
+
+func (e *batchExporter) ExportItems(_ context.Context, items []*int) error {
+	batch := make([]int, 0, len(items))
+	for _, item := range items {
+		batch = append(batch, *item)
+	}
+
+	e.batches = append(e.batches, batch)
+
+	return e.err
+}
+
+func (e *batchExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func ints(values ...int) []*int {
+	items := make([]*int, len(values))
+	for i := range values {
+		items[i] = &values[i]
+	}
+
+	return items
+}
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+
+	inner := &batchExporter{}
+	exporter := Chain[int](inner, Dedup[int](processor.KeyByJSONHash[int](), 50*time.Millisecond))
+
+	for _, batch := range [][]*int{ints(1, 2, 2), ints(2, 3), ints(1, 2)} {
+		if err := exporter.ExportItems(ctx, batch); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// The last batch was entirely replayed, so it's never exported.
+	want := [][]int{{1, 2}, {3}}
+	if len(inner.batches) != len(want) {
+		t.Fatalf("expected batches %v, got %v", want, inner.batches)
+	}
+
+	for i := range want {
+		if len(inner.batches[i]) != len(want[i]) || inner.batches[i][0] != want[i][0] {
+			t.Fatalf("expected batches %v, got %v", want, inner.batches)
+		}
+	}
+
+	// Failed items aren't remembered.
+	inner.err = errors.New("export failed")
+
+	if err := exporter.ExportItems(ctx, ints(4)); !errors.Is(err, inner.err) {
+		t.Fatalf("expected the exporter's error, got %v", err)
+	}
+
+	inner.err = nil
+
+	if err := exporter.ExportItems(ctx, ints(4)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := len(inner.batches); got != 4 {
+		t.Fatalf("expected the failed item to be retried, got %d batches", got)
+	}
+
+	// Items expire after the TTL.
+	time.Sleep(60 * time.Millisecond)
+
+	if err := exporter.ExportItems(ctx, ints(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := len(inner.batches); got != 5 {
+		t.Fatalf("expected the expired item to be exported again, got %d batches", got)
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/sirupsen/logrus"
+)
+
+// LoggingOption configures Logging.
+type LoggingOption func(*loggingOptions)
+
+type loggingOptions struct {
+	level      logrus.Level
+	errorLevel logrus.Level
+	sampling   float64
+}
+
+// WithLogLevel sets the level successful exports are logged at. It defaults
+// to debug.
+func WithLogLevel(level logrus.Level) LoggingOption {
+	return func(o *loggingOptions) {
+		o.level = level
+	}
+}
+
+// WithErrorLogLevel sets the level failed exports are logged at. It defaults
+// to warn.
+func WithErrorLogLevel(level logrus.Level) LoggingOption {
+	return func(o *loggingOptions) {
+		o.errorLevel = level
+	}
+}
+
+// WithLogSampling logs only a fraction, between 0 and 1, of successful
+// exports. Failed exports are always logged.
+func WithLogSampling(fraction float64) LoggingOption {
+	return func(o *loggingOptions) {
+		o.sampling = fraction
+	}
+}
+
+// Logging logs the size, duration and outcome of every export, for quick
+// diagnostics of an exporter the processor's metrics don't see into, such
+// as one behind a fanout or a third party sink.
+func Logging[T any](log logrus.FieldLogger, opts ...LoggingOption) Middleware[T] {
+	o := loggingOptions{
+		level:      logrus.DebugLevel,
+		errorLevel: logrus.WarnLevel,
+		sampling:   1,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next processor.ItemExporter[T]) processor.ItemExporter[T] {
+		return &loggingExporter[T]{
+			next: next,
+			log:  log,
+			o:    o,
+		}
+	}
+}
+
+type loggingExporter[T any] struct {
+	next processor.ItemExporter[T]
+	log  logrus.FieldLogger
+	o    loggingOptions
+}
+
+func (e *loggingExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	start := time.Now()
+
+	err := e.next.ExportItems(ctx, items)
+
+	if err == nil && e.o.sampling < 1 && rand.Float64() >= e.o.sampling {
+		return nil
+	}
+
+	entry := e.log.WithFields(logrus.Fields{
+		"items":    len(items),
+		"duration": time.Since(start),
+	})
+
+	if err != nil {
+		entry.WithError(err).Log(e.o.errorLevel, "Export failed")
+	} else {
+		entry.Log(e.o.level, "Export succeeded")
+	}
+
+	return err
+}
+
+func (e *loggingExporter[T]) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogging(t *testing.T) {
+	ctx := context.Background()
+	log, hook := test.NewNullLogger()
+	log.SetLevel(logrus.DebugLevel)
+
+	inner := &countingExporter{}
+	exporter := Chain[int](inner, Logging[int](log, WithLogLevel(logrus.InfoLevel)))
+
+	one := 1
+
+	if err := exporter.ExportItems(ctx, []*int{&one}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.InfoLevel || entry.Data["items"] != 1 {
+		t.Fatalf("expected an info entry for 1 item, got %+v", entry)
+	}
+
+	inner.err = errors.New("export failed")
+
+	if err := exporter.ExportItems(ctx, nil); !errors.Is(err, inner.err) {
+		t.Fatalf("expected the exporter's error, got %v", err)
+	}
+
+	entry = hook.LastEntry()
+	if entry.Level != logrus.WarnLevel || !errors.Is(entry.Data[logrus.ErrorKey].(error), inner.err) {
+		t.Fatalf("expected a warn entry with the error, got %+v", entry)
+	}
+
+	// With no sampling only failures are logged.
+	hook.Reset()
+
+	inner.err = nil
+	exporter = Chain[int](inner, Logging[int](log, WithLogSampling(0)))
+
+	for range 10 {
+		if err := exporter.ExportItems(ctx, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if got := len(hook.AllEntries()); got != 0 {
+		t.Fatalf("expected no entries, got %d", got)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	exporterExports = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "exporter_exports_total",
+		Namespace: "batch_processor",
+		Help:      "Number of exports by a wrapped exporter, by outcome",
+	}, []string{"exporter", "outcome"})
+	exporterItems = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "exporter_items_total",
+		Namespace: "batch_processor",
+		Help:      "Number of items passed to a wrapped exporter, by outcome",
+	}, []string{"exporter", "outcome"})
+	exporterExportDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:      "exporter_export_duration_seconds",
+		Namespace: "batch_processor",
+		Help:      "Duration of exports by a wrapped exporter",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	}, []string{"exporter"})
+	exporterBatchSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:      "exporter_batch_size",
+		Namespace: "batch_processor",
+		Help:      "Size of batches passed to a wrapped exporter",
+		Buckets:   prometheus.ExponentialBucketsRange(1, 50000, 10),
+	}, []string{"exporter"})
+)
+
+func init() {
+	prometheus.MustRegister(exporterExports, exporterItems, exporterExportDuration, exporterBatchSize)
+}
+
+// Metrics records the duration, size and outcome of every export under the
+// given exporter label, so exporters nested behind failover, fanout or
+// other middleware get the visibility the processor's own metrics only give
+// the outermost one. Outcomes are "success" and "failure".
+func Metrics[T any](name string) Middleware[T] {
+	return func(next processor.ItemExporter[T]) processor.ItemExporter[T] {
+		return &metricsExporter[T]{
+			next:      next,
+			successes: exporterExports.WithLabelValues(name, "success"),
+			failures:  exporterExports.WithLabelValues(name, "failure"),
+			exported:  exporterItems.WithLabelValues(name, "success"),
+			failed:    exporterItems.WithLabelValues(name, "failure"),
+			duration:  exporterExportDuration.WithLabelValues(name),
+			size:      exporterBatchSize.WithLabelValues(name),
+		}
+	}
+}
+
+type metricsExporter[T any] struct {
+	next processor.ItemExporter[T]
+
+	successes prometheus.Counter
+	failures  prometheus.Counter
+	exported  prometheus.Counter
+	failed    prometheus.Counter
+	duration  prometheus.Observer
+	size      prometheus.Observer
+}
+
+func (e *metricsExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	start := time.Now()
+
+	err := e.next.ExportItems(ctx, items)
+
+	e.duration.Observe(time.Since(start).Seconds())
+	e.size.Observe(float64(len(items)))
+
+	if err != nil {
+		e.failures.Inc()
+		e.failed.Add(float64(len(items)))
+	} else {
+		e.successes.Inc()
+		e.exported.Add(float64(len(items)))
+	}
+
+	return err
+}
+
+func (e *metricsExporter[T]) Shutdown(ctx context.Context) error {
+	return e.next.Shutdown(ctx)
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+
+	return m.GetCounter().GetValue()
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	inner := &countingExporter{}
+	exporter := Chain[int](inner, Metrics[int]("metrics-test"))
+
+	one, two := 1, 2
+
+	if err := exporter.ExportItems(ctx, []*int{&one, &two}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inner.err = errors.New("export failed")
+
+	if err := exporter.ExportItems(ctx, []*int{&one}); !errors.Is(err, inner.err) {
+		t.Fatalf("expected the exporter's error, got %v", err)
+	}
+
+	for _, tc := range []struct {
+		counter prometheus.Counter
+		want    float64
+	}{
+		{exporterExports.WithLabelValues("metrics-test", "success"), 1},
+		{exporterExports.WithLabelValues("metrics-test", "failure"), 1},
+		{exporterItems.WithLabelValues("metrics-test", "success"), 2},
+		{exporterItems.WithLabelValues("metrics-test", "failure"), 1},
+	} {
+		if got := counterValue(t, tc.counter); got != tc.want {
+			t.Errorf("expected %v, got %v", tc.want, got)
+		}
+	}
+}
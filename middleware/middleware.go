@@ -0,0 +1,19 @@
+// Package middleware provides composable decorators for item exporters.
+package middleware
+
+import (
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+// Middleware wraps an exporter with additional behavior.
+type Middleware[T any] func(next processor.ItemExporter[T]) processor.ItemExporter[T]
+
+// Chain wraps exporter with the given middlewares. The first middleware is
+// the outermost, so it sees every batch first.
+func Chain[T any](exporter processor.ItemExporter[T], middlewares ...Middleware[T]) processor.ItemExporter[T] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		exporter = middlewares[i](exporter)
+	}
+
+	return exporter
+}
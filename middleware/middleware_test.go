@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+type orderExporter struct {
+	name  string
+	next  processor.ItemExporter[int]
+	calls *[]string
+}
+
+func (e *orderExporter) ExportItems(ctx context.Context, items []*int) error {
+	*e.calls = append(*e.calls, e.name)
+
+	if e.next == nil {
+		return nil
+	}
+
+	return e.next.ExportItems(ctx, items)
+}
+
+func (e *orderExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+
+	named := func(name string) Middleware[int] {
+		return func(next processor.ItemExporter[int]) processor.ItemExporter[int] {
+			return &orderExporter{name: name, next: next, calls: &calls}
+		}
+	}
+
+	exporter := Chain[int](&orderExporter{name: "exporter", calls: &calls}, named("first"), named("second"))
+
+	if err := exporter.ExportItems(context.Background(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"first", "second", "exporter"}
+	for i := range want {
+		if i >= len(calls) || calls[i] != want[i] {
+			t.Fatalf("expected calls %v, got %v", want, calls)
+		}
+	}
+}
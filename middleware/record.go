@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+// Metadata keys of recorded batch envelopes.
+const (
+	// RecordStartKey is when the export started, in RFC 3339 format.
+	RecordStartKey = "start"
+	// RecordDurationKey is how long the export took.
+	RecordDurationKey = "duration"
+	// RecordErrorKey is the export error, if any.
+	RecordErrorKey = "error"
+)
+
+// Record writes every batch passed to the exporter to w, with its timing and
+// result, so it can be fed back with Replay to reproduce an issue locally.
+// Batches are encoded with codec and written as processor.Envelope. Exports aren't failed by recording errors;
+// the first one is returned from Shutdown instead.
+func Record[T any](w io.Writer, codec processor.Codec[T]) Middleware[T] {
+	return func(next processor.ItemExporter[T]) processor.ItemExporter[T] {
+		return &recordingExporter[T]{next: next, w: w, codec: codec}
+	}
+}
+
+type recordingExporter[T any] struct {
+	next  processor.ItemExporter[T]
+	w     io.Writer
+	codec processor.Codec[T]
+
+	mu  sync.Mutex
+	err error
+}
+
+func (e *recordingExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	start := time.Now()
+	err := e.next.ExportItems(ctx, items)
+
+	e.record(start, time.Since(start), items, err)
+
+	return err
+}
+
+func (e *recordingExporter[T]) record(start time.Time, duration time.Duration, items []*T, exportErr error) {
+	err := e.write(start, duration, items, exportErr)
+	if err == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.err == nil {
+		e.err = fmt.Errorf("failed to record batch: %w", err)
+	}
+}
+
+func (e *recordingExporter[T]) write(start time.Time, duration time.Duration, items []*T, exportErr error) error {
+	payload, err := e.codec.Encode(items)
+	if err != nil {
+		return err
+	}
+
+	envelope := processor.Envelope{
+		Codec: processor.CodecName(e.codec),
+		Metadata: map[string]string{
+			RecordStartKey:    start.Format(time.RFC3339Nano),
+			RecordDurationKey: duration.String(),
+		},
+		Payload: payload,
+	}
+
+	if exportErr != nil {
+		envelope.Metadata[RecordErrorKey] = exportErr.Error()
+	}
+
+	data, err := envelope.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err = e.w.Write(data)
+
+	return err
+}
+
+func (e *recordingExporter[T]) Shutdown(ctx context.Context) error {
+	err := e.next.Shutdown(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return errors.Join(err, e.err)
+}
+
+// Replay feeds batches recorded by Record to feed, in order. feed is
+// typically a processor's Write or an exporter's ExportItems. With realtime,
+// batches are spaced out as they were recorded. Replay stops at the first
+// error returned by feed.
+func Replay[T any](
+	ctx context.Context,
+	r io.Reader,
+	codec processor.Codec[T],
+	feed func(ctx context.Context, items []*T) error,
+	realtime bool,
+) error {
+	br := bufio.NewReader(r)
+	name := processor.CodecName(codec)
+
+	var previous time.Time
+
+	for {
+		envelope, err := processor.ReadEnvelope(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return fmt.Errorf("failed to read recording: %w", err)
+		}
+
+		if envelope.Codec != name {
+			return fmt.Errorf("batch was recorded with codec %q, not %q", envelope.Codec, name)
+		}
+
+		start, err := time.Parse(time.RFC3339Nano, envelope.Metadata[RecordStartKey])
+		if err != nil {
+			return fmt.Errorf("invalid recorded batch start: %w", err)
+		}
+
+		if realtime && !previous.IsZero() {
+			if err := sleep(ctx, start.Sub(previous)); err != nil {
+				return err
+			}
+		}
+
+		previous = start
+
+		items, err := codec.Decode(envelope.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to decode recorded batch: %w", err)
+		}
+
+		if err := feed(ctx, items); err != nil {
+			return fmt.Errorf("failed to replay batch: %w", err)
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
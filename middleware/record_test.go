@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+type failingExporter struct {
+	err error
+}
+
+func (e *failingExporter) ExportItems(_ context.Context, _ []*int) error {
+	return e.err
+}
+
+func (e *failingExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	exportErr := errors.New("sink unavailable")
+
+	var recording bytes.Buffer
+
+	exporter := Chain[int](&failingExporter{}, Record[int](&recording, processor.JSONCodec[int]{}))
+
+	one, two, three := 1, 2, 3
+
+	if err := exporter.ExportItems(ctx, []*int{&one, &two}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	failing := Chain[int](&failingExporter{err: exportErr}, Record[int](&recording, processor.JSONCodec[int]{}))
+
+	if err := failing.ExportItems(ctx, []*int{&three}); !errors.Is(err, exportErr) {
+		t.Fatalf("expected export error, got %v", err)
+	}
+
+	var replayed [][]int
+
+	err := Replay(ctx, &recording, processor.JSONCodec[int]{}, func(_ context.Context, items []*int) error {
+		batch := make([]int, 0, len(items))
+		for _, item := range items {
+			batch = append(batch, *item)
+		}
+
+		replayed = append(replayed, batch)
+
+		return nil
+	}, true)
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+
+	want := [][]int{{1, 2}, {3}}
+
+	if len(replayed) != len(want) {
+		t.Fatalf("expected %d batches, got %d", len(want), len(replayed))
+	}
+
+	for i := range want {
+		if len(replayed[i]) != len(want[i]) {
+			t.Fatalf("batch %d: expected %v, got %v", i, want[i], replayed[i])
+		}
+
+		for j := range want[i] {
+			if replayed[i][j] != want[i][j] {
+				t.Errorf("batch %d: expected %v, got %v", i, want[i], replayed[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"sync"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+// Shadow mirrors a fraction, between 0 and 1, of batches to shadow, for
+// testing a new sink against live traffic. Shadow exports run in the
+// background with the batch's deadline and their errors are ignored, so
+// they never slow down or fail the wrapped exporter. At most one shadow
+// export runs at a time; batches sampled while it's busy are skipped. Shadow
+// exporters must not modify items.
+func Shadow[T any](shadow processor.ItemExporter[T], fraction float64) Middleware[T] {
+	return func(next processor.ItemExporter[T]) processor.ItemExporter[T] {
+		return &shadowExporter[T]{
+			next:     next,
+			shadow:   shadow,
+			fraction: fraction,
+			busy:     make(chan struct{}, 1),
+		}
+	}
+}
+
+type shadowExporter[T any] struct {
+	next     processor.ItemExporter[T]
+	shadow   processor.ItemExporter[T]
+	fraction float64
+
+	busy chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (e *shadowExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	if rand.Float64() < e.fraction {
+		e.mirror(ctx, items)
+	}
+
+	return e.next.ExportItems(ctx, items)
+}
+
+// mirror starts a shadow export unless one is already running.
+func (e *shadowExporter[T]) mirror(ctx context.Context, items []*T) {
+	select {
+	case e.busy <- struct{}{}:
+	default:
+		return
+	}
+
+	shadowCtx, cancel := detach(ctx)
+
+	// The processor may reuse the slice once the export returns.
+	items = slices.Clone(items)
+
+	e.wg.Add(1)
+
+	go func() {
+		defer e.wg.Done()
+		defer func() { <-e.busy }()
+		defer cancel()
+
+		_ = e.shadow.ExportItems(shadowCtx, items)
+	}()
+}
+
+func (e *shadowExporter[T]) Shutdown(ctx context.Context) error {
+	e.wg.Wait()
+
+	return errors.Join(e.next.Shutdown(ctx), e.shadow.Shutdown(ctx))
+}
+
+// detach returns a context that outlives ctx's cancellation but keeps its
+// values and deadline, for work that continues after an export returns.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+
+	return context.WithCancel(detached)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+type countingExporter struct {
+	calls atomic.Int64
+	err   error
+}
+
+func (e *countingExporter) ExportItems(_ context.Context, _ []*int) error {
+	e.calls.Add(1)
+
+	return e.err
+}
+
+func (e *countingExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestShadow(t *testing.T) {
+	ctx := context.Background()
+
+	primary := &countingExporter{}
+	shadow := &countingExporter{err: errors.New("shadow failed")}
+
+	exporter := Chain[int](primary, Shadow[int](shadow, 1))
+
+	if err := exporter.ExportItems(ctx, nil); err != nil {
+		t.Fatalf("expected shadow errors to be ignored, got %v", err)
+	}
+
+	// Shutdown waits for the shadow export.
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if got := primary.calls.Load(); got != 1 {
+		t.Errorf("expected 1 primary export, got %d", got)
+	}
+
+	if got := shadow.calls.Load(); got != 1 {
+		t.Errorf("expected 1 shadow export, got %d", got)
+	}
+
+	none := &countingExporter{}
+
+	exporter = Chain[int](&countingExporter{}, Shadow[int](none, 0))
+
+	if err := exporter.ExportItems(ctx, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if got := none.calls.Load(); got != 0 {
+		t.Errorf("expected no shadow exports with a zero fraction, got %d", got)
+	}
+}
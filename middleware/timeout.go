@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+// ErrExportTimeout matches, with errors.Is, the *TimeoutError returned when
+// an export overruns its deadline.
+var ErrExportTimeout = errors.New("export timed out")
+
+// TimeoutError is returned by Timeout when the wrapped exporter doesn't
+// finish in time. It unwraps to context.DeadlineExceeded and, like a
+// net.Error, reports itself as a temporary timeout, so retry policies can
+// classify it without knowing about this package.
+type TimeoutError struct {
+	// After is the timeout that was exceeded.
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s after %s", ErrExportTimeout, e.After)
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrExportTimeout
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// Timeout reports that the error is a timeout.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary reports that the export may succeed if retried.
+func (e *TimeoutError) Temporary() bool {
+	return true
+}
+
+// Timeout bounds every export by timeout, independently of the processor's
+// export timeout, so one slow sink can be held to a tighter deadline than
+// the others. The wrapped exporter's context is cancelled at the deadline
+// and, if the exporter ignores it, the export is abandoned and a
+// *TimeoutError returned anyway. Abandoned exports keep running in the
+// background with a copy of the batch; Shutdown waits for them until its
+// context is done. Overruns of a deadline set by the caller's context are
+// returned as they are.
+func Timeout[T any](timeout time.Duration) Middleware[T] {
+	return func(next processor.ItemExporter[T]) processor.ItemExporter[T] {
+		return &timeoutExporter[T]{
+			next:    next,
+			timeout: timeout,
+		}
+	}
+}
+
+type timeoutExporter[T any] struct {
+	next    processor.ItemExporter[T]
+	timeout time.Duration
+
+	wg sync.WaitGroup
+}
+
+func (e *timeoutExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	exportCtx, cancel := context.WithTimeout(ctx, e.timeout)
+
+	// The processor may reuse the slice once the export returns, even if
+	// the wrapped exporter is still running.
+	items = slices.Clone(items)
+	done := make(chan error, 1)
+
+	e.wg.Add(1)
+
+	go func() {
+		defer e.wg.Done()
+		defer cancel()
+
+		done <- e.next.ExportItems(exportCtx, items)
+	}()
+
+	var err error
+
+	select {
+	case err = <-done:
+	case <-exportCtx.Done():
+		// Prefer the result if the exporter returned at the deadline.
+		select {
+		case err = <-done:
+		default:
+			err = exportCtx.Err()
+		}
+	}
+
+	if ctx.Err() == nil && errors.Is(exportCtx.Err(), context.DeadlineExceeded) && errors.Is(err, context.DeadlineExceeded) {
+		return &TimeoutError{After: e.timeout}
+	}
+
+	return err
+}
+
+// Shutdown waits for abandoned exports until ctx is done, then shuts down
+// the wrapped exporter.
+func (e *timeoutExporter[T]) Shutdown(ctx context.Context) error {
+	abandoned := make(chan struct{})
+
+	go func() {
+		e.wg.Wait()
+		close(abandoned)
+	}()
+
+	select {
+	case <-abandoned:
+	case <-ctx.Done():
+		return errors.Join(ctx.Err(), e.next.Shutdown(ctx))
+	}
+
+	return e.next.Shutdown(ctx)
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stuckExporter ignores its context until released.
+type stuckExporter struct {
+	release chan struct{}
+}
+
+func (e *stuckExporter) ExportItems(_ context.Context, _ []*int) error {
+	<-e.release
+
+	return nil
+}
+
+func (e *stuckExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	stuck := &stuckExporter{release: make(chan struct{})}
+	exporter := Chain[int](stuck, Timeout[int](10*time.Millisecond))
+
+	err := exporter.ExportItems(ctx, nil)
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || !errors.Is(err, ErrExportTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+
+	if !timeoutErr.Temporary() || !timeoutErr.Timeout() {
+		t.Error("expected the timeout to be classified as a temporary timeout")
+	}
+
+	// The abandoned export holds up shutdown until released or the
+	// shutdown context is done.
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	if err := exporter.Shutdown(shutdownCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected shutdown to wait for the abandoned export, got %v", err)
+	}
+
+	close(stuck.release)
+
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	failing := &countingExporter{err: errors.New("export failed")}
+	exporter = Chain[int](failing, Timeout[int](time.Second))
+
+	if err := exporter.ExportItems(ctx, nil); !errors.Is(err, failing.err) {
+		t.Fatalf("expected the exporter's own error, got %v", err)
+	}
+
+	// The caller's deadline isn't reported as the middleware's timeout.
+	stuck = &stuckExporter{release: make(chan struct{})}
+	defer close(stuck.release)
+
+	callerCtx, cancelCaller := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancelCaller()
+
+	err = Chain[int](stuck, Timeout[int](time.Second)).ExportItems(callerCtx, nil)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrExportTimeout) {
+		t.Fatalf("expected the caller's deadline, got %v", err)
+	}
+}
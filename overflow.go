@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"context"
+)
+
+// ItemWriter accepts items for processing. BatchItemProcessor implements it.
+type ItemWriter[T any] interface {
+	Write(ctx context.Context, items []*T) error
+}
+
+var _ ItemWriter[any] = (*BatchItemProcessor[any])(nil)
+
+// WithOverflow writes items the queue can't hold to overflow, typically a
+// second processor exporting to cheap cold storage, instead of dropping them.
+// Items are only dropped if overflow fails too. Overflow is written to from
+// the writing goroutine, one item at a time, and isn't shut down with the
+// processor.
+func WithOverflow[T any](overflow ItemWriter[T]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.Overflow = overflow
+	}
+}
+
+// overflowItem writes an item the queue can't hold to the overflow writer. It
+// returns false if there is no overflow writer or it failed. Sync writers
+// waiting on the item are released with the overflow result.
+func (bvp *BatchItemProcessor[T]) overflowItem(item *TraceableItem[T]) bool {
+	if bvp.overflow == nil {
+		return false
+	}
+
+	if err := bvp.overflow.Write(context.Background(), []*T{item.item}); err != nil {
+		bvp.log.WithError(err).Debug("Failed to write item to the overflow")
+
+		return false
+	}
+
+	bvp.metrics.IncItemsOverflowedBy(bvp.label, float64(1))
+
+	if item.errCh != nil {
+		item.errCh <- nil
+		close(item.errCh)
+	}
+
+	if item.completedCh != nil {
+		item.completedCh <- struct{}{}
+		close(item.completedCh)
+	}
+
+	return true
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_Overflow(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	ctx := context.Background()
+	coldExporter := &mockExporter[int]{}
+
+	cold, err := NewBatchItemProcessor[int](
+		coldExporter,
+		"cold",
+		log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(1),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create overflow processor: %v", err)
+	}
+
+	if err := cold.Start(ctx); err != nil {
+		t.Fatalf("failed to start overflow processor: %v", err)
+	}
+
+	defer cold.Shutdown(ctx)
+
+	proc, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{},
+		"test",
+		log,
+		WithMaxQueueSize(1),
+		WithMaxExportBatchSize(1),
+		WithWorkers(1),
+		WithOverflow[int](cold),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	items := make([]*int, 3)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	overflowed := counterValue(t, DefaultMetrics.itemsOverflowed.WithLabelValues("test"))
+	dropped := counterValue(t, DefaultMetrics.itemsDropped.WithLabelValues("test"))
+
+	// The processor isn't started, so only the first item fits in the queue.
+	errs, err := proc.WriteEach(ctx, items)
+	if err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("item %d: expected no error, got %v", i, err)
+		}
+	}
+
+	if got := coldExporter.exportCount.Load(); got != 2 {
+		t.Fatalf("expected 2 items exported by the overflow, got %d", got)
+	}
+
+	if got := counterValue(t, DefaultMetrics.itemsOverflowed.WithLabelValues("test")) - overflowed; got != 2 {
+		t.Errorf("expected 2 overflowed items, got %v", got)
+	}
+
+	if got := counterValue(t, DefaultMetrics.itemsDropped.WithLabelValues("test")) - dropped; got != 0 {
+		t.Errorf("expected no dropped items, got %v", got)
+	}
+}
@@ -0,0 +1,52 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WriteAcceptance is what Write does with the rest of a write once the queue
+// fills up part way through it.
+type WriteAcceptance string
+
+const (
+	// WriteAcceptanceAll fails the write with ErrQueueFull at the first
+	// item that doesn't fit, without telling the caller which were queued.
+	WriteAcceptanceAll WriteAcceptance = "all"
+	// WriteAcceptancePartial queues as many items as fit and fails the write
+	// with a *PartialWriteError holding the rest.
+	WriteAcceptancePartial WriteAcceptance = "partial"
+)
+
+// WithWriteAcceptance sets what Write does when a write holds more items
+// than the queue has room for. With WriteAcceptancePartial the items that
+// fit are queued, and exported before Write returns with the sync shipping
+// method, and the caller gets the remainder back to retry or shed.
+func WithWriteAcceptance(acceptance WriteAcceptance) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.WriteAcceptance = acceptance
+	}
+}
+
+// PartialWriteError is returned by Write with WriteAcceptancePartial when the
+// queue filled up part way through a write. Every item written before
+// Remaining was accepted. It matches ErrQueueFull.
+type PartialWriteError[T any] struct {
+	// Remaining holds the items that weren't queued, in the order written.
+	Remaining []*T
+}
+
+func (e *PartialWriteError[T]) Error() string {
+	return fmt.Sprintf("%s: %d items not written", ErrQueueFull, len(e.Remaining))
+}
+
+// Unwrap returns ErrQueueFull.
+func (e *PartialWriteError[T]) Unwrap() error {
+	return ErrQueueFull
+}
+
+// acceptsPartialWrites reports whether a write failing with err should be
+// reported as a partial write.
+func (bvp *BatchItemProcessor[T]) acceptsPartialWrites(err error) bool {
+	return bvp.o.WriteAcceptance == WriteAcceptancePartial && errors.Is(err, ErrQueueFull)
+}
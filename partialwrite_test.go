@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_PartialWrite(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	for _, acceptance := range []WriteAcceptance{WriteAcceptanceAll, WriteAcceptancePartial} {
+		t.Run(string(acceptance), func(t *testing.T) {
+			proc, err := NewBatchItemProcessor[int](
+				&mockExporter[int]{},
+				"test",
+				log,
+				WithMaxQueueSize(2),
+				WithMaxExportBatchSize(2),
+				WithBatchTimeout(10*time.Second),
+				WithWorkers(1),
+				WithWriteAcceptance(acceptance),
+			)
+			if err != nil {
+				t.Fatalf("failed to create processor: %v", err)
+			}
+
+			// The processor isn't started, so only two items fit in the
+			// queue.
+			items := ints(4)
+
+			err = proc.Write(context.Background(), items)
+			if !errors.Is(err, ErrQueueFull) {
+				t.Fatalf("expected the queue full, got %v", err)
+			}
+
+			var partial *PartialWriteError[int]
+			if !errors.As(err, &partial) {
+				if acceptance == WriteAcceptancePartial {
+					t.Fatalf("expected a partial write, got %v", err)
+				}
+
+				return
+			}
+
+			if acceptance != WriteAcceptancePartial {
+				t.Fatalf("expected no partial write, got %v", err)
+			}
+
+			if len(partial.Remaining) != 2 || partial.Remaining[0] != items[2] || partial.Remaining[1] != items[3] {
+				t.Fatalf("expected the last 2 items remaining, got %v", partial.Remaining)
+			}
+		})
+	}
+}
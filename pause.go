@@ -0,0 +1,62 @@
+package processor
+
+import "context"
+
+// PauseExports holds back exports, for example through a short downstream
+// maintenance window, while writes are still accepted. Items queue up to
+// MaxQueueSize and are then dropped, or held back as configured, as usual.
+// Exports already in flight finish. Exports resume with ResumeExports, or
+// when the processor shuts down.
+func (bvp *BatchItemProcessor[T]) PauseExports() {
+	bvp.pauseMu.Lock()
+	defer bvp.pauseMu.Unlock()
+
+	if bvp.resumed != nil {
+		return
+	}
+
+	bvp.resumed = make(chan struct{})
+
+	bvp.log.Info("Paused exports")
+}
+
+// ResumeExports resumes exports paused by PauseExports.
+func (bvp *BatchItemProcessor[T]) ResumeExports() {
+	bvp.pauseMu.Lock()
+	defer bvp.pauseMu.Unlock()
+
+	if bvp.resumed == nil {
+		return
+	}
+
+	close(bvp.resumed)
+	bvp.resumed = nil
+
+	bvp.log.Info("Resumed exports")
+}
+
+// ExportsPaused reports whether exports are paused by PauseExports.
+func (bvp *BatchItemProcessor[T]) ExportsPaused() bool {
+	bvp.pauseMu.Lock()
+	defer bvp.pauseMu.Unlock()
+
+	return bvp.resumed != nil
+}
+
+// waitExportsResumed blocks while exports are paused, until the processor
+// shuts down or ctx is done.
+func (bvp *BatchItemProcessor[T]) waitExportsResumed(ctx context.Context) {
+	bvp.pauseMu.Lock()
+	resumed := bvp.resumed
+	bvp.pauseMu.Unlock()
+
+	if resumed == nil {
+		return
+	}
+
+	select {
+	case <-resumed:
+	case <-bvp.stopCh:
+	case <-ctx.Done():
+	}
+}
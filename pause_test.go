@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_PauseExports(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(5),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	proc.PauseExports()
+
+	if !proc.ExportsPaused() {
+		t.Fatal("expected exports paused")
+	}
+
+	// Writes are still accepted while exports are paused.
+	if err := proc.Write(ctx, ints(10)); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if got := exporter.exportCount.Load(); got != 0 {
+		t.Fatalf("expected nothing exported while paused, got %d items", got)
+	}
+
+	proc.ResumeExports()
+
+	drainCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := proc.Drain(drainCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 10 {
+		t.Fatalf("expected 10 items exported once resumed, got %d", got)
+	}
+
+	// Shutting down resumes paused exports.
+	proc.PauseExports()
+
+	if err := proc.Write(ctx, ints(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 15 {
+		t.Fatalf("expected 15 items exported by shutdown, got %d", got)
+	}
+}
@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/ethpandaops/go-batch-processor/registry"
+)
+
+// Config describes a whole pipeline. It carries both json and yaml tags, so it
+// can be decoded from either format; ParseConfig handles JSON.
+type Config struct {
+	// Name is the processor name, used in logs and metrics.
+	Name string `json:"name" yaml:"name"`
+	// Processor configures the batch item processor.
+	Processor ProcessorConfig `json:"processor" yaml:"processor"`
+	// Source optionally feeds items in to the pipeline. Without a source,
+	// items are written with Pipeline.Write.
+	Source *ComponentConfig `json:"source,omitempty" yaml:"source,omitempty"`
+	// Transforms are applied to every item, in order, before it is queued.
+	Transforms []ComponentConfig `json:"transforms,omitempty" yaml:"transforms,omitempty"`
+	// Triggers are custom flush conditions.
+	Triggers []ComponentConfig `json:"triggers,omitempty" yaml:"triggers,omitempty"`
+	// Middleware wraps the exporter. The first entry is the outermost.
+	Middleware []ComponentConfig `json:"middleware,omitempty" yaml:"middleware,omitempty"`
+	// Exporter is the sink items are exported to.
+	Exporter ComponentConfig `json:"exporter" yaml:"exporter"`
+}
+
+// ComponentConfig names a registered component and its configuration.
+type ComponentConfig struct {
+	// Type is the name the component's factory is registered under.
+	Type string `json:"type" yaml:"type"`
+	// Config is passed to the component's factory.
+	Config map[string]any `json:"config,omitempty" yaml:"config,omitempty"`
+}
+
+// ProcessorConfig mirrors processor.BatchItemProcessorOptions. Zero values
+// keep the processor defaults.
+type ProcessorConfig struct {
+	MaxQueueSize       int                      `json:"max_queue_size,omitempty" yaml:"max_queue_size,omitempty"`
+	MaxExportBatchSize int                      `json:"max_export_batch_size,omitempty" yaml:"max_export_batch_size,omitempty"`
+	BatchTimeout       registry.Duration        `json:"batch_timeout,omitempty" yaml:"batch_timeout,omitempty"`
+	ExportTimeout      registry.Duration        `json:"export_timeout,omitempty" yaml:"export_timeout,omitempty"`
+	Workers            int                      `json:"workers,omitempty" yaml:"workers,omitempty"`
+	ShippingMethod     processor.ShippingMethod `json:"shipping_method,omitempty" yaml:"shipping_method,omitempty"`
+}
+
+// ParseConfig decodes a JSON pipeline config.
+func ParseConfig(data []byte) (Config, error) {
+	var cfg Config
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse pipeline config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// options converts the config in to processor options.
+func (c ProcessorConfig) options() []processor.BatchItemProcessorOption {
+	var opts []processor.BatchItemProcessorOption
+
+	if c.MaxQueueSize > 0 {
+		opts = append(opts, processor.WithMaxQueueSize(c.MaxQueueSize))
+	}
+
+	if c.MaxExportBatchSize > 0 {
+		opts = append(opts, processor.WithMaxExportBatchSize(c.MaxExportBatchSize))
+	}
+
+	if c.BatchTimeout > 0 {
+		opts = append(opts, processor.WithBatchTimeout(time.Duration(c.BatchTimeout)))
+	}
+
+	if c.ExportTimeout > 0 {
+		opts = append(opts, processor.WithExportTimeout(time.Duration(c.ExportTimeout)))
+	}
+
+	if c.Workers > 0 {
+		opts = append(opts, processor.WithWorkers(c.Workers))
+	}
+
+	if c.ShippingMethod != "" {
+		opts = append(opts, processor.WithShippingMethod(c.ShippingMethod))
+	}
+
+	return opts
+}
@@ -0,0 +1,187 @@
+// Package pipeline builds a complete batching pipeline (source, transforms,
+// processor, middleware and exporter) from a single configuration document.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/ethpandaops/go-batch-processor/middleware"
+	"github.com/ethpandaops/go-batch-processor/registry"
+	"github.com/sirupsen/logrus"
+)
+
+// WriteFunc writes items in to a pipeline.
+type WriteFunc[T any] func(ctx context.Context, items []*T) error
+
+// Source produces items for a pipeline.
+type Source[T any] interface {
+	// Run writes items with write until ctx is cancelled or the source is
+	// exhausted.
+	Run(ctx context.Context, write WriteFunc[T]) error
+}
+
+// Transform maps an item before it is queued. Returning nil drops the item.
+type Transform[T any] func(item *T) *T
+
+// Components holds every registry a pipeline config can reference.
+type Components[T any] struct {
+	*registry.Components[T]
+
+	Sources    *registry.Registry[Source[T]]
+	Transforms *registry.Registry[Transform[T]]
+}
+
+// NewComponents creates the registries for a pipeline, with the built-in
+// backoff strategies registered.
+func NewComponents[T any]() *Components[T] {
+	return &Components[T]{
+		Components: registry.NewComponents[T](),
+		Sources:    registry.New[Source[T]]("source"),
+		Transforms: registry.New[Transform[T]]("transform"),
+	}
+}
+
+// Pipeline is a processor wired to its source, transforms and exporter.
+type Pipeline[T any] struct {
+	Processor *processor.BatchItemProcessor[T]
+
+	log        logrus.FieldLogger
+	source     Source[T]
+	transforms []Transform[T]
+	sourceDone chan struct{}
+	cancel     context.CancelFunc
+}
+
+// Build constructs a pipeline from cfg, resolving every component through
+// components.
+func Build[T any](cfg Config, components *Components[T], log logrus.FieldLogger) (*Pipeline[T], error) {
+	if cfg.Exporter.Type == "" {
+		return nil, errors.New("pipeline exporter type is required")
+	}
+
+	exporter, err := components.Exporters.Build(cfg.Exporter.Type, cfg.Exporter.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	middlewares := make([]middleware.Middleware[T], 0, len(cfg.Middleware))
+
+	for _, c := range cfg.Middleware {
+		m, err := components.Middleware.Build(c.Type, c.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		middlewares = append(middlewares, m)
+	}
+
+	opts := cfg.Processor.options()
+
+	for _, c := range cfg.Triggers {
+		trigger, err := components.Triggers.Build(c.Type, c.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, processor.WithTrigger(trigger))
+	}
+
+	p := &Pipeline[T]{
+		log:        log.WithField("pipeline", cfg.Name),
+		transforms: make([]Transform[T], 0, len(cfg.Transforms)),
+	}
+
+	for _, c := range cfg.Transforms {
+		transform, err := components.Transforms.Build(c.Type, c.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		p.transforms = append(p.transforms, transform)
+	}
+
+	if cfg.Source != nil {
+		if p.source, err = components.Sources.Build(cfg.Source.Type, cfg.Source.Config); err != nil {
+			return nil, err
+		}
+	}
+
+	p.Processor, err = processor.NewBatchItemProcessor[T](
+		middleware.Chain(exporter, middlewares...),
+		cfg.Name,
+		log,
+		opts...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build pipeline processor: %w", err)
+	}
+
+	return p, nil
+}
+
+// Start starts the processor and, if configured, the source.
+func (p *Pipeline[T]) Start(ctx context.Context) error {
+	if err := p.Processor.Start(ctx); err != nil {
+		return err
+	}
+
+	if p.source == nil {
+		return nil
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.sourceDone = make(chan struct{})
+
+	go func() {
+		defer close(p.sourceDone)
+
+		if err := p.source.Run(ctx, p.Write); err != nil && !errors.Is(err, context.Canceled) {
+			p.log.WithError(err).Error("Pipeline source failed")
+		}
+	}()
+
+	return nil
+}
+
+// Write applies the transforms to items and writes the survivors to the processor.
+func (p *Pipeline[T]) Write(ctx context.Context, items []*T) error {
+	if len(p.transforms) == 0 {
+		return p.Processor.Write(ctx, items)
+	}
+
+	transformed := make([]*T, 0, len(items))
+
+	for _, item := range items {
+		for _, transform := range p.transforms {
+			if item == nil {
+				break
+			}
+
+			item = transform(item)
+		}
+
+		if item != nil {
+			transformed = append(transformed, item)
+		}
+	}
+
+	return p.Processor.Write(ctx, transformed)
+}
+
+// Shutdown stops the source, then shuts down the processor.
+func (p *Pipeline[T]) Shutdown(ctx context.Context) error {
+	if p.cancel != nil {
+		p.cancel()
+
+		select {
+		case <-p.sourceDone:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return p.Processor.Shutdown(ctx)
+}
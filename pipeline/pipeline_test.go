@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/sirupsen/logrus"
+)
+
+type collectExporter struct {
+	mu    sync.Mutex
+	items []int
+}
+
+func (e *collectExporter) ExportItems(_ context.Context, items []*int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, item := range items {
+		e.items = append(e.items, *item)
+	}
+
+	return nil
+}
+
+func (e *collectExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func (e *collectExporter) collected() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]int(nil), e.items...)
+}
+
+func TestBuild(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	cfg, err := ParseConfig([]byte(`{
+		"name": "test_pipeline",
+		"processor": {"max_export_batch_size": 10, "batch_timeout": "20ms", "workers": 1},
+		"source": {"type": "events"},
+		"transforms": [{"type": "drop_odd"}, {"type": "double"}],
+		"exporter": {"type": "collect"}
+	}`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+
+	exporter := &collectExporter{}
+	components := NewComponents[int]()
+
+	components.Exporters.MustRegister("collect", func(_ map[string]any) (processor.ItemExporter[int], error) {
+		return exporter, nil
+	})
+	components.Transforms.MustRegister("drop_odd", func(_ map[string]any) (Transform[int], error) {
+		return func(item *int) *int {
+			if *item%2 == 1 {
+				return nil
+			}
+
+			return item
+		}, nil
+	})
+	components.Transforms.MustRegister("double", func(_ map[string]any) (Transform[int], error) {
+		return func(item *int) *int {
+			doubled := *item * 2
+
+			return &doubled
+		}, nil
+	})
+
+	events := make(chan *int, 10)
+	if err := RegisterChannelSource(components, "events", events, 5); err != nil {
+		t.Fatalf("failed to register source: %v", err)
+	}
+
+	p, err := Build(cfg, components, log)
+	if err != nil {
+		t.Fatalf("failed to build pipeline: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := p.Start(ctx); err != nil {
+		t.Fatalf("failed to start pipeline: %v", err)
+	}
+
+	for i := 0; i < 6; i++ {
+		v := i
+		events <- &v
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if err := p.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	got := exporter.collected()
+	want := []int{0, 4, 8}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestBuild_UnknownExporter(t *testing.T) {
+	_, err := Build(Config{Exporter: ComponentConfig{Type: "missing"}}, NewComponents[int](), logrus.New())
+	if err == nil {
+		t.Error("expected unknown exporter to fail")
+	}
+}
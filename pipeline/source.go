@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"context"
+)
+
+// ChannelSource reads items from a channel and writes them in groups of up to
+// size items, writing early whenever the channel has no more items ready.
+type ChannelSource[T any] struct {
+	ch   <-chan *T
+	size int
+}
+
+// NewChannelSource creates a source reading from ch. Register it with
+// RegisterChannelSource to reference it from configuration.
+func NewChannelSource[T any](ch <-chan *T, size int) *ChannelSource[T] {
+	if size < 1 {
+		size = 1
+	}
+
+	return &ChannelSource[T]{ch: ch, size: size}
+}
+
+// Run reads from the channel until it is closed or ctx is cancelled.
+func (s *ChannelSource[T]) Run(ctx context.Context, write WriteFunc[T]) error {
+	for {
+		var items []*T
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case item, ok := <-s.ch:
+			if !ok {
+				return nil
+			}
+
+			items = append(items, item)
+		}
+
+	fill:
+		for len(items) < s.size {
+			select {
+			case item, ok := <-s.ch:
+				if !ok {
+					break fill
+				}
+
+				items = append(items, item)
+			default:
+				break fill
+			}
+		}
+
+		if err := write(ctx, items); err != nil {
+			return err
+		}
+	}
+}
+
+// RegisterChannelSource registers ch under name. Channels only exist in
+// code, so the source ignores its config.
+func RegisterChannelSource[T any](components *Components[T], name string, ch <-chan *T, size int) error {
+	source := NewChannelSource(ch, size)
+
+	return components.Sources.Register(name, func(_ map[string]any) (Source[T], error) {
+		return source, nil
+	})
+}
@@ -0,0 +1,244 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultExporterPoolSize is the default maximum number of exporter instances
+// in an ExporterPool.
+const DefaultExporterPoolSize = 4
+
+// ErrPoolClosed is returned when exporting through a pool that was shut down.
+var ErrPoolClosed = errors.New("exporter pool is closed")
+
+// ExporterPool is an ItemExporter that lends an exporter instance out for each
+// batch. Unlike WithExporterFactory, instances aren't tied to workers: they are
+// created on demand up to the pool size, reused across workers, and evicted
+// when they reach their maximum lifetime or fail a health check. Instances are
+// only used by one batch at a time, so they need no internal locking.
+type ExporterPool[T any] struct {
+	factory ExporterFactory[T]
+	o       exporterPoolOptions
+
+	// slots holds a token for every borrowed instance, bounding the pool size.
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []*pooledExporter[T]
+	nextID int
+	closed bool
+}
+
+var _ ItemExporter[any] = (*ExporterPool[any])(nil)
+
+type pooledExporter[T any] struct {
+	exporter ItemExporter[T]
+	created  time.Time
+}
+
+type exporterPoolOptions struct {
+	size        int
+	maxLifetime time.Duration
+}
+
+// ExporterPoolOption is a functional option for ExporterPool.
+type ExporterPoolOption func(o *exporterPoolOptions)
+
+// WithPoolSize sets the maximum number of exporter instances. Exports wait for
+// an instance when all are borrowed.
+func WithPoolSize(size int) ExporterPoolOption {
+	return func(o *exporterPoolOptions) {
+		o.size = size
+	}
+}
+
+// WithPoolMaxLifetime evicts instances once they are older than lifetime, so
+// long-lived connections are periodically re-established. Zero keeps instances
+// forever.
+func WithPoolMaxLifetime(lifetime time.Duration) ExporterPoolOption {
+	return func(o *exporterPoolOptions) {
+		o.maxLifetime = lifetime
+	}
+}
+
+// NewExporterPool creates a pool of exporters created by factory. Instances
+// implementing ExporterStarter are started when created. Instances
+// implementing HealthChecker are checked before being lent out, and replaced
+// if the check fails.
+func NewExporterPool[T any](factory ExporterFactory[T], options ...ExporterPoolOption) (*ExporterPool[T], error) {
+	if factory == nil {
+		return nil, errors.New("exporter factory is nil")
+	}
+
+	o := exporterPoolOptions{
+		size: DefaultExporterPoolSize,
+	}
+
+	for _, opt := range options {
+		opt(&o)
+	}
+
+	if o.size < 1 {
+		return nil, errors.New("pool size must be at least one")
+	}
+
+	if o.maxLifetime < 0 {
+		return nil, errors.New("pool max lifetime must not be negative")
+	}
+
+	return &ExporterPool[T]{
+		factory: factory,
+		o:       o,
+		slots:   make(chan struct{}, o.size),
+	}, nil
+}
+
+// ExportItems borrows an instance, exports items with it and returns it to
+// the pool.
+func (p *ExporterPool[T]) ExportItems(ctx context.Context, items []*T) error {
+	pe, err := p.borrow(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = pe.exporter.ExportItems(ctx, items)
+
+	p.release(ctx, pe)
+
+	return err
+}
+
+// Shutdown shuts down idle instances. Borrowed instances are shut down when
+// they are returned.
+func (p *ExporterPool[T]) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	errs := make([]error, 0, len(idle))
+
+	for _, pe := range idle {
+		if err := pe.exporter.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// borrow takes an idle instance, or creates one if none is usable.
+func (p *ExporterPool[T]) borrow(ctx context.Context) (*pooledExporter[T], error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		pe, id, err := p.takeIdle()
+		if err != nil {
+			<-p.slots
+
+			return nil, err
+		}
+
+		if pe == nil {
+			exporter, err := p.factory(id)
+			if err == nil && exporter == nil {
+				err = errors.New("factory returned a nil exporter")
+			}
+
+			if err == nil {
+				err = startExporter(ctx, exporter)
+			}
+
+			if err != nil {
+				<-p.slots
+
+				return nil, err
+			}
+
+			return &pooledExporter[T]{exporter: exporter, created: time.Now()}, nil
+		}
+
+		if p.usable(ctx, pe) {
+			return pe, nil
+		}
+
+		p.discard(ctx, pe)
+	}
+}
+
+// takeIdle pops the most recently used idle instance. If there is none, it
+// reserves the id for a new instance instead.
+func (p *ExporterPool[T]) takeIdle() (*pooledExporter[T], int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, 0, ErrPoolClosed
+	}
+
+	if n := len(p.idle); n > 0 {
+		pe := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+
+		return pe, 0, nil
+	}
+
+	id := p.nextID
+	p.nextID++
+
+	return nil, id, nil
+}
+
+// release returns a borrowed instance to the pool.
+func (p *ExporterPool[T]) release(ctx context.Context, pe *pooledExporter[T]) {
+	defer func() { <-p.slots }()
+
+	if p.expired(pe) {
+		p.discard(ctx, pe)
+
+		return
+	}
+
+	p.mu.Lock()
+
+	if p.closed {
+		p.mu.Unlock()
+		p.discard(ctx, pe)
+
+		return
+	}
+
+	p.idle = append(p.idle, pe)
+	p.mu.Unlock()
+}
+
+func (p *ExporterPool[T]) expired(pe *pooledExporter[T]) bool {
+	return p.o.maxLifetime > 0 && time.Since(pe.created) >= p.o.maxLifetime
+}
+
+// usable reports whether an idle instance can be lent out.
+func (p *ExporterPool[T]) usable(ctx context.Context, pe *pooledExporter[T]) bool {
+	if p.expired(pe) {
+		return false
+	}
+
+	checker, ok := pe.exporter.(HealthChecker)
+	if !ok {
+		return true
+	}
+
+	return checker.HealthCheck(ctx) == nil
+}
+
+func (p *ExporterPool[T]) discard(ctx context.Context, pe *pooledExporter[T]) {
+	//nolint:errcheck // The instance is being replaced, there is nothing to do on failure.
+	pe.exporter.Shutdown(ctx)
+}
@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pooledTestExporter counts concurrent use and can be made unhealthy.
+type pooledTestExporter struct {
+	shutdownExporter[int]
+	inUse     atomic.Int32
+	unhealthy atomic.Bool
+}
+
+func (e *pooledTestExporter) ExportItems(ctx context.Context, items []*int) error {
+	if e.inUse.Add(1) > 1 {
+		return errors.New("instance used concurrently")
+	}
+	defer e.inUse.Add(-1)
+
+	return e.mockExporter.ExportItems(ctx, items)
+}
+
+func (e *pooledTestExporter) HealthCheck(_ context.Context) error {
+	if e.unhealthy.Load() {
+		return errors.New("connection lost")
+	}
+
+	return nil
+}
+
+func newTestPool(t *testing.T, options ...ExporterPoolOption) (*ExporterPool[int], *[]*pooledTestExporter) {
+	t.Helper()
+
+	var (
+		mu      sync.Mutex
+		created []*pooledTestExporter
+	)
+
+	pool, err := NewExporterPool(func(_ int) (ItemExporter[int], error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		e := &pooledTestExporter{}
+		e.exportDelay = 5 * time.Millisecond
+		created = append(created, e)
+
+		return e, nil
+	}, options...)
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+
+	return pool, &created
+}
+
+func TestExporterPool_Size(t *testing.T) {
+	pool, created := newTestPool(t, WithPoolSize(2))
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			val := 1
+			if err := pool.ExportItems(ctx, []*int{&val}); err != nil {
+				t.Errorf("failed to export: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if len(*created) != 2 {
+		t.Errorf("expected 2 instances, got %d", len(*created))
+	}
+
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	for i, e := range *created {
+		if !e.shutdown.Load() {
+			t.Errorf("instance %d: expected shutdown", i)
+		}
+	}
+
+	val := 1
+	if err := pool.ExportItems(ctx, []*int{&val}); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("expected %v after shutdown, got %v", ErrPoolClosed, err)
+	}
+}
+
+func TestExporterPool_Eviction(t *testing.T) {
+	ctx := context.Background()
+	val := 1
+
+	t.Run("max lifetime", func(t *testing.T) {
+		pool, created := newTestPool(t, WithPoolMaxLifetime(10*time.Millisecond))
+
+		if err := pool.ExportItems(ctx, []*int{&val}); err != nil {
+			t.Fatalf("failed to export: %v", err)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+
+		if err := pool.ExportItems(ctx, []*int{&val}); err != nil {
+			t.Fatalf("failed to export: %v", err)
+		}
+
+		if len(*created) != 2 {
+			t.Fatalf("expected expired instance to be replaced, got %d instances", len(*created))
+		}
+
+		if !(*created)[0].shutdown.Load() {
+			t.Error("expected expired instance to be shut down")
+		}
+	})
+
+	t.Run("health check", func(t *testing.T) {
+		pool, created := newTestPool(t)
+
+		if err := pool.ExportItems(ctx, []*int{&val}); err != nil {
+			t.Fatalf("failed to export: %v", err)
+		}
+
+		(*created)[0].unhealthy.Store(true)
+
+		if err := pool.ExportItems(ctx, []*int{&val}); err != nil {
+			t.Fatalf("failed to export: %v", err)
+		}
+
+		if len(*created) != 2 {
+			t.Fatalf("expected unhealthy instance to be replaced, got %d instances", len(*created))
+		}
+
+		if (*created)[0].exportCount.Load() != 1 {
+			t.Errorf("expected unhealthy instance not to be used again")
+		}
+	})
+}
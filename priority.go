@@ -0,0 +1,169 @@
+package processor
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// PriorityFunc returns the priority of an item. Higher priorities are
+// batched first and shed last.
+type PriorityFunc[T any] func(item *T) int
+
+// WithPriorityFunc makes the queue a priority queue. Items with a higher
+// priority, such as finality events, jump ahead of lower priority ones when
+// batches are formed, and items of equal priority keep their order. When the
+// queue is full an item evicts the newest item of the lowest priority queued,
+// if that is lower than its own, rather than being dropped; evicted items are
+// counted in queue_full_items_dropped_total under the lowest_priority policy.
+//
+// It requires the channel queue, and can't be combined with priority lanes or
+// DropOldest.
+func WithPriorityFunc[T any](priority PriorityFunc[T]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.PriorityFunc = priority
+	}
+}
+
+// shedLowestPriority labels items shed by the priority queue in
+// queue_full_items_dropped_total.
+const shedLowestPriority = "lowest_priority"
+
+// PriorityQueue is a Queue popping the highest priority item first, and
+// items of equal priority oldest first.
+type PriorityQueue[E any] struct {
+	priority func(item E) int
+
+	mu     sync.Mutex
+	size   int
+	len    int
+	closed bool
+	// levels holds the queued priorities in ascending order, each with
+	// its items oldest first.
+	levels []priorityLevel[E]
+}
+
+type priorityLevel[E any] struct {
+	priority int
+	items    []E
+	head     int
+}
+
+// NewPriorityQueue returns a priority queue holding up to size items,
+// prioritized with priority.
+func NewPriorityQueue[E any](size int, priority func(item E) int) *PriorityQueue[E] {
+	return &PriorityQueue[E]{size: size, priority: priority}
+}
+
+func (q *PriorityQueue[E]) Push(item E) bool {
+	priority := q.priority(item)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.len == q.size {
+		return false
+	}
+
+	i, ok := slices.BinarySearchFunc(q.levels, priority, func(l priorityLevel[E], p int) int {
+		return cmp.Compare(l.priority, p)
+	})
+	if !ok {
+		q.levels = slices.Insert(q.levels, i, priorityLevel[E]{priority: priority})
+	}
+
+	l := &q.levels[i]
+
+	// Reuse the room left by popped items before growing.
+	if l.head > 0 && len(l.items) == cap(l.items) {
+		n := copy(l.items, l.items[l.head:])
+		clear(l.items[n:])
+		l.items = l.items[:n]
+		l.head = 0
+	}
+
+	l.items = append(l.items, item)
+	q.len++
+
+	return true
+}
+
+func (q *PriorityQueue[E]) Pop() (E, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var zero E
+
+	if q.len == 0 {
+		return zero, false
+	}
+
+	last := len(q.levels) - 1
+	l := &q.levels[last]
+
+	item := l.items[l.head]
+	l.items[l.head] = zero
+	l.head++
+	q.len--
+
+	if l.head == len(l.items) {
+		q.levels = q.levels[:last]
+	}
+
+	return item, true
+}
+
+// Shed removes the newest item of the lowest priority queued, if that is
+// lower than priority, making room for an item of that priority. It returns
+// false if there is no lower priority item.
+func (q *PriorityQueue[E]) Shed(priority int) (E, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var zero E
+
+	if q.len == 0 || q.levels[0].priority >= priority {
+		return zero, false
+	}
+
+	l := &q.levels[0]
+
+	last := len(l.items) - 1
+	item := l.items[last]
+	l.items[last] = zero
+	l.items = l.items[:last]
+	q.len--
+
+	if l.head == len(l.items) {
+		q.levels = slices.Delete(q.levels, 0, 1)
+	}
+
+	return item, true
+}
+
+func (q *PriorityQueue[E]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.len
+}
+
+func (q *PriorityQueue[E]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+}
+
+// shedLowerPriority makes room in the priority queue for item by evicting a
+// lower priority item. It returns false if there is none.
+func (bvp *BatchItemProcessor[T]) shedLowerPriority(item *TraceableItem[T]) bool {
+	shed, ok := bvp.priority.Shed(bvp.priority.priority(item))
+	if !ok {
+		return false
+	}
+
+	bvp.evict(shed, shedLowestPriority)
+
+	return true
+}
@@ -0,0 +1,139 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	q := NewPriorityQueue(4, func(item [2]int) int { return item[0] })
+
+	for _, item := range [][2]int{{1, 0}, {2, 1}, {1, 2}, {3, 3}} {
+		if !q.Push(item) {
+			t.Fatalf("push %v failed below capacity", item)
+		}
+	}
+
+	if q.Push([2]int{5, 4}) {
+		t.Fatal("expected a push to a full queue to fail")
+	}
+
+	if _, ok := q.Shed(1); ok {
+		t.Fatal("expected nothing shed for an item of the lowest priority")
+	}
+
+	// The newest of the lowest priority is shed.
+	if item, ok := q.Shed(5); !ok || item != [2]int{1, 2} {
+		t.Fatalf("expected {1 2} shed, got %v (%v)", item, ok)
+	}
+
+	if !q.Push([2]int{5, 4}) {
+		t.Fatal("expected the push to fit once an item was shed")
+	}
+
+	// Higher priorities pop first, and equal ones oldest first.
+	for _, want := range [][2]int{{5, 4}, {3, 3}, {2, 1}, {1, 0}} {
+		if item, ok := q.Pop(); !ok || item != want {
+			t.Fatalf("expected to pop %v, got %v (%v)", want, item, ok)
+		}
+	}
+
+	if _, ok := q.Pop(); ok {
+		t.Fatal("expected a pop from an empty queue to fail")
+	}
+}
+
+func TestPriorityQueue_ReusesPoppedRoom(t *testing.T) {
+	q := NewPriorityQueue(4, func(int) int { return 0 })
+
+	for i := range 100 {
+		if !q.Push(i) {
+			t.Fatalf("push %d failed below capacity", i)
+		}
+
+		if item, ok := q.Pop(); !ok || item != i {
+			t.Fatalf("expected to pop %d, got %d (%v)", i, item, ok)
+		}
+	}
+
+	for i := range 4 {
+		q.Push(i)
+	}
+
+	q.Pop()
+	q.Push(4)
+
+	if l := q.levels[0]; cap(l.items) > 8 {
+		t.Fatalf("expected popped room reused, got a capacity of %d", cap(l.items))
+	}
+
+	for want := 1; want <= 4; want++ {
+		if item, _ := q.Pop(); item != want {
+			t.Fatalf("expected to pop %d, got %d", want, item)
+		}
+	}
+}
+
+func TestBatchItemProcessor_PriorityFunc(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "priority-func-test", log,
+		WithMaxQueueSize(3),
+		WithMaxExportBatchSize(3),
+		WithBatchTimeout(10*time.Millisecond),
+		WithWorkers(1),
+		WithPriorityFunc(func(item *int) int { return *item }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// The processor isn't started, so items wait in the priority queue.
+	for _, v := range []int{1, 5, 2, 9, 3, 0} {
+		err := proc.Write(ctx, []*int{&v})
+
+		if v == 0 {
+			if !errors.Is(err, ErrQueueFull) {
+				t.Fatalf("expected the lowest priority item dropped, got %v", err)
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+
+	want := []int{9, 5, 3}
+
+	if len(exporter.exportedItems) != len(want) {
+		t.Fatalf("expected %v exported, got %d items", want, len(exporter.exportedItems))
+	}
+
+	for i, item := range exporter.exportedItems {
+		if *item != want[i] {
+			t.Fatalf("expected %v exported in order, got %d at %d", want, *item, i)
+		}
+	}
+
+	if got := counterValue(t, DefaultMetrics.itemsDroppedByPolicy.WithLabelValues("priority-func-test", shedLowestPriority)); got != 2 {
+		t.Fatalf("expected 2 lower priority items shed, got %v", got)
+	}
+}
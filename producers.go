@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Producer writes items to a processor under a queue quota, so one busy or
+// misbehaving producer can't fill the queue shared with the others. Items
+// beyond the quota are dropped with ErrQuotaExceeded, and drops are also
+// reported per producer. A Producer is safe for concurrent use.
+type Producer[T any] struct {
+	bvp    *BatchItemProcessor[T]
+	name   string
+	label  string
+	quota  int64
+	queued atomic.Int64
+}
+
+type producerKey struct{}
+
+// ContextWithProducer names the producer of items written with the returned
+// context, for processors tracking producers with WithProducerTracking.
+// Registered producers are identified without it.
+func ContextWithProducer(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, producerKey{}, name)
+}
+
+// WithProducerTracking adds a producer label to enqueue and drop metrics for
+// all writes, so queue pressure can be attributed to upstream components.
+// Producers are named by ContextWithProducer or by registering them. At most
+// maxProducers distinct names are tracked; further names are reported as
+// OverflowLabel and unnamed writes as UnknownLabel. Registered producers are
+// always reported.
+func WithProducerTracking(maxProducers int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.MaxProducerLabels = maxProducers
+	}
+}
+
+// producerLabel returns the producer label for items written with ctx, or an
+// empty string if they aren't attributed to a producer.
+func (bvp *BatchItemProcessor[T]) producerLabel(ctx context.Context, producer *Producer[T]) string {
+	if producer != nil {
+		return producer.label
+	}
+
+	if bvp.producerLabels == nil {
+		return ""
+	}
+
+	name, _ := ctx.Value(producerKey{}).(string)
+	if name == "" {
+		return UnknownLabel
+	}
+
+	return bvp.producerLabels.Label(name)
+}
+
+// producerRegistry holds the producers registered with a processor.
+type producerRegistry[T any] struct {
+	mu        sync.Mutex
+	producers map[string]*Producer[T]
+}
+
+// Producer registers a named producer that may have at most quota items
+// queued at once. Names must be unique per processor, as they are used as
+// metric labels.
+func (bvp *BatchItemProcessor[T]) Producer(name string, quota int) (*Producer[T], error) {
+	if quota < 1 {
+		return nil, errors.New("producer quota must be at least one")
+	}
+
+	bvp.producers.mu.Lock()
+	defer bvp.producers.mu.Unlock()
+
+	if _, ok := bvp.producers.producers[name]; ok {
+		return nil, fmt.Errorf("producer %q is already registered", name)
+	}
+
+	if bvp.producers.producers == nil {
+		bvp.producers.producers = make(map[string]*Producer[T])
+	}
+
+	p := &Producer[T]{
+		bvp:   bvp,
+		name:  name,
+		label: sanitizeLabel(name),
+		quota: int64(quota),
+	}
+
+	bvp.producers.producers[name] = p
+
+	return p, nil
+}
+
+// Name returns the producer's name.
+func (p *Producer[T]) Name() string {
+	return p.name
+}
+
+// Queued returns the number of the producer's items currently queued.
+func (p *Producer[T]) Queued() int {
+	return int(p.queued.Load())
+}
+
+// Write writes items to the processor like BatchItemProcessor.Write, counting
+// them against the producer's quota until they leave the queue.
+func (p *Producer[T]) Write(ctx context.Context, items []*T) error {
+	return p.bvp.write(ctx, items, p)
+}
+
+// reserve takes a slot in the quota, returning false if it is used up.
+func (p *Producer[T]) reserve() bool {
+	if p.queued.Add(1) > p.quota {
+		p.queued.Add(-1)
+
+		return false
+	}
+
+	return true
+}
+
+// release frees a slot taken by reserve.
+func (p *Producer[T]) release() {
+	p.queued.Add(-1)
+}
@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_ProducerQuota(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test_producer_quota",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(10*time.Millisecond),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	noisy, err := proc.Producer("noisy", 3)
+	if err != nil {
+		t.Fatalf("failed to register producer: %v", err)
+	}
+
+	quiet, err := proc.Producer("quiet", 3)
+	if err != nil {
+		t.Fatalf("failed to register producer: %v", err)
+	}
+
+	if _, err := proc.Producer("noisy", 3); err == nil {
+		t.Error("expected duplicate producer to be rejected")
+	}
+
+	dropped := DefaultMetrics.producerItemsDropped.WithLabelValues("test_producer_quota", "noisy")
+	before := counterValue(t, dropped)
+
+	ctx := context.Background()
+
+	// The processor isn't started, so items stay queued.
+	items := make([]*int, 5)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	if err := noisy.Write(ctx, items); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected %v, got %v", ErrQuotaExceeded, err)
+	}
+
+	if got := noisy.Queued(); got != 3 {
+		t.Errorf("expected 3 queued items, got %d", got)
+	}
+
+	if got := counterValue(t, dropped) - before; got != 1 {
+		t.Errorf("expected 1 dropped item, got %v", got)
+	}
+
+	// Other producers still have room.
+	if err := quiet.Write(ctx, items[:3]); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	// Quota is returned once items leave the queue.
+	deadline := time.Now().Add(time.Second)
+	for noisy.Queued() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := noisy.Write(ctx, items[:3]); err != nil {
+		t.Fatalf("failed to write items after the queue drained: %v", err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 9 {
+		t.Errorf("expected 9 items exported, got %d", got)
+	}
+}
+
+func TestBatchItemProcessor_ProducerTracking(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	const name = "test_producer_tracking"
+
+	proc, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{},
+		name,
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithWorkers(1),
+		WithProducerTracking(1),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	enqueued := func(producer string) float64 {
+		return counterValue(t, DefaultMetrics.producerItemsEnqueued.WithLabelValues(name, producer))
+	}
+
+	before := map[string]float64{}
+	for _, producer := range []string{"a", OverflowLabel, UnknownLabel} {
+		before[producer] = enqueued(producer)
+	}
+
+	ctx := context.Background()
+	one, two := 1, 2
+
+	writes := []struct {
+		ctx   context.Context
+		items []*int
+	}{
+		{ContextWithProducer(ctx, "a"), []*int{&one, &two}},
+		{ContextWithProducer(ctx, "b"), []*int{&one}},
+		{ctx, []*int{&two}},
+	}
+
+	for _, w := range writes {
+		if err := proc.Write(w.ctx, w.items); err != nil {
+			t.Fatalf("failed to write items: %v", err)
+		}
+	}
+
+	// Only one producer fits in the label bound, so "b" overflows.
+	want := map[string]float64{"a": 2, OverflowLabel: 1, UnknownLabel: 1}
+
+	for producer, count := range want {
+		if got := enqueued(producer) - before[producer]; got != count {
+			t.Errorf("producer %s: expected %v enqueued, got %v", producer, count, got)
+		}
+	}
+}
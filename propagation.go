@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"context"
+	"maps"
+	"reflect"
+)
+
+// writeContext is the request-scoped state captured when items are written
+// and restored when they are exported.
+type writeContext struct {
+	keys     []any
+	values   []any
+	metadata Metadata
+}
+
+// WithContextPropagation captures the values of the given context keys when
+// items are written and sets them on the context passed to ExportItems, so
+// request-scoped values such as tenant IDs or auth tokens survive the queue.
+// Batches are split so every export only holds items written with equal
+// values. Values are not kept for batches buffered to disk.
+func WithContextPropagation(keys ...any) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ContextKeys = append(o.ContextKeys, keys...)
+	}
+}
+
+// captureContext returns the write context for items written with ctx, or
+// nil if nothing is propagated.
+func (bvp *BatchItemProcessor[T]) captureContext(ctx context.Context) *writeContext {
+	md := MetadataFromContext(ctx)
+
+	if len(bvp.o.ContextKeys) == 0 && md == nil {
+		return nil
+	}
+
+	wctx := &writeContext{
+		keys:     bvp.o.ContextKeys,
+		values:   make([]any, len(bvp.o.ContextKeys)),
+		metadata: md,
+	}
+
+	for i, key := range bvp.o.ContextKeys {
+		wctx.values[i] = ctx.Value(key)
+	}
+
+	return wctx
+}
+
+// apply sets the captured values on ctx.
+func (w *writeContext) apply(ctx context.Context) context.Context {
+	if w == nil {
+		return ctx
+	}
+
+	if w.metadata != nil {
+		ctx = context.WithValue(ctx, metadataKey{}, w.metadata)
+	}
+
+	for i, key := range w.keys {
+		if w.values[i] != nil {
+			ctx = context.WithValue(ctx, key, w.values[i])
+		}
+	}
+
+	return ctx
+}
+
+// equal reports whether two write contexts hold the same values. Values that
+// can't be compared are only equal if they come from the same write.
+func (w *writeContext) equal(other *writeContext) bool {
+	if w == other {
+		return true
+	}
+
+	if w == nil || other == nil || !maps.Equal(w.metadata, other.metadata) {
+		return false
+	}
+
+	for i, value := range w.values {
+		if !sameValue(value, other.values[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameValue(a, b any) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
+
+// splitByWriteContext groups a batch by write context, preserving the order
+// of items within each group.
+func splitByWriteContext[T any](batch []*TraceableItem[T]) [][]*TraceableItem[T] {
+	groups := make([][]*TraceableItem[T], 0, 1)
+
+	for _, item := range batch {
+		placed := false
+
+		for i, group := range groups {
+			if group[0].wctx.equal(item.wctx) {
+				groups[i] = append(group, item)
+				placed = true
+
+				break
+			}
+		}
+
+		if !placed {
+			groups = append(groups, []*TraceableItem[T]{item})
+		}
+	}
+
+	return groups
+}
+
+// hasWriteContext reports whether any item carries a write context.
+func hasWriteContext[T any](batch []*TraceableItem[T]) bool {
+	for _, item := range batch {
+		if item.wctx != nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type tenantKey struct{}
+
+// tenantExporter records the tenant of every exported batch.
+type tenantExporter struct {
+	mockExporter[int]
+	mu      sync.Mutex
+	tenants map[any]int
+}
+
+func (e *tenantExporter) ExportItems(ctx context.Context, items []*int) error {
+	e.mu.Lock()
+	e.tenants[ctx.Value(tenantKey{})] += len(items)
+	e.mu.Unlock()
+
+	return e.mockExporter.ExportItems(ctx, items)
+}
+
+func TestBatchItemProcessor_ContextPropagation(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &tenantExporter{tenants: make(map[any]int)}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(10*time.Second),
+		WithWorkers(1),
+		WithContextPropagation(tenantKey{}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	// Writes from different tenants end up in the same batch, which is split
+	// before export.
+	for i, tenant := range []any{"a", "b", "a", nil, "b"} {
+		writeCtx := ctx
+		if tenant != nil {
+			writeCtx = context.WithValue(ctx, tenantKey{}, tenant)
+		}
+
+		val := i
+		if err := proc.Write(writeCtx, []*int{&val}); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	want := map[any]int{"a": 2, "b": 2, nil: 1}
+
+	for tenant, count := range want {
+		if got := exporter.tenants[tenant]; got != count {
+			t.Errorf("tenant %v: expected %d items, got %d", tenant, count, got)
+		}
+	}
+}
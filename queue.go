@@ -0,0 +1,334 @@
+package processor
+
+import (
+	"fmt"
+	"sync"
+)
+
+// QueueKind selects the queue implementation items wait in before they are
+// batched.
+type QueueKind string
+
+const (
+	// QueueKindChannel queues items in a buffered channel. It is the default,
+	// with the lowest latency, and allocates the whole queue up front.
+	QueueKindChannel QueueKind = "channel"
+	// QueueKindRing queues items in a preallocated ring buffer behind a
+	// mutex. Its memory use is fixed and it never allocates after
+	// construction.
+	QueueKindRing QueueKind = "ring"
+	// QueueKindSegments queues items in a linked list of fixed size
+	// segments allocated as the queue grows, so idle processors with large
+	// queues hold little memory.
+	QueueKindSegments QueueKind = "segments"
+)
+
+// queueSegmentSize is the number of items held by each segment of a
+// segmented queue.
+const queueSegmentSize = 256
+
+// Queue is a bounded queue, FIFO unless the implementation says otherwise.
+// Push may be called concurrently; Pop is only called by a single consumer.
+type Queue[E any] interface {
+	// Push adds an item without blocking. It returns false if the queue is
+	// full or closed.
+	Push(item E) bool
+	// Pop removes the oldest item without blocking. It returns false if the
+	// queue is empty.
+	Pop() (E, bool)
+	// Len returns the number of queued items.
+	Len() int
+	// Close stops the queue accepting items. Queued items can still be
+	// popped.
+	Close()
+}
+
+// NewQueue returns a queue of the given kind holding up to size items.
+func NewQueue[E any](kind QueueKind, size int) (Queue[E], error) {
+	switch kind {
+	case QueueKindChannel:
+		return NewChannelQueue[E](size), nil
+	case QueueKindRing:
+		return NewRingQueue[E](size), nil
+	case QueueKindSegments:
+		return NewSegmentQueue[E](size), nil
+	case QueueKindSharded:
+		return NewShardedQueue[E](size), nil
+	default:
+		return nil, fmt.Errorf("unknown queue kind %q", kind)
+	}
+}
+
+// WithQueue selects the queue implementation. Anything other than the default
+// channel queue sits in front of a queue holding the next batch, like
+// priority lanes, and can't be combined with them.
+func WithQueue(kind QueueKind) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.QueueKind = kind
+	}
+}
+
+// ChannelQueue is a Queue backed by a buffered channel.
+type ChannelQueue[E any] struct {
+	mu     sync.RWMutex
+	ch     chan E
+	closed bool
+}
+
+// NewChannelQueue returns a channel queue holding up to size items.
+func NewChannelQueue[E any](size int) *ChannelQueue[E] {
+	return &ChannelQueue[E]{ch: make(chan E, size)}
+}
+
+func (q *ChannelQueue[E]) Push(item E) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return false
+	}
+
+	select {
+	case q.ch <- item:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *ChannelQueue[E]) Pop() (E, bool) {
+	select {
+	case item := <-q.ch:
+		return item, true
+	default:
+		var zero E
+
+		return zero, false
+	}
+}
+
+func (q *ChannelQueue[E]) Len() int {
+	return len(q.ch)
+}
+
+func (q *ChannelQueue[E]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+}
+
+// RingQueue is a Queue backed by a preallocated ring buffer.
+type RingQueue[E any] struct {
+	mu     sync.Mutex
+	items  []E
+	head   int
+	len    int
+	closed bool
+}
+
+// NewRingQueue returns a ring queue holding up to size items.
+func NewRingQueue[E any](size int) *RingQueue[E] {
+	return &RingQueue[E]{items: make([]E, size)}
+}
+
+func (q *RingQueue[E]) Push(item E) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.len == len(q.items) {
+		return false
+	}
+
+	q.items[(q.head+q.len)%len(q.items)] = item
+	q.len++
+
+	return true
+}
+
+func (q *RingQueue[E]) Pop() (E, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var zero E
+
+	if q.len == 0 {
+		return zero, false
+	}
+
+	item := q.items[q.head]
+	q.items[q.head] = zero
+	q.head = (q.head + 1) % len(q.items)
+	q.len--
+
+	return item, true
+}
+
+func (q *RingQueue[E]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.len
+}
+
+func (q *RingQueue[E]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+}
+
+// SegmentQueue is a Queue backed by a linked list of fixed size segments.
+// Segments are allocated as the queue grows and released once drained,
+// keeping one spare to avoid churn around a segment boundary.
+type SegmentQueue[E any] struct {
+	mu     sync.Mutex
+	size   int
+	len    int
+	head   *queueSegment[E]
+	tail   *queueSegment[E]
+	spare  *queueSegment[E]
+	closed bool
+}
+
+type queueSegment[E any] struct {
+	items      [queueSegmentSize]E
+	start, end int
+	next       *queueSegment[E]
+}
+
+// NewSegmentQueue returns a segmented queue holding up to size items.
+func NewSegmentQueue[E any](size int) *SegmentQueue[E] {
+	return &SegmentQueue[E]{size: size}
+}
+
+func (q *SegmentQueue[E]) Push(item E) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.len == q.size {
+		return false
+	}
+
+	if q.tail == nil || q.tail.end == queueSegmentSize {
+		segment := q.spare
+		if segment != nil {
+			q.spare = nil
+		} else {
+			segment = &queueSegment[E]{}
+		}
+
+		if q.tail == nil {
+			q.head = segment
+		} else {
+			q.tail.next = segment
+		}
+
+		q.tail = segment
+	}
+
+	q.tail.items[q.tail.end] = item
+	q.tail.end++
+	q.len++
+
+	return true
+}
+
+func (q *SegmentQueue[E]) Pop() (E, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var zero E
+
+	if q.len == 0 {
+		return zero, false
+	}
+
+	segment := q.head
+	item := segment.items[segment.start]
+	segment.items[segment.start] = zero
+	segment.start++
+	q.len--
+
+	if segment.start == segment.end {
+		q.head = segment.next
+		if q.head == nil {
+			q.tail = nil
+		}
+
+		*segment = queueSegment[E]{}
+		q.spare = segment
+	}
+
+	return item, true
+}
+
+func (q *SegmentQueue[E]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.len
+}
+
+func (q *SegmentQueue[E]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+}
+
+// stagedQueue holds items in a Queue ahead of the processor's queue, waking
+// the queue pump when items are pushed.
+type stagedQueue[T any] struct {
+	q      Queue[*TraceableItem[T]]
+	signal chan struct{}
+}
+
+func newStagedQueue[T any](q Queue[*TraceableItem[T]]) *stagedQueue[T] {
+	return &stagedQueue[T]{
+		q:      q,
+		signal: make(chan struct{}, 1),
+	}
+}
+
+// push adds an item without blocking. Like the plain queue it panics if the
+// queue is closed while the item is being pushed.
+func (s *stagedQueue[T]) push(item *TraceableItem[T]) bool {
+	if !s.q.Push(item) {
+		return false
+	}
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// close stops the queue accepting items. Queued items are still pumped.
+func (s *stagedQueue[T]) close() {
+	s.q.Close()
+
+	close(s.signal)
+}
+
+// queuePump moves items from the staged queue to the processor's queue,
+// closing it once the staged queue is closed and empty.
+func (bvp *BatchItemProcessor[T]) queuePump() {
+	defer close(bvp.queue)
+
+	s := bvp.staged
+
+	for {
+		if item, ok := s.q.Pop(); ok {
+			bvp.queue <- item
+
+			continue
+		}
+
+		if _, ok := <-s.signal; !ok && s.q.Len() == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fifoQueueKinds are the queue kinds that pop items in push order.
+var fifoQueueKinds = []QueueKind{QueueKindChannel, QueueKindRing, QueueKindSegments}
+
+var queueKinds = append(fifoQueueKinds, QueueKindSharded)
+
+func TestQueue_FIFO(t *testing.T) {
+	// The size spans several segments and wraps the ring.
+	size := queueSegmentSize*2 + 10
+
+	for _, kind := range fifoQueueKinds {
+		t.Run(string(kind), func(t *testing.T) {
+			q, err := NewQueue[int](kind, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pushed, popped := 0, 0
+
+			push := func(n int) {
+				for range n {
+					if !q.Push(pushed) {
+						t.Fatalf("push %d failed below capacity", pushed)
+					}
+
+					pushed++
+				}
+			}
+
+			pop := func(n int) {
+				for range n {
+					item, ok := q.Pop()
+					if !ok || item != popped {
+						t.Fatalf("expected to pop %d, got %d (%v)", popped, item, ok)
+					}
+
+					popped++
+				}
+			}
+
+			push(size)
+
+			if q.Push(-1) {
+				t.Fatal("expected a push to a full queue to fail")
+			}
+
+			// Refilling the drained half wraps the ring and appends
+			// segments behind the partly drained head.
+			pop(size / 2)
+			push(size / 2)
+
+			if q.Len() != size {
+				t.Fatalf("expected %d queued items, got %d", size, q.Len())
+			}
+
+			pop(size)
+
+			if _, ok := q.Pop(); ok {
+				t.Fatal("expected a pop from an empty queue to fail")
+			}
+		})
+	}
+}
+
+func TestQueue_Close(t *testing.T) {
+	for _, kind := range queueKinds {
+		t.Run(string(kind), func(t *testing.T) {
+			q, err := NewQueue[int](kind, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			q.Push(1)
+			q.Close()
+
+			if q.Push(2) {
+				t.Fatal("expected a push to a closed queue to fail")
+			}
+
+			if item, ok := q.Pop(); !ok || item != 1 {
+				t.Fatalf("expected queued items to be popped after close, got %d (%v)", item, ok)
+			}
+		})
+	}
+}
+
+func TestNewQueue_UnknownKind(t *testing.T) {
+	if _, err := NewQueue[int]("heap", 10); err == nil {
+		t.Fatal("expected an error for an unknown queue kind")
+	}
+}
+
+func TestBatchItemProcessor_QueueKinds(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	for _, kind := range queueKinds {
+		t.Run(string(kind), func(t *testing.T) {
+			exporter := &mockExporter[string]{}
+
+			bvp, err := NewBatchItemProcessor[string](exporter, fmt.Sprintf("queue-%s", kind), log,
+				WithQueue(kind),
+				WithMaxQueueSize(1000),
+				WithMaxExportBatchSize(10),
+				WithBatchTimeout(time.Hour),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+
+			if err := bvp.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			items := make([]*string, 95)
+			for i := range items {
+				s := fmt.Sprintf("item-%d", i)
+				items[i] = &s
+			}
+
+			if err := bvp.Write(ctx, items); err != nil {
+				t.Fatal(err)
+			}
+
+			// The partial batch is only exported by the shutdown drain.
+			if err := bvp.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := exporter.exportCount.Load(); got != 95 {
+				t.Fatalf("expected 95 items exported, got %d", got)
+			}
+		})
+	}
+}
+
+func TestBatchItemProcessorOptions_QueueWithLanes(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	_, err := NewBatchItemProcessor[string](&mockExporter[string]{}, "queue-lanes", log,
+		WithQueue(QueueKindRing),
+		WithPriorityLanes(func(*string) int { return 0 }, 1),
+	)
+	if err == nil {
+		t.Fatal("expected priority lanes with a ring queue to be rejected")
+	}
+}
@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithExportRateLimit throttles exports to itemsPerSecond, allowing bursts
+// of up to burst items, for sinks that rate limit their clients. Workers
+// wait for a batch's items to be allowed before exporting it, in the order
+// they asked, so batches larger than burst are still exported, once the
+// bucket has refilled for them. Items keep queueing while exports are
+// throttled. Retries aren't throttled.
+func WithExportRateLimit(itemsPerSecond float64, burst int) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ExportRateLimit = itemsPerSecond
+		o.ExportRateBurst = burst
+	}
+}
+
+// tokenBucket is a token bucket rate limiter. Tokens are taken up front and
+// may go negative, so callers wait their turn in the order they took them.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+// take takes n tokens, returning how long to wait until they are available.
+func (b *tokenBucket) take(n int, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// waitExportRate blocks until the export rate limit allows n items to be
+// exported, or ctx is done.
+func (bvp *BatchItemProcessor[T]) waitExportRate(ctx context.Context, n int) {
+	if bvp.exportRate == nil || n == 0 {
+		return
+	}
+
+	//nolint:errcheck // A cancelled wait exports as soon as possible.
+	sleep(ctx, bvp.exportRate.take(n, time.Now()))
+}
@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(10, 5, now)
+
+	if wait := b.take(5, now); wait != 0 {
+		t.Fatalf("expected the burst allowed straight away, got a wait of %s", wait)
+	}
+
+	// Taking past the burst waits for the bucket to refill, in order.
+	if wait := b.take(2, now); wait != 200*time.Millisecond {
+		t.Fatalf("expected a wait of 200ms, got %s", wait)
+	}
+
+	if wait := b.take(10, now); wait != 1200*time.Millisecond {
+		t.Fatalf("expected a wait of 1.2s for a batch larger than the burst, got %s", wait)
+	}
+
+	// The bucket refills up to the burst.
+	now = now.Add(time.Hour)
+
+	if wait := b.take(5, now); wait != 0 {
+		t.Fatalf("expected a refilled burst allowed straight away, got a wait of %s", wait)
+	}
+
+	if wait := b.take(1, now); wait != 100*time.Millisecond {
+		t.Fatalf("expected the bucket capped at its burst, got a wait of %s", wait)
+	}
+}
+
+func TestBatchItemProcessor_ExportRateLimit(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(10),
+		WithWorkers(2),
+		WithExportRateLimit(200, 10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	start := time.Now()
+
+	// The first batch is the burst, the next two wait 50ms each.
+	if err := proc.Write(ctx, ints(30)); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("expected exports throttled to 200 items a second, took %s", elapsed)
+	}
+
+	if got := exporter.exportCount.Load(); got != 30 {
+		t.Fatalf("expected 30 items exported, got %d", got)
+	}
+}
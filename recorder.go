@@ -0,0 +1,264 @@
+package processor
+
+import "time"
+
+// The interfaces below extend MetricsRecorder with the metrics of optional
+// features. A recorder implements those it supports; the processor checks
+// for each with a type assertion and skips the metrics of those it doesn't,
+// so recorders written against MetricsRecorder keep working as metrics are
+// added. Metrics and StatsDMetrics implement them all.
+
+// KeyMetricsRecorder records the metrics of WithKeyFunc.
+type KeyMetricsRecorder interface {
+	IncItemsDeduplicatedBy(name string, count float64)
+}
+
+// DiskBufferMetricsRecorder records the metrics of WithDiskBuffer.
+type DiskBufferMetricsRecorder interface {
+	IncItemsBufferedBy(name string, count float64)
+	IncItemsReplayedBy(name string, count float64)
+	SetDiskBufferBytes(name string, size float64)
+}
+
+// HealthMetricsRecorder records the results of exporter health probes.
+type HealthMetricsRecorder interface {
+	SetExporterHealthy(name string, healthy bool)
+}
+
+// ProducerMetricsRecorder records the metrics of named producers.
+type ProducerMetricsRecorder interface {
+	IncProducerItemsDroppedBy(name, producer string, count float64)
+	IncProducerItemsEnqueuedBy(name, producer string, count float64)
+	ObserveProducerBlockedDuration(name string, duration time.Duration)
+}
+
+// OverflowMetricsRecorder records the metrics of WithOverflow.
+type OverflowMetricsRecorder interface {
+	IncItemsOverflowedBy(name string, count float64)
+}
+
+// CapacityMetricsRecorder records how much of the processor's capacity is
+// in use.
+type CapacityMetricsRecorder interface {
+	SetCapacityUtilization(name string, utilization float64)
+	SetItemsQueuedRatio(name string, ratio float64)
+}
+
+// WorkStealingMetricsRecorder records the metrics of WithWorkStealing.
+type WorkStealingMetricsRecorder interface {
+	IncBatchesStolen(name string)
+}
+
+// SharedExporterMetricsRecorder records the metrics of SharedExporter.
+type SharedExporterMetricsRecorder interface {
+	ObserveSharedExporterWait(name string, duration time.Duration)
+}
+
+// RetryMetricsRecorder records the metrics of WithRetry.
+type RetryMetricsRecorder interface {
+	IncExportRetries(name string)
+}
+
+// BatchMetricsRecorder records how long batches wait to export and, with a
+// sizer, their size in bytes.
+type BatchMetricsRecorder interface {
+	ObserveBatchWaitDuration(name string, duration time.Duration)
+	ObserveBatchBytes(name string, bytes float64)
+	IncBytesExportedBy(name string, bytes float64)
+}
+
+// DropPolicyMetricsRecorder records the items dropped by each drop policy.
+type DropPolicyMetricsRecorder interface {
+	IncQueueFullItemsDroppedBy(name, policy string, count float64)
+}
+
+// WorkerMetricsRecorder records the per-worker metrics of
+// WithWorkerMetrics.
+type WorkerMetricsRecorder interface {
+	SetWorkerExporting(name, worker string, exporting bool)
+	ObserveWorkerExportDuration(name, worker string, duration time.Duration)
+}
+
+// InfoMetricsRecorder records each processor's configuration.
+type InfoMetricsRecorder interface {
+	SetProcessorInfo(name string, batchSize, queueSize, workers int, batchTimeout time.Duration)
+}
+
+// QueueLatencyMetricsRecorder records the metrics of WithMaxQueueLatency.
+type QueueLatencyMetricsRecorder interface {
+	IncQueueLatencyItemsShedBy(name string, count float64)
+}
+
+// recorder records metrics with a MetricsRecorder, passing on those of the
+// extension interfaces it implements and skipping the rest.
+type recorder struct {
+	MetricsRecorder
+
+	keys         KeyMetricsRecorder
+	diskBuffer   DiskBufferMetricsRecorder
+	health       HealthMetricsRecorder
+	producers    ProducerMetricsRecorder
+	overflow     OverflowMetricsRecorder
+	capacity     CapacityMetricsRecorder
+	workStealing WorkStealingMetricsRecorder
+	shared       SharedExporterMetricsRecorder
+	retry        RetryMetricsRecorder
+	batches      BatchMetricsRecorder
+	dropPolicy   DropPolicyMetricsRecorder
+	workers      WorkerMetricsRecorder
+	info         InfoMetricsRecorder
+	queueLatency QueueLatencyMetricsRecorder
+}
+
+func newRecorder(metrics MetricsRecorder) *recorder {
+	r := &recorder{MetricsRecorder: metrics}
+
+	r.keys, _ = metrics.(KeyMetricsRecorder)
+	r.diskBuffer, _ = metrics.(DiskBufferMetricsRecorder)
+	r.health, _ = metrics.(HealthMetricsRecorder)
+	r.producers, _ = metrics.(ProducerMetricsRecorder)
+	r.overflow, _ = metrics.(OverflowMetricsRecorder)
+	r.capacity, _ = metrics.(CapacityMetricsRecorder)
+	r.workStealing, _ = metrics.(WorkStealingMetricsRecorder)
+	r.shared, _ = metrics.(SharedExporterMetricsRecorder)
+	r.retry, _ = metrics.(RetryMetricsRecorder)
+	r.batches, _ = metrics.(BatchMetricsRecorder)
+	r.dropPolicy, _ = metrics.(DropPolicyMetricsRecorder)
+	r.workers, _ = metrics.(WorkerMetricsRecorder)
+	r.info, _ = metrics.(InfoMetricsRecorder)
+	r.queueLatency, _ = metrics.(QueueLatencyMetricsRecorder)
+
+	return r
+}
+
+func (r *recorder) IncItemsDeduplicatedBy(name string, count float64) {
+	if r.keys != nil {
+		r.keys.IncItemsDeduplicatedBy(name, count)
+	}
+}
+
+func (r *recorder) IncItemsBufferedBy(name string, count float64) {
+	if r.diskBuffer != nil {
+		r.diskBuffer.IncItemsBufferedBy(name, count)
+	}
+}
+
+func (r *recorder) IncItemsReplayedBy(name string, count float64) {
+	if r.diskBuffer != nil {
+		r.diskBuffer.IncItemsReplayedBy(name, count)
+	}
+}
+
+func (r *recorder) SetDiskBufferBytes(name string, size float64) {
+	if r.diskBuffer != nil {
+		r.diskBuffer.SetDiskBufferBytes(name, size)
+	}
+}
+
+func (r *recorder) SetExporterHealthy(name string, healthy bool) {
+	if r.health != nil {
+		r.health.SetExporterHealthy(name, healthy)
+	}
+}
+
+func (r *recorder) IncProducerItemsDroppedBy(name, producer string, count float64) {
+	if r.producers != nil {
+		r.producers.IncProducerItemsDroppedBy(name, producer, count)
+	}
+}
+
+func (r *recorder) IncProducerItemsEnqueuedBy(name, producer string, count float64) {
+	if r.producers != nil {
+		r.producers.IncProducerItemsEnqueuedBy(name, producer, count)
+	}
+}
+
+func (r *recorder) ObserveProducerBlockedDuration(name string, duration time.Duration) {
+	if r.producers != nil {
+		r.producers.ObserveProducerBlockedDuration(name, duration)
+	}
+}
+
+func (r *recorder) IncItemsOverflowedBy(name string, count float64) {
+	if r.overflow != nil {
+		r.overflow.IncItemsOverflowedBy(name, count)
+	}
+}
+
+func (r *recorder) SetCapacityUtilization(name string, utilization float64) {
+	if r.capacity != nil {
+		r.capacity.SetCapacityUtilization(name, utilization)
+	}
+}
+
+func (r *recorder) SetItemsQueuedRatio(name string, ratio float64) {
+	if r.capacity != nil {
+		r.capacity.SetItemsQueuedRatio(name, ratio)
+	}
+}
+
+func (r *recorder) IncBatchesStolen(name string) {
+	if r.workStealing != nil {
+		r.workStealing.IncBatchesStolen(name)
+	}
+}
+
+func (r *recorder) ObserveSharedExporterWait(name string, duration time.Duration) {
+	if r.shared != nil {
+		r.shared.ObserveSharedExporterWait(name, duration)
+	}
+}
+
+func (r *recorder) IncExportRetries(name string) {
+	if r.retry != nil {
+		r.retry.IncExportRetries(name)
+	}
+}
+
+func (r *recorder) ObserveBatchWaitDuration(name string, duration time.Duration) {
+	if r.batches != nil {
+		r.batches.ObserveBatchWaitDuration(name, duration)
+	}
+}
+
+func (r *recorder) ObserveBatchBytes(name string, bytes float64) {
+	if r.batches != nil {
+		r.batches.ObserveBatchBytes(name, bytes)
+	}
+}
+
+func (r *recorder) IncBytesExportedBy(name string, bytes float64) {
+	if r.batches != nil {
+		r.batches.IncBytesExportedBy(name, bytes)
+	}
+}
+
+func (r *recorder) IncQueueFullItemsDroppedBy(name, policy string, count float64) {
+	if r.dropPolicy != nil {
+		r.dropPolicy.IncQueueFullItemsDroppedBy(name, policy, count)
+	}
+}
+
+func (r *recorder) SetWorkerExporting(name, worker string, exporting bool) {
+	if r.workers != nil {
+		r.workers.SetWorkerExporting(name, worker, exporting)
+	}
+}
+
+func (r *recorder) ObserveWorkerExportDuration(name, worker string, duration time.Duration) {
+	if r.workers != nil {
+		r.workers.ObserveWorkerExportDuration(name, worker, duration)
+	}
+}
+
+func (r *recorder) SetProcessorInfo(name string, batchSize, queueSize, workers int, batchTimeout time.Duration) {
+	if r.info != nil {
+		r.info.SetProcessorInfo(name, batchSize, queueSize, workers, batchTimeout)
+	}
+}
+
+func (r *recorder) IncQueueLatencyItemsShedBy(name string, count float64) {
+	if r.queueLatency != nil {
+		r.queueLatency.IncQueueLatencyItemsShedBy(name, count)
+	}
+}
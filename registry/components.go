@@ -0,0 +1,131 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/ethpandaops/go-batch-processor/middleware"
+)
+
+// Components bundles the registries needed to describe a whole pipeline for
+// items of type T in configuration.
+type Components[T any] struct {
+	Exporters  *Registry[processor.ItemExporter[T]]
+	Middleware *Registry[middleware.Middleware[T]]
+	Triggers   *Registry[processor.Trigger]
+	Backoffs   *Registry[processor.Backoff]
+}
+
+// NewComponents creates a set of registries. The built-in backoff strategies
+// are registered; exporters, middleware and triggers are registered by their
+// packages.
+func NewComponents[T any]() *Components[T] {
+	c := &Components[T]{
+		Exporters:  NewExporterRegistry[T](),
+		Middleware: NewMiddlewareRegistry[T](),
+		Triggers:   NewTriggerRegistry(),
+		Backoffs:   NewBackoffRegistry(),
+	}
+
+	if err := RegisterBackoffs(c.Backoffs); err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// NewMiddlewareRegistry creates an empty registry of middleware for items of type T.
+func NewMiddlewareRegistry[T any]() *Registry[middleware.Middleware[T]] {
+	return New[middleware.Middleware[T]]("middleware")
+}
+
+// NewTriggerRegistry creates an empty registry of flush triggers.
+func NewTriggerRegistry() *Registry[processor.Trigger] {
+	return New[processor.Trigger]("trigger")
+}
+
+// NewBackoffRegistry creates an empty registry of backoff strategies.
+func NewBackoffRegistry() *Registry[processor.Backoff] {
+	return New[processor.Backoff]("backoff")
+}
+
+// RegisterBackoffs registers the built-in backoff strategies: "exponential",
+// configured like processor.BackoffConfig, and "constant" with an "interval".
+func RegisterBackoffs(r *Registry[processor.Backoff]) error {
+	err := r.Register("exponential", func(config map[string]any) (processor.Backoff, error) {
+		defaults := processor.DefaultBackoffConfig()
+
+		cfg := struct {
+			InitialInterval Duration `json:"initial_interval"`
+			MaxInterval     Duration `json:"max_interval"`
+			Multiplier      float64  `json:"multiplier"`
+			Jitter          float64  `json:"jitter"`
+		}{
+			InitialInterval: Duration(defaults.InitialInterval),
+			MaxInterval:     Duration(defaults.MaxInterval),
+			Multiplier:      defaults.Multiplier,
+			Jitter:          defaults.Jitter,
+		}
+
+		if err := DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		return processor.BackoffConfig{
+			InitialInterval: time.Duration(cfg.InitialInterval),
+			MaxInterval:     time.Duration(cfg.MaxInterval),
+			Multiplier:      cfg.Multiplier,
+			Jitter:          cfg.Jitter,
+		}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.Register("constant", func(config map[string]any) (processor.Backoff, error) {
+		var cfg struct {
+			Interval Duration `json:"interval"`
+		}
+
+		if err := DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		return processor.ConstantBackoff(cfg.Interval), nil
+	})
+}
+
+// Duration is a time.Duration that decodes from strings such as "2s" as well
+// as from integer nanoseconds, for use in component configs.
+type Duration time.Duration
+
+// UnmarshalJSON decodes a duration string or integer nanoseconds.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case float64:
+		*d = Duration(value)
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %v", v)
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
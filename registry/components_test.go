@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+func TestComponents_Backoffs(t *testing.T) {
+	c := NewComponents[int]()
+
+	backoff, err := c.Backoffs.Build("exponential", map[string]any{
+		"initial_interval": "1s",
+		"multiplier":       3,
+		"jitter":           0,
+	})
+	if err != nil {
+		t.Fatalf("failed to build backoff: %v", err)
+	}
+
+	if got := backoff.Next(2); got != 3*time.Second {
+		t.Errorf("expected 3s, got %v", got)
+	}
+
+	backoff, err = c.Backoffs.Build("constant", map[string]any{"interval": "250ms"})
+	if err != nil {
+		t.Fatalf("failed to build backoff: %v", err)
+	}
+
+	if got := backoff.Next(5); got != 250*time.Millisecond {
+		t.Errorf("expected 250ms, got %v", got)
+	}
+
+	if _, err := c.Backoffs.Build("constant", map[string]any{"interval": "soon"}); err == nil {
+		t.Error("expected invalid duration to fail")
+	}
+
+	if _, ok := backoff.(processor.ConstantBackoff); !ok {
+		t.Errorf("expected ConstantBackoff, got %T", backoff)
+	}
+}
@@ -0,0 +1,128 @@
+// Package registry provides named factories for pipeline components, so
+// exporters and other components can be constructed from configuration.
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+// ErrNotFound is returned when no factory is registered under a name.
+var ErrNotFound = errors.New("component not registered")
+
+// Factory constructs a component from its configuration.
+type Factory[C any] func(config map[string]any) (C, error)
+
+// Registry holds factories for one kind of component, keyed by name. It is
+// safe for concurrent use.
+type Registry[C any] struct {
+	kind string
+
+	mu        sync.RWMutex
+	factories map[string]Factory[C]
+}
+
+// New creates an empty registry. kind names the component type in errors,
+// such as "exporter".
+func New[C any](kind string) *Registry[C] {
+	return &Registry[C]{
+		kind:      kind,
+		factories: make(map[string]Factory[C]),
+	}
+}
+
+// NewExporterRegistry creates an empty registry of exporters for items of type T.
+func NewExporterRegistry[T any]() *Registry[processor.ItemExporter[T]] {
+	return New[processor.ItemExporter[T]]("exporter")
+}
+
+// Register adds a factory under name. Registering the same name twice is an
+// error.
+func (r *Registry[C]) Register(name string, factory Factory[C]) error {
+	if name == "" {
+		return fmt.Errorf("%s name must not be empty", r.kind)
+	}
+
+	if factory == nil {
+		return fmt.Errorf("%s factory must not be nil: %s", r.kind, name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[name]; ok {
+		return fmt.Errorf("%s already registered: %s", r.kind, name)
+	}
+
+	r.factories[name] = factory
+
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is intended for use
+// in package init functions.
+func (r *Registry[C]) MustRegister(name string, factory Factory[C]) {
+	if err := r.Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Build constructs the component registered under name from config.
+func (r *Registry[C]) Build(name string, config map[string]any) (C, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		var zero C
+
+		return zero, fmt.Errorf("%w: %s %q", ErrNotFound, r.kind, name)
+	}
+
+	component, err := factory(config)
+	if err != nil {
+		return component, fmt.Errorf("failed to build %s %q: %w", r.kind, name, err)
+	}
+
+	return component, nil
+}
+
+// Names returns the registered names in sorted order.
+func (r *Registry[C]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// DecodeConfig decodes a config map in to out, a pointer to a struct with
+// json tags. Config maps typically come from YAML or JSON documents, so a JSON
+// round trip gives factories the same decoding rules as those documents.
+func DecodeConfig(config map[string]any, out any) error {
+	if len(config) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+type nopExporter struct {
+	endpoint string
+}
+
+func (e *nopExporter) ExportItems(_ context.Context, _ []*int) error {
+	return nil
+}
+
+func (e *nopExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewExporterRegistry[int]()
+
+	factory := func(config map[string]any) (processor.ItemExporter[int], error) {
+		var cfg struct {
+			Endpoint string `json:"endpoint"`
+		}
+
+		if err := DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		return &nopExporter{endpoint: cfg.Endpoint}, nil
+	}
+
+	if err := r.Register("nop", factory); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	if err := r.Register("nop", factory); err == nil {
+		t.Error("expected duplicate registration to fail")
+	}
+
+	exporter, err := r.Build("nop", map[string]any{"endpoint": "http://localhost"})
+	if err != nil {
+		t.Fatalf("failed to build: %v", err)
+	}
+
+	if got := exporter.(*nopExporter).endpoint; got != "http://localhost" {
+		t.Errorf("expected endpoint to be decoded, got %q", got)
+	}
+
+	if _, err := r.Build("missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if names := r.Names(); len(names) != 1 || names[0] != "nop" {
+		t.Errorf("expected [nop], got %v", names)
+	}
+}
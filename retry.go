@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WithRetry retries failed exports before counting their batch as failed,
+// making up to maxAttempts attempts in total and waiting backoff.Next(n)
+// before retry n. Each attempt gets its own export timeout, so an attempt
+// that timed out is retried; retries stop once the context the batch is
+// exported with is done. A batch that only partly failed, with a
+// *PartialExportError, isn't retried, so items already exported aren't
+// exported twice. Each retry is counted in the export_retries_total metric.
+func WithRetry(maxAttempts int, backoff BackoffConfig) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.RetryMaxAttempts = maxAttempts
+		o.RetryBackoff = backoff
+	}
+}
+
+// exportWithRetry exports items, retrying failures as set with WithRetry.
+func (bvp *BatchItemProcessor[T]) exportWithRetry(ctx context.Context, exporter ItemExporter[T], items []*T) error {
+	err := bvp.exportAttempt(ctx, exporter, items, 1)
+
+	for attempt := 1; attempt < bvp.o.RetryMaxAttempts && retryable(ctx, err); attempt++ {
+		bvp.metrics.IncExportRetries(bvp.label)
+
+		bvp.log.WithError(err).WithField("attempt", attempt).Debug("Export failed, retrying")
+
+		if sleep(ctx, bvp.o.RetryBackoff.Next(attempt)) != nil {
+			break
+		}
+
+		err = bvp.exportAttempt(ctx, exporter, items, attempt+1)
+	}
+
+	return err
+}
+
+// exportAttempt makes a single attempt at exporting items, numbered from 1.
+func (bvp *BatchItemProcessor[T]) exportAttempt(ctx context.Context, exporter ItemExporter[T], items []*T, attempt int) error {
+	ctx, cancel := bvp.attemptContext(ctx)
+	defer cancel()
+
+	ctx, span := bvp.startAttemptSpan(ctx, len(items), attempt)
+
+	startTime := time.Now()
+
+	err := exportItems(ctx, exporter, items)
+
+	duration := time.Since(startTime)
+
+	endSpan(span, err)
+
+	bvp.metrics.ObserveExportDuration(bvp.label, duration)
+	bvp.exportLatency.observe(duration)
+
+	return err
+}
+
+// retryable reports whether a failed export may be retried.
+func retryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, ErrExporterClosed) {
+		return false
+	}
+
+	var partial *PartialExportError
+
+	return !errors.As(err, &partial)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,128 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// flakyExporter fails the first failures exports.
+type flakyExporter struct {
+	mockExporter[int]
+	failures int64
+	attempts atomic.Int64
+}
+
+func (f *flakyExporter) ExportItems(ctx context.Context, items []*int) error {
+	if f.attempts.Add(1) <= f.failures {
+		return errors.New("export failed")
+	}
+
+	return f.mockExporter.ExportItems(ctx, items)
+}
+
+func TestBatchItemProcessor_Retry(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	backoff := BackoffConfig{InitialInterval: time.Millisecond, Multiplier: 2}
+
+	for _, tc := range []struct {
+		name     string
+		failures int64
+		wantErr  bool
+	}{
+		{name: "recovers", failures: 2},
+		{name: "gives-up", failures: 3, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter := &flakyExporter{failures: tc.failures}
+
+			proc, err := NewBatchItemProcessor[int](exporter, "retry-"+tc.name, log,
+				WithShippingMethod(ShippingMethodSync),
+				WithMaxExportBatchSize(5),
+				WithRetry(3, backoff),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+
+			if err := proc.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			defer proc.Shutdown(ctx)
+
+			before := counterValue(t, DefaultMetrics.exportRetries.WithLabelValues("retry-"+tc.name))
+
+			err = proc.Write(ctx, ints(5))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			if got := exporter.attempts.Load(); got != 3 {
+				t.Fatalf("expected 3 attempts, got %d", got)
+			}
+
+			retries := counterValue(t, DefaultMetrics.exportRetries.WithLabelValues("retry-"+tc.name)) - before
+			if retries != 2 {
+				t.Fatalf("expected 2 retries counted, got %v", retries)
+			}
+		})
+	}
+}
+
+// hangingExporter hangs until its context is done on its first attempts.
+type hangingExporter struct {
+	flakyExporter
+}
+
+func (h *hangingExporter) ExportItems(ctx context.Context, items []*int) error {
+	if h.attempts.Add(1) <= h.failures {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}
+
+	return h.mockExporter.ExportItems(ctx, items)
+}
+
+func TestBatchItemProcessor_RetryTimeout(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	exporter := &hangingExporter{flakyExporter{failures: 1}}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "retry-timeout", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(5),
+		WithExportTimeout(20*time.Millisecond),
+		WithRetry(2, BackoffConfig{InitialInterval: time.Millisecond, Multiplier: 2}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer proc.Shutdown(ctx)
+
+	// Each attempt gets its own timeout, so a timed out attempt is retried.
+	if err := proc.Write(ctx, ints(5)); err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+
+	if got := exporter.attempts.Load(); got != 2 {
+		t.Fatalf("expected 2 attempts, got %d", got)
+	}
+}
@@ -0,0 +1,138 @@
+package processor
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// QueueKindSharded queues items in one ring buffer per P, picked by the
+// runtime's per-P random source, so many producers rarely contend on the
+// same lock or cache line. Items from different producers can be dequeued
+// in a different order than they were enqueued.
+const QueueKindSharded QueueKind = "sharded"
+
+// ShardedQueue is a Queue striped over ring buffers, one per P by default.
+// Each shard is locked on its own and padded to its own cache lines.
+type ShardedQueue[E any] struct {
+	shards []queueShard[E]
+	// next is the shard the consumer pops from next.
+	next int
+}
+
+type queueShard[E any] struct {
+	mu     sync.Mutex
+	items  []E
+	head   int
+	len    atomic.Int64
+	closed bool
+
+	_ [64]byte
+}
+
+// NewShardedQueue returns a sharded queue holding up to size items over
+// GOMAXPROCS shards.
+func NewShardedQueue[E any](size int) *ShardedQueue[E] {
+	shards := min(runtime.GOMAXPROCS(0), max(size, 1))
+	perShard := (size + shards - 1) / shards
+
+	q := &ShardedQueue[E]{shards: make([]queueShard[E], shards)}
+
+	for i := range q.shards {
+		q.shards[i].items = make([]E, perShard)
+	}
+
+	return q
+}
+
+// Push adds the item to a random shard, trying the others if it's full.
+func (q *ShardedQueue[E]) Push(item E) bool {
+	start := rand.IntN(len(q.shards))
+
+	for i := range q.shards {
+		shard := &q.shards[(start+i)%len(q.shards)]
+
+		pushed, closed := shard.push(item)
+		if pushed || closed {
+			return pushed
+		}
+	}
+
+	return false
+}
+
+func (q *ShardedQueue[E]) Pop() (E, bool) {
+	for range q.shards {
+		shard := &q.shards[q.next]
+
+		q.next = (q.next + 1) % len(q.shards)
+
+		if item, ok := shard.pop(); ok {
+			return item, true
+		}
+	}
+
+	var zero E
+
+	return zero, false
+}
+
+func (q *ShardedQueue[E]) Len() int {
+	n := 0
+
+	for i := range q.shards {
+		n += int(q.shards[i].len.Load())
+	}
+
+	return n
+}
+
+func (q *ShardedQueue[E]) Close() {
+	for i := range q.shards {
+		shard := &q.shards[i]
+
+		shard.mu.Lock()
+		shard.closed = true
+		shard.mu.Unlock()
+	}
+}
+
+func (s *queueShard[E]) push(item E) (pushed, closed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := int(s.len.Load())
+
+	if s.closed || n == len(s.items) {
+		return false, s.closed
+	}
+
+	s.items[(s.head+n)%len(s.items)] = item
+	s.len.Add(1)
+
+	return true, false
+}
+
+func (s *queueShard[E]) pop() (E, bool) {
+	var zero E
+
+	// Skip empty shards without taking their lock.
+	if s.len.Load() == 0 {
+		return zero, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.len.Load() == 0 {
+		return zero, false
+	}
+
+	item := s.items[s.head]
+	s.items[s.head] = zero
+	s.head = (s.head + 1) % len(s.items)
+	s.len.Add(-1)
+
+	return item, true
+}
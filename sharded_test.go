@@ -0,0 +1,102 @@
+package processor
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestShardedQueue_ConcurrentPush(t *testing.T) {
+	const (
+		producers = 8
+		perWriter = 1000
+	)
+
+	q := NewShardedQueue[int](producers * perWriter)
+
+	var wg sync.WaitGroup
+
+	for p := range producers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range perWriter {
+				if !q.Push(p*perWriter + i) {
+					t.Errorf("push failed below capacity")
+
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if q.Len() != producers*perWriter {
+		t.Fatalf("expected %d queued items, got %d", producers*perWriter, q.Len())
+	}
+
+	// Each producer's items stay in order within its shard, and every
+	// item is popped exactly once.
+	seen := make([]bool, producers*perWriter)
+
+	for {
+		item, ok := q.Pop()
+		if !ok {
+			break
+		}
+
+		if seen[item] {
+			t.Fatalf("item %d popped twice", item)
+		}
+
+		seen[item] = true
+	}
+
+	for item, ok := range seen {
+		if !ok {
+			t.Fatalf("item %d was lost", item)
+		}
+	}
+}
+
+func BenchmarkQueue_ParallelPush(b *testing.B) {
+	for _, kind := range queueKinds {
+		b.Run(string(kind), func(b *testing.B) {
+			q, err := NewQueue[int](kind, 1<<16)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			// A single consumer drains the queue, like the queue pump.
+			done := make(chan struct{})
+			drained := make(chan struct{})
+
+			go func() {
+				defer close(drained)
+
+				for {
+					select {
+					case <-done:
+						return
+					default:
+						q.Pop()
+					}
+				}
+			}()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					for !q.Push(1) {
+						runtime.Gosched()
+					}
+				}
+			})
+
+			close(done)
+			<-drained
+		})
+	}
+}
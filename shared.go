@@ -0,0 +1,127 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SharedExporter lets many processors export through one exporter, such as
+// one holding the process's database connection pool. At most concurrency
+// exports run at once across every processor; the rest wait for a slot, and
+// the wait is recorded per source as shared_exporter_wait_duration_seconds.
+//
+// Give each processor its own handle from For. The exporter is started by
+// the first handle started and shut down once every handle has been shut
+// down.
+type SharedExporter[T any] struct {
+	exporter ItemExporter[T]
+	metrics  *recorder
+	slots    chan struct{}
+
+	startOnce sync.Once
+	startErr  error
+
+	mu      sync.Mutex
+	handles int
+}
+
+// NewSharedExporter returns a shared exporter running up to concurrency
+// exports at once, or one if concurrency isn't positive. Waits are recorded
+// with metrics, or DefaultMetrics if it is nil.
+func NewSharedExporter[T any](exporter ItemExporter[T], concurrency int, metrics MetricsRecorder) *SharedExporter[T] {
+	if metrics == nil {
+		metrics = DefaultMetrics
+	}
+
+	return &SharedExporter[T]{
+		exporter: exporter,
+		metrics:  newRecorder(metrics),
+		slots:    make(chan struct{}, max(concurrency, 1)),
+	}
+}
+
+// For returns a handle on the exporter for one source, usually the name of
+// the processor it is given to.
+func (s *SharedExporter[T]) For(source string) ItemExporter[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handles++
+
+	return &sharedHandle[T]{shared: s, source: source}
+}
+
+// sharedHandle is one source's handle on a SharedExporter.
+type sharedHandle[T any] struct {
+	shared *SharedExporter[T]
+	source string
+
+	shutdownOnce sync.Once
+}
+
+var (
+	_ ExporterStarter = (*sharedHandle[struct{}])(nil)
+	_ HealthChecker   = (*sharedHandle[struct{}])(nil)
+)
+
+// Start starts the shared exporter the first time any handle is started.
+func (h *sharedHandle[T]) Start(ctx context.Context) error {
+	s := h.shared
+
+	s.startOnce.Do(func() {
+		s.startErr = startExporter(ctx, s.exporter)
+	})
+
+	return s.startErr
+}
+
+// ExportItems waits for a slot, then exports through the shared exporter.
+func (h *sharedHandle[T]) ExportItems(ctx context.Context, items []*T) error {
+	s := h.shared
+	start := time.Now()
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	defer func() { <-s.slots }()
+
+	s.metrics.ObserveSharedExporterWait(h.source, time.Since(start))
+
+	return s.exporter.ExportItems(ctx, items)
+}
+
+// HealthCheck checks the shared exporter, if it implements HealthChecker.
+func (h *sharedHandle[T]) HealthCheck(ctx context.Context) error {
+	checker, ok := h.shared.exporter.(HealthChecker)
+	if !ok {
+		return nil
+	}
+
+	return checker.HealthCheck(ctx)
+}
+
+// Shutdown releases the handle, shutting the shared exporter down once
+// every handle is released.
+func (h *sharedHandle[T]) Shutdown(ctx context.Context) error {
+	last := false
+
+	h.shutdownOnce.Do(func() {
+		s := h.shared
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.handles--
+		last = s.handles == 0
+	})
+
+	if !last {
+		return nil
+	}
+
+	return h.shared.exporter.Shutdown(ctx)
+}
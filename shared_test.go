@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// overlapExporter records the most exports it saw running at once and how
+// often it was shut down.
+type overlapExporter struct {
+	mockExporter[int]
+	running   atomic.Int64
+	peak      atomic.Int64
+	shutdowns atomic.Int64
+}
+
+func (e *overlapExporter) ExportItems(ctx context.Context, items []*int) error {
+	running := e.running.Add(1)
+	defer e.running.Add(-1)
+
+	if running > e.peak.Load() {
+		e.peak.Store(running)
+	}
+
+	return e.mockExporter.ExportItems(ctx, items)
+}
+
+func (e *overlapExporter) Shutdown(_ context.Context) error {
+	e.shutdowns.Add(1)
+
+	return nil
+}
+
+func TestSharedExporter(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &overlapExporter{}
+	exporter.exportDelay = time.Millisecond
+
+	shared := NewSharedExporter[int](exporter, 1, nil)
+	ctx := context.Background()
+
+	procs := make([]*BatchItemProcessor[int], 3)
+
+	for i := range procs {
+		name := []string{"shared-a", "shared-b", "shared-c"}[i]
+
+		proc, err := NewBatchItemProcessor[int](shared.For(name), name, log,
+			WithMaxExportBatchSize(5),
+			WithWorkers(2),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := proc.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		procs[i] = proc
+	}
+
+	for i := range 100 {
+		for _, proc := range procs {
+			if err := proc.Write(ctx, []*int{&i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for i, proc := range procs {
+		if err := proc.Shutdown(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if want := int64(i / (len(procs) - 1)); exporter.shutdowns.Load() != want {
+			t.Fatalf("expected %d exporter shutdowns after %d processors, got %d", want, i+1, exporter.shutdowns.Load())
+		}
+	}
+
+	if got := exporter.exportCount.Load(); got != 300 {
+		t.Fatalf("expected 300 items exported, got %d", got)
+	}
+
+	if got := exporter.peak.Load(); got != 1 {
+		t.Fatalf("expected exports to be serialized, saw %d at once", got)
+	}
+}
@@ -0,0 +1,257 @@
+// Package kafka provides a pipeline source consuming from Kafka with
+// franz-go. Offsets are committed only once the records' items have been
+// exported, so delivery from topic to sink is at least once.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethpandaops/go-batch-processor/pipeline"
+	"github.com/sirupsen/logrus"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// DefaultMaxInFlight is the default number of fetches written concurrently.
+const DefaultMaxInFlight = 4
+
+// Client is the part of a franz-go client the source uses. *kgo.Client
+// implements it; it must be a consumer group member with auto committing
+// disabled through kgo.DisableAutoCommit.
+type Client interface {
+	PollFetches(ctx context.Context) kgo.Fetches
+	CommitRecords(ctx context.Context, rs ...*kgo.Record) error
+}
+
+// Decoder turns a record in to an item. Returning an error skips the record;
+// it is still committed, so a malformed record can't block its partition.
+type Decoder[T any] func(record *kgo.Record) (*T, error)
+
+// Option configures a Source.
+type Option func(*options)
+
+type options struct {
+	maxInFlight int
+}
+
+// WithMaxInFlight sets how many fetches are written concurrently. Each
+// write holds a fetch's records until they're exported, so this bounds the
+// records awaiting export.
+func WithMaxInFlight(n int) Option {
+	return func(o *options) {
+		o.maxInFlight = n
+	}
+}
+
+// Source writes the records consumed by a Kafka client to a pipeline,
+// committing their offsets once the write returns. Writes only return once
+// items are exported with the sync shipping method, which the source relies
+// on: with the async method offsets are committed once items are queued and
+// delivery is at most once.
+//
+// Fetches are written concurrently but committed in the order they were
+// polled, so a committed offset never skips records awaiting export. If a
+// write fails the source stops without committing it, and the records are
+// consumed again after a restart or rebalance.
+type Source[T any] struct {
+	client Client
+	decode Decoder[T]
+	log    logrus.FieldLogger
+	o      options
+}
+
+var _ pipeline.Source[struct{}] = (*Source[struct{}])(nil)
+
+// NewSource creates a source consuming from client and decoding records with
+// decode.
+func NewSource[T any](client Client, decode Decoder[T], log logrus.FieldLogger, opts ...Option) *Source[T] {
+	o := options{maxInFlight: DefaultMaxInFlight}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	o.maxInFlight = max(o.maxInFlight, 1)
+
+	return &Source[T]{
+		client: client,
+		decode: decode,
+		log:    log.WithField("source", "kafka"),
+		o:      o,
+	}
+}
+
+// Run consumes until ctx is cancelled, the client is closed or a write
+// fails. It waits for writes in flight and commits them before returning.
+func (s *Source[T]) Run(ctx context.Context, write pipeline.WriteFunc[T]) error {
+	c := &committer{client: s.client}
+
+	// Writes outlive the cancellation of ctx, so records already polled are
+	// still exported and committed.
+	writeCtx := context.WithoutCancel(ctx)
+
+	// A failed write stops polling.
+	pollCtx, stopPolling := context.WithCancel(ctx)
+	defer stopPolling()
+
+	slots := make(chan struct{}, s.o.maxInFlight)
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		writeErr error
+	)
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-pollCtx.Done():
+		}
+
+		if pollCtx.Err() != nil {
+			break
+		}
+
+		fetches := s.client.PollFetches(pollCtx)
+
+		if fetches.IsClientClosed() || pollCtx.Err() != nil {
+			break
+		}
+
+		if err := fetchErr(fetches); err != nil {
+			stopPolling()
+			wg.Wait()
+
+			return err
+		}
+
+		items, records := s.decodeFetches(fetches)
+		pending := c.track(records)
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if len(items) > 0 {
+				if err := write(writeCtx, items); err != nil {
+					failOnce.Do(func() {
+						writeErr = fmt.Errorf("failed to write records: %w", err)
+						stopPolling()
+					})
+
+					return
+				}
+			}
+
+			if err := c.done(writeCtx, pending); err != nil {
+				s.log.WithError(err).Warn("Failed to commit offsets")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return writeErr
+}
+
+// decodeFetches decodes every record in fetches, returning the items and the
+// records to commit once they're exported.
+func (s *Source[T]) decodeFetches(fetches kgo.Fetches) ([]*T, []*kgo.Record) {
+	var (
+		items   []*T
+		records []*kgo.Record
+	)
+
+	fetches.EachRecord(func(record *kgo.Record) {
+		records = append(records, record)
+
+		item, err := s.decode(record)
+		if err != nil {
+			s.log.WithError(err).WithFields(logrus.Fields{
+				"topic":     record.Topic,
+				"partition": record.Partition,
+				"offset":    record.Offset,
+			}).Warn("Skipping record that failed to decode")
+
+			return
+		}
+
+		items = append(items, item)
+	})
+
+	return items, records
+}
+
+// fetchErr returns the errors in a fetch, ignoring those from ctx being
+// cancelled.
+func fetchErr(fetches kgo.Fetches) error {
+	var errs []error
+
+	for _, fe := range fetches.Errors() {
+		if errors.Is(fe.Err, context.Canceled) || errors.Is(fe.Err, context.DeadlineExceeded) {
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("failed to fetch %s/%d: %w", fe.Topic, fe.Partition, fe.Err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// committer commits fetches in the order they were polled, once each fetch
+// and every fetch before it is exported.
+type committer struct {
+	client Client
+
+	mu      sync.Mutex
+	pending []*pendingFetch
+
+	// commitMu keeps commits in order.
+	commitMu sync.Mutex
+}
+
+type pendingFetch struct {
+	records []*kgo.Record
+	done    bool
+}
+
+// track queues a fetch's records for commit.
+func (c *committer) track(records []*kgo.Record) *pendingFetch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := &pendingFetch{records: records}
+	c.pending = append(c.pending, p)
+
+	return p
+}
+
+// done marks a fetch exported and commits every exported fetch at the head
+// of the queue.
+func (c *committer) done(ctx context.Context, p *pendingFetch) error {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	c.mu.Lock()
+
+	p.done = true
+
+	var records []*kgo.Record
+
+	for len(c.pending) > 0 && c.pending[0].done {
+		records = append(records, c.pending[0].records...)
+		c.pending = c.pending[1:]
+	}
+
+	c.mu.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	return c.client.CommitRecords(ctx, records...)
+}
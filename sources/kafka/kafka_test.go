@@ -0,0 +1,168 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+type event struct {
+	Slot uint64 `json:"slot"`
+}
+
+func decodeEvent(record *kgo.Record) (*event, error) {
+	var e event
+
+	if err := json.Unmarshal(record.Value, &e); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// fakeClient serves one fetch per poll, then blocks until ctx is done.
+type fakeClient struct {
+	fetches chan kgo.Fetches
+
+	mu        sync.Mutex
+	committed map[int32]int64
+}
+
+func newFakeClient(batches ...[]*kgo.Record) *fakeClient {
+	c := &fakeClient{
+		fetches:   make(chan kgo.Fetches, len(batches)),
+		committed: make(map[int32]int64),
+	}
+
+	for _, records := range batches {
+		partitions := make(map[int32][]*kgo.Record)
+		for _, r := range records {
+			partitions[r.Partition] = append(partitions[r.Partition], r)
+		}
+
+		topic := kgo.FetchTopic{Topic: "events"}
+		for partition, rs := range partitions {
+			topic.Partitions = append(topic.Partitions, kgo.FetchPartition{Partition: partition, Records: rs})
+		}
+
+		c.fetches <- kgo.Fetches{{Topics: []kgo.FetchTopic{topic}}}
+	}
+
+	return c
+}
+
+func (c *fakeClient) PollFetches(ctx context.Context) kgo.Fetches {
+	select {
+	case f := <-c.fetches:
+		return f
+	case <-ctx.Done():
+		return kgo.Fetches{{Topics: []kgo.FetchTopic{{Partitions: []kgo.FetchPartition{{Err: ctx.Err()}}}}}}
+	}
+}
+
+func (c *fakeClient) CommitRecords(_ context.Context, rs ...*kgo.Record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, r := range rs {
+		c.committed[r.Partition] = max(c.committed[r.Partition], r.Offset+1)
+	}
+
+	return nil
+}
+
+func (c *fakeClient) offset(partition int32) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.committed[partition]
+}
+
+func record(partition int32, offset int64, value string) *kgo.Record {
+	return &kgo.Record{Topic: "events", Partition: partition, Offset: offset, Value: []byte(value)}
+}
+
+func TestSource_CommitsAfterWrite(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	client := newFakeClient(
+		[]*kgo.Record{record(0, 0, `{"slot":1}`), record(1, 0, `{"slot":2}`)},
+		[]*kgo.Record{record(0, 1, `not json`), record(0, 2, `{"slot":3}`)},
+	)
+
+	// The first fetch's write is held until the second one is exported.
+	release := make(chan struct{})
+	written := make(chan uint64, 3)
+
+	write := func(_ context.Context, items []*event) error {
+		if items[0].Slot == 1 {
+			<-release
+		}
+
+		for _, item := range items {
+			written <- item.Slot
+		}
+
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- NewSource(client, decodeEvent, log).Run(ctx, write)
+	}()
+
+	if slot := <-written; slot != 3 {
+		t.Fatalf("expected the second fetch to be written first, got slot %d", slot)
+	}
+
+	// The second fetch can't be committed ahead of the first.
+	if got := client.offset(0); got != 0 {
+		t.Fatalf("expected no commit while the first fetch is pending, got offset %d", got)
+	}
+
+	close(release)
+	<-written
+	<-written
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The record that failed to decode is committed with its fetch.
+	if got := client.offset(0); got != 3 {
+		t.Errorf("expected partition 0 committed to offset 3, got %d", got)
+	}
+
+	if got := client.offset(1); got != 1 {
+		t.Errorf("expected partition 1 committed to offset 1, got %d", got)
+	}
+}
+
+func TestSource_StopsOnWriteError(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	client := newFakeClient([]*kgo.Record{record(0, 0, `{"slot":1}`)})
+	writeErr := errors.New("export failed")
+
+	err := NewSource(client, decodeEvent, log).Run(context.Background(), func(_ context.Context, _ []*event) error {
+		return writeErr
+	})
+	if !errors.Is(err, writeErr) {
+		t.Fatalf("expected the write error, got %v", err)
+	}
+
+	if got := client.offset(0); got != 0 {
+		t.Errorf("expected nothing committed, got offset %d", got)
+	}
+}
@@ -0,0 +1,301 @@
+// Package outbox provides a pipeline source relaying items from a database
+// table, for applications applying the outbox pattern with a relational
+// database they already run. Items are inserted in to the table, usually in
+// the transaction that produced them, and relayed in batches, each deleted
+// in the same transaction it was selected in once its items are exported.
+// A batch is only relayed again if the relay stops between its export and
+// its commit, so delivery is exactly once in all but that window.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/ethpandaops/go-batch-processor/pipeline"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultBatchSize is the default maximum number of rows relayed at
+	// once.
+	DefaultBatchSize = 512
+	// DefaultPollInterval is the default time waited for rows once the
+	// table is empty.
+	DefaultPollInterval = time.Second
+)
+
+// Dialect holds the SQL that differs between databases.
+type Dialect struct {
+	// Placeholder returns the placeholder of the nth query argument,
+	// counting from 1.
+	Placeholder func(n int) string
+	// Lock is appended to the select of each batch, so concurrent relays
+	// don't select the same rows.
+	Lock string
+	// Schema creates the table, with the table name for %s.
+	Schema string
+}
+
+var (
+	// Postgres is the dialect of PostgreSQL. Concurrent relays skip rows
+	// locked by each other.
+	Postgres = Dialect{
+		Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		Lock:        " FOR UPDATE SKIP LOCKED",
+		Schema:      "CREATE TABLE IF NOT EXISTS %s (id BIGSERIAL PRIMARY KEY, payload BYTEA NOT NULL)",
+	}
+	// MySQL is the dialect of MySQL 8 and later. Concurrent relays skip
+	// rows locked by each other.
+	MySQL = Dialect{
+		Placeholder: func(int) string { return "?" },
+		Lock:        " FOR UPDATE SKIP LOCKED",
+		Schema:      "CREATE TABLE IF NOT EXISTS %s (id BIGINT AUTO_INCREMENT PRIMARY KEY, payload LONGBLOB NOT NULL)",
+	}
+	// SQLite is the dialect of SQLite, which has no row locks: run a single
+	// relay per table.
+	SQLite = Dialect{
+		Placeholder: func(int) string { return "?" },
+		Schema:      "CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, payload BLOB NOT NULL)",
+	}
+)
+
+// Execer runs statements. *sql.DB, *sql.Tx and *sql.Conn implement it.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Option configures an Outbox.
+type Option func(*options)
+
+type options struct {
+	dialect      Dialect
+	batchSize    int
+	pollInterval time.Duration
+}
+
+// WithDialect sets the dialect of the database. It defaults to Postgres.
+func WithDialect(dialect Dialect) Option {
+	return func(o *options) {
+		o.dialect = dialect
+	}
+}
+
+// WithBatchSize sets the maximum number of rows relayed at once.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithPollInterval sets how long the relay waits before looking for rows
+// again once the table is empty.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+	}
+}
+
+// Outbox is a durable queue in a database table, with a row per item.
+//
+// Run relays the rows to a pipeline in id order and deletes them once the
+// write returns. Writes only return once items are exported with the sync
+// shipping method, which the relay relies on: with the async method rows
+// are deleted once items are queued and may be lost in a crash.
+type Outbox[T any] struct {
+	db    *sql.DB
+	table string
+	codec processor.Codec[T]
+	log   logrus.FieldLogger
+	o     options
+
+	insert      string
+	selectBatch string
+}
+
+var _ pipeline.Source[struct{}] = (*Outbox[struct{}])(nil)
+
+// New creates an outbox in table, encoding items with codec. The table name
+// is used in queries as is, so it must not come from untrusted input.
+func New[T any](db *sql.DB, table string, codec processor.Codec[T], log logrus.FieldLogger, opts ...Option) *Outbox[T] {
+	o := options{
+		dialect:      Postgres,
+		batchSize:    DefaultBatchSize,
+		pollInterval: DefaultPollInterval,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	o.batchSize = max(o.batchSize, 1)
+
+	return &Outbox[T]{
+		db:    db,
+		table: table,
+		codec: codec,
+		log:   log.WithField("source", "outbox"),
+		o:     o,
+
+		insert:      fmt.Sprintf("INSERT INTO %s (payload) VALUES (%s)", table, o.dialect.Placeholder(1)),
+		selectBatch: fmt.Sprintf("SELECT id, payload FROM %s ORDER BY id LIMIT %s%s", table, o.dialect.Placeholder(1), o.dialect.Lock),
+	}
+}
+
+// CreateTable creates the outbox table if it doesn't exist.
+func (o *Outbox[T]) CreateTable(ctx context.Context) error {
+	if _, err := o.db.ExecContext(ctx, fmt.Sprintf(o.o.dialect.Schema, o.table)); err != nil {
+		return fmt.Errorf("failed to create outbox table: %w", err)
+	}
+
+	return nil
+}
+
+// Write inserts items in to the outbox.
+func (o *Outbox[T]) Write(ctx context.Context, items []*T) error {
+	return o.Insert(ctx, o.db, items)
+}
+
+// Insert inserts items in to the outbox with exec, usually the transaction
+// that produced them, so they're relayed if and only if it commits.
+func (o *Outbox[T]) Insert(ctx context.Context, exec Execer, items []*T) error {
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+
+		payload, err := o.codec.Encode([]*T{item})
+		if err != nil {
+			return fmt.Errorf("failed to encode item: %w", err)
+		}
+
+		if _, err := exec.ExecContext(ctx, o.insert, payload); err != nil {
+			return fmt.Errorf("failed to insert item: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Run relays rows until ctx is cancelled or a write fails. A batch being
+// relayed when ctx is cancelled is still exported and deleted. If a write
+// fails its batch is left in the table and Run returns the error.
+func (o *Outbox[T]) Run(ctx context.Context, write pipeline.WriteFunc[T]) error {
+	// Batches outlive the cancellation of ctx, so rows already selected
+	// are still exported and deleted.
+	relayCtx := context.WithoutCancel(ctx)
+
+	for ctx.Err() == nil {
+		relayed, err := o.relay(relayCtx, write)
+		if err != nil {
+			return err
+		}
+
+		// A full batch means more rows may be waiting.
+		if relayed == o.o.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(o.o.pollInterval):
+		}
+	}
+
+	return nil
+}
+
+// relay writes a batch of rows and deletes them, returning the number of
+// rows relayed.
+func (o *Outbox[T]) relay(ctx context.Context, write pipeline.WriteFunc[T]) (int, error) {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin relay: %w", err)
+	}
+
+	//nolint:errcheck // Rolling back after a commit is a no-op.
+	defer tx.Rollback()
+
+	ids, items, err := o.selectRows(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	if len(items) > 0 {
+		if err := write(ctx, items); err != nil {
+			return 0, fmt.Errorf("failed to write items: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, o.deleteQuery(len(ids)), ids...); err != nil {
+		return 0, fmt.Errorf("failed to delete relayed rows: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit relay: %w", err)
+	}
+
+	return len(ids), nil
+}
+
+// selectRows selects the next batch of rows, returning their ids and items.
+// Rows that fail to decode are logged and deleted with the batch, so a
+// malformed row can't block the outbox.
+func (o *Outbox[T]) selectRows(ctx context.Context, tx *sql.Tx) ([]any, []*T, error) {
+	rows, err := tx.QueryContext(ctx, o.selectBatch, o.o.batchSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to select rows: %w", err)
+	}
+
+	defer rows.Close()
+
+	var (
+		ids   []any
+		items []*T
+	)
+
+	for rows.Next() {
+		var (
+			id      int64
+			payload []byte
+		)
+
+		if err := rows.Scan(&id, &payload); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		ids = append(ids, id)
+
+		decoded, err := o.codec.Decode(payload)
+		if err != nil {
+			o.log.WithError(err).WithField("id", id).Warn("Dropping row that failed to decode")
+
+			continue
+		}
+
+		items = append(items, decoded...)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to select rows: %w", err)
+	}
+
+	return ids, items, nil
+}
+
+// deleteQuery returns the statement deleting n rows by id.
+func (o *Outbox[T]) deleteQuery(n int) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = o.o.dialect.Placeholder(i + 1)
+	}
+
+	return fmt.Sprintf("DELETE FROM %s WHERE id IN (%s)", o.table, strings.Join(placeholders, ", "))
+}
@@ -0,0 +1,293 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/sirupsen/logrus"
+)
+
+type event struct {
+	Slot uint64 `json:"slot"`
+}
+
+// fakeTable is an outbox table understanding the outbox's queries. Deletes
+// made in a transaction only apply once it commits.
+type fakeTable struct {
+	mu     sync.Mutex
+	nextID int64
+	rows   map[int64][]byte
+}
+
+func openFake(t *testing.T) (*sql.DB, *fakeTable) {
+	t.Helper()
+
+	table := &fakeTable{rows: make(map[int64][]byte)}
+	db := sql.OpenDB(fakeConnector{table: table})
+
+	t.Cleanup(func() { db.Close() })
+
+	return db, table
+}
+
+func (f *fakeTable) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.rows)
+}
+
+type fakeConnector struct {
+	table *fakeTable
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{table: c.table}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	table   *fakeTable
+	deletes []int64
+	inTx    bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	c.deletes = nil
+
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.table.mu.Lock()
+	defer c.table.mu.Unlock()
+
+	for _, id := range c.deletes {
+		delete(c.table.rows, id)
+	}
+
+	c.inTx = false
+
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.inTx = false
+
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	table := s.conn.table
+
+	table.mu.Lock()
+	defer table.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		table.nextID++
+		table.rows[table.nextID] = args[0].([]byte)
+	case strings.HasPrefix(s.query, "DELETE"):
+		for _, arg := range args {
+			s.conn.deletes = append(s.conn.deletes, arg.(int64))
+		}
+
+		if !s.conn.inTx {
+			for _, id := range s.conn.deletes {
+				delete(table.rows, id)
+			}
+		}
+	case strings.HasPrefix(s.query, "CREATE"):
+	default:
+		return nil, errors.New("unexpected statement: " + s.query)
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if !strings.HasPrefix(s.query, "SELECT") {
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+
+	table := s.conn.table
+
+	table.mu.Lock()
+	defer table.mu.Unlock()
+
+	ids := make([]int64, 0, len(table.rows))
+	for id := range table.rows {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	rows := &fakeRows{}
+
+	for _, id := range ids[:min(len(ids), int(args[0].(int64)))] {
+		rows.rows = append(rows.rows, []driver.Value{id, table.rows[id]})
+	}
+
+	return rows, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id", "payload"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+func newOutbox(db *sql.DB) *Outbox[event] {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	return New[event](db, "outbox", processor.JSONCodec[event]{}, log,
+		WithDialect(SQLite),
+		WithBatchSize(2),
+		WithPollInterval(10*time.Millisecond),
+	)
+}
+
+func TestOutbox_Relay(t *testing.T) {
+	db, table := openFake(t)
+	o := newOutbox(db)
+
+	ctx := context.Background()
+
+	if err := o.CreateTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.Write(ctx, []*event{{Slot: 1}, {Slot: 2}, {Slot: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A malformed row is dropped rather than blocking the rows after it.
+	if _, err := db.ExecContext(ctx, "INSERT INTO outbox (payload) VALUES (?)", []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := o.Write(ctx, []*event{{Slot: 4}, {Slot: 5}}); err != nil {
+		t.Fatal(err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		slots []uint64
+	)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- o.Run(runCtx, func(_ context.Context, items []*event) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, item := range items {
+				slots = append(slots, item.Slot)
+			}
+
+			return nil
+		})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for table.len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !slices.Equal(slots, []uint64{1, 2, 3, 4, 5}) {
+		t.Fatalf("expected slots 1 to 5 relayed in order, got %v", slots)
+	}
+
+	if n := table.len(); n != 0 {
+		t.Fatalf("expected relayed rows deleted, %d left", n)
+	}
+}
+
+func TestOutbox_WriteFailure(t *testing.T) {
+	db, table := openFake(t)
+	o := newOutbox(db)
+
+	ctx := context.Background()
+
+	if err := o.Write(ctx, []*event{{Slot: 1}, {Slot: 2}, {Slot: 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	writeErr := errors.New("write failed")
+
+	err := o.Run(ctx, func(context.Context, []*event) error {
+		return writeErr
+	})
+	if !errors.Is(err, writeErr) {
+		t.Fatalf("expected the write error, got %v", err)
+	}
+
+	if n := table.len(); n != 3 {
+		t.Fatalf("expected the failed batch left in the table, got %d rows", n)
+	}
+}
@@ -0,0 +1,32 @@
+package processor
+
+// Stats is a point-in-time snapshot of a processor's state.
+type Stats struct {
+	// Workers is the configured number of workers, including the default
+	// derived when WithWorkers isn't set.
+	Workers int
+	// ActiveWorkers is the number of workers currently running. It is lower
+	// than Workers with lazy workers or an idle timeout.
+	ActiveWorkers int
+	// ItemsQueued is the number of items waiting to be batched.
+	ItemsQueued int
+	// MaxQueueSize is the configured queue capacity.
+	MaxQueueSize int
+	// MaxExportBatchSize is the configured maximum batch size.
+	MaxExportBatchSize int
+}
+
+// Stats returns a snapshot of the processor's state.
+func (bvp *BatchItemProcessor[T]) Stats() Stats {
+	bvp.workersMu.Lock()
+	active := bvp.activeWorkers
+	bvp.workersMu.Unlock()
+
+	return Stats{
+		Workers:            bvp.o.Workers,
+		ActiveWorkers:      active,
+		ItemsQueued:        bvp.queuedItems(),
+		MaxQueueSize:       bvp.o.MaxQueueSize,
+		MaxExportBatchSize: bvp.o.MaxExportBatchSize,
+	}
+}
@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_DefaultWorkers(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "test", log)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	if got, want := proc.Stats().Workers, runtime.GOMAXPROCS(0); got != want {
+		t.Errorf("expected %d workers, got %d", want, got)
+	}
+
+	// The default is capped at the number of full batches the queue holds.
+	proc, err = NewBatchItemProcessor[int](
+		&mockExporter[int]{},
+		"test",
+		log,
+		WithMaxQueueSize(10),
+		WithMaxExportBatchSize(10),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	if got := proc.Stats().Workers; got != 1 {
+		t.Errorf("expected 1 worker, got %d", got)
+	}
+}
+
+func TestBatchItemProcessor_Stats(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	proc, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{},
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithWorkers(3),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// The processor isn't started, so written items stay queued.
+	one, two := 1, 2
+	if err := proc.Write(ctx, []*int{&one, &two}); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	want := Stats{
+		Workers:            3,
+		ItemsQueued:        2,
+		MaxQueueSize:       100,
+		MaxExportBatchSize: 10,
+	}
+
+	if got := proc.Stats(); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,367 @@
+package processor
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StatsDFlavor selects the wire format used by StatsDMetrics.
+type StatsDFlavor string
+
+const (
+	// StatsDFlavorStatsD emits plain StatsD lines. Plain StatsD has no tags,
+	// so the processor name is embedded in the metric name.
+	StatsDFlavorStatsD StatsDFlavor = "statsd"
+	// StatsDFlavorDogStatsD emits DogStatsD lines with the processor name as a tag.
+	StatsDFlavorDogStatsD StatsDFlavor = "dogstatsd"
+)
+
+// StatsDOption is a functional option for StatsDMetrics.
+type StatsDOption func(m *StatsDMetrics)
+
+// StatsDMetrics is a MetricsRecorder that emits metrics to a StatsD or
+// DogStatsD agent over UDP. Send errors are ignored, as metrics must never
+// interfere with item processing.
+type StatsDMetrics struct {
+	conn   net.Conn
+	prefix string
+	flavor StatsDFlavor
+	tags   []string
+
+	mu       sync.Mutex
+	inFlight map[string]int64
+}
+
+var (
+	_ MetricsRecorder               = (*StatsDMetrics)(nil)
+	_ KeyMetricsRecorder            = (*StatsDMetrics)(nil)
+	_ DiskBufferMetricsRecorder     = (*StatsDMetrics)(nil)
+	_ HealthMetricsRecorder         = (*StatsDMetrics)(nil)
+	_ ProducerMetricsRecorder       = (*StatsDMetrics)(nil)
+	_ OverflowMetricsRecorder       = (*StatsDMetrics)(nil)
+	_ CapacityMetricsRecorder       = (*StatsDMetrics)(nil)
+	_ WorkStealingMetricsRecorder   = (*StatsDMetrics)(nil)
+	_ SharedExporterMetricsRecorder = (*StatsDMetrics)(nil)
+	_ RetryMetricsRecorder          = (*StatsDMetrics)(nil)
+	_ BatchMetricsRecorder          = (*StatsDMetrics)(nil)
+	_ DropPolicyMetricsRecorder     = (*StatsDMetrics)(nil)
+	_ WorkerMetricsRecorder         = (*StatsDMetrics)(nil)
+	_ InfoMetricsRecorder           = (*StatsDMetrics)(nil)
+	_ QueueLatencyMetricsRecorder   = (*StatsDMetrics)(nil)
+)
+
+// NewStatsDMetrics creates a StatsDMetrics that sends to the agent at addr
+// (host:port). Metric names are prefixed with "<namespace>_processor", matching
+// the names used by the Prometheus recorder.
+func NewStatsDMetrics(addr, namespace string, options ...StatsDOption) (*StatsDMetrics, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial statsd agent: %w", err)
+	}
+
+	if namespace != "" {
+		namespace += "_"
+	}
+
+	m := &StatsDMetrics{
+		conn:     conn,
+		prefix:   namespace + "processor",
+		flavor:   StatsDFlavorStatsD,
+		inFlight: make(map[string]int64),
+	}
+
+	for _, opt := range options {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+// WithStatsDFlavor sets the wire format.
+func WithStatsDFlavor(flavor StatsDFlavor) StatsDOption {
+	return func(m *StatsDMetrics) {
+		m.flavor = flavor
+	}
+}
+
+// WithStatsDTags sets constant tags ("key:value") added to every metric. Tags
+// are only emitted by the DogStatsD flavor.
+func WithStatsDTags(tags ...string) StatsDOption {
+	return func(m *StatsDMetrics) {
+		m.tags = append(m.tags, tags...)
+	}
+}
+
+// Close closes the underlying connection.
+func (m *StatsDMetrics) Close() error {
+	return m.conn.Close()
+}
+
+// SetItemsQueued sets the number of items queued for the given processor.
+func (m *StatsDMetrics) SetItemsQueued(name string, count float64) {
+	m.send(name, "items_queued", count, "g")
+}
+
+// IncItemsDroppedBy increments the number of items dropped by the given count.
+func (m *StatsDMetrics) IncItemsDroppedBy(name string, count float64) {
+	m.send(name, "items_dropped_total", count, "c")
+}
+
+// IncItemsExportedBy increments the number of items exported by the given count.
+func (m *StatsDMetrics) IncItemsExportedBy(name string, count float64) {
+	m.send(name, "items_exported_total", count, "c")
+}
+
+// IncItemsFailedBy increments the number of items failed by the given count.
+func (m *StatsDMetrics) IncItemsFailedBy(name string, count float64) {
+	m.send(name, "items_failed_total", count, "c")
+}
+
+// ObserveExportDuration records the duration of an export operation.
+func (m *StatsDMetrics) ObserveExportDuration(name string, duration time.Duration) {
+	m.send(name, "export_duration", float64(duration.Milliseconds()), "ms")
+}
+
+// ObserveBatchSize records the size of a processed batch.
+func (m *StatsDMetrics) ObserveBatchSize(name string, size float64) {
+	m.send(name, "batch_size", size, m.histogramType())
+}
+
+// SetWorkerCount sets the number of active workers for the given processor.
+func (m *StatsDMetrics) SetWorkerCount(name string, count float64) {
+	m.send(name, "worker_count", count, "g")
+}
+
+// IncWorkerExportInProgress increments the number of workers currently exporting.
+func (m *StatsDMetrics) IncWorkerExportInProgress(name string) {
+	m.send(name, "worker_export_in_progress", m.addInFlight(name, 1), "g")
+}
+
+// DecWorkerExportInProgress decrements the number of workers currently exporting.
+func (m *StatsDMetrics) DecWorkerExportInProgress(name string) {
+	m.send(name, "worker_export_in_progress", m.addInFlight(name, -1), "g")
+}
+
+// SetItemsThroughput sets the number of items exported per second for the given processor.
+func (m *StatsDMetrics) SetItemsThroughput(name string, rate float64) {
+	m.send(name, "throughput_items_per_second", rate, "g")
+}
+
+// SetBytesThroughput sets the number of bytes exported per second for the given processor.
+func (m *StatsDMetrics) SetBytesThroughput(name string, rate float64) {
+	m.send(name, "throughput_bytes_per_second", rate, "g")
+}
+
+// SetShutdownDuration sets the duration of the last shutdown for the given processor.
+func (m *StatsDMetrics) SetShutdownDuration(name string, duration time.Duration) {
+	m.send(name, "shutdown_duration", float64(duration.Milliseconds()), "ms")
+}
+
+// SetShutdownItemsDrained sets the number of items drained during the last shutdown.
+func (m *StatsDMetrics) SetShutdownItemsDrained(name string, count float64) {
+	m.send(name, "shutdown_items_drained", count, "g")
+}
+
+// SetShutdownItemsUnfinished sets the number of items not yet exported when the last shutdown timed out.
+func (m *StatsDMetrics) SetShutdownItemsUnfinished(name string, count float64) {
+	m.send(name, "shutdown_items_unfinished", count, "g")
+}
+
+// IncShutdowns increments the number of shutdowns with the given outcome.
+func (m *StatsDMetrics) IncShutdowns(name, outcome string) {
+	m.send(name, "shutdowns_total", 1, "c", "outcome:"+outcome)
+}
+
+// IncItemsDeduplicatedBy increments the number of items removed as duplicates by the given count.
+func (m *StatsDMetrics) IncItemsDeduplicatedBy(name string, count float64) {
+	m.send(name, "items_deduplicated_total", count, "c")
+}
+
+// IncItemsBufferedBy increments the number of items written to the disk buffer by the given count.
+func (m *StatsDMetrics) IncItemsBufferedBy(name string, count float64) {
+	m.send(name, "items_buffered_total", count, "c")
+}
+
+// IncItemsReplayedBy increments the number of items replayed from the disk buffer by the given count.
+func (m *StatsDMetrics) IncItemsReplayedBy(name string, count float64) {
+	m.send(name, "items_replayed_total", count, "c")
+}
+
+// SetDiskBufferBytes sets the size of the disk buffer for the given processor.
+func (m *StatsDMetrics) SetDiskBufferBytes(name string, size float64) {
+	m.send(name, "disk_buffer_bytes", size, "g")
+}
+
+// SetExporterHealthy sets whether the latest exporter health check passed.
+func (m *StatsDMetrics) SetExporterHealthy(name string, healthy bool) {
+	m.send(name, "exporter_healthy", boolToFloat(healthy), "g")
+}
+
+// IncProducerItemsDroppedBy increments the number of items dropped for the given producer.
+func (m *StatsDMetrics) IncProducerItemsDroppedBy(name, producer string, count float64) {
+	m.send(name, "producer_items_dropped_total", count, "c", "producer:"+producer)
+}
+
+// IncProducerItemsEnqueuedBy increments the number of items enqueued for the given producer.
+func (m *StatsDMetrics) IncProducerItemsEnqueuedBy(name, producer string, count float64) {
+	m.send(name, "producer_items_enqueued_total", count, "c", "producer:"+producer)
+}
+
+// IncItemsOverflowedBy increments the number of items written to the overflow by the given count.
+func (m *StatsDMetrics) IncItemsOverflowedBy(name string, count float64) {
+	m.send(name, "items_overflowed_total", count, "c")
+}
+
+// SetCapacityUtilization sets the arrival rate as a fraction of the estimated export capacity.
+func (m *StatsDMetrics) SetCapacityUtilization(name string, utilization float64) {
+	m.send(name, "capacity_utilization", utilization, "g")
+}
+
+// ObserveProducerBlockedDuration records how long a writer was blocked waiting for queue space.
+func (m *StatsDMetrics) ObserveProducerBlockedDuration(name string, duration time.Duration) {
+	m.send(name, "producer_blocked_duration", float64(duration.Milliseconds()), "ms")
+}
+
+// IncBatchesStolen increments the number of batches stolen by idle workers.
+func (m *StatsDMetrics) IncBatchesStolen(name string) {
+	m.send(name, "batches_stolen_total", 1, "c")
+}
+
+// ObserveSharedExporterWait records how long a batch waited for a slot on a shared exporter.
+func (m *StatsDMetrics) ObserveSharedExporterWait(name string, duration time.Duration) {
+	m.send(name, "shared_exporter_wait_duration", float64(duration.Milliseconds()), "ms")
+}
+
+// IncExportRetries increments the number of export attempts retried after a failure.
+func (m *StatsDMetrics) IncExportRetries(name string) {
+	m.send(name, "export_retries_total", 1, "c")
+}
+
+// SetItemsQueuedRatio sets the items queued as a fraction of the queue capacity for the given processor.
+func (m *StatsDMetrics) SetItemsQueuedRatio(name string, ratio float64) {
+	m.send(name, "items_queued_ratio", ratio, "g")
+}
+
+// ObserveBatchWaitDuration records how long a batch's oldest item waited between being queued and the batch's export starting.
+func (m *StatsDMetrics) ObserveBatchWaitDuration(name string, duration time.Duration) {
+	m.send(name, "batch_wait_duration", float64(duration.Milliseconds()), "ms")
+}
+
+// ObserveBatchBytes records the size in bytes of an exported batch.
+func (m *StatsDMetrics) ObserveBatchBytes(name string, bytes float64) {
+	m.send(name, "batch_bytes", bytes, m.histogramType())
+}
+
+// IncBytesExportedBy increments the number of bytes exported.
+func (m *StatsDMetrics) IncBytesExportedBy(name string, bytes float64) {
+	m.send(name, "bytes_exported_total", bytes, "c")
+}
+
+// IncQueueFullItemsDroppedBy increments the number of items dropped because the queue was full under the given drop policy.
+func (m *StatsDMetrics) IncQueueFullItemsDroppedBy(name, policy string, count float64) {
+	m.send(name, "queue_full_items_dropped_total", count, "c", "policy:"+policy)
+}
+
+// SetWorkerExporting sets whether the given worker is exporting a batch.
+func (m *StatsDMetrics) SetWorkerExporting(name, worker string, exporting bool) {
+	m.send(name, "worker_exporting", boolToFloat(exporting), "g", "worker:"+worker)
+}
+
+// ObserveWorkerExportDuration records how long the given worker took to export a batch.
+func (m *StatsDMetrics) ObserveWorkerExportDuration(name, worker string, duration time.Duration) {
+	m.send(name, "worker_export_duration", float64(duration.Milliseconds()), "ms", "worker:"+worker)
+}
+
+// SetProcessorInfo reports the configuration of the given processor, replacing any reported before.
+func (m *StatsDMetrics) SetProcessorInfo(name string, batchSize, queueSize, workers int, batchTimeout time.Duration) {
+	m.send(name, "processor_info", 1, "g",
+		"max_export_batch_size:"+strconv.Itoa(batchSize),
+		"max_queue_size:"+strconv.Itoa(queueSize),
+		"workers:"+strconv.Itoa(workers),
+		"batch_timeout:"+batchTimeout.String(),
+	)
+}
+
+// IncQueueLatencyItemsShedBy increments the number of items shed because they couldn't be exported within the maximum queue latency.
+func (m *StatsDMetrics) IncQueueLatencyItemsShedBy(name string, count float64) {
+	m.send(name, "queue_latency_items_shed_total", count, "c")
+}
+
+// addInFlight tracks in-progress gauges locally, since DogStatsD has no
+// relative gauge updates.
+func (m *StatsDMetrics) addInFlight(name string, delta int64) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight[name] += delta
+
+	return float64(m.inFlight[name])
+}
+
+func (m *StatsDMetrics) histogramType() string {
+	if m.flavor == StatsDFlavorDogStatsD {
+		return "h"
+	}
+
+	return "ms"
+}
+
+// statsdPathEscaper escapes values embedded in plain StatsD metric names,
+// where '.' separates path segments and ':', '|', '@' and '#' delimit the
+// line.
+var statsdPathEscaper = strings.NewReplacer(".", "_", ":", "_", "|", "_", "@", "_", "#", "_")
+
+// statsdTagEscaper escapes DogStatsD tags, where ',' separates tags and '|',
+// '@' and '#' delimit the line.
+var statsdTagEscaper = strings.NewReplacer(",", "_", "|", "_", "@", "_", "#", "_")
+
+// send writes a single metric line. Extra tags ("key:value") are emitted as
+// tags by DogStatsD and appended to the metric name by plain StatsD.
+// Names and tag values are escaped so they can't break up the line.
+func (m *StatsDMetrics) send(name, metric string, value float64, kind string, tags ...string) {
+	var b strings.Builder
+
+	b.WriteString(m.prefix)
+	b.WriteByte('.')
+
+	if m.flavor != StatsDFlavorDogStatsD {
+		b.WriteString(statsdPathEscaper.Replace(name))
+		b.WriteByte('.')
+	}
+
+	b.WriteString(metric)
+
+	if m.flavor != StatsDFlavorDogStatsD {
+		for _, tag := range tags {
+			b.WriteByte('.')
+			b.WriteString(statsdPathEscaper.Replace(tag[strings.IndexByte(tag, ':')+1:]))
+		}
+	}
+
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	b.WriteByte('|')
+	b.WriteString(kind)
+
+	if m.flavor == StatsDFlavorDogStatsD {
+		b.WriteString("|#processor:")
+		b.WriteString(statsdTagEscaper.Replace(name))
+
+		for _, tag := range m.tags {
+			b.WriteByte(',')
+			b.WriteString(tag)
+		}
+
+		for _, tag := range tags {
+			b.WriteByte(',')
+			b.WriteString(statsdTagEscaper.Replace(tag))
+		}
+	}
+
+	//nolint:errcheck // Metrics are best effort.
+	m.conn.Write([]byte(b.String()))
+}
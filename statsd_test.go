@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func readStatsDPacket(t *testing.T, conn net.PacketConn) string {
+	t.Helper()
+
+	buf := make([]byte, 1024)
+
+	if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %v", err)
+	}
+
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read packet: %v", err)
+	}
+
+	return string(buf[:n])
+}
+
+func TestStatsDMetrics_Flavors(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	plain, err := NewStatsDMetrics(conn.LocalAddr().String(), "batch")
+	if err != nil {
+		t.Fatalf("failed to create metrics: %v", err)
+	}
+	defer plain.Close()
+
+	plain.IncItemsExportedBy("test", 5)
+
+	if got, want := readStatsDPacket(t, conn), "batch_processor.test.items_exported_total:5|c"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	plain.IncShutdowns("test", "success")
+
+	if got, want := readStatsDPacket(t, conn), "batch_processor.test.shutdowns_total.success:1|c"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Names and tag values can't add path segments or break up the line.
+	plain.IncItemsExportedBy("eth.mainnet:head", 1)
+
+	if got, want := readStatsDPacket(t, conn), "batch_processor.eth_mainnet_head.items_exported_total:1|c"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	plain.SetProcessorInfo("test", 10, 100, 2, 1500*time.Millisecond)
+
+	if got, want := readStatsDPacket(t, conn), "batch_processor.test.processor_info.10.100.2.1_5s:1|g"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	dog, err := NewStatsDMetrics(
+		conn.LocalAddr().String(),
+		"batch",
+		WithStatsDFlavor(StatsDFlavorDogStatsD),
+		WithStatsDTags("env:test"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create metrics: %v", err)
+	}
+	defer dog.Close()
+
+	dog.IncWorkerExportInProgress("test")
+	dog.IncWorkerExportInProgress("test")
+
+	readStatsDPacket(t, conn)
+
+	want := "batch_processor.worker_export_in_progress:2|g|#processor:test,env:test"
+	if got := readStatsDPacket(t, conn); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,255 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// workStealInterval is how often idle workers look for batches to steal.
+const workStealInterval = 10 * time.Millisecond
+
+// WithWorkStealing lets idle workers export batches waiting for busy workers
+// when WithKeyOrdering routes batches to fixed workers, smoothing out the skew
+// caused by hot keys. Per-key order is kept: a batch sharing a key with an
+// earlier batch not yet exported waits for it, and a stolen one is exported
+// after it by the worker exporting it, so only batches of keys not in flight
+// are exported in parallel.
+func WithWorkStealing() BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.WorkStealing = true
+	}
+}
+
+// stealBatches exports batches waiting for other workers, most loaded worker
+// first, until none are waiting. It reports whether any were stolen.
+//
+// A stolen batch whose turn hasn't come is parked rather than waited for, as
+// the batch before it may be queued for the stealing worker itself. The
+// worker ending the turn before it exports it.
+func (bvp *BatchItemProcessor[T]) stealBatches(ctx context.Context, number int) bool {
+	stole := false
+
+	for {
+		victim := bvp.stealVictim(number)
+		if victim < 0 {
+			return stole
+		}
+
+		select {
+		case batch := <-bvp.workerChs[victim]:
+			bvp.metrics.IncBatchesStolen(bvp.label)
+
+			if !bvp.parkTurn(batch) {
+				bvp.exportBatch(ctx, number, batch)
+			}
+
+			stole = true
+		default:
+			// The owner took the batch first.
+			return stole
+		}
+	}
+}
+
+// stealVictim returns the worker with the most batches waiting, other than
+// the given worker, or -1 if none are waiting.
+func (bvp *BatchItemProcessor[T]) stealVictim(number int) int {
+	victim, most := -1, 0
+
+	for i, ch := range bvp.workerChs {
+		if i != number && len(ch) > most {
+			victim, most = i, len(ch)
+		}
+	}
+
+	return victim
+}
+
+// keyTurns makes batches sharing a key take turns to export, in the order
+// they were routed, when work stealing or a resized ring lets different
+// workers export them.
+type keyTurns struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	next uint64
+	// queues holds the turns of the batches routed with each key, oldest
+	// first.
+	queues map[any][]uint64
+	// keys holds the keys of each batch with a turn.
+	keys map[uint64][]any
+	// parked holds the stolen batches waiting for their turn.
+	parked map[uint64]any
+	// runnable holds the parked batches whose turn has come, for the
+	// worker that ended the turn before them to export.
+	runnable []any
+}
+
+func newKeyTurns() *keyTurns {
+	k := &keyTurns{
+		queues: make(map[any][]uint64),
+		keys:   make(map[uint64][]any),
+		parked: make(map[uint64]any),
+	}
+
+	k.cond = sync.NewCond(&k.mu)
+
+	return k
+}
+
+// queue gives a batch with keys the next turn for each of them.
+func (k *keyTurns) queue(keys []any) uint64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.next++
+
+	for _, key := range keys {
+		k.queues[key] = append(k.queues[key], k.next)
+	}
+
+	k.keys[k.next] = keys
+
+	return k.next
+}
+
+// wait blocks until it is the turn of a batch for each of its keys.
+func (k *keyTurns) wait(turn uint64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for !k.ready(turn) {
+		k.cond.Wait()
+	}
+}
+
+func (k *keyTurns) ready(turn uint64) bool {
+	for _, key := range k.keys[turn] {
+		if k.queues[key][0] != turn {
+			return false
+		}
+	}
+
+	return true
+}
+
+// park holds batch until its turn comes, unless it already has. It reports
+// whether the batch was parked.
+func (k *keyTurns) park(turn uint64, batch any) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.ready(turn) {
+		return false
+	}
+
+	k.parked[turn] = batch
+
+	return true
+}
+
+// done ends the turn of a batch, passing each of its keys to the next batch.
+// Parked batches whose turn has come are made runnable.
+func (k *keyTurns) done(turn uint64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, key := range k.keys[turn] {
+		q := k.queues[key][1:]
+		if len(q) == 0 {
+			delete(k.queues, key)
+
+			continue
+		}
+
+		k.queues[key] = q
+
+		if batch, ok := k.parked[q[0]]; ok && k.ready(q[0]) {
+			delete(k.parked, q[0])
+			k.runnable = append(k.runnable, batch)
+		}
+	}
+
+	delete(k.keys, turn)
+
+	k.cond.Broadcast()
+}
+
+// nextRunnable returns a parked batch whose turn has come.
+func (k *keyTurns) nextRunnable() (any, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if len(k.runnable) == 0 {
+		return nil, false
+	}
+
+	batch := k.runnable[0]
+	k.runnable = k.runnable[1:]
+
+	return batch, true
+}
+
+// queueTurns gives each routed batch its turn among the batches sharing its
+// keys. Batches are routed in the order their workers take them, so turns
+// follow write order for every key.
+func (bvp *BatchItemProcessor[T]) queueTurns(routed []routedBatch[T]) {
+	key := bvp.itemKey()
+
+	for _, r := range routed {
+		if r.worker < 0 || len(r.items) == 0 {
+			continue
+		}
+
+		var (
+			keys []any
+			seen = make(map[any]struct{})
+		)
+
+		for _, item := range r.items {
+			k := key(item)
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				keys = append(keys, k)
+			}
+		}
+
+		r.items[0].turn = bvp.turns.queue(keys)
+	}
+}
+
+// takeTurn waits for the turn of a batch to export, returning a func ending
+// it and exporting the parked batches whose turn has then come.
+func (bvp *BatchItemProcessor[T]) takeTurn(ctx context.Context, number int, batch []*TraceableItem[T]) func() {
+	if bvp.turns == nil || len(batch) == 0 || batch[0].turn == 0 {
+		return func() {}
+	}
+
+	// The batch's items may be recycled before its turn ends.
+	turn := batch[0].turn
+
+	bvp.turns.wait(turn)
+
+	return func() {
+		bvp.turns.done(turn)
+
+		for {
+			next, ok := bvp.turns.nextRunnable()
+			if !ok {
+				return
+			}
+
+			bvp.exportBatch(ctx, number, next.([]*TraceableItem[T]))
+		}
+	}
+}
+
+// parkTurn parks a stolen batch until its turn comes, reporting whether it
+// was parked.
+func (bvp *BatchItemProcessor[T]) parkTurn(batch []*TraceableItem[T]) bool {
+	if bvp.turns == nil || len(batch) == 0 || batch[0].turn == 0 {
+		return false
+	}
+
+	return bvp.turns.park(batch[0].turn, batch)
+}
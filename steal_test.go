@@ -0,0 +1,242 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_WorkStealing(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporters := []*mockExporter[string]{
+		{exportDelay: 50 * time.Millisecond},
+		{exportDelay: 50 * time.Millisecond},
+	}
+
+	before := counterValue(t, DefaultMetrics.batchesStolen.WithLabelValues("test"))
+
+	proc, err := NewBatchItemProcessor[string](exporters[0], "test", log,
+		WithMaxExportBatchSize(1),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(2),
+		WithExportPipelining(10),
+		WithKeyFunc(func(item *string) string { return *item }),
+		WithKeyOrdering(),
+		WithWorkStealing(),
+		WithExporterFactory(func(worker int) (ItemExporter[string], error) {
+			return exporters[worker], nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two hot keys routed to the same worker. Batches of one can be stolen
+	// while the other's export.
+	var hot []string
+
+	for i := 0; len(hot) < 2; i++ {
+		if key := fmt.Sprintf("hot-%d", i); proc.workerFor(key) == 0 {
+			hot = append(hot, key)
+		}
+	}
+
+	items := make([]*string, 10)
+
+	for i := range items {
+		items[i] = &hot[i%len(hot)]
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shutdown drains the batches anyway, so wait for them to be exported
+	// while the processor runs.
+	deadline := time.Now().Add(5 * time.Second)
+
+	for exporters[0].exportCount.Load()+exporters[1].exportCount.Load() != 10 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the batches to be exported")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := exporters[0].exportCount.Load(), exporters[1].exportCount.Load()
+	if first+second != 10 {
+		t.Fatalf("expected 10 items exported, got %d", first+second)
+	}
+
+	if first == 0 || second == 0 {
+		t.Fatalf("expected both workers to export, got %d and %d items", first, second)
+	}
+
+	if stolen := counterValue(t, DefaultMetrics.batchesStolen.WithLabelValues("test")) - before; stolen == 0 {
+		t.Fatal("expected stolen batches to be counted")
+	}
+}
+
+// orderExporter records the order items are exported in, and whether
+// exports overlapped.
+type orderExporter struct {
+	mu         sync.Mutex
+	exporting  bool
+	overlapped bool
+	order      []int
+}
+
+func (e *orderExporter) ExportItems(_ context.Context, items []*int) error {
+	e.mu.Lock()
+	e.overlapped = e.overlapped || e.exporting
+	e.exporting = true
+	e.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.exporting = false
+
+	for _, item := range items {
+		e.order = append(e.order, *item)
+	}
+
+	return nil
+}
+
+func (e *orderExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestBatchItemProcessor_WorkStealingKeepsKeyOrder(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &orderExporter{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxExportBatchSize(1),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(2),
+		WithExportPipelining(10),
+		WithKeyFunc(func(*int) string { return "hot" }),
+		WithKeyOrdering(),
+		WithWorkStealing(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Write(ctx, ints(10)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stolen batches wait for earlier batches of their key.
+	if exporter.overlapped {
+		t.Fatal("expected batches of the same key not to be exported in parallel")
+	}
+
+	want := make([]int, 10)
+	for i := range want {
+		want[i] = i
+	}
+
+	if !slices.Equal(exporter.order, want) {
+		t.Fatalf("expected the key exported in write order, got %v", exporter.order)
+	}
+}
+
+func TestBatchItemProcessor_WorkStealingOwnEarlierTurn(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &orderExporter{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithWorkers(2),
+		WithKeyFunc(func(*int) string { return "hot" }),
+		WithKeyOrdering(),
+		WithWorkStealing(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// A key moved between workers, as a resized ring moves keys: its first
+	// batch waits for worker 0 and its second for worker 1.
+	for worker, value := range []int{0, 1} {
+		routed := []routedBatch[int]{{
+			items:  []*TraceableItem[int]{proc.newTraceableItem(&value, writeOrigin[int]{})},
+			worker: worker,
+		}}
+
+		proc.queueTurns(routed)
+		proc.workerChs[worker] <- routed[0].items
+	}
+
+	// Worker 0 steals the second batch before taking its own, earlier one,
+	// which only it is left to export.
+	stolen := make(chan bool)
+
+	go func() {
+		stolen <- proc.stealBatches(ctx, 0)
+	}()
+
+	select {
+	case ok := <-stolen:
+		if !ok {
+			t.Fatal("expected the batch stolen")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker deadlocked waiting for a turn only it could take")
+	}
+
+	// Exporting its own batch ends the turn before the stolen one, which
+	// is then exported.
+	proc.exportBatch(ctx, 0, <-proc.workerChs[0])
+
+	if !slices.Equal(exporter.order, []int{0, 1}) {
+		t.Fatalf("expected the key exported in write order, got %v", exporter.order)
+	}
+}
+
+func TestBatchItemProcessorOptions_WorkStealingRequiresKeyOrdering(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	_, err := NewBatchItemProcessor[string](&mockExporter[string]{}, "test", log, WithWorkStealing())
+	if err == nil {
+		t.Fatal("expected work stealing without key ordering to be rejected")
+	}
+}
@@ -0,0 +1,278 @@
+package processor
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Stepper drives processors deterministically in tests. A processor created
+// with WithStepper runs no workers: batches are cut as usual, but wait for
+// Step or RunUntilIdle to export them, one at a time on the calling
+// goroutine in the order they were cut. The batch timeout only elapses when
+// the stepper's clock is advanced, so flushes and exports happen at the
+// same points on every run and tests need no sleeps.
+//
+// Step, RunUntilIdle and Advance first wait for the batch builder to take
+// every written item and cut batch, so whatever the test wrote before
+// calling them is accounted for. Item deadlines and triggers still use the
+// real clock. Writes with the sync shipping method block until their batch
+// is stepped, so make them from another goroutine. Shutdown exports the
+// batches still waiting.
+//
+// A stepper can drive several processors, interleaving their exports in
+// the order their batches were cut.
+type Stepper struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	now  time.Time
+
+	// transit counts items and batches handed to a batch builder or
+	// dispatcher and not yet taken care of.
+	transit int
+	pending []stepExport
+	timers  []*stepTimer
+}
+
+// stepExport is a batch waiting for Step, with the processor it belongs to.
+type stepExport struct {
+	owner  any
+	export func()
+}
+
+// NewStepper returns a stepper whose clock starts at now.
+func NewStepper(now time.Time) *Stepper {
+	s := &Stepper{now: now}
+	s.cond = sync.NewCond(&s.mu)
+
+	return s
+}
+
+// WithStepper drives the processor with a Stepper instead of workers and
+// the real clock. It is meant for tests.
+func WithStepper(s *Stepper) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.Stepper = s
+	}
+}
+
+// Now returns the stepper's clock.
+func (s *Stepper) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.now
+}
+
+// Advance moves the clock on by d, firing the batch timeouts that are due,
+// and waits for the batch builders to act on them.
+func (s *Stepper) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = s.now.Add(d)
+
+	for _, t := range s.timers {
+		if !t.armed || t.at.After(s.now) {
+			continue
+		}
+
+		t.armed = false
+
+		select {
+		case t.c <- s.now:
+			s.transit++
+		default:
+		}
+	}
+
+	s.waitSettled()
+}
+
+// Step exports the oldest batch waiting for export. It returns false if no
+// batch was waiting.
+func (s *Stepper) Step() bool {
+	s.mu.Lock()
+
+	s.waitSettled()
+
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+
+		return false
+	}
+
+	next := s.pending[0]
+	s.pending = s.pending[1:]
+
+	s.mu.Unlock()
+
+	next.export()
+
+	return true
+}
+
+// RunUntilIdle steps until no batch is waiting, returning the number of
+// batches exported.
+func (s *Stepper) RunUntilIdle() int {
+	exported := 0
+
+	for s.Step() {
+		exported++
+	}
+
+	return exported
+}
+
+// Pending returns the number of batches waiting for export.
+func (s *Stepper) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.waitSettled()
+
+	return len(s.pending)
+}
+
+// waitSettled waits until nothing is in transit. It must be called with the
+// stepper locked.
+func (s *Stepper) waitSettled() {
+	for s.transit > 0 {
+		s.cond.Wait()
+	}
+}
+
+// add records work handed to a batch builder or dispatcher.
+func (s *Stepper) add(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.transit += n
+}
+
+// settle records work taken care of.
+func (s *Stepper) settle(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settleLocked(n)
+}
+
+func (s *Stepper) settleLocked(n int) {
+	s.transit -= n
+
+	if s.transit == 0 {
+		s.cond.Broadcast()
+	}
+}
+
+// park queues an export for Step.
+func (s *Stepper) park(owner any, export func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, stepExport{owner: owner, export: export})
+}
+
+// drain exports the batches of owner still waiting, for its shutdown.
+func (s *Stepper) drain(owner any) {
+	for {
+		s.mu.Lock()
+
+		s.waitSettled()
+
+		i := slices.IndexFunc(s.pending, func(e stepExport) bool { return e.owner == owner })
+		if i < 0 {
+			s.mu.Unlock()
+
+			return
+		}
+
+		next := s.pending[i]
+		s.pending = slices.Delete(s.pending, i, i+1)
+
+		s.mu.Unlock()
+
+		next.export()
+	}
+}
+
+// newTimer returns a flush timer on the stepper's clock.
+func (s *Stepper) newTimer(d time.Duration) *stepTimer {
+	t := &stepTimer{
+		s: s,
+		c: make(chan time.Time, 1),
+	}
+
+	s.mu.Lock()
+	s.timers = append(s.timers, t)
+	s.mu.Unlock()
+
+	t.Reset(d)
+
+	return t
+}
+
+// stepTimer is a flushTimer on a stepper's clock. Its deadline is guarded by
+// the stepper's lock.
+type stepTimer struct {
+	s     *Stepper
+	c     chan time.Time
+	at    time.Time
+	armed bool
+}
+
+func (t *stepTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *stepTimer) Reset(d time.Duration) {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	t.at = t.s.now.Add(d)
+	t.armed = true
+}
+
+// Stop disarms the timer, draining a value nobody received so Advance
+// doesn't wait for it to be acted on.
+func (t *stepTimer) Stop() {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	t.armed = false
+
+	select {
+	case <-t.c:
+		t.s.settleLocked(1)
+	default:
+	}
+}
+
+// addStep records work handed to the batch builder or dispatcher, if the
+// processor is driven by a stepper.
+func (bvp *BatchItemProcessor[T]) addStep(n int) {
+	if bvp.stepper != nil {
+		bvp.stepper.add(n)
+	}
+}
+
+// settleStep records work taken care of, if the processor is driven by a
+// stepper.
+func (bvp *BatchItemProcessor[T]) settleStep(n int) {
+	if bvp.stepper != nil {
+		bvp.stepper.settle(n)
+	}
+}
+
+// parkBatches queues routed batches for the stepper to export.
+func (bvp *BatchItemProcessor[T]) parkBatches(routed []routedBatch[T]) {
+	for _, r := range routed {
+		worker := max(r.worker, 0)
+		items := r.items
+
+		bvp.stepper.park(bvp, func() {
+			bvp.exportBatch(bvp.workerCtx, worker, items)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newSteppedProcessor(t *testing.T, stepper *Stepper, exporter ItemExporter[int], opts ...BatchItemProcessorOption) *BatchItemProcessor[int] {
+	t.Helper()
+
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	opts = append([]BatchItemProcessorOption{
+		WithStepper(stepper),
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(time.Second),
+		WithWorkers(4),
+	}, opts...)
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return proc
+}
+
+func TestStepper_BatchTimeout(t *testing.T) {
+	stepper := NewStepper(time.Unix(0, 0))
+	exporter := &mockExporter[int]{}
+	proc := newSteppedProcessor(t, stepper, exporter)
+
+	ctx := context.Background()
+
+	if err := proc.Write(ctx, ints(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := stepper.Pending(); n != 0 {
+		t.Fatalf("expected no batch before the timeout, got %d", n)
+	}
+
+	stepper.Advance(999 * time.Millisecond)
+
+	if n := stepper.Pending(); n != 0 {
+		t.Fatalf("expected no batch just before the timeout, got %d", n)
+	}
+
+	stepper.Advance(time.Millisecond)
+
+	if !stepper.Step() {
+		t.Fatal("expected a batch once the timeout elapsed")
+	}
+
+	if got := exporter.exportCount.Load(); got != 3 {
+		t.Fatalf("expected 3 items exported, got %d", got)
+	}
+
+	if stepper.Step() {
+		t.Fatal("expected no more batches")
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStepper_RunUntilIdle(t *testing.T) {
+	stepper := NewStepper(time.Unix(0, 0))
+	exporter := &mockExporter[int]{}
+	proc := newSteppedProcessor(t, stepper, exporter)
+
+	ctx := context.Background()
+
+	if err := proc.Write(ctx, ints(25)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is exported until stepped.
+	if got := exporter.exportCount.Load(); got != 0 {
+		t.Fatalf("expected nothing exported before stepping, got %d", got)
+	}
+
+	if n := stepper.RunUntilIdle(); n != 2 {
+		t.Fatalf("expected 2 full batches, got %d", n)
+	}
+
+	if got := exporter.exportCount.Load(); got != 20 {
+		t.Fatalf("expected 20 items exported, got %d", got)
+	}
+
+	// Shutdown exports the partial batch.
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exporter.exportCount.Load(); got != 25 {
+		t.Fatalf("expected 25 items exported after shutdown, got %d", got)
+	}
+}
+
+func TestStepper_SyncWrite(t *testing.T) {
+	stepper := NewStepper(time.Unix(0, 0))
+	exporter := &mockExporter[int]{}
+	proc := newSteppedProcessor(t, stepper, exporter, WithShippingMethod(ShippingMethodSync))
+
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- proc.Write(ctx, ints(10))
+	}()
+
+	// The write only returns once its batch is stepped.
+	for !stepper.Step() {
+		stepper.Advance(0)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func ints(n int) []*int {
+	items := make([]*int, n)
+	for i := range items {
+		items[i] = &i
+	}
+
+	return items
+}
@@ -0,0 +1,379 @@
+// Package stress drives a batch item processor with configurable load and
+// exporter faults and checks that it keeps its guarantees, so a processor
+// configuration can be soak tested before it goes to production.
+package stress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+var (
+	// ErrLostItems is reported when items accepted by Write never reached
+	// the exporter.
+	ErrLostItems = errors.New("accepted items were never exported")
+	// ErrMemoryBound is reported when the heap grew past Config.MaxHeapBytes.
+	ErrMemoryBound = errors.New("heap exceeded the memory bound")
+	// ErrInjectedFault is returned by exports failed by the fault profile.
+	ErrInjectedFault = errors.New("injected export fault")
+)
+
+// memorySampleInterval is how often the heap is sampled during a run.
+const memorySampleInterval = 10 * time.Millisecond
+
+// Item is the item written by producers. Producer and Seq identify it, and
+// Payload pads it to the configured size.
+type Item struct {
+	Producer int
+	Seq      int
+	Payload  []byte
+}
+
+// Processor is the part of a processor driven by the harness.
+type Processor interface {
+	Start(ctx context.Context) error
+	Write(ctx context.Context, items []*Item) error
+	Shutdown(ctx context.Context) error
+}
+
+// Faults describes how the exporter misbehaves.
+type Faults struct {
+	// ErrorRate is the fraction of exports that fail with ErrInjectedFault.
+	ErrorRate float64
+	// Latency is added to every export, plus up to Jitter more.
+	Latency time.Duration
+	Jitter  time.Duration
+}
+
+// Config describes a stress run.
+type Config struct {
+	// Producers is the number of goroutines writing items.
+	Producers int
+	// Rate is the number of items each producer writes per second. Zero
+	// writes as fast as the processor accepts them.
+	Rate float64
+	// WriteSize is the number of items per Write. It defaults to 1.
+	WriteSize int
+	// ItemSize is the payload size of each item in bytes.
+	ItemSize int
+	// Duration is how long producers write for.
+	Duration time.Duration
+	// Faults are injected in to the exporter.
+	Faults Faults
+	// MaxHeapBytes bounds the heap in use during the run, including the
+	// harness's own bookkeeping of about a byte per item. Zero disables
+	// the check.
+	MaxHeapBytes uint64
+}
+
+// Result summarizes a stress run.
+type Result struct {
+	// Written is the number of items passed to Write, and Accepted the
+	// number Write returned no error for.
+	Written  int
+	Accepted int
+	// Exported is the number of distinct items exported successfully and
+	// Failed the number of export attempts failed by the fault profile.
+	Exported int
+	Failed   int
+	// Duplicates counts items exported successfully more than once.
+	Duplicates int
+	// Lost counts accepted items the exporter never saw.
+	Lost int
+	// PeakHeapBytes is the largest heap in use sampled during the run.
+	PeakHeapBytes uint64
+	// Elapsed is how long the run took, including shutdown.
+	Elapsed time.Duration
+
+	maxHeapBytes uint64
+}
+
+// Err returns the invariants the run violated, or nil.
+func (r Result) Err() error {
+	var errs []error
+
+	if r.Lost > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d of %d", ErrLostItems, r.Lost, r.Accepted))
+	}
+
+	if r.maxHeapBytes > 0 && r.PeakHeapBytes > r.maxHeapBytes {
+		errs = append(errs, fmt.Errorf("%w: peaked at %d bytes, bound is %d", ErrMemoryBound, r.PeakHeapBytes, r.maxHeapBytes))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run drives the processor returned by newProcessor, which must export to the
+// given exporter, possibly through middleware. It starts the processor, runs
+// the producers for the configured duration, shuts it down and checks the
+// invariants. The error is only for failures to start or shut down; violated
+// invariants are reported by Result.Err.
+func Run(ctx context.Context, cfg Config, newProcessor func(exporter processor.ItemExporter[Item]) (Processor, error)) (Result, error) {
+	cfg.Producers = max(cfg.Producers, 1)
+	cfg.WriteSize = max(cfg.WriteSize, 1)
+
+	ledger := newLedger(cfg.Producers)
+	exporter := &faultExporter{faults: cfg.Faults, ledger: ledger}
+
+	proc, err := newProcessor(exporter)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create processor: %w", err)
+	}
+
+	start := time.Now()
+
+	if err := proc.Start(ctx); err != nil {
+		return Result{}, fmt.Errorf("failed to start processor: %w", err)
+	}
+
+	sampler := newHeapSampler()
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	for p := range cfg.Producers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ledger.produce(runCtx, proc, p, cfg)
+		}()
+	}
+
+	wg.Wait()
+
+	shutdownErr := proc.Shutdown(ctx)
+
+	result := ledger.result()
+	result.PeakHeapBytes = sampler.stop()
+	result.Elapsed = time.Since(start)
+	result.maxHeapBytes = cfg.MaxHeapBytes
+
+	if shutdownErr != nil {
+		return result, fmt.Errorf("failed to shut down processor: %w", shutdownErr)
+	}
+
+	return result, nil
+}
+
+// ledger records what happened to every item. Producers record writes and
+// acks to their own slices; exports are recorded under a lock.
+type ledger struct {
+	producers []producerLedger
+
+	mu       sync.Mutex
+	exported [][]uint8
+	failed   int
+}
+
+type producerLedger struct {
+	written  int
+	accepted []bool
+}
+
+func newLedger(producers int) *ledger {
+	return &ledger{
+		producers: make([]producerLedger, producers),
+		exported:  make([][]uint8, producers),
+	}
+}
+
+// produce writes items for producer p until ctx is done.
+func (l *ledger) produce(ctx context.Context, proc Processor, p int, cfg Config) {
+	own := &l.producers[p]
+	start := time.Now()
+	items := make([]*Item, cfg.WriteSize)
+
+	for ctx.Err() == nil {
+		if cfg.Rate > 0 {
+			// Pace writes so producer p has written Rate items a second.
+			due := start.Add(time.Duration(float64(own.written) / cfg.Rate * float64(time.Second)))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Until(due)):
+			}
+		}
+
+		for i := range items {
+			items[i] = &Item{Producer: p, Seq: own.written + i, Payload: make([]byte, cfg.ItemSize)}
+		}
+
+		// A failed write may have enqueued some of its items, so only
+		// successful writes count as accepted.
+		err := proc.Write(ctx, items)
+
+		for range items {
+			own.accepted = append(own.accepted, err == nil)
+		}
+
+		own.written += len(items)
+	}
+}
+
+func (l *ledger) recordExport(items []*Item, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err != nil {
+		l.failed += len(items)
+	}
+
+	for _, item := range items {
+		seen := l.exported[item.Producer]
+		if item.Seq >= len(seen) {
+			seen = append(seen, make([]uint8, item.Seq-len(seen)+1)...)
+			l.exported[item.Producer] = seen
+		}
+
+		// Failed attempts are marked so accepted items that only failed
+		// aren't reported as lost.
+		switch {
+		case err != nil:
+			seen[item.Seq] |= 1
+		case seen[item.Seq]>>1 < 127:
+			seen[item.Seq] += 2
+		}
+	}
+}
+
+func (l *ledger) result() Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r := Result{Failed: l.failed}
+
+	for p, own := range l.producers {
+		r.Written += own.written
+
+		seen := l.exported[p]
+
+		for seq, accepted := range own.accepted {
+			var state uint8
+			if seq < len(seen) {
+				state = seen[seq]
+			}
+
+			successes := state >> 1
+
+			if successes > 0 {
+				r.Exported++
+			}
+
+			if successes > 1 {
+				r.Duplicates++
+			}
+
+			if accepted {
+				r.Accepted++
+
+				if state == 0 {
+					r.Lost++
+				}
+			}
+		}
+	}
+
+	return r
+}
+
+// faultExporter records exports in the ledger, injecting the configured
+// faults.
+type faultExporter struct {
+	faults Faults
+	ledger *ledger
+}
+
+func (e *faultExporter) ExportItems(ctx context.Context, items []*Item) error {
+	delay := e.faults.Latency
+	if e.faults.Jitter > 0 {
+		delay += rand.N(e.faults.Jitter)
+	}
+
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			e.ledger.recordExport(items, ctx.Err())
+
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	var err error
+	if e.faults.ErrorRate > 0 && rand.Float64() < e.faults.ErrorRate {
+		err = ErrInjectedFault
+	}
+
+	e.ledger.recordExport(items, err)
+
+	return err
+}
+
+func (e *faultExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+// heapSampler records the peak heap in use until stopped.
+type heapSampler struct {
+	peak atomic.Uint64
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newHeapSampler() *heapSampler {
+	s := &heapSampler{done: make(chan struct{})}
+
+	s.sample()
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(memorySampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	}()
+
+	return s
+}
+
+func (s *heapSampler) sample() {
+	var stats runtime.MemStats
+
+	runtime.ReadMemStats(&stats)
+
+	if stats.HeapInuse > s.peak.Load() {
+		s.peak.Store(stats.HeapInuse)
+	}
+}
+
+// stop stops sampling and returns the peak.
+func (s *heapSampler) stop() uint64 {
+	close(s.done)
+	s.wg.Wait()
+
+	s.sample()
+
+	return s.peak.Load()
+}
@@ -0,0 +1,118 @@
+package stress
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/sirupsen/logrus"
+)
+
+func newProcessor(t *testing.T, options ...processor.BatchItemProcessorOption) func(processor.ItemExporter[Item]) (Processor, error) {
+	t.Helper()
+
+	log := logrus.New()
+	log.SetLevel(logrus.FatalLevel)
+
+	return func(exporter processor.ItemExporter[Item]) (Processor, error) {
+		return processor.NewBatchItemProcessor[Item](exporter, "stress", log, options...)
+	}
+}
+
+func TestRun(t *testing.T) {
+	cfg := Config{
+		Producers: 4,
+		Rate:      2000,
+		WriteSize: 5,
+		ItemSize:  64,
+		Duration:  300 * time.Millisecond,
+		Faults: Faults{
+			ErrorRate: 0.2,
+			Latency:   time.Millisecond,
+			Jitter:    time.Millisecond,
+		},
+		MaxHeapBytes: 1 << 30,
+	}
+
+	result, err := Run(context.Background(), cfg, newProcessor(t,
+		processor.WithMaxExportBatchSize(50),
+		processor.WithBatchTimeout(10*time.Millisecond),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := result.Err(); err != nil {
+		t.Fatalf("expected no violations, got %v", err)
+	}
+
+	if result.Accepted == 0 || result.Exported == 0 {
+		t.Fatalf("expected items to be accepted and exported, got %+v", result)
+	}
+
+	if result.Failed == 0 {
+		t.Errorf("expected injected faults to fail some exports, got %+v", result)
+	}
+}
+
+// leakyProcessor acknowledges every other write without writing it.
+type leakyProcessor struct {
+	Processor
+	writes int
+}
+
+func (p *leakyProcessor) Write(ctx context.Context, items []*Item) error {
+	p.writes++
+
+	if p.writes%2 == 0 {
+		return nil
+	}
+
+	return p.Processor.Write(ctx, items)
+}
+
+func TestRun_DetectsLostItems(t *testing.T) {
+	build := newProcessor(t, processor.WithBatchTimeout(10*time.Millisecond))
+
+	cfg := Config{
+		Producers: 1,
+		Rate:      1000,
+		Duration:  100 * time.Millisecond,
+	}
+
+	result, err := Run(context.Background(), cfg, func(exporter processor.ItemExporter[Item]) (Processor, error) {
+		proc, err := build(exporter)
+		if err != nil {
+			return nil, err
+		}
+
+		return &leakyProcessor{Processor: proc}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(result.Err(), ErrLostItems) {
+		t.Fatalf("expected lost items to be reported, got %v", result.Err())
+	}
+}
+
+func TestRun_MemoryBound(t *testing.T) {
+	cfg := Config{
+		Producers:    1,
+		Rate:         100,
+		Duration:     50 * time.Millisecond,
+		MaxHeapBytes: 1,
+	}
+
+	result, err := Run(context.Background(), cfg, newProcessor(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !errors.Is(result.Err(), ErrMemoryBound) {
+		t.Fatalf("expected the memory bound to be reported, got %v", result.Err())
+	}
+}
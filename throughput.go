@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"sync"
+	"time"
+)
+
+// throughputMeter tracks exported items and bytes over a rolling window.
+//
+// Samples are aggregated into one second buckets so the memory footprint is
+// bounded by the window size regardless of the export rate.
+type throughputMeter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	started time.Time
+	buckets []throughputBucket
+}
+
+type throughputBucket struct {
+	second int64
+	items  float64
+	bytes  float64
+}
+
+func newThroughputMeter(window time.Duration, now time.Time) *throughputMeter {
+	size := int(window / time.Second)
+	if size < 1 {
+		size = 1
+	}
+
+	return &throughputMeter{
+		window:  time.Duration(size) * time.Second,
+		started: now,
+		buckets: make([]throughputBucket, size),
+	}
+}
+
+// add records a successful export of items totalling bytes.
+func (m *throughputMeter) add(now time.Time, items, bytes float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	second := now.Unix()
+	b := &m.buckets[second%int64(len(m.buckets))]
+
+	if b.second != second {
+		*b = throughputBucket{second: second}
+	}
+
+	b.items += items
+	b.bytes += bytes
+}
+
+// rates returns the items and bytes exported per second over the window. If
+// the meter is younger than the window, the elapsed time is used instead so
+// the rate is not underestimated shortly after a restart.
+func (m *throughputMeter) rates(now time.Time) (itemsPerSecond, bytesPerSecond float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldest := now.Unix() - int64(len(m.buckets))
+
+	var items, bytes float64
+
+	for _, b := range m.buckets {
+		if b.second > oldest {
+			items += b.items
+			bytes += b.bytes
+		}
+	}
+
+	elapsed := now.Sub(m.started)
+	if elapsed > m.window {
+		elapsed = m.window
+	}
+
+	if elapsed < time.Second {
+		elapsed = time.Second
+	}
+
+	return items / elapsed.Seconds(), bytes / elapsed.Seconds()
+}
@@ -0,0 +1,33 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThroughputMeter_Rates(t *testing.T) {
+	start := time.Unix(1000, 0)
+	meter := newThroughputMeter(10*time.Second, start)
+
+	meter.add(start, 10, 100)
+	meter.add(start.Add(time.Second), 10, 100)
+
+	// Younger than the window, so the rate uses elapsed time.
+	items, bytes := meter.rates(start.Add(2 * time.Second))
+	if items != 10 || bytes != 100 {
+		t.Errorf("expected 10 items/s and 100 bytes/s, got %v and %v", items, bytes)
+	}
+
+	// Once the window has elapsed the old samples fall out.
+	items, bytes = meter.rates(start.Add(20 * time.Second))
+	if items != 0 || bytes != 0 {
+		t.Errorf("expected 0 items/s and 0 bytes/s, got %v and %v", items, bytes)
+	}
+
+	meter.add(start.Add(20*time.Second), 50, 500)
+
+	items, _ = meter.rates(start.Add(20 * time.Second))
+	if items != 5 {
+		t.Errorf("expected 5 items/s, got %v", items)
+	}
+}
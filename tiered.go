@@ -0,0 +1,53 @@
+package processor
+
+import (
+	"context"
+)
+
+// ProcessorExporter exports batches by writing them to another processor,
+// for two tier batching: a processor cutting small batches quickly feeds one
+// cutting large batches toward a remote sink. Write errors, such as
+// ErrQueueFull from an async processor, fail the batch.
+type ProcessorExporter[T any] struct {
+	proc *BatchItemProcessor[T]
+}
+
+var (
+	_ ItemExporter[struct{}] = (*ProcessorExporter[struct{}])(nil)
+	_ ExporterStarter        = (*ProcessorExporter[struct{}])(nil)
+	_ HealthChecker          = (*ProcessorExporter[struct{}])(nil)
+)
+
+// NewProcessorExporter returns an exporter writing to proc. The exporter
+// owns proc: it starts proc from Start and shuts it down from Shutdown, so
+// the outer processor drains into proc before proc drains into its sink.
+func NewProcessorExporter[T any](proc *BatchItemProcessor[T]) *ProcessorExporter[T] {
+	return &ProcessorExporter[T]{proc: proc}
+}
+
+// Start starts the processor.
+func (e *ProcessorExporter[T]) Start(ctx context.Context) error {
+	return e.proc.Start(ctx)
+}
+
+// ExportItems writes items to the processor. With the async shipping method
+// it returns once they are queued.
+func (e *ProcessorExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	return e.proc.Write(ctx, items)
+}
+
+// HealthCheck checks the processor's exporter, if it implements
+// HealthChecker.
+func (e *ProcessorExporter[T]) HealthCheck(ctx context.Context) error {
+	checker, ok := e.proc.e.(HealthChecker)
+	if !ok {
+		return nil
+	}
+
+	return checker.HealthCheck(ctx)
+}
+
+// Shutdown shuts the processor down, exporting the items it still holds.
+func (e *ProcessorExporter[T]) Shutdown(ctx context.Context) error {
+	return e.proc.Shutdown(ctx)
+}
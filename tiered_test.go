@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sizeExporter records the size of every batch it exports.
+type sizeExporter[T any] struct {
+	mockExporter[T]
+	sizes []int
+}
+
+func (e *sizeExporter[T]) ExportItems(ctx context.Context, items []*T) error {
+	e.mu.Lock()
+	e.sizes = append(e.sizes, len(items))
+	e.mu.Unlock()
+
+	return e.mockExporter.ExportItems(ctx, items)
+}
+
+func TestProcessorExporter_TwoTiers(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	sink := &sizeExporter[int]{}
+
+	remote, err := NewBatchItemProcessor[int](sink, "remote", log,
+		WithMaxQueueSize(1000),
+		WithMaxExportBatchSize(100),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := NewBatchItemProcessor[int](NewProcessorExporter(remote), "local", log,
+		WithMaxQueueSize(1000),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	// Starting the local tier starts the remote one.
+	if err := local.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 250 {
+		if err := local.Write(ctx, []*int{&i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Shutting down the local tier drains both.
+	if err := local.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sink.exportCount.Load(); got != 250 {
+		t.Fatalf("expected 250 items to reach the sink, got %d", got)
+	}
+
+	for _, size := range sink.sizes {
+		if size < 10 {
+			t.Fatalf("expected the remote tier to export large batches, got sizes %v", sink.sizes)
+		}
+	}
+}
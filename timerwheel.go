@@ -0,0 +1,226 @@
+package processor
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimerWheelTick is the resolution of a timer wheel created with a
+	// non-positive tick.
+	DefaultTimerWheelTick = 10 * time.Millisecond
+
+	timerWheelSlots = 512
+)
+
+// TimerWheel drives the flush timers of many processors from one goroutine.
+// With hundreds of processors in a process, each resetting its own runtime
+// timer after every batch, the timer churn becomes measurable; a shared
+// wheel replaces it with one ticker. Timers fire on the first tick at or
+// after their timeout, so the tick bounds how late a flush can be.
+//
+// A wheel starts with its first timer and runs until Stop is called. Share
+// one wheel between processors with WithTimerWheel.
+type TimerWheel struct {
+	tick time.Duration
+
+	mu    sync.Mutex
+	slots [timerWheelSlots]map[*wheelTimer]struct{}
+	pos   int
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+}
+
+// NewTimerWheel returns a timer wheel ticking every tick.
+func NewTimerWheel(tick time.Duration) *TimerWheel {
+	if tick <= 0 {
+		tick = DefaultTimerWheelTick
+	}
+
+	w := &TimerWheel{
+		tick:   tick,
+		stopCh: make(chan struct{}),
+	}
+
+	for i := range w.slots {
+		w.slots[i] = make(map[*wheelTimer]struct{})
+	}
+
+	return w
+}
+
+// WithTimerWheel drives the processor's batch timeout from a shared timer
+// wheel instead of its own timer.
+func WithTimerWheel(wheel *TimerWheel) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.TimerWheel = wheel
+	}
+}
+
+// Stop stops the wheel. Pending timers no longer fire.
+func (w *TimerWheel) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+	})
+}
+
+// newTimer returns a timer on the wheel that fires after d.
+func (w *TimerWheel) newTimer(d time.Duration) *wheelTimer {
+	w.startOnce.Do(func() {
+		go w.run()
+	})
+
+	t := &wheelTimer{
+		wheel: w,
+		c:     make(chan time.Time, 1),
+		slot:  -1,
+	}
+
+	t.Reset(d)
+
+	return t
+}
+
+func (w *TimerWheel) run() {
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case now := <-ticker.C:
+			w.advance(now)
+		}
+	}
+}
+
+// advance moves the wheel on one slot, firing the timers that are due.
+func (w *TimerWheel) advance(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pos = (w.pos + 1) % timerWheelSlots
+
+	for t := range w.slots[w.pos] {
+		if t.rounds > 0 {
+			t.rounds--
+
+			continue
+		}
+
+		delete(w.slots[w.pos], t)
+		t.slot = -1
+
+		select {
+		case t.c <- now:
+		default:
+		}
+	}
+}
+
+// schedule places a timer in the slot it fires from. It must be called with
+// the wheel locked.
+func (w *TimerWheel) schedule(t *wheelTimer, d time.Duration) {
+	ticks := max(int((d+w.tick-1)/w.tick), 1)
+
+	t.slot = (w.pos + ticks) % timerWheelSlots
+	t.rounds = (ticks - 1) / timerWheelSlots
+
+	w.slots[t.slot][t] = struct{}{}
+}
+
+// unschedule removes a timer from its slot. It must be called with the wheel
+// locked.
+func (w *TimerWheel) unschedule(t *wheelTimer) bool {
+	if t.slot < 0 {
+		return false
+	}
+
+	delete(w.slots[t.slot], t)
+	t.slot = -1
+
+	return true
+}
+
+// flushTimer is the batch timeout timer of a processor: a runtime timer, a
+// timer on a shared wheel or one on a stepper's clock.
+type flushTimer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
+func newFlushTimer(wheel *TimerWheel, stepper *Stepper, d time.Duration) flushTimer {
+	if wheel != nil {
+		return wheel.newTimer(d)
+	}
+
+	if stepper != nil {
+		return stepper.newTimer(d)
+	}
+
+	return runtimeTimer{time.NewTimer(d)}
+}
+
+// runtimeTimer is a flushTimer backed by its own runtime timer.
+type runtimeTimer struct {
+	*time.Timer
+}
+
+func (t runtimeTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+func (t runtimeTimer) Reset(d time.Duration) {
+	t.Timer.Reset(d)
+}
+
+func (t runtimeTimer) Stop() {
+	t.Timer.Stop()
+}
+
+// wheelTimer is a flushTimer on a shared timer wheel. Its slot and rounds are
+// guarded by the wheel's lock.
+type wheelTimer struct {
+	wheel  *TimerWheel
+	c      chan time.Time
+	slot   int
+	rounds int
+}
+
+func (t *wheelTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Reset reschedules the timer to fire after d. Like a runtime timer, a value
+// sent before the reset may still be received.
+func (t *wheelTimer) Reset(d time.Duration) {
+	t.wheel.mu.Lock()
+	defer t.wheel.mu.Unlock()
+
+	t.wheel.unschedule(t)
+	t.wheel.schedule(t, d)
+}
+
+func (t *wheelTimer) Stop() {
+	t.wheel.mu.Lock()
+	defer t.wheel.mu.Unlock()
+
+	t.wheel.unschedule(t)
+}
+
+// resetFlushTimer stops a flush timer, drains a timeout it already sent and
+// rearms it.
+func resetFlushTimer(t flushTimer, d time.Duration) {
+	t.Stop()
+
+	select {
+	case <-t.C():
+	default:
+	}
+
+	t.Reset(d)
+}
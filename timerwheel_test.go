@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTimerWheel_Fires(t *testing.T) {
+	w := NewTimerWheel(time.Millisecond)
+	defer w.Stop()
+
+	// Timeouts longer than one turn of the wheel wait out the extra rounds.
+	timeout := 600 * time.Millisecond
+
+	start := time.Now()
+	timer := w.newTimer(timeout)
+
+	select {
+	case <-timer.C():
+		if elapsed := time.Since(start); elapsed < timeout {
+			t.Fatalf("timer fired after %s, before its %s timeout", elapsed, timeout)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timer didn't fire")
+	}
+}
+
+func TestTimerWheel_StopAndReset(t *testing.T) {
+	w := NewTimerWheel(time.Millisecond)
+	defer w.Stop()
+
+	timer := w.newTimer(20 * time.Millisecond)
+	timer.Stop()
+
+	select {
+	case <-timer.C():
+		t.Fatal("stopped timer fired")
+	case <-time.After(60 * time.Millisecond):
+	}
+
+	timer.Reset(10 * time.Millisecond)
+
+	select {
+	case <-timer.C():
+	case <-time.After(5 * time.Second):
+		t.Fatal("reset timer didn't fire")
+	}
+}
+
+func TestBatchItemProcessor_TimerWheel(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	wheel := NewTimerWheel(5 * time.Millisecond)
+	defer wheel.Stop()
+
+	ctx := context.Background()
+	exporters := make([]*mockExporter[string], 10)
+
+	for i := range exporters {
+		exporters[i] = &mockExporter[string]{}
+
+		bvp, err := NewBatchItemProcessor[string](exporters[i], fmt.Sprintf("wheel-%d", i), log,
+			WithBatchTimeout(20*time.Millisecond),
+			WithMaxExportBatchSize(100),
+			WithTimerWheel(wheel),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := bvp.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		defer bvp.Shutdown(ctx)
+
+		item := "item"
+
+		if err := bvp.Write(ctx, []*string{&item}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Partial batches are only flushed by the timeout.
+	deadline := time.Now().Add(5 * time.Second)
+
+	for _, exporter := range exporters {
+		for exporter.exportCount.Load() != 1 {
+			if time.Now().After(deadline) {
+				t.Fatal("the wheel didn't flush every processor's batch")
+			}
+
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
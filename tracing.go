@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the processor's spans.
+const tracerName = "github.com/ethpandaops/go-batch-processor"
+
+// WithTracerProvider enables tracing. Every export runs in an "export" span
+// linked to the spans that were active when its items were written, so traces
+// connect producing requests with the batch export that shipped their items.
+// Each attempt at exporting the batch, including retries, runs in a child
+// "export_attempt" span recording the attempt number and its error.
+func WithTracerProvider(tp trace.TracerProvider) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.TracerProvider = tp
+	}
+}
+
+// captureSpan returns the span context active when items are written, or nil
+// if tracing is disabled or there is no valid span.
+func (bvp *BatchItemProcessor[T]) captureSpan(ctx context.Context) *trace.SpanContext {
+	if bvp.tracer == nil {
+		return nil
+	}
+
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return &sc
+}
+
+// startExportSpan starts the export span for a batch, linked to the spans
+// that wrote its items. It returns a no-op span if tracing is disabled.
+func (bvp *BatchItemProcessor[T]) startExportSpan(
+	ctx context.Context,
+	batch []*TraceableItem[T],
+) (context.Context, trace.Span) {
+	if bvp.tracer == nil {
+		return ctx, noop.Span{}
+	}
+
+	type spanID struct {
+		trace trace.TraceID
+		span  trace.SpanID
+	}
+
+	var (
+		links []trace.Link
+		seen  = make(map[spanID]struct{})
+	)
+
+	for _, item := range batch {
+		if item == nil || item.span == nil {
+			continue
+		}
+
+		id := spanID{trace: item.span.TraceID(), span: item.span.SpanID()}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+
+		seen[id] = struct{}{}
+
+		links = append(links, trace.Link{SpanContext: *item.span})
+	}
+
+	return bvp.tracer.Start(
+		ctx,
+		"export",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.String("processor", bvp.name),
+			attribute.Int("batch.size", len(batch)),
+		),
+	)
+}
+
+// startAttemptSpan starts the span of an attempt at exporting a batch of
+// size items. It returns a no-op span if tracing is disabled.
+func (bvp *BatchItemProcessor[T]) startAttemptSpan(ctx context.Context, size, attempt int) (context.Context, trace.Span) {
+	if bvp.tracer == nil {
+		return ctx, noop.Span{}
+	}
+
+	return bvp.tracer.Start(
+		ctx,
+		"export_attempt",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.Int("batch.size", size),
+			attribute.Int("attempt", attempt),
+		),
+	)
+}
+
+// endSpan records the outcome of an export on its span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
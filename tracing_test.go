@@ -0,0 +1,194 @@
+package processor
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestBatchItemProcessor_TraceLinks(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	proc, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{},
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(10*time.Second),
+		WithWorkers(1),
+		WithTracerProvider(tp),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	// Two requests write in to the same batch.
+	var producers []*tracetest.SpanStub
+
+	for i := 0; i < 2; i++ {
+		reqCtx, span := tp.Tracer("test").Start(ctx, "request")
+
+		one, two := 1, 2
+		if err := proc.Write(reqCtx, []*int{&one, &two}); err != nil {
+			t.Fatalf("failed to write items: %v", err)
+		}
+
+		span.End()
+
+		stub := tracetest.SpanStubFromReadOnlySpan(recorder.Ended()[i])
+		producers = append(producers, &stub)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	var exports []sdktrace.ReadOnlySpan
+
+	for _, span := range recorder.Ended() {
+		if span.Name() == "export" {
+			exports = append(exports, span)
+		}
+	}
+
+	if len(exports) != 1 {
+		t.Fatalf("expected 1 export span, got %d", len(exports))
+	}
+
+	links := exports[0].Links()
+	if len(links) != len(producers) {
+		t.Fatalf("expected %d links, got %d", len(producers), len(links))
+	}
+
+	for i, producer := range producers {
+		if links[i].SpanContext.SpanID() != producer.SpanContext.SpanID() {
+			t.Errorf("link %d: expected span %s, got %s", i, producer.SpanContext.SpanID(), links[i].SpanContext.SpanID())
+		}
+	}
+}
+
+func TestBatchItemProcessor_TraceAttempts(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	proc, err := NewBatchItemProcessor[int](&flakyExporter{failures: 1}, "test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(5),
+		WithRetry(3, BackoffConfig{InitialInterval: time.Millisecond, Multiplier: 2}),
+		WithTracerProvider(tp),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Write(ctx, ints(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		export   sdktrace.ReadOnlySpan
+		attempts []sdktrace.ReadOnlySpan
+	)
+
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "export":
+			export = span
+		case "export_attempt":
+			attempts = append(attempts, span)
+		}
+	}
+
+	if export == nil || !slices.Contains(export.Attributes(), attribute.Int("batch.size", 5)) {
+		t.Fatalf("expected an export span with the batch size, got %v", export)
+	}
+
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 attempt spans, got %d", len(attempts))
+	}
+
+	for i, span := range attempts {
+		if span.Parent().SpanID() != export.SpanContext().SpanID() {
+			t.Errorf("attempt %d: expected a child of the export span", i+1)
+		}
+
+		if !slices.Contains(span.Attributes(), attribute.Int("attempt", i+1)) {
+			t.Errorf("attempt %d: expected the attempt number, got %v", i+1, span.Attributes())
+		}
+	}
+
+	if got := attempts[0].Status().Code; got != codes.Error {
+		t.Errorf("expected the first attempt to fail, got %v", got)
+	}
+
+	if got := attempts[1].Status().Code; got == codes.Error {
+		t.Errorf("expected the retry to succeed, got %v", got)
+	}
+}
+
+func TestBatchItemProcessor_TracingDisabledLeavesCallerSpan(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	proc, err := NewBatchItemProcessor[int](&flakyExporter{failures: 1}, "test", log,
+		WithShippingMethod(ShippingMethodSync),
+		WithMaxExportBatchSize(5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The caller's span is carried by the contexts exports run with.
+	ctx, span := tp.Tracer("test").Start(context.Background(), "caller")
+	defer span.End()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proc.Write(ctx, ints(5)); err == nil {
+		t.Fatal("expected the export to fail")
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if ended := recorder.Ended(); len(ended) != 0 {
+		t.Fatalf("expected exports to leave the caller's span open, got %d ended", len(ended))
+	}
+}
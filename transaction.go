@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrBatchRolledBack wraps the error of a batch whose transaction was rolled
+// back.
+var ErrBatchRolledBack = errors.New("batch rolled back")
+
+// TransactionalExporter is an optional interface for exporters that wrap each
+// batch in a transaction, such as database sinks. The processor begins a
+// transaction before ExportItems, commits it if the export succeeds and
+// rolls it back if the export fails or its context is cancelled, so a batch
+// is never left half written.
+type TransactionalExporter interface {
+	// BeginBatch starts a transaction, returning the context ExportItems,
+	// CommitBatch and RollbackBatch are called with. Exporters usually
+	// carry the transaction in it.
+	BeginBatch(ctx context.Context) (context.Context, error)
+	// CommitBatch commits the transaction.
+	CommitBatch(ctx context.Context) error
+	// RollbackBatch rolls the transaction back. Its context is never
+	// cancelled, so the rollback can run after the export was.
+	RollbackBatch(ctx context.Context) error
+}
+
+// exportItems exports items, in a transaction if the exporter is a
+// TransactionalExporter.
+func exportItems[T any](ctx context.Context, exporter ItemExporter[T], items []*T) error {
+	tx, ok := exporter.(TransactionalExporter)
+	if !ok {
+		return exporter.ExportItems(ctx, items)
+	}
+
+	txCtx, err := tx.BeginBatch(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin batch: %w", err)
+	}
+
+	err = exporter.ExportItems(txCtx, items)
+	if err == nil {
+		err = txCtx.Err()
+	}
+
+	if err != nil {
+		// Nothing in a rolled back batch was exported, so per item
+		// results no longer apply.
+		if partial, ok := partialErrors(err, len(items)); ok {
+			err = errors.Join(partial.ItemErrors...)
+		}
+
+		err = fmt.Errorf("%w: %w", ErrBatchRolledBack, err)
+
+		if rollbackErr := tx.RollbackBatch(context.WithoutCancel(txCtx)); rollbackErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to roll back batch: %w", rollbackErr))
+		}
+
+		return err
+	}
+
+	if err := tx.CommitBatch(txCtx); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return nil
+}
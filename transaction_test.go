@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type txKey struct{}
+
+// txExporter records the transactions around its exports.
+type txExporter struct {
+	mockExporter[int]
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *txExporter) record(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.events = append(e.events, event)
+}
+
+func (e *txExporter) BeginBatch(ctx context.Context) (context.Context, error) {
+	e.record("begin")
+
+	return context.WithValue(ctx, txKey{}, true), nil
+}
+
+func (e *txExporter) ExportItems(ctx context.Context, items []*int) error {
+	if ctx.Value(txKey{}) == nil {
+		return errors.New("export outside a transaction")
+	}
+
+	return e.mockExporter.ExportItems(ctx, items)
+}
+
+func (e *txExporter) CommitBatch(_ context.Context) error {
+	e.record("commit")
+
+	return nil
+}
+
+func (e *txExporter) RollbackBatch(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return errors.New("rollback with a cancelled context")
+	}
+
+	e.record("rollback")
+
+	return nil
+}
+
+func TestBatchItemProcessor_TransactionalExporter(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exportErr := errors.New("export failed")
+
+	tests := []struct {
+		name     string
+		setup    func(e *txExporter)
+		timeout  time.Duration
+		wantErr  error
+		wantLast string
+	}{
+		{name: "commit", wantLast: "commit"},
+		{
+			name:     "rollback on error",
+			setup:    func(e *txExporter) { e.exportErr = exportErr },
+			wantErr:  exportErr,
+			wantLast: "rollback",
+		},
+		{
+			name:     "rollback on partial error",
+			setup:    func(e *txExporter) { e.exportErr = &PartialExportError{ItemErrors: []error{exportErr}} },
+			wantErr:  exportErr,
+			wantLast: "rollback",
+		},
+		{
+			name:     "rollback on cancellation",
+			setup:    func(e *txExporter) { e.exportDelay = time.Second },
+			timeout:  10 * time.Millisecond,
+			wantErr:  context.DeadlineExceeded,
+			wantLast: "rollback",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &txExporter{}
+			if tt.setup != nil {
+				tt.setup(exporter)
+			}
+
+			opts := []BatchItemProcessorOption{WithShippingMethod(ShippingMethodSync), WithMaxExportBatchSize(1), WithWorkers(1)}
+			if tt.timeout > 0 {
+				opts = append(opts, WithExportTimeout(tt.timeout))
+			}
+
+			proc, err := NewBatchItemProcessor[int](exporter, "test", log, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+
+			if err := proc.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			defer proc.Shutdown(ctx)
+
+			item := 1
+
+			err = proc.Write(ctx, []*int{&item})
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.wantErr != nil && (!errors.Is(err, tt.wantErr) || !errors.Is(err, ErrBatchRolledBack)) {
+				t.Fatalf("expected a rolled back %v, got %v", tt.wantErr, err)
+			}
+
+			var partial *PartialExportError
+			if errors.As(err, &partial) {
+				t.Fatal("expected a rolled back batch not to report per item results")
+			}
+
+			exporter.mu.Lock()
+			defer exporter.mu.Unlock()
+
+			if len(exporter.events) != 2 || exporter.events[0] != "begin" || exporter.events[1] != tt.wantLast {
+				t.Fatalf("expected begin then %s, got %v", tt.wantLast, exporter.events)
+			}
+		})
+	}
+}
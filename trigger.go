@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"time"
+)
+
+// BatchState describes the batch being assembled by the batch builder.
+type BatchState struct {
+	// Items is the number of items in the batch.
+	Items int
+	// Bytes is the total size of the items in the batch as reported by the
+	// Sizer. It is always zero when no Sizer is configured.
+	Bytes int
+	// Age is the time since the first item was added to the batch.
+	Age time.Duration
+}
+
+// Trigger is a custom flush condition. Triggers let callers flush batches on
+// conditions beyond the built-in batch size and timeout, such as a byte budget.
+// The triggers package provides common implementations.
+type Trigger interface {
+	// ShouldFlush reports whether the batch should be flushed now. It is called
+	// from the batch builder goroutine and must not block.
+	ShouldFlush(state BatchState) bool
+}
+
+// TriggerFunc adapts a function to the Trigger interface.
+type TriggerFunc func(state BatchState) bool
+
+// ShouldFlush calls f(state).
+func (f TriggerFunc) ShouldFlush(state BatchState) bool {
+	return f(state)
+}
+
+// WithTrigger adds a custom flush trigger.
+func WithTrigger(trigger Trigger) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.Triggers = append(o.Triggers, trigger)
+	}
+}
+
+// WithTriggerInterval sets how often triggers are evaluated while idle.
+func WithTriggerInterval(interval time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.TriggerInterval = interval
+	}
+}
+
+// triggered reports whether any configured trigger fires for the batch.
+func (bvp *BatchItemProcessor[T]) triggered(items, bytes int, started time.Time) bool {
+	if len(bvp.o.Triggers) == 0 {
+		return false
+	}
+
+	state := BatchState{
+		Items: items,
+		Bytes: bytes,
+		Age:   time.Since(started),
+	}
+
+	for _, trigger := range bvp.o.Triggers {
+		if trigger.ShouldFlush(state) {
+			return true
+		}
+	}
+
+	return false
+}
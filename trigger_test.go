@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_Trigger(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[string]{}
+
+	proc, err := NewBatchItemProcessor[string](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(1000),
+		WithMaxExportBatchSize(100),
+		WithBatchTimeout(10*time.Second), // Long timeout to ensure the trigger fires.
+		WithWorkers(1),
+		WithSizer(func(item *string) int { return len(*item) }),
+		WithTrigger(TriggerFunc(func(state BatchState) bool {
+			return state.Bytes >= 10
+		})),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	items := make([]*string, 3)
+	for i := range items {
+		s := "hello"
+		items[i] = &s
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The first two items reach the byte budget, the third waits for the timeout.
+	if exporter.exportCount.Load() != 2 {
+		t.Errorf("expected 2 items exported, got %d", exporter.exportCount.Load())
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package triggers
+
+import (
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/ethpandaops/go-batch-processor/registry"
+)
+
+// Register registers the triggers in this package: "count" with "items",
+// "bytes" with "bytes", "age" with "age" and "slot_boundary" with "genesis"
+// and "slot". The combinators "any" and "all" take "triggers", a list of
+// triggers each with a "name" and "config", and "not" takes a "trigger".
+func Register(r *registry.Registry[processor.Trigger]) error {
+	err := r.Register("count", func(config map[string]any) (processor.Trigger, error) {
+		var cfg struct {
+			Items int `json:"items"`
+		}
+
+		if err := registry.DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		return Count(cfg.Items), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.Register("bytes", func(config map[string]any) (processor.Trigger, error) {
+		var cfg struct {
+			Bytes int `json:"bytes"`
+		}
+
+		if err := registry.DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		return Bytes(cfg.Bytes), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.Register("age", func(config map[string]any) (processor.Trigger, error) {
+		var cfg struct {
+			Age registry.Duration `json:"age"`
+		}
+
+		if err := registry.DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		return Age(time.Duration(cfg.Age)), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.Register("slot_boundary", func(config map[string]any) (processor.Trigger, error) {
+		var cfg struct {
+			Genesis time.Time         `json:"genesis"`
+			Slot    registry.Duration `json:"slot"`
+		}
+
+		if err := registry.DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		return SlotBoundary(cfg.Genesis, time.Duration(cfg.Slot)), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.Register("any", func(config map[string]any) (processor.Trigger, error) {
+		triggers, err := buildTriggers(r, config)
+		if err != nil {
+			return nil, err
+		}
+
+		return Any(triggers...), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.Register("all", func(config map[string]any) (processor.Trigger, error) {
+		triggers, err := buildTriggers(r, config)
+		if err != nil {
+			return nil, err
+		}
+
+		return All(triggers...), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.Register("not", func(config map[string]any) (processor.Trigger, error) {
+		var cfg struct {
+			Trigger triggerConfig `json:"trigger"`
+		}
+
+		if err := registry.DecodeConfig(config, &cfg); err != nil {
+			return nil, err
+		}
+
+		trigger, err := r.Build(cfg.Trigger.Name, cfg.Trigger.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		return Not(trigger), nil
+	})
+}
+
+// triggerConfig names a trigger nested in a combinator's config.
+type triggerConfig struct {
+	Name   string         `json:"name"`
+	Config map[string]any `json:"config"`
+}
+
+// buildTriggers builds the triggers listed in a combinator's config.
+func buildTriggers(r *registry.Registry[processor.Trigger], config map[string]any) ([]processor.Trigger, error) {
+	var cfg struct {
+		Triggers []triggerConfig `json:"triggers"`
+	}
+
+	if err := registry.DecodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	triggers := make([]processor.Trigger, 0, len(cfg.Triggers))
+
+	for _, tc := range cfg.Triggers {
+		trigger, err := r.Build(tc.Name, tc.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		triggers = append(triggers, trigger)
+	}
+
+	return triggers, nil
+}
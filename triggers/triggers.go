@@ -0,0 +1,102 @@
+// Package triggers provides flush triggers for the batch item processor.
+package triggers
+
+import (
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+)
+
+// Count fires once the batch holds at least n items.
+func Count(n int) processor.Trigger {
+	return processor.TriggerFunc(func(state processor.BatchState) bool {
+		return state.Items >= n
+	})
+}
+
+// Bytes fires once the batch holds at least n bytes. It requires a Sizer to be
+// configured on the processor.
+func Bytes(n int) processor.Trigger {
+	return processor.TriggerFunc(func(state processor.BatchState) bool {
+		return state.Bytes >= n
+	})
+}
+
+// Age fires once the first item in the batch is at least d old.
+func Age(d time.Duration) processor.Trigger {
+	return processor.TriggerFunc(func(state processor.BatchState) bool {
+		return state.Items > 0 && state.Age >= d
+	})
+}
+
+// Any fires once any of triggers fires, so "500 items or 1MB or 2s" is
+// Any(Count(500), Bytes(1<<20), Age(2*time.Second)). It never fires without
+// triggers.
+func Any(triggers ...processor.Trigger) processor.Trigger {
+	return processor.TriggerFunc(func(state processor.BatchState) bool {
+		for _, trigger := range triggers {
+			if trigger.ShouldFlush(state) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// All fires once every one of triggers fires, such as a batch that is both
+// old and large enough. It never fires without triggers.
+func All(triggers ...processor.Trigger) processor.Trigger {
+	return processor.TriggerFunc(func(state processor.BatchState) bool {
+		for _, trigger := range triggers {
+			if !trigger.ShouldFlush(state) {
+				return false
+			}
+		}
+
+		return len(triggers) > 0
+	})
+}
+
+// Not fires whenever trigger doesn't, to hold back other triggers with All.
+func Not(trigger processor.Trigger) processor.Trigger {
+	return processor.TriggerFunc(func(state processor.BatchState) bool {
+		return !trigger.ShouldFlush(state)
+	})
+}
+
+// SlotBoundary fires once the batch spans a slot boundary, slots being
+// consecutive periods of length slot from genesis, such as beacon chain
+// slots. Triggers are evaluated after an item is added and every trigger
+// interval, never before an item is added, so when an item of the next
+// slot arrives before the interval's evaluation, the batch is flushed with
+// that item as its last. Batches then hold the items of one slot, plus at
+// most the first item of the next.
+func SlotBoundary(genesis time.Time, slot time.Duration) processor.Trigger {
+	return slotBoundary(genesis, slot, time.Now)
+}
+
+func slotBoundary(genesis time.Time, slot time.Duration, now func() time.Time) processor.Trigger {
+	return processor.TriggerFunc(func(state processor.BatchState) bool {
+		if state.Items == 0 || slot <= 0 {
+			return false
+		}
+
+		current := now()
+
+		return slotOf(current.Add(-state.Age), genesis, slot) != slotOf(current, genesis, slot)
+	})
+}
+
+// slotOf returns the number of the slot t falls in, counting back from
+// genesis for times before it.
+func slotOf(t, genesis time.Time, slot time.Duration) int64 {
+	d := t.Sub(genesis)
+
+	n := int64(d / slot)
+	if d < 0 && d%slot != 0 {
+		n--
+	}
+
+	return n
+}
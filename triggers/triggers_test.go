@@ -0,0 +1,139 @@
+package triggers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	processor "github.com/ethpandaops/go-batch-processor"
+	"github.com/ethpandaops/go-batch-processor/registry"
+)
+
+func TestTriggers(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger processor.Trigger
+		state   processor.BatchState
+		want    bool
+	}{
+		{"count below", Count(10), processor.BatchState{Items: 9}, false},
+		{"count reached", Count(10), processor.BatchState{Items: 10}, true},
+		{"bytes below", Bytes(1024), processor.BatchState{Items: 1, Bytes: 1023}, false},
+		{"bytes reached", Bytes(1024), processor.BatchState{Items: 1, Bytes: 2048}, true},
+		{"age below", Age(time.Second), processor.BatchState{Items: 1, Age: time.Millisecond}, false},
+		{"age reached", Age(time.Second), processor.BatchState{Items: 1, Age: time.Second}, true},
+		{"age empty batch", Age(time.Second), processor.BatchState{Age: time.Hour}, false},
+		{"any none", Any(), processor.BatchState{Items: 1}, false},
+		{"any below", Any(Count(10), Bytes(1024)), processor.BatchState{Items: 9, Bytes: 1023}, false},
+		{"any reached", Any(Count(10), Bytes(1024)), processor.BatchState{Items: 1, Bytes: 1024}, true},
+		{"all none", All(), processor.BatchState{Items: 1}, false},
+		{"all partly reached", All(Count(10), Age(time.Second)), processor.BatchState{Items: 10}, false},
+		{"all reached", All(Count(10), Age(time.Second)), processor.BatchState{Items: 10, Age: time.Second}, true},
+		{"not", Not(Count(10)), processor.BatchState{Items: 9}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.trigger.ShouldFlush(tt.state); got != tt.want {
+				t.Errorf("ShouldFlush() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlotBoundary(t *testing.T) {
+	genesis := time.Unix(1000, 0)
+	now := genesis.Add(25 * time.Second)
+
+	trigger := slotBoundary(genesis, 12*time.Second, func() time.Time { return now })
+
+	tests := []struct {
+		name  string
+		state processor.BatchState
+		want  bool
+	}{
+		{"same slot", processor.BatchState{Items: 1, Age: time.Second}, false},
+		{"previous slot", processor.BatchState{Items: 1, Age: 2 * time.Second}, true},
+		{"empty batch", processor.BatchState{Age: time.Minute}, false},
+		{"several slots back", processor.BatchState{Items: 1, Age: 26 * time.Second}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trigger.ShouldFlush(tt.state); got != tt.want {
+				t.Errorf("ShouldFlush() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A batch started just before genesis spans the boundary in to slot 0.
+	now = genesis.Add(time.Second)
+
+	if !trigger.ShouldFlush(processor.BatchState{Items: 1, Age: 2 * time.Second}) {
+		t.Error("expected a batch spanning genesis to fire")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := registry.NewTriggerRegistry()
+
+	if err := Register(r); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	trigger, err := r.Build("age", map[string]any{"age": "2s"})
+	if err != nil {
+		t.Fatalf("failed to build trigger: %v", err)
+	}
+
+	if !trigger.ShouldFlush(processor.BatchState{Items: 1, Age: 3 * time.Second}) {
+		t.Error("expected age trigger to fire")
+	}
+}
+
+func TestRegister_Combinators(t *testing.T) {
+	r := registry.NewTriggerRegistry()
+
+	if err := Register(r); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+
+	trigger, err := r.Build("any", map[string]any{
+		"triggers": []any{
+			map[string]any{"name": "count", "config": map[string]any{"items": 500}},
+			map[string]any{"name": "all", "config": map[string]any{
+				"triggers": []any{
+					map[string]any{"name": "age", "config": map[string]any{"age": "2s"}},
+					map[string]any{"name": "not", "config": map[string]any{
+						"trigger": map[string]any{"name": "count", "config": map[string]any{"items": 2}},
+					}},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to build trigger: %v", err)
+	}
+
+	tests := []struct {
+		state processor.BatchState
+		want  bool
+	}{
+		{processor.BatchState{Items: 500}, true},
+		{processor.BatchState{Items: 1, Age: 3 * time.Second}, true},
+		{processor.BatchState{Items: 2, Age: 3 * time.Second}, false},
+		{processor.BatchState{Items: 1, Age: time.Second}, false},
+	}
+
+	for _, tt := range tests {
+		if got := trigger.ShouldFlush(tt.state); got != tt.want {
+			t.Errorf("ShouldFlush(%+v) = %v, want %v", tt.state, got, tt.want)
+		}
+	}
+
+	if _, err := r.Build("any", map[string]any{
+		"triggers": []any{map[string]any{"name": "unknown"}},
+	}); !errors.Is(err, registry.ErrNotFound) {
+		t.Fatalf("expected an unknown nested trigger to fail, got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBatchAborted wraps the error of a batch whose two-phase export was
+// aborted because part of it failed to prepare.
+var ErrBatchAborted = errors.New("batch aborted")
+
+// TwoPhaseExporter is an optional interface for exporters whose sink has an
+// external commit point, such as uploading objects and then writing the
+// manifest that makes them visible. Batches are exported in two phases.
+// Each part a batch is split in to by write context, key grouping or key
+// ordering is prepared by whichever worker picks it up, and once every part
+// is prepared CommitPrepared is called with their results. If any part
+// fails to prepare, AbortPrepared is called with the results of those that
+// were prepared instead. Sync writers get the outcome of the commit.
+//
+// ExportItems is still called where a batch is exported on its own, such
+// as replays from the disk buffer, and should prepare and commit it in one
+// go. Batches exported in two phases aren't written to the disk buffer.
+type TwoPhaseExporter[T any] interface {
+	// PrepareItems stages part of a batch without making it visible,
+	// returning what CommitPrepared or AbortPrepared needs to find it.
+	PrepareItems(ctx context.Context, items []*T) (any, error)
+	// CommitPrepared makes every prepared part of a batch visible at once.
+	// A batch whose commit fails is neither retried nor aborted.
+	CommitPrepared(ctx context.Context, prepared []any) error
+	// AbortPrepared discards the prepared parts of a batch. Its context is
+	// never cancelled, so the abort can run after the export was.
+	AbortPrepared(ctx context.Context, prepared []any) error
+}
+
+// twoPhaseExporter returns the exporter as a TwoPhaseExporter, if it is one
+// and every worker exports with it.
+func (bvp *BatchItemProcessor[T]) twoPhaseExporter() (TwoPhaseExporter[T], bool) {
+	if bvp.workerExporters != nil {
+		return nil, false
+	}
+
+	exporter, ok := bvp.e.(TwoPhaseExporter[T])
+
+	return exporter, ok
+}
+
+// preparedRound tracks the parts of a batch through a two-phase export. The
+// worker preparing the last part commits or aborts the round, so no worker
+// waits on another.
+type preparedRound[T any] struct {
+	exporter TwoPhaseExporter[T]
+
+	mu       sync.Mutex
+	pending  int
+	batches  [][]*TraceableItem[T]
+	prepared []any
+	errs     []error
+}
+
+// newPreparedRound starts a round for the routed parts of a batch, tagging
+// their items with it.
+func newPreparedRound[T any](exporter TwoPhaseExporter[T], routed []routedBatch[T]) *preparedRound[T] {
+	r := &preparedRound[T]{
+		exporter: exporter,
+		pending:  len(routed),
+	}
+
+	for _, b := range routed {
+		for _, item := range b.items {
+			item.round = r
+		}
+	}
+
+	return r
+}
+
+// add records the outcome of preparing a part. Once the last part is in it
+// returns every part of the round and true.
+func (r *preparedRound[T]) add(batch []*TraceableItem[T], prepared any, err error) ([][]*TraceableItem[T], bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches = append(r.batches, batch)
+
+	if err != nil {
+		// Nothing in an aborted batch is exported, so per item results
+		// no longer apply.
+		var partial *PartialExportError
+		if errors.As(err, &partial) {
+			err = errors.Join(partial.ItemErrors...)
+		}
+
+		r.errs = append(r.errs, err)
+	} else {
+		r.prepared = append(r.prepared, prepared)
+	}
+
+	r.pending--
+
+	return r.batches, r.pending == 0
+}
+
+// settle commits the round if every part was prepared and aborts it
+// otherwise, returning the round's outcome.
+func (r *preparedRound[T]) settle(ctx context.Context) error {
+	if len(r.errs) == 0 {
+		if err := r.exporter.CommitPrepared(ctx, r.prepared); err != nil {
+			return fmt.Errorf("failed to commit prepared batch: %w", err)
+		}
+
+		return nil
+	}
+
+	err := fmt.Errorf("%w: %w", ErrBatchAborted, errors.Join(r.errs...))
+
+	if len(r.prepared) > 0 {
+		if abortErr := r.exporter.AbortPrepared(context.WithoutCancel(ctx), r.prepared); abortErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to abort prepared batch: %w", abortErr))
+		}
+	}
+
+	return err
+}
+
+// prepareBatch prepares a part of a two-phase round. The worker preparing
+// the last part settles the round and then every part of it.
+func (bvp *BatchItemProcessor[T]) prepareBatch(ctx context.Context, number int, round *preparedRound[T], batch []*TraceableItem[T]) {
+	bvp.metrics.IncWorkerExportInProgress(bvp.label)
+	defer bvp.metrics.DecWorkerExportInProgress(bvp.label)
+
+	ctx, cancel := bvp.exportContext(ctx, batch)
+	defer cancel()
+
+	items := bvp.collectItems(batch, bvp.exportBuffer(number))
+
+	ctx, span := bvp.startExportSpan(ctx, batch)
+
+	if bvp.invariants != nil {
+		bvp.invariants.export(len(items))
+	}
+
+	startTime := time.Now()
+
+	prepared, err := round.exporter.PrepareItems(ctx, items)
+
+	bvp.metrics.ObserveExportDuration(bvp.label, time.Since(startTime))
+	endSpan(span, err)
+
+	batches, last := round.add(batch, prepared, err)
+	if !last {
+		return
+	}
+
+	err = round.settle(ctx)
+	if err != nil {
+		bvp.log.WithError(err).Error("failed to export items")
+	}
+
+	for _, b := range batches {
+		if err != nil {
+			bvp.metrics.IncItemsFailedBy(bvp.label, float64(len(b)))
+		} else {
+			bvp.metrics.IncItemsExportedBy(bvp.label, float64(len(b)))
+			bvp.metrics.ObserveBatchSize(bvp.label, float64(len(b)))
+		}
+
+		bvp.publishExport(number, len(b), time.Since(startTime), err)
+
+		signalWriters(b, err, nil)
+		bvp.settleBatch(ctx, b, err)
+	}
+}
@@ -0,0 +1,142 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// twoPhaseExporter records the prepared parts of each batch and what became
+// of them.
+type twoPhaseExporter struct {
+	mockExporter[int]
+
+	failKey int
+
+	mu        sync.Mutex
+	prepares  int
+	committed [][]any
+	aborted   [][]any
+}
+
+func (e *twoPhaseExporter) PrepareItems(_ context.Context, items []*int) (any, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.committed) > 0 || len(e.aborted) > 0 {
+		return nil, errors.New("prepared after the batch was settled")
+	}
+
+	e.prepares++
+
+	key := *items[0] % 3
+	if key == e.failKey {
+		return nil, errors.New("prepare failed")
+	}
+
+	return key, nil
+}
+
+func (e *twoPhaseExporter) CommitPrepared(_ context.Context, prepared []any) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.committed = append(e.committed, prepared)
+
+	return nil
+}
+
+func (e *twoPhaseExporter) AbortPrepared(ctx context.Context, prepared []any) error {
+	if ctx.Err() != nil {
+		return errors.New("abort with a cancelled context")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.aborted = append(e.aborted, prepared)
+
+	return nil
+}
+
+func TestBatchItemProcessor_TwoPhaseExporter(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	tests := []struct {
+		name        string
+		failKey     int
+		wantErr     bool
+		wantSettled []any
+	}{
+		{name: "commit", failKey: -1, wantSettled: []any{0, 1, 2}},
+		{name: "abort", failKey: 1, wantErr: true, wantSettled: []any{0, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &twoPhaseExporter{failKey: tt.failKey}
+
+			proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+				WithShippingMethod(ShippingMethodSync),
+				WithMaxExportBatchSize(6),
+				WithWorkers(2),
+				WithKeyFunc(func(item *int) int { return *item % 3 }),
+				WithKeyGrouping(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+
+			if err := proc.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			defer proc.Shutdown(ctx)
+
+			items := make([]*int, 6)
+			for i := range items {
+				items[i] = &i
+			}
+
+			err = proc.Write(ctx, items)
+
+			if tt.wantErr != errors.Is(err, ErrBatchAborted) {
+				t.Fatalf("expected aborted %v, got %v", tt.wantErr, err)
+			}
+
+			exporter.mu.Lock()
+			defer exporter.mu.Unlock()
+
+			if exporter.prepares != 3 {
+				t.Fatalf("expected 3 parts prepared, got %d", exporter.prepares)
+			}
+
+			settled := exporter.committed
+			if tt.wantErr {
+				settled = exporter.aborted
+
+				if len(exporter.committed) > 0 {
+					t.Fatalf("expected nothing committed, got %v", exporter.committed)
+				}
+			}
+
+			if len(settled) != 1 {
+				t.Fatalf("expected the batch settled once, got %v", settled)
+			}
+
+			got := slices.Clone(settled[0])
+			slices.SortFunc(got, func(a, b any) int { return a.(int) - b.(int) })
+
+			if !slices.Equal(got, tt.wantSettled) {
+				t.Fatalf("expected parts %v settled, got %v", tt.wantSettled, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationError lists every problem found validating processor options.
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].Error()
+	}
+
+	msgs := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		msgs[i] = problem.Error()
+	}
+
+	return fmt.Sprintf("%d problems: %s", len(e.Problems), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the problems, so errors.Is and errors.As match each of them.
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// joinValidationErrors adds problem to err, which is nil or a
+// *ValidationError.
+func joinValidationErrors(err error, problem error) error {
+	var validationErr *ValidationError
+
+	if errors.As(err, &validationErr) {
+		validationErr.Problems = append(validationErr.Problems, problem)
+
+		return validationErr
+	}
+
+	return &ValidationError{Problems: []error{problem}}
+}
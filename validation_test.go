@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestNewBatchItemProcessor_ReportsEveryProblem(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	_, err := NewBatchItemProcessor[int](
+		nil,
+		"test",
+		log,
+		WithMaxQueueSize(10),
+		WithMaxExportBatchSize(20),
+		WithWorkers(0),
+		WithExportTimeout(-time.Second),
+	)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+
+	want := []string{
+		"max export batch size (20) cannot be greater than max queue size (10)",
+		"workers must be greater than 0, got 0",
+		"export timeout must not be negative, got -1s",
+		"exporter must not be nil",
+	}
+
+	if len(validationErr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), validationErr.Problems)
+	}
+
+	for _, msg := range want {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %q", msg, err.Error())
+		}
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+	o := BatchItemProcessorOptions{
+		MaxQueueSize:       10,
+		MaxExportBatchSize: 5,
+		Workers:            1,
+		PipelineDepth:      1,
+		QueueKind:          QueueKindChannel,
+		WaitStrategy:       WaitStrategyBlock,
+		WriteAcceptance:    WriteAcceptanceAll,
+		DropPolicy:         DropNewest,
+		CancelBehavior:     CancelBehaviorNone,
+		ThroughputWindow:   time.Second,
+	}
+
+	if err := o.Validate(); err != nil {
+		t.Errorf("expected valid options, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"runtime"
+	"time"
+)
+
+// WaitStrategy is how workers wait for batches.
+type WaitStrategy string
+
+const (
+	// WaitStrategyBlock parks workers until a batch arrives.
+	WaitStrategyBlock WaitStrategy = "block"
+	// WaitStrategySpin has workers poll for batches for the spin duration
+	// before parking, trading CPU for a lower wake-up latency.
+	WaitStrategySpin WaitStrategy = "spin"
+)
+
+// DefaultSpinDuration is how long workers spin with WaitStrategySpin.
+const DefaultSpinDuration = 50 * time.Microsecond
+
+// WithWaitStrategy sets how workers wait for batches. With WaitStrategySpin
+// a worker that runs out of batches keeps polling for spin before parking,
+// so back to back batches don't pay for a wake-up. A non-positive spin uses
+// DefaultSpinDuration.
+func WithWaitStrategy(strategy WaitStrategy, spin time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		if spin <= 0 {
+			spin = DefaultSpinDuration
+		}
+
+		o.WaitStrategy = strategy
+		o.SpinDuration = spin
+	}
+}
+
+// spinForBatch polls the worker's channels for a batch for the spin
+// duration.
+func (bvp *BatchItemProcessor[T]) spinForBatch(number int) ([]*TraceableItem[T], bool) {
+	deadline := time.Now().Add(bvp.o.SpinDuration)
+
+	for {
+		select {
+		case batch := <-bvp.batchCh:
+			return batch, true
+		case batch := <-bvp.workerCh(number):
+			return batch, true
+		default:
+		}
+
+		if time.Now().After(deadline) {
+			return nil, false
+		}
+
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBatchItemProcessor_SpinWaitStrategy(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](exporter, "test", log,
+		WithMaxExportBatchSize(10),
+		WithBatchTimeout(time.Hour),
+		WithWorkers(2),
+		WithWaitStrategy(WaitStrategySpin, time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	if err := proc.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	items := make([]*int, 100)
+	for i := range items {
+		items[i] = &i
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for exporter.exportCount.Load() != 100 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 100 items exported, got %d", exporter.exportCount.Load())
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchItemProcessor_SpinForBatch(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	proc, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "test", log,
+		WithWaitStrategy(WaitStrategySpin, 0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if proc.o.SpinDuration != DefaultSpinDuration {
+		t.Fatalf("expected the default spin duration, got %s", proc.o.SpinDuration)
+	}
+
+	if _, ok := proc.spinForBatch(0); ok {
+		t.Fatal("expected spinning on an empty channel to give up")
+	}
+
+	batch := []*TraceableItem[int]{{}}
+	proc.batchCh <- batch
+
+	if got, ok := proc.spinForBatch(0); !ok || len(got) != 1 {
+		t.Fatal("expected spinning to pick up the waiting batch")
+	}
+}
+
+func TestBatchItemProcessorOptions_UnknownWaitStrategy(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	_, err := NewBatchItemProcessor[int](&mockExporter[int]{}, "test", log, WithWaitStrategy("yield", 0))
+	if err == nil {
+		t.Fatal("expected an unknown wait strategy to be rejected")
+	}
+}
@@ -0,0 +1,27 @@
+package processor
+
+import "time"
+
+// WithWorkerMetrics labels export metrics by worker, in worker_exporting and
+// worker_export_duration_seconds, so skew between workers, such as one stuck
+// on a bad connection, is visible. It adds a series per worker, so it is off
+// by default.
+func WithWorkerMetrics(enabled bool) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.WorkerMetrics = enabled
+	}
+}
+
+// workerExportStarted records a worker starting to export a batch.
+func (bvp *BatchItemProcessor[T]) workerExportStarted(worker int) {
+	bvp.metrics.SetWorkerExporting(bvp.label, bvp.workerLabels[worker], true)
+}
+
+// workerExportFinished records a worker finishing a batch it started
+// exporting at start.
+func (bvp *BatchItemProcessor[T]) workerExportFinished(worker int, start time.Time) {
+	label := bvp.workerLabels[worker]
+
+	bvp.metrics.SetWorkerExporting(bvp.label, label, false)
+	bvp.metrics.ObserveWorkerExportDuration(bvp.label, label, time.Since(start))
+}
@@ -0,0 +1,254 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// WithLazyWorkers defers starting workers until batches arrive. The first
+// batch starts one worker and further workers are added, one at a time, while
+// batches are waiting for a free worker, up to Workers.
+func WithLazyWorkers() BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.LazyWorkers = true
+	}
+}
+
+// defaultWorkers derives the worker count used when WithWorkers isn't set: one
+// worker per usable CPU, but no more than the number of full batches the queue
+// can hold, since extra workers could never all be busy.
+func defaultWorkers(maxQueueSize, maxExportBatchSize int) int {
+	workers := runtime.GOMAXPROCS(0)
+
+	if maxExportBatchSize > 0 {
+		workers = min(workers, maxQueueSize/maxExportBatchSize)
+	}
+
+	return max(workers, 1)
+}
+
+// ExporterFactory creates an exporter instance. The argument identifies the
+// instance: the worker number for WithExporterFactory, or a sequence number
+// for ExporterPool.
+type ExporterFactory[T any] func(id int) (ItemExporter[T], error)
+
+// WithExporterFactory gives each worker its own exporter, created by factory
+// when the processor is constructed. Exporters holding connections that aren't
+// safe for concurrent use, such as database sessions or streams, then need no
+// internal locking. The exporter passed to NewBatchItemProcessor is still used
+// for health checks and disk buffer replay. Worker exporters are shut down with
+// the processor.
+func WithExporterFactory[T any](factory ExporterFactory[T]) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.ExporterFactory = factory
+	}
+}
+
+// newWorkerExporters creates one exporter per worker, or none if no factory
+// is configured.
+func newWorkerExporters[T any](factory ExporterFactory[T], workers int) ([]ItemExporter[T], error) {
+	if factory == nil {
+		return nil, nil
+	}
+
+	exporters := make([]ItemExporter[T], 0, workers)
+
+	for i := 0; i < workers; i++ {
+		exporter, err := factory(i)
+		if err == nil && exporter == nil {
+			err = errors.New("factory returned a nil exporter")
+		}
+
+		if err != nil {
+			for _, created := range exporters {
+				//nolint:errcheck // Already failing, the creation error is more useful.
+				created.Shutdown(context.Background())
+			}
+
+			return nil, fmt.Errorf("worker %d: %w", i, err)
+		}
+
+		exporters = append(exporters, exporter)
+	}
+
+	return exporters, nil
+}
+
+// workerExporter returns the exporter the given worker exports with.
+func (bvp *BatchItemProcessor[T]) workerExporter(num int) ItemExporter[T] {
+	if bvp.workerExporters == nil {
+		return bvp.exporter()
+	}
+
+	return bvp.workerExporters[num]
+}
+
+// shutdownWorkerExporters shuts down every worker exporter, returning all
+// errors.
+func (bvp *BatchItemProcessor[T]) shutdownWorkerExporters(ctx context.Context) error {
+	errs := make([]error, 0, len(bvp.workerExporters))
+
+	for i, exporter := range bvp.workerExporters {
+		if err := exporter.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("worker %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WithWorkerIdleTimeout stops workers that haven't received a batch for the
+// given duration. They are started again on demand when batches arrive.
+func WithWorkerIdleTimeout(timeout time.Duration) BatchItemProcessorOption {
+	return func(o *BatchItemProcessorOptions) {
+		o.WorkerIdleTimeout = timeout
+	}
+}
+
+// startWorkers starts the initial workers.
+func (bvp *BatchItemProcessor[T]) startWorkers(ctx context.Context) {
+	bvp.workersMu.Lock()
+	defer bvp.workersMu.Unlock()
+
+	bvp.workerCtx = ctx
+
+	if bvp.stepper != nil {
+		bvp.log.Infof("Exporting batches for %s with a stepper", bvp.name)
+
+		return
+	}
+
+	if bvp.o.LazyWorkers {
+		bvp.log.Infof("Starting up to %d workers on demand for %s", bvp.o.Workers, bvp.name)
+
+		return
+	}
+
+	bvp.log.Infof("Starting %d workers for %s", bvp.o.Workers, bvp.name)
+
+	for i := 0; i < bvp.o.Workers; i++ {
+		bvp.startWorkerLocked(i)
+	}
+}
+
+// ensureWorkers starts a worker if a batch was just sent and nobody will pick
+// it up. A target of 0 or more requires that specific worker to be running,
+// for batches routed to it; otherwise one more worker is started if there are
+// none, or if batches are waiting and the pool isn't full. With a resizable
+// ring, a running target also lets the pool grow, since the ring only routes
+// keys to running workers.
+func (bvp *BatchItemProcessor[T]) ensureWorkers(target int) {
+	bvp.workersMu.Lock()
+	defer bvp.workersMu.Unlock()
+
+	if bvp.workersStopped {
+		return
+	}
+
+	if target >= 0 {
+		if !bvp.workerRunning[target] {
+			bvp.startWorkerLocked(target)
+
+			return
+		}
+
+		if !resizableRing(&bvp.o) {
+			return
+		}
+	}
+
+	if bvp.activeWorkers > 0 && (bvp.activeWorkers >= bvp.o.Workers || bvp.batchesPending() == 0) {
+		return
+	}
+
+	for i, running := range bvp.workerRunning {
+		if !running {
+			bvp.startWorkerLocked(i)
+
+			return
+		}
+	}
+}
+
+// stopStartingWorkers prevents further workers from starting, so Shutdown
+// can wait for the running ones.
+func (bvp *BatchItemProcessor[T]) stopStartingWorkers() {
+	bvp.workersMu.Lock()
+	defer bvp.workersMu.Unlock()
+
+	bvp.workersStopped = true
+}
+
+// startWorkerLocked starts the worker in the given slot. The caller must hold
+// bvp.workersMu.
+func (bvp *BatchItemProcessor[T]) startWorkerLocked(num int) {
+	bvp.workerRunning[num] = true
+	bvp.activeWorkers++
+	bvp.rebuildRingLocked()
+
+	bvp.metrics.SetWorkerCount(bvp.label, float64(bvp.activeWorkers))
+	bvp.publish(EventWorkerStarted, Event{Worker: num})
+
+	bvp.stopWait.Add(1)
+
+	go func() {
+		defer bvp.stopWait.Done()
+
+		bvp.worker(bvp.workerCtx, num)
+	}()
+}
+
+// retireWorker stops an idle worker unless batches are waiting for it, in
+// which case it must keep running.
+func (bvp *BatchItemProcessor[T]) retireWorker(num int) bool {
+	bvp.workersMu.Lock()
+	defer bvp.workersMu.Unlock()
+
+	// Batches are sent before ensureWorkers runs, so checking for waiting
+	// batches under the lock guarantees none is left without a worker.
+	if len(bvp.batchCh) > 0 || len(bvp.workerCh(num)) > 0 {
+		return false
+	}
+
+	bvp.workerExitedLocked(num)
+
+	return true
+}
+
+func (bvp *BatchItemProcessor[T]) workerExited(num int) {
+	bvp.workersMu.Lock()
+	defer bvp.workersMu.Unlock()
+
+	bvp.workerExitedLocked(num)
+}
+
+// workerExitedLocked frees the worker's slot. The caller must hold
+// bvp.workersMu.
+func (bvp *BatchItemProcessor[T]) workerExitedLocked(num int) {
+	bvp.workerRunning[num] = false
+	bvp.activeWorkers--
+	bvp.rebuildRingLocked()
+
+	bvp.metrics.SetWorkerCount(bvp.label, float64(bvp.activeWorkers))
+	bvp.publish(EventWorkerStopped, Event{Worker: num})
+}
+
+// resetTimer stops, drains and resets a timer that may have already fired.
+func resetTimer(t *time.Timer, d time.Duration) {
+	stopTimer(t)
+
+	t.Reset(d)
+}
+
+// stopTimer stops a timer and drains it if it already fired.
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func (bvp *BatchItemProcessor[T]) runningWorkers() int {
+	bvp.workersMu.Lock()
+	defer bvp.workersMu.Unlock()
+
+	return bvp.activeWorkers
+}
+
+func TestBatchItemProcessor_LazyWorkers(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{exportDelay: 20 * time.Millisecond}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(1000),
+		WithMaxExportBatchSize(1),
+		WithBatchTimeout(10*time.Millisecond),
+		WithWorkers(4),
+		WithLazyWorkers(),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	if got := proc.runningWorkers(); got != 0 {
+		t.Fatalf("expected no workers before items arrive, got %d", got)
+	}
+
+	items := make([]*int, 20)
+	for i := range items {
+		val := i
+		items[i] = &val
+	}
+
+	if err := proc.Write(ctx, items); err != nil {
+		t.Fatalf("failed to write items: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	// Slow exports leave batches waiting, so the pool ramps up.
+	if got := proc.runningWorkers(); got < 2 || got > 4 {
+		t.Errorf("expected the pool to ramp up to between 2 and 4 workers, got %d", got)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if exporter.exportCount.Load() != 20 {
+		t.Errorf("expected 20 items exported, got %d", exporter.exportCount.Load())
+	}
+}
+
+func TestBatchItemProcessor_WorkerIdleTimeout(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(1),
+		WithShippingMethod(ShippingMethodSync),
+		WithWorkers(2),
+		WithWorkerIdleTimeout(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if got := proc.runningWorkers(); got != 0 {
+		t.Fatalf("expected idle workers to stop, got %d running", got)
+	}
+
+	// Workers are started again on demand.
+	for i := 0; i < 3; i++ {
+		val := i
+		if err := proc.Write(ctx, []*int{&val}); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+
+	if exporter.exportCount.Load() != 3 {
+		t.Errorf("expected 3 items exported, got %d", exporter.exportCount.Load())
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+}
+
+// shutdownExporter records whether it was shut down.
+type shutdownExporter[T any] struct {
+	mockExporter[T]
+	shutdown atomic.Bool
+}
+
+func (e *shutdownExporter[T]) Shutdown(_ context.Context) error {
+	e.shutdown.Store(true)
+
+	return nil
+}
+
+func TestBatchItemProcessor_ExporterFactory(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	exporter := &mockExporter[int]{}
+	workerExporters := make([]*shutdownExporter[int], 0, 2)
+
+	proc, err := NewBatchItemProcessor[int](
+		exporter,
+		"test",
+		log,
+		WithMaxQueueSize(100),
+		WithMaxExportBatchSize(1),
+		WithShippingMethod(ShippingMethodSync),
+		WithWorkers(2),
+		WithExporterFactory(func(worker int) (ItemExporter[int], error) {
+			e := &shutdownExporter[int]{}
+			workerExporters = append(workerExporters, e)
+
+			return e, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create processor: %v", err)
+	}
+
+	if len(workerExporters) != 2 {
+		t.Fatalf("expected 2 worker exporters, got %d", len(workerExporters))
+	}
+
+	ctx := context.Background()
+	proc.Start(ctx)
+
+	for i := 0; i < 10; i++ {
+		val := i
+		if err := proc.Write(ctx, []*int{&val}); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+
+	if err := proc.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shutdown: %v", err)
+	}
+
+	if exporter.exportCount.Load() != 0 {
+		t.Errorf("expected no items exported by the shared exporter, got %d", exporter.exportCount.Load())
+	}
+
+	var exported int64
+
+	for i, e := range workerExporters {
+		exported += e.exportCount.Load()
+
+		if !e.shutdown.Load() {
+			t.Errorf("worker %d: expected exporter to be shut down", i)
+		}
+	}
+
+	if exported != 10 {
+		t.Errorf("expected 10 items exported by worker exporters, got %d", exported)
+	}
+}
+
+func TestBatchItemProcessor_ExporterFactoryError(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.ErrorLevel)
+
+	created := &shutdownExporter[int]{}
+
+	_, err := NewBatchItemProcessor[int](
+		&mockExporter[int]{},
+		"test",
+		log,
+		WithWorkers(2),
+		WithExporterFactory(func(worker int) (ItemExporter[int], error) {
+			if worker == 1 {
+				return nil, errors.New("connection refused")
+			}
+
+			return created, nil
+		}),
+	)
+	if err == nil {
+		t.Fatal("expected factory error")
+	}
+
+	if !created.shutdown.Load() {
+		t.Error("expected exporters created before the error to be shut down")
+	}
+}